@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -14,23 +15,46 @@ import (
 	"github.com/joho/godotenv"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/nobuww/simpel-ktp/internal/nik"
 	"github.com/nobuww/simpel-ktp/internal/store"
 	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
 )
 
+type kecamatanSeed struct {
+	Nama        string
+	Kota        string
+	KodeWilayah string
+	Alamat      string
+}
+
 type kelurahanSeed struct {
 	Nama     string
 	KodeArea string
+	Alamat   string
 }
 
 type petugasSeed struct {
 	NIP         string
 	Nama        string
 	Username    string
+	Email       string
 	Password    string
+	KecamatanID *int16
 	KelurahanID *int16
 }
 
+// role mirrors the set_petugas_role trigger
+func (p petugasSeed) role() string {
+	switch {
+	case p.KelurahanID != nil:
+		return "ADMIN_KELURAHAN"
+	case p.KecamatanID != nil:
+		return "ADMIN_KECAMATAN"
+	default:
+		return "ADMIN_KOTA"
+	}
+}
+
 type pendudukSeed struct {
 	NIK          string
 	Nama         string
@@ -83,10 +107,36 @@ func main() {
 		fmt.Println("Reset completed — database cleaned for seeding.")
 	}
 
+	kecamatan := kecamatanSeed{
+		Nama:        "Pademangan",
+		Kota:        "Jakarta Utara",
+		KodeWilayah: "317205",
+		Alamat:      "Jl. Budi Mulia No. 1, Pademangan",
+	}
+
 	kelurahanList := []kelurahanSeed{
-		{Nama: "Pademangan Barat", KodeArea: "PMB"},
-		{Nama: "Pademangan Timur", KodeArea: "PMT"},
-		{Nama: "Ancol", KodeArea: "ACL"},
+		{Nama: "Pademangan Barat", KodeArea: "PMB", Alamat: "Jl. Pademangan II No. 1"},
+		{Nama: "Pademangan Timur", KodeArea: "PMT", Alamat: "Jl. Pademangan Timur VII No. 2"},
+		{Nama: "Ancol", KodeArea: "ACL", Alamat: "Jl. Lodan Raya No. 2"},
+	}
+
+	fmt.Println("\nSeeding Kecamatan...")
+	kecamatanRow, err := s.GetKecamatanByKodeWilayah(ctx, kecamatan.KodeWilayah)
+	if err == nil {
+		fmt.Printf("   ✓ Kecamatan %s already exists (ID: %d)\n", kecamatanRow.NamaKecamatan, kecamatanRow.ID)
+	} else {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Fatalf("Failed to query kecamatan %s: %v", kecamatan.KodeWilayah, err)
+		}
+		kecamatanRow, err = s.CreateKecamatan(ctx, pg_store.CreateKecamatanParams{
+			NamaKecamatan: kecamatan.Nama,
+			NamaKota:      kecamatan.Kota,
+			KodeWilayah:   kecamatan.KodeWilayah,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create kecamatan %s: %v", kecamatan.Nama, err)
+		}
+		fmt.Printf("   ✓ Created kecamatan: %s (ID: %d, Kode: %s)\n", kecamatanRow.NamaKecamatan, kecamatanRow.ID, kecamatanRow.KodeWilayah)
 	}
 
 	kelurahanIDs := make(map[string]int16)
@@ -105,6 +155,7 @@ func main() {
 		}
 
 		created, err := s.CreateKelurahan(ctx, pg_store.CreateKelurahanParams{
+			KecamatanID:   kecamatanRow.ID,
 			NamaKelurahan: k.Nama,
 			KodeArea:      k.KodeArea,
 		})
@@ -115,18 +166,47 @@ func main() {
 		kelurahanIDs[k.KodeArea] = created.ID
 	}
 
+	fmt.Println("\nSeeding Lokasi Layanan...")
+	seedLokasi(ctx, s, pg_store.CreateLokasiLayananParams{
+		KecamatanID: kecamatanRow.ID,
+		Kode:        kodeLokasiKecamatan(kecamatanRow.KodeWilayah),
+		NamaLokasi:  "Kantor Kecamatan " + kecamatan.Nama,
+		Alamat:      pgtype.Text{String: kecamatan.Alamat, Valid: true},
+	})
+	for _, k := range kelurahanList {
+		seedLokasi(ctx, s, pg_store.CreateLokasiLayananParams{
+			KecamatanID: kecamatanRow.ID,
+			KelurahanID: pgtype.Int2{Int16: kelurahanIDs[k.KodeArea], Valid: true},
+			Kode:        k.KodeArea,
+			NamaLokasi:  "Kantor Kelurahan " + k.Nama,
+			Alamat:      pgtype.Text{String: k.Alamat, Valid: true},
+		})
+	}
+
 	petugasList := []petugasSeed{
+		{
+			NIP:         "197812032005011001",
+			Nama:        "Rudi Hartono",
+			Username:    "admin.disdukcapil",
+			Email:       "admin.disdukcapil@example.com",
+			Password:    "admin123",
+			KecamatanID: nil,
+			KelurahanID: nil,
+		},
 		{
 			NIP:         "198501152010011001",
 			Nama:        "Budi Santoso",
 			Username:    "admin.kecamatan",
+			Email:       "admin.kecamatan@example.com",
 			Password:    "admin123",
+			KecamatanID: ptr(kecamatanRow.ID),
 			KelurahanID: nil,
 		},
 		{
 			NIP:         "199003202015012001",
 			Nama:        "Siti Rahayu",
 			Username:    "admin.pademanganbarat",
+			Email:       "admin.pademanganbarat@example.com",
 			Password:    "admin123",
 			KelurahanID: ptr(kelurahanIDs["PMB"]),
 		},
@@ -134,6 +214,7 @@ func main() {
 			NIP:         "198807112012011002",
 			Nama:        "Ahmad Hidayat",
 			Username:    "admin.pademangantimur",
+			Email:       "admin.pademangantimur@example.com",
 			Password:    "admin123",
 			KelurahanID: ptr(kelurahanIDs["PMT"]),
 		},
@@ -141,6 +222,7 @@ func main() {
 			NIP:         "199205182018012003",
 			Nama:        "Dewi Lestari",
 			Username:    "admin.ancol",
+			Email:       "admin.ancol@example.com",
 			Password:    "admin123",
 			KelurahanID: ptr(kelurahanIDs["ACL"]),
 		},
@@ -166,10 +248,14 @@ func main() {
 			NamaPetugas:  p.Nama,
 			Username:     p.Username,
 			PasswordHash: string(hashedPassword),
+			Email:        pgtype.Text{String: p.Email, Valid: p.Email != ""},
 		}
 
 		params.Nip = pgtype.Text{String: p.NIP, Valid: true}
 
+		if p.KecamatanID != nil {
+			params.KecamatanID = pgtype.Int2{Int16: *p.KecamatanID, Valid: true}
+		}
 		if p.KelurahanID != nil {
 			if *p.KelurahanID == 0 {
 				log.Fatalf("Invalid kelurahan ID for petugas %s: id is 0 — check kelurahan mapping", p.Username)
@@ -182,41 +268,33 @@ func main() {
 			log.Fatalf("Failed to create petugas %s: %v", p.Username, err)
 		}
 
-		role := "ADMIN_KECAMATAN"
-		if p.KelurahanID != nil {
-			role = "ADMIN_KELURAHAN"
-		}
-		fmt.Printf("   ✓ Created petugas: %s (%s) - Role: %s\n", created.NamaPetugas, created.Username, role)
+		fmt.Printf("   ✓ Created petugas: %s (%s) - Role: %s\n", created.NamaPetugas, created.Username, created.Role)
 	}
 
 	fmt.Println("\nDatabase seeding completed!")
 	fmt.Println("\nLogin Credentials:")
 	fmt.Println("   ─────────────────────────────────────────")
 	for _, p := range petugasList {
-		role := "ADMIN_KECAMATAN"
-		if p.KelurahanID != nil {
-			role = "ADMIN_KELURAHAN"
-		}
-		fmt.Printf("   Username: %-25s Password: %s (%s)\n", p.Username, p.Password, role)
+		fmt.Printf("   Username: %-25s Password: %s (%s)\n", p.Username, p.Password, p.role())
 	}
 
 	// Data Penduduk (15 entries)
 	pendudukList := []pendudukSeed{
-		{NIK: "3172010101800001", Nama: "Ahmad Supriyadi", Email: "ahmad.supriyadi@example.com", Alamat: "Jl. Pademangan II Gg. 2 No. 10", NoHP: "081234567890", JenisKelamin: "LAKI_LAKI", KodeArea: "PMB"},
-		{NIK: "3172010101850002", Nama: "Siti Aminah", Email: "siti.aminah@example.com", Alamat: "Jl. Pademangan III No. 5", NoHP: "081234567891", JenisKelamin: "PEREMPUAN", KodeArea: "PMB"},
-		{NIK: "3172010101900003", Nama: "Budi Harsono", Email: "budi.harsono@example.com", Alamat: "Jl. Pademangan IV No. 12", NoHP: "081234567892", JenisKelamin: "LAKI_LAKI", KodeArea: "PMB"},
-		{NIK: "3172010101950004", Nama: "Dewi Kartika", Email: "dewi.kartika@example.com", Alamat: "Jl. Budi Mulia No. 8", NoHP: "081234567893", JenisKelamin: "PEREMPUAN", KodeArea: "PMB"},
-		{NIK: "3172010101880005", Nama: "Eko Prasetyo", Email: "eko.prasetyo@example.com", Alamat: "Jl. Hidup Baru No. 3", NoHP: "081234567894", JenisKelamin: "LAKI_LAKI", KodeArea: "PMB"},
-		{NIK: "3172020202820001", Nama: "Fajar Nugraha", Email: "fajar.nugraha@example.com", Alamat: "Jl. Pademangan Timur VIII No. 20", NoHP: "081234567895", JenisKelamin: "LAKI_LAKI", KodeArea: "PMT"},
-		{NIK: "3172020202870002", Nama: "Gita Permata", Email: "gita.permata@example.com", Alamat: "Jl. Pademangan Timur IX No. 15", NoHP: "081234567896", JenisKelamin: "PEREMPUAN", KodeArea: "PMT"},
-		{NIK: "3172020202920003", Nama: "Hendra Wijaya", Email: "hendra.wijaya@example.com", Alamat: "Jl. Pademangan Timur X No. 7", NoHP: "081234567897", JenisKelamin: "LAKI_LAKI", KodeArea: "PMT"},
-		{NIK: "3172020202970004", Nama: "Indah Sari", Email: "indah.sari@example.com", Alamat: "Jl. Pademangan Timur XI No. 4", NoHP: "081234567898", JenisKelamin: "PEREMPUAN", KodeArea: "PMT"},
-		{NIK: "3172020202850005", Nama: "Joko Susilo", Email: "joko.susilo@example.com", Alamat: "Jl. Pademangan Timur XII No. 9", NoHP: "081234567899", JenisKelamin: "LAKI_LAKI", KodeArea: "PMT"},
-		{NIK: "3172030303830001", Nama: "Kartini Putri", Email: "kartini.putri@example.com", Alamat: "Jl. Lodan Raya No. 10", NoHP: "081234567900", JenisKelamin: "PEREMPUAN", KodeArea: "ACL"},
-		{NIK: "3172030303880002", Nama: "Lukman Hakim", Email: "lukman.hakim@example.com", Alamat: "Jl. Pasir Putih No. 5", NoHP: "081234567901", JenisKelamin: "LAKI_LAKI", KodeArea: "ACL"},
-		{NIK: "3172030303930003", Nama: "Maya Puspita", Email: "maya.puspita@example.com", Alamat: "Jl. Pantai Indah No. 12", NoHP: "081234567902", JenisKelamin: "PEREMPUAN", KodeArea: "ACL"},
-		{NIK: "3172030303980004", Nama: "Nurhadi Surya", Email: "nurhadi.surya@example.com", Alamat: "Jl. Ancol Barat No. 8", NoHP: "081234567903", JenisKelamin: "LAKI_LAKI", KodeArea: "ACL"},
-		{NIK: "3172030303860005", Nama: "Oki Pradana", Email: "oki.pradana@example.com", Alamat: "Jl. Ancol Timur No. 3", NoHP: "081234567904", JenisKelamin: "LAKI_LAKI", KodeArea: "ACL"},
+		{NIK: "3172050101800001", Nama: "Ahmad Supriyadi", Email: "ahmad.supriyadi@example.com", Alamat: "Jl. Pademangan II Gg. 2 No. 10", NoHP: "081234567890", JenisKelamin: "LAKI_LAKI", KodeArea: "PMB"},
+		{NIK: "3172054101850002", Nama: "Siti Aminah", Email: "siti.aminah@example.com", Alamat: "Jl. Pademangan III No. 5", NoHP: "081234567891", JenisKelamin: "PEREMPUAN", KodeArea: "PMB"},
+		{NIK: "3172050101900003", Nama: "Budi Harsono", Email: "budi.harsono@example.com", Alamat: "Jl. Pademangan IV No. 12", NoHP: "081234567892", JenisKelamin: "LAKI_LAKI", KodeArea: "PMB"},
+		{NIK: "3172054101950004", Nama: "Dewi Kartika", Email: "dewi.kartika@example.com", Alamat: "Jl. Budi Mulia No. 8", NoHP: "081234567893", JenisKelamin: "PEREMPUAN", KodeArea: "PMB"},
+		{NIK: "3172050101880005", Nama: "Eko Prasetyo", Email: "eko.prasetyo@example.com", Alamat: "Jl. Hidup Baru No. 3", NoHP: "081234567894", JenisKelamin: "LAKI_LAKI", KodeArea: "PMB"},
+		{NIK: "3172050202820001", Nama: "Fajar Nugraha", Email: "fajar.nugraha@example.com", Alamat: "Jl. Pademangan Timur VIII No. 20", NoHP: "081234567895", JenisKelamin: "LAKI_LAKI", KodeArea: "PMT"},
+		{NIK: "3172054202870002", Nama: "Gita Permata", Email: "gita.permata@example.com", Alamat: "Jl. Pademangan Timur IX No. 15", NoHP: "081234567896", JenisKelamin: "PEREMPUAN", KodeArea: "PMT"},
+		{NIK: "3172050202920003", Nama: "Hendra Wijaya", Email: "hendra.wijaya@example.com", Alamat: "Jl. Pademangan Timur X No. 7", NoHP: "081234567897", JenisKelamin: "LAKI_LAKI", KodeArea: "PMT"},
+		{NIK: "3172054202970004", Nama: "Indah Sari", Email: "indah.sari@example.com", Alamat: "Jl. Pademangan Timur XI No. 4", NoHP: "081234567898", JenisKelamin: "PEREMPUAN", KodeArea: "PMT"},
+		{NIK: "3172050202850005", Nama: "Joko Susilo", Email: "joko.susilo@example.com", Alamat: "Jl. Pademangan Timur XII No. 9", NoHP: "081234567899", JenisKelamin: "LAKI_LAKI", KodeArea: "PMT"},
+		{NIK: "3172054303830001", Nama: "Kartini Putri", Email: "kartini.putri@example.com", Alamat: "Jl. Lodan Raya No. 10", NoHP: "081234567900", JenisKelamin: "PEREMPUAN", KodeArea: "ACL"},
+		{NIK: "3172050303880002", Nama: "Lukman Hakim", Email: "lukman.hakim@example.com", Alamat: "Jl. Pasir Putih No. 5", NoHP: "081234567901", JenisKelamin: "LAKI_LAKI", KodeArea: "ACL"},
+		{NIK: "3172054303930003", Nama: "Maya Puspita", Email: "maya.puspita@example.com", Alamat: "Jl. Pantai Indah No. 12", NoHP: "081234567902", JenisKelamin: "PEREMPUAN", KodeArea: "ACL"},
+		{NIK: "3172050303980004", Nama: "Nurhadi Surya", Email: "nurhadi.surya@example.com", Alamat: "Jl. Ancol Barat No. 8", NoHP: "081234567903", JenisKelamin: "LAKI_LAKI", KodeArea: "ACL"},
+		{NIK: "3172050303860005", Nama: "Oki Pradana", Email: "oki.pradana@example.com", Alamat: "Jl. Ancol Timur No. 3", NoHP: "081234567904", JenisKelamin: "LAKI_LAKI", KodeArea: "ACL"},
 	}
 
 	fmt.Println("\n👥 Seeding Penduduk...")
@@ -241,15 +319,26 @@ func main() {
 			log.Fatalf("Kode area %s not found for penduduk %s", p.KodeArea, p.Nama)
 		}
 
+		// Seed data must pass the same NIK checks as registration
+		parsed, err := nik.Parse(p.NIK, time.Now())
+		if err == nil {
+			err = parsed.Check(p.JenisKelamin)
+		}
+		if err != nil {
+			log.Fatalf("Invalid NIK %s for penduduk %s: %v", p.NIK, p.Nama, err)
+		}
+
 		created, err := s.CreatePenduduk(ctx, pg_store.CreatePendudukParams{
-			Nik:          p.NIK,
-			KelurahanID:  pgtype.Int2{Int16: kelID, Valid: true},
-			Email:        pgtype.Text{String: p.Email, Valid: true},
-			PasswordHash: pgtype.Text{String: string(hashedPendudukPassword), Valid: true},
-			NamaLengkap:  p.Nama,
-			Alamat:       pgtype.Text{String: p.Alamat, Valid: true},
-			NoHp:         pgtype.Text{String: p.NoHP, Valid: true},
-			JenisKelamin: p.JenisKelamin,
+			Nik:              p.NIK,
+			KelurahanID:      pgtype.Int2{Int16: kelID, Valid: true},
+			Email:            pgtype.Text{String: p.Email, Valid: true},
+			PasswordHash:     pgtype.Text{String: string(hashedPendudukPassword), Valid: true},
+			NamaLengkap:      p.Nama,
+			Alamat:           pgtype.Text{String: p.Alamat, Valid: true},
+			NoHp:             pgtype.Text{String: p.NoHP, Valid: true},
+			JenisKelamin:     p.JenisKelamin,
+			TanggalLahir:     pgtype.Date{Time: parsed.TanggalLahir, Valid: true},
+			StatusPerkawinan: "BELUM_KAWIN",
 		})
 		if err != nil {
 			log.Fatalf("Failed to create penduduk %s: %v", p.Nama, err)
@@ -258,11 +347,122 @@ func main() {
 		fmt.Printf("   ✓ Created penduduk: %s (%s) - %s\n", created.NamaLengkap, created.Nik, p.KodeArea)
 	}
 
+	// Ahmad and Siti share a Kartu Keluarga with a son who has no account, so
+	// applying on behalf of a household member can be tried out
+	fmt.Println("\n🏠 Seeding Kartu Keluarga...")
+	seedKeluarga(ctx, s, "3172050101100001", kelurahanIDs["PMB"], "Jl. Pademangan II Gg. 2 No. 10", []anggotaSeed{
+		{NIK: "3172050101800001", Hubungan: "KEPALA_KELUARGA"},
+		{NIK: "3172054101850002", Hubungan: "ISTRI"},
+		{NIK: "3172050101090006", Nama: "Rizki Supriyadi", JenisKelamin: "LAKI_LAKI", Hubungan: "ANAK"},
+	})
+
 	fmt.Println("\nPenduduk Credentials (All):")
 	fmt.Println("   Password: rahasia123")
 	fmt.Println("   ─────────────────────────────────────────")
 }
 
+// anggotaSeed is a KK member. Members with a Nama are created without an
+// account; the others must already exist.
+type anggotaSeed struct {
+	NIK          string
+	Nama         string
+	JenisKelamin string
+	Hubungan     string
+}
+
+func seedKeluarga(ctx context.Context, s *store.Store, noKK string, kelurahanID int16, alamat string, anggota []anggotaSeed) {
+	if _, err := s.GetKartuKeluarga(ctx, noKK); err == nil {
+		fmt.Printf("   ✓ Kartu Keluarga %s already exists\n", noKK)
+		return
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Fatalf("Failed to query kartu keluarga %s: %v", noKK, err)
+	}
+
+	err := s.ExecTx(ctx, func(q *pg_store.Queries) error {
+		if err := q.CreateKartuKeluarga(ctx, pg_store.CreateKartuKeluargaParams{
+			NoKk:        noKK,
+			KelurahanID: pgtype.Int2{Int16: kelurahanID, Valid: true},
+			Alamat:      pgtype.Text{String: alamat, Valid: true},
+		}); err != nil {
+			return err
+		}
+		for _, a := range anggota {
+			kk := pgtype.Text{String: noKK, Valid: true}
+			hubungan := pgtype.Text{String: a.Hubungan, Valid: true}
+			if a.Nama == "" {
+				if err := q.SetKeluargaPenduduk(ctx, pg_store.SetKeluargaPendudukParams{
+					Nik:              a.NIK,
+					NoKk:             kk,
+					HubunganKeluarga: hubungan,
+				}); err != nil {
+					return err
+				}
+				// Seeded guardians count as verified; the query leaves
+				// other members untouched
+				if _, err := q.ApproveWaliPenduduk(ctx, pg_store.ApproveWaliPendudukParams{Nik: a.NIK}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			parsed, err := nik.Parse(a.NIK, time.Now())
+			if err == nil {
+				err = parsed.Check(a.JenisKelamin)
+			}
+			if err != nil {
+				return fmt.Errorf("invalid NIK %s: %w", a.NIK, err)
+			}
+			if err := q.CreateAnggotaKeluarga(ctx, pg_store.CreateAnggotaKeluargaParams{
+				Nik:              a.NIK,
+				KelurahanID:      pgtype.Int2{Int16: kelurahanID, Valid: true},
+				NamaLengkap:      a.Nama,
+				Alamat:           pgtype.Text{String: alamat, Valid: true},
+				JenisKelamin:     a.JenisKelamin,
+				TanggalLahir:     pgtype.Date{Time: parsed.TanggalLahir, Valid: true},
+				StatusPerkawinan: "BELUM_KAWIN",
+				NoKk:             kk,
+				HubunganKeluarga: hubungan,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to create kartu keluarga %s: %v", noKK, err)
+	}
+	fmt.Printf("   ✓ Created Kartu Keluarga %s with %d anggota\n", noKK, len(anggota))
+}
+
 func ptr(v int16) *int16 {
 	return &v
 }
+
+// kodeLokasiKecamatan derives the booking code prefix of a kantor kecamatan
+// from the kecamatan digits of its Kemendagri code, e.g. K05 for 317205.
+// lokasi_layanan.kode is unique, and kelurahan codes are letters only.
+func kodeLokasiKecamatan(kodeWilayah string) string {
+	return "K" + kodeWilayah[4:6]
+}
+
+func seedLokasi(ctx context.Context, s *store.Store, arg pg_store.CreateLokasiLayananParams) {
+	existing, err := s.GetLokasiLayananByWilayah(ctx, pg_store.GetLokasiLayananByWilayahParams{
+		KecamatanID: arg.KecamatanID,
+		KelurahanID: arg.KelurahanID,
+	})
+	if err == nil {
+		fmt.Printf("   ✓ Lokasi %s already exists (ID: %d)\n", existing.NamaLokasi, existing.ID)
+		return
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		log.Fatalf("Failed to query lokasi %s: %v", arg.Kode, err)
+	}
+
+	arg.JamBuka = pgtype.Time{Microseconds: 8 * 3600 * 1e6, Valid: true}
+	arg.JamTutup = pgtype.Time{Microseconds: 15 * 3600 * 1e6, Valid: true}
+	created, err := s.CreateLokasiLayanan(ctx, arg)
+	if err != nil {
+		log.Fatalf("Failed to create lokasi %s: %v", arg.NamaLokasi, err)
+	}
+	fmt.Printf("   ✓ Created lokasi: %s (ID: %d, Kode: %s)\n", created.NamaLokasi, created.ID, created.Kode)
+}
@@ -5,13 +5,18 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/notify"
 	"github.com/nobuww/simpel-ktp/internal/router"
 	"github.com/nobuww/simpel-ktp/internal/session"
+	"github.com/nobuww/simpel-ktp/internal/sla"
 	"github.com/nobuww/simpel-ktp/internal/store"
+	"github.com/nobuww/simpel-ktp/internal/tiket"
 	"github.com/nobuww/simpel-ktp/internal/vite"
 )
 
@@ -43,9 +48,15 @@ func main() {
 	queryStore := store.New(dbPool)
 
 	// Initialize session manager
-	sessionMgr := session.New(os.Getenv("SESSION_SECRET"))
+	sessionMgr := session.New(queryStore)
+	clk := clock.New()
+	notifier := notify.New()
 
-	r := router.New(queryStore, sessionMgr)
+	go purgeExpiredSessions(sessionMgr)
+	go refreshLaporan(queryStore)
+	go sla.NewDigest(queryStore, notifier, clk).Run()
+
+	r := router.New(queryStore, sessionMgr, clk, notifier, tiket.New())
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -57,3 +68,34 @@ func main() {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
+
+// purgeExpiredSessions deletes expired session rows once an hour
+func purgeExpiredSessions(sm *session.Manager) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		if err := sm.PurgeExpired(context.Background()); err != nil {
+			log.Printf("Failed to purge expired sessions: %v", err)
+		}
+	}
+}
+
+// refreshLaporan rebuilds the report views once an hour. Each refresh runs
+// concurrently, so the reports page keeps reading the previous data meanwhile.
+func refreshLaporan(s *store.Store) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		ctx := context.Background()
+		for name, refresh := range map[string]func(context.Context) error{
+			"permohonan": s.RefreshLaporanPermohonan,
+			"durasi":     s.RefreshLaporanDurasi,
+			"penolakan":  s.RefreshLaporanPenolakan,
+			"sesi":       s.RefreshLaporanSesi,
+		} {
+			if err := refresh(ctx); err != nil {
+				log.Printf("Failed to refresh laporan %s: %v", name, err)
+			}
+		}
+	}
+}
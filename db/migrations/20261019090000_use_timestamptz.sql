@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Existing values were written by a server running in UTC
+ALTER TABLE ref_kelurahan ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE penduduk ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE petugas ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE permohonan ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE dokumen_syarat ALTER COLUMN uploaded_at TYPE TIMESTAMPTZ USING uploaded_at AT TIME ZONE 'UTC';
+ALTER TABLE riwayat_status ALTER COLUMN waktu_proses TYPE TIMESTAMPTZ USING waktu_proses AT TIME ZONE 'UTC';
+
+-- jadwal_sesi.tanggal, jam_mulai and jam_selesai stay as DATE/TIME:
+-- they are wall-clock values at the office (Asia/Jakarta), not instants.
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE riwayat_status ALTER COLUMN waktu_proses TYPE TIMESTAMP USING waktu_proses AT TIME ZONE 'UTC';
+ALTER TABLE dokumen_syarat ALTER COLUMN uploaded_at TYPE TIMESTAMP USING uploaded_at AT TIME ZONE 'UTC';
+ALTER TABLE permohonan ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE petugas ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE penduduk ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE ref_kelurahan ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+-- +goose StatementEnd
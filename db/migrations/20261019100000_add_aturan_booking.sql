@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Table: aturan_booking
+-- Per-location booking rules. lokasi_kelurahan_id NULL is the kantor kecamatan.
+CREATE TABLE aturan_booking (
+    id SMALLSERIAL PRIMARY KEY,
+    lokasi_kelurahan_id SMALLINT REFERENCES ref_kelurahan(id) ON DELETE CASCADE,
+    min_jam_pemberitahuan SMALLINT NOT NULL DEFAULT 0,
+    maks_hari_ke_depan SMALLINT NOT NULL DEFAULT 30,
+    batas_jam_hari_sama TIME, -- NULL: same-day booking is not allowed
+    maks_booking_per_bulan SMALLINT, -- NULL: unlimited
+    updated_by UUID REFERENCES petugas(id),
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_min_jam_pemberitahuan CHECK (min_jam_pemberitahuan >= 0),
+    CONSTRAINT chk_maks_hari_ke_depan CHECK (maks_hari_ke_depan >= 1),
+    CONSTRAINT chk_maks_booking_per_bulan CHECK (maks_booking_per_bulan IS NULL OR maks_booking_per_bulan >= 1)
+);
+
+-- One row per location, treating the kecamatan (NULL) as a single location
+CREATE UNIQUE INDEX idx_aturan_booking_lokasi ON aturan_booking ((COALESCE(lokasi_kelurahan_id, 0)));
+
+CREATE INDEX idx_permohonan_nik_created ON permohonan(nik, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_permohonan_nik_created;
+DROP TABLE IF EXISTS aturan_booking;
+-- +goose StatementEnd
@@ -0,0 +1,184 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Table: ref_kecamatan
+CREATE TABLE ref_kecamatan (
+    id SMALLSERIAL PRIMARY KEY,
+    nama_kecamatan TEXT NOT NULL,
+    nama_kota TEXT NOT NULL,
+    kode_wilayah CHAR(6) NOT NULL UNIQUE, -- Kemendagri code, e.g. 317205
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE ref_kelurahan ADD COLUMN kecamatan_id SMALLINT REFERENCES ref_kecamatan(id);
+
+-- Table: lokasi_layanan
+-- A place where sessions are held: the kantor kecamatan (kelurahan_id NULL)
+-- or a kantor kelurahan.
+CREATE TABLE lokasi_layanan (
+    id SMALLSERIAL PRIMARY KEY,
+    kecamatan_id SMALLINT NOT NULL REFERENCES ref_kecamatan(id),
+    kelurahan_id SMALLINT UNIQUE REFERENCES ref_kelurahan(id),
+    kode CHAR(3) NOT NULL UNIQUE, -- Prefix of kode_booking
+    nama_lokasi TEXT NOT NULL,
+    alamat TEXT,
+    jam_buka TIME NOT NULL DEFAULT '08:00',
+    jam_tutup TIME NOT NULL DEFAULT '15:00',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_jam_operasional CHECK (jam_buka < jam_tutup)
+);
+
+-- Only one kantor kecamatan per kecamatan
+CREATE UNIQUE INDEX idx_lokasi_kantor_kecamatan ON lokasi_layanan(kecamatan_id) WHERE kelurahan_id IS NULL;
+
+-- Existing deployments all served Kecamatan Pademangan, Jakarta Utara
+INSERT INTO ref_kecamatan (nama_kecamatan, nama_kota, kode_wilayah)
+SELECT 'Pademangan', 'Jakarta Utara', '317205'
+WHERE EXISTS (SELECT 1 FROM ref_kelurahan);
+
+UPDATE ref_kelurahan SET kecamatan_id = (SELECT id FROM ref_kecamatan ORDER BY id LIMIT 1);
+ALTER TABLE ref_kelurahan ALTER COLUMN kecamatan_id SET NOT NULL;
+
+-- A kantor kecamatan's code comes from its kecamatan digits (K05 for
+-- 317205), since codes are unique across every kecamatan of the kota
+INSERT INTO lokasi_layanan (kecamatan_id, kelurahan_id, kode, nama_lokasi)
+SELECT id, NULL, 'K' || substr(kode_wilayah, 5, 2), 'Kantor Kecamatan ' || nama_kecamatan FROM ref_kecamatan;
+
+INSERT INTO lokasi_layanan (kecamatan_id, kelurahan_id, kode, nama_lokasi)
+SELECT kecamatan_id, id, kode_area, 'Kantor Kelurahan ' || nama_kelurahan FROM ref_kelurahan;
+
+-- jadwal_sesi: point at lokasi_layanan instead of a nullable kelurahan
+ALTER TABLE jadwal_sesi ADD COLUMN lokasi_id SMALLINT REFERENCES lokasi_layanan(id);
+
+UPDATE jadwal_sesi js
+SET lokasi_id = l.id
+FROM lokasi_layanan l
+WHERE l.kelurahan_id IS NOT DISTINCT FROM js.lokasi_kelurahan_id;
+
+ALTER TABLE jadwal_sesi ALTER COLUMN lokasi_id SET NOT NULL;
+ALTER TABLE jadwal_sesi DROP CONSTRAINT unique_sesi_lokasi;
+ALTER TABLE jadwal_sesi DROP COLUMN lokasi_kelurahan_id;
+ALTER TABLE jadwal_sesi ADD CONSTRAINT unique_sesi_lokasi UNIQUE (tanggal, jam_mulai, lokasi_id);
+
+-- aturan_booking: one row per lokasi_layanan
+ALTER TABLE aturan_booking ADD COLUMN lokasi_id SMALLINT REFERENCES lokasi_layanan(id) ON DELETE CASCADE;
+
+UPDATE aturan_booking ab
+SET lokasi_id = l.id
+FROM lokasi_layanan l
+WHERE l.kelurahan_id IS NOT DISTINCT FROM ab.lokasi_kelurahan_id;
+
+DELETE FROM aturan_booking WHERE lokasi_id IS NULL;
+ALTER TABLE aturan_booking ALTER COLUMN lokasi_id SET NOT NULL;
+DROP INDEX idx_aturan_booking_lokasi;
+ALTER TABLE aturan_booking DROP COLUMN lokasi_kelurahan_id;
+ALTER TABLE aturan_booking ADD CONSTRAINT unique_aturan_booking_lokasi UNIQUE (lokasi_id);
+
+-- Booking codes are prefixed with the lokasi code
+CREATE OR REPLACE FUNCTION process_new_permohonan()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_kode_area CHAR(3);
+    v_tanggal_day TEXT;
+    v_random_str TEXT;
+    v_kuota_max INT;
+    v_kuota_now INT;
+BEGIN
+    -- Lock row for concurrency safety
+    SELECT
+        l.kode,
+        TO_CHAR(j.tanggal, 'DD'),
+        j.kuota_maksimal,
+        j.kuota_terisi
+    INTO v_kode_area, v_tanggal_day, v_kuota_max, v_kuota_now
+    FROM jadwal_sesi j
+    JOIN lokasi_layanan l ON j.lokasi_id = l.id
+    WHERE j.id = NEW.jadwal_sesi_id
+    FOR UPDATE OF j; -- Explicitly lock ONLY jadwal_sesi table
+
+    -- Validation
+    IF v_kuota_now >= v_kuota_max THEN
+        RAISE EXCEPTION 'Session is full (Quota Reached)';
+    END IF;
+
+    -- Update Session
+    UPDATE jadwal_sesi
+    SET kuota_terisi = kuota_terisi + 1,
+        status_sesi = CASE WHEN (kuota_terisi + 1) >= kuota_maksimal THEN 'PENUH' ELSE status_sesi END
+    WHERE id = NEW.jadwal_sesi_id;
+
+    -- Set Queue Number
+    NEW.nomor_antrian_sesi := v_kuota_now + 1;
+
+    -- Generate Booking Code
+    v_random_str := substring(md5(random()::text), 1, 4);
+    NEW.kode_booking := UPPER(v_kode_area || '-' || v_tanggal_day || '-' || v_random_str);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION process_new_permohonan()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_kode_area CHAR(3);
+    v_tanggal_day TEXT;
+    v_random_str TEXT;
+    v_kuota_max INT;
+    v_kuota_now INT;
+BEGIN
+    -- Lock row for concurrency safety
+    SELECT
+        COALESCE(k.kode_area, 'KEC'), -- Handle NULL location
+        TO_CHAR(j.tanggal, 'DD'),
+        j.kuota_maksimal,
+        j.kuota_terisi
+    INTO v_kode_area, v_tanggal_day, v_kuota_max, v_kuota_now
+    FROM jadwal_sesi j
+    LEFT JOIN ref_kelurahan k ON j.lokasi_kelurahan_id = k.id -- LEFT JOIN
+    WHERE j.id = NEW.jadwal_sesi_id
+    FOR UPDATE OF j; -- Explicitly lock ONLY jadwal_sesi table
+
+    -- Validation
+    IF v_kuota_now >= v_kuota_max THEN
+        RAISE EXCEPTION 'Session is full (Quota Reached)';
+    END IF;
+
+    -- Update Session
+    UPDATE jadwal_sesi
+    SET kuota_terisi = kuota_terisi + 1,
+        status_sesi = CASE WHEN (kuota_terisi + 1) >= kuota_maksimal THEN 'PENUH' ELSE status_sesi END
+    WHERE id = NEW.jadwal_sesi_id;
+
+    -- Set Queue Number
+    NEW.nomor_antrian_sesi := v_kuota_now + 1;
+
+    -- Generate Booking Code
+    v_random_str := substring(md5(random()::text), 1, 4);
+    NEW.kode_booking := UPPER(v_kode_area || '-' || v_tanggal_day || '-' || v_random_str);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE aturan_booking ADD COLUMN lokasi_kelurahan_id SMALLINT REFERENCES ref_kelurahan(id) ON DELETE CASCADE;
+UPDATE aturan_booking ab SET lokasi_kelurahan_id = l.kelurahan_id FROM lokasi_layanan l WHERE l.id = ab.lokasi_id;
+ALTER TABLE aturan_booking DROP CONSTRAINT unique_aturan_booking_lokasi;
+ALTER TABLE aturan_booking DROP COLUMN lokasi_id;
+CREATE UNIQUE INDEX idx_aturan_booking_lokasi ON aturan_booking ((COALESCE(lokasi_kelurahan_id, 0)));
+
+ALTER TABLE jadwal_sesi ADD COLUMN lokasi_kelurahan_id SMALLINT REFERENCES ref_kelurahan(id);
+UPDATE jadwal_sesi js SET lokasi_kelurahan_id = l.kelurahan_id FROM lokasi_layanan l WHERE l.id = js.lokasi_id;
+ALTER TABLE jadwal_sesi DROP CONSTRAINT unique_sesi_lokasi;
+ALTER TABLE jadwal_sesi DROP COLUMN lokasi_id;
+ALTER TABLE jadwal_sesi ADD CONSTRAINT unique_sesi_lokasi UNIQUE (tanggal, jam_mulai, lokasi_kelurahan_id);
+
+DROP TABLE IF EXISTS lokasi_layanan;
+ALTER TABLE ref_kelurahan DROP COLUMN kecamatan_id;
+DROP TABLE IF EXISTS ref_kecamatan;
+-- +goose StatementEnd
@@ -0,0 +1,76 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- petugas: scope by kecamatan as well as kelurahan
+-- kelurahan_id set          -> ADMIN_KELURAHAN
+-- only kecamatan_id set     -> ADMIN_KECAMATAN
+-- neither set               -> ADMIN_KOTA (Disdukcapil, sees the whole city)
+ALTER TABLE petugas ADD COLUMN kecamatan_id SMALLINT REFERENCES ref_kecamatan(id);
+
+UPDATE petugas p
+SET kecamatan_id = k.kecamatan_id
+FROM ref_kelurahan k
+WHERE p.kelurahan_id = k.id;
+
+-- Existing admin kecamatan all belonged to the single kecamatan
+UPDATE petugas
+SET kecamatan_id = (SELECT id FROM ref_kecamatan ORDER BY id LIMIT 1)
+WHERE kelurahan_id IS NULL;
+
+ALTER TABLE petugas DROP CONSTRAINT chk_petugas_role;
+ALTER TABLE petugas ADD CONSTRAINT chk_petugas_role CHECK (role IN ('ADMIN_KOTA', 'ADMIN_KECAMATAN', 'ADMIN_KELURAHAN'));
+
+CREATE INDEX idx_petugas_wilayah ON petugas(kecamatan_id, kelurahan_id);
+
+-- Function: Auto-Assign Role
+CREATE OR REPLACE FUNCTION set_petugas_role()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.kelurahan_id IS NOT NULL THEN
+        -- Keep kecamatan_id consistent with the kelurahan
+        SELECT kecamatan_id INTO NEW.kecamatan_id FROM ref_kelurahan WHERE id = NEW.kelurahan_id;
+        NEW.role := 'ADMIN_KELURAHAN';
+    ELSIF NEW.kecamatan_id IS NOT NULL THEN
+        NEW.role := 'ADMIN_KECAMATAN';
+    ELSE
+        NEW.role := 'ADMIN_KOTA';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_set_petugas_role ON petugas;
+CREATE TRIGGER trg_set_petugas_role
+BEFORE INSERT OR UPDATE OF kelurahan_id, kecamatan_id ON petugas
+FOR EACH ROW EXECUTE FUNCTION set_petugas_role();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trg_set_petugas_role ON petugas;
+
+CREATE OR REPLACE FUNCTION set_petugas_role()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.kelurahan_id IS NULL THEN
+        NEW.role := 'ADMIN_KECAMATAN';
+    ELSE
+        NEW.role := 'ADMIN_KELURAHAN';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_set_petugas_role
+BEFORE INSERT OR UPDATE OF kelurahan_id ON petugas
+FOR EACH ROW EXECUTE FUNCTION set_petugas_role();
+
+UPDATE petugas SET role = 'ADMIN_KECAMATAN' WHERE role = 'ADMIN_KOTA';
+
+ALTER TABLE petugas DROP CONSTRAINT chk_petugas_role;
+ALTER TABLE petugas ADD CONSTRAINT chk_petugas_role CHECK (role IN ('ADMIN_KECAMATAN', 'ADMIN_KELURAHAN'));
+
+DROP INDEX IF EXISTS idx_petugas_wilayah;
+ALTER TABLE petugas DROP COLUMN kecamatan_id;
+-- +goose StatementEnd
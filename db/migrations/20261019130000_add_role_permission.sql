@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Named permissions checked by the application (policy.Can)
+CREATE TABLE ref_permission (
+    kode VARCHAR(50) PRIMARY KEY,
+    deskripsi VARCHAR(255) NOT NULL
+);
+
+-- Permissions granted to each petugas role
+CREATE TABLE role_permission (
+    role VARCHAR(20) NOT NULL,
+    permission VARCHAR(50) NOT NULL REFERENCES ref_permission(kode) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission),
+    CONSTRAINT chk_role_permission_role CHECK (role IN ('ADMIN_KOTA', 'ADMIN_KECAMATAN', 'ADMIN_KELURAHAN'))
+);
+
+INSERT INTO ref_permission (kode, deskripsi) VALUES
+    ('permohonan.verify', 'Memverifikasi dan memproses permohonan'),
+    ('permohonan.reject', 'Menolak permohonan'),
+    ('jadwal.manage', 'Mengelola jadwal sesi, lokasi layanan, dan aturan booking'),
+    ('petugas.manage', 'Mengelola akun petugas'),
+    ('penduduk.view_pii', 'Melihat data pribadi penduduk secara lengkap');
+
+-- Defaults mirror what each role could do before permissions existed
+INSERT INTO role_permission (role, permission)
+SELECT r.role, p.kode
+FROM (VALUES ('ADMIN_KOTA'), ('ADMIN_KECAMATAN')) AS r(role)
+CROSS JOIN ref_permission p;
+
+INSERT INTO role_permission (role, permission) VALUES
+    ('ADMIN_KELURAHAN', 'permohonan.verify'),
+    ('ADMIN_KELURAHAN', 'permohonan.reject'),
+    ('ADMIN_KELURAHAN', 'jadwal.manage'),
+    ('ADMIN_KELURAHAN', 'penduduk.view_pii');
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS role_permission;
+DROP TABLE IF EXISTS ref_permission;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- sesi_versi is copied into the petugas session at login; bumping it
+-- (deactivation, password reset, wilayah change) invalidates every
+-- session issued before
+ALTER TABLE petugas ADD COLUMN updated_by UUID REFERENCES petugas(id);
+ALTER TABLE petugas ADD COLUMN updated_at TIMESTAMPTZ;
+ALTER TABLE petugas ADD COLUMN sesi_versi INT NOT NULL DEFAULT 1;
+
+UPDATE petugas SET is_active = TRUE WHERE is_active IS NULL;
+ALTER TABLE petugas ALTER COLUMN is_active SET NOT NULL;
+
+-- Audit trail of petugas account changes
+CREATE TABLE riwayat_petugas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    petugas_id UUID NOT NULL REFERENCES petugas(id) ON DELETE CASCADE,
+    aksi VARCHAR(20) NOT NULL,
+    dilakukan_oleh UUID REFERENCES petugas(id),
+    keterangan TEXT,
+    waktu TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_riwayat_petugas_aksi CHECK (aksi IN ('DIBUAT', 'DIUBAH', 'DIPINDAH', 'DINONAKTIFKAN', 'DIAKTIFKAN', 'RESET_PASSWORD'))
+);
+
+CREATE INDEX idx_riwayat_petugas ON riwayat_petugas(petugas_id, waktu DESC);
+
+INSERT INTO riwayat_petugas (petugas_id, aksi, dilakukan_oleh, waktu)
+SELECT id, 'DIBUAT', created_by, created_at FROM petugas
+WHERE created_at IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS riwayat_petugas;
+ALTER TABLE petugas ALTER COLUMN is_active DROP NOT NULL;
+ALTER TABLE petugas DROP COLUMN sesi_versi;
+ALTER TABLE petugas DROP COLUMN updated_at;
+ALTER TABLE petugas DROP COLUMN updated_by;
+-- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Server-side login sessions. The cookie carries a random token; only its
+-- SHA-256 hash is stored, so a database leak does not expose live sessions.
+-- Deleting a row revokes the session immediately.
+CREATE TABLE sesi_login (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    user_type VARCHAR(10) NOT NULL,
+    user_id TEXT NOT NULL,
+    user_name TEXT NOT NULL,
+    user_role TEXT NOT NULL DEFAULT '',
+    kecamatan_id SMALLINT,
+    kelurahan_id SMALLINT,
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    user_agent TEXT,
+    ip_address TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT chk_sesi_login_user_type CHECK (user_type IN ('warga', 'petugas'))
+);
+
+CREATE INDEX idx_sesi_login_user ON sesi_login(user_type, user_id);
+CREATE INDEX idx_sesi_login_expires ON sesi_login(expires_at);
+
+-- Account changes now revoke sessions directly
+ALTER TABLE petugas DROP COLUMN sesi_versi;
+
+ALTER TABLE riwayat_petugas DROP CONSTRAINT chk_riwayat_petugas_aksi;
+ALTER TABLE riwayat_petugas ADD CONSTRAINT chk_riwayat_petugas_aksi CHECK (aksi IN ('DIBUAT', 'DIUBAH', 'DIPINDAH', 'DINONAKTIFKAN', 'DIAKTIFKAN', 'RESET_PASSWORD', 'SESI_DIAKHIRI'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM riwayat_petugas WHERE aksi = 'SESI_DIAKHIRI';
+ALTER TABLE riwayat_petugas DROP CONSTRAINT chk_riwayat_petugas_aksi;
+ALTER TABLE riwayat_petugas ADD CONSTRAINT chk_riwayat_petugas_aksi CHECK (aksi IN ('DIBUAT', 'DIUBAH', 'DIPINDAH', 'DINONAKTIFKAN', 'DIAKTIFKAN', 'RESET_PASSWORD'));
+
+ALTER TABLE petugas ADD COLUMN sesi_versi INT NOT NULL DEFAULT 1;
+
+DROP TABLE IF EXISTS sesi_login;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- One-time codes for warga password resets and account claims. Only a bcrypt
+-- hash of the code is stored; a code is spent by setting digunakan_at.
+CREATE TABLE kode_reset_password (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    nik CHAR(16) NOT NULL REFERENCES penduduk(nik) ON DELETE CASCADE,
+    kode_hash TEXT NOT NULL,
+    kanal VARCHAR(10) NOT NULL,
+    percobaan SMALLINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    digunakan_at TIMESTAMPTZ,
+    CONSTRAINT chk_kode_reset_password_kanal CHECK (kanal IN ('email', 'sms'))
+);
+
+CREATE INDEX idx_kode_reset_password_nik ON kode_reset_password(nik, created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS kode_reset_password;
+-- +goose StatementEnd
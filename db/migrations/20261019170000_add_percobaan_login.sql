@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Audit trail of every login attempt, also used for per-IP rate limiting
+CREATE TABLE percobaan_login (
+    id BIGSERIAL PRIMARY KEY,
+    user_type VARCHAR(10) NOT NULL,
+    identifier TEXT NOT NULL,
+    ip_address TEXT,
+    user_agent TEXT,
+    berhasil BOOLEAN NOT NULL,
+    alasan VARCHAR(20) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_percobaan_login_user_type CHECK (user_type IN ('warga', 'petugas')),
+    CONSTRAINT chk_percobaan_login_alasan CHECK (alasan IN ('BERHASIL', 'PASSWORD_SALAH', 'TIDAK_DITEMUKAN', 'AKUN_TERKUNCI', 'IP_DIBATASI', 'NONAKTIF', 'TANPA_PASSWORD'))
+);
+
+CREATE INDEX idx_percobaan_login_ip ON percobaan_login(ip_address, created_at);
+CREATE INDEX idx_percobaan_login_identifier ON percobaan_login(user_type, identifier, created_at);
+
+-- Consecutive failures per account (NIK for warga, NIP for petugas). The row
+-- is removed on a successful login; terkunci_sampai holds the current lockout.
+CREATE TABLE kunci_login (
+    id UUID UNIQUE NOT NULL DEFAULT gen_random_uuid(),
+    user_type VARCHAR(10) NOT NULL,
+    identifier TEXT NOT NULL,
+    gagal_beruntun INT NOT NULL DEFAULT 0,
+    gagal_terakhir_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ip_terakhir TEXT,
+    terkunci_sampai TIMESTAMPTZ,
+    dibuka_oleh UUID REFERENCES petugas(id) ON DELETE SET NULL,
+    dibuka_at TIMESTAMPTZ,
+    PRIMARY KEY (user_type, identifier),
+    CONSTRAINT chk_kunci_login_user_type CHECK (user_type IN ('warga', 'petugas'))
+);
+
+CREATE INDEX idx_kunci_login_terkunci ON kunci_login(terkunci_sampai);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS kunci_login;
+DROP TABLE IF EXISTS percobaan_login;
+-- +goose StatementEnd
@@ -0,0 +1,70 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- TOTP second factor for petugas. A row with aktif = false is an enrolment
+-- that has not been confirmed with a code yet. terakhir_step is the last
+-- accepted time step, so that a code cannot be used twice.
+CREATE TABLE petugas_totp (
+    petugas_id UUID PRIMARY KEY REFERENCES petugas(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    aktif BOOLEAN NOT NULL DEFAULT false,
+    terakhir_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    aktif_at TIMESTAMPTZ
+);
+
+-- Single-use recovery codes, stored as SHA-256 hashes
+CREATE TABLE kode_pemulihan (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    petugas_id UUID NOT NULL REFERENCES petugas(id) ON DELETE CASCADE,
+    kode_hash CHAR(64) NOT NULL,
+    digunakan_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_kode_pemulihan UNIQUE (petugas_id, kode_hash)
+);
+
+-- Roles whose petugas must use 2FA
+CREATE TABLE kebijakan_2fa (
+    role TEXT PRIMARY KEY,
+    wajib BOOLEAN NOT NULL DEFAULT false,
+    CONSTRAINT chk_kebijakan_2fa_role CHECK (role IN ('ADMIN_KOTA', 'ADMIN_KECAMATAN', 'ADMIN_KELURAHAN'))
+);
+
+INSERT INTO kebijakan_2fa (role, wajib) VALUES
+    ('ADMIN_KOTA', false),
+    ('ADMIN_KECAMATAN', true),
+    ('ADMIN_KELURAHAN', false);
+
+-- Sessions that passed the password step but still owe a code (VERIFIKASI)
+-- or must enrol before using the admin panel (PENDAFTARAN)
+ALTER TABLE sesi_login ADD COLUMN tahap_2fa VARCHAR(12) NOT NULL DEFAULT '';
+ALTER TABLE sesi_login ADD COLUMN ingat_saya BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE sesi_login ADD CONSTRAINT chk_sesi_login_tahap_2fa CHECK (tahap_2fa IN ('', 'VERIFIKASI', 'PENDAFTARAN'));
+
+ALTER TABLE percobaan_login DROP CONSTRAINT chk_percobaan_login_alasan;
+ALTER TABLE percobaan_login ADD CONSTRAINT chk_percobaan_login_alasan CHECK (alasan IN ('BERHASIL', 'PASSWORD_SALAH', 'TIDAK_DITEMUKAN', 'AKUN_TERKUNCI', 'IP_DIBATASI', 'NONAKTIF', 'TANPA_PASSWORD', 'KODE_2FA_SALAH'));
+
+ALTER TABLE riwayat_petugas DROP CONSTRAINT chk_riwayat_petugas_aksi;
+ALTER TABLE riwayat_petugas ADD CONSTRAINT chk_riwayat_petugas_aksi CHECK (aksi IN ('DIBUAT', 'DIUBAH', 'DIPINDAH', 'DINONAKTIFKAN', 'DIAKTIFKAN', 'RESET_PASSWORD', 'SESI_DIAKHIRI', 'RESET_2FA'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM riwayat_petugas WHERE aksi = 'RESET_2FA';
+ALTER TABLE riwayat_petugas DROP CONSTRAINT chk_riwayat_petugas_aksi;
+ALTER TABLE riwayat_petugas ADD CONSTRAINT chk_riwayat_petugas_aksi CHECK (aksi IN ('DIBUAT', 'DIUBAH', 'DIPINDAH', 'DINONAKTIFKAN', 'DIAKTIFKAN', 'RESET_PASSWORD', 'SESI_DIAKHIRI'));
+
+DELETE FROM percobaan_login WHERE alasan = 'KODE_2FA_SALAH';
+ALTER TABLE percobaan_login DROP CONSTRAINT chk_percobaan_login_alasan;
+ALTER TABLE percobaan_login ADD CONSTRAINT chk_percobaan_login_alasan CHECK (alasan IN ('BERHASIL', 'PASSWORD_SALAH', 'TIDAK_DITEMUKAN', 'AKUN_TERKUNCI', 'IP_DIBATASI', 'NONAKTIF', 'TANPA_PASSWORD'));
+
+DELETE FROM sesi_login WHERE tahap_2fa <> '';
+ALTER TABLE sesi_login DROP CONSTRAINT chk_sesi_login_tahap_2fa;
+ALTER TABLE sesi_login DROP COLUMN ingat_saya;
+ALTER TABLE sesi_login DROP COLUMN tahap_2fa;
+
+DROP TABLE IF EXISTS kebijakan_2fa;
+DROP TABLE IF EXISTS kode_pemulihan;
+DROP TABLE IF EXISTS petugas_totp;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Per-session CSRF token. New sessions get theirs from the application; the
+-- default only fills in tokens for sessions that exist during the migration.
+ALTER TABLE sesi_login ADD COLUMN csrf_token TEXT NOT NULL DEFAULT encode(sha256(gen_random_uuid()::text::bytea), 'hex');
+ALTER TABLE sesi_login ALTER COLUMN csrf_token DROP DEFAULT;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sesi_login DROP COLUMN csrf_token;
+-- +goose StatementEnd
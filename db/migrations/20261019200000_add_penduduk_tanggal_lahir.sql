@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Date of birth and marital status decide who may apply for a first KTP:
+-- citizens become eligible at 17 or on marriage, whichever comes first.
+ALTER TABLE penduduk ADD COLUMN tanggal_lahir DATE;
+ALTER TABLE penduduk ADD COLUMN status_perkawinan TEXT NOT NULL DEFAULT 'BELUM_KAWIN';
+ALTER TABLE penduduk ADD CONSTRAINT chk_penduduk_status_perkawinan
+    CHECK (status_perkawinan IN ('BELUM_KAWIN', 'KAWIN', 'CERAI_HIDUP', 'CERAI_MATI'));
+
+-- Backfill from the DDMMYY digits of the NIK (40 is added to the day for
+-- women). Like nik.Parse, a date that would fall after today is placed in the
+-- previous century; the full dates are compared as (year, month, day) rows
+-- because make_date fails on digits that are not a real date. NIKs that do
+-- not encode a real date are left NULL.
+WITH digits AS (
+    SELECT nik,
+           substr(nik, 7, 2)::int - CASE WHEN substr(nik, 7, 2)::int > 40 THEN 40 ELSE 0 END AS d,
+           substr(nik, 9, 2)::int AS m,
+           substr(nik, 11, 2)::int AS yy
+    FROM penduduk
+    WHERE nik ~ '^[0-9]{16}$'
+), lahir AS (
+    SELECT nik, d, m,
+           yy + CASE WHEN (2000 + yy, m, d) > (extract(year FROM CURRENT_DATE)::int,
+                                              extract(month FROM CURRENT_DATE)::int,
+                                              extract(day FROM CURRENT_DATE)::int)
+                     THEN 1900 ELSE 2000 END AS y
+    FROM digits
+)
+UPDATE penduduk p
+SET tanggal_lahir = make_date(l.y, l.m, l.d)
+FROM lahir l
+WHERE p.nik = l.nik
+  AND l.d >= 1
+  AND l.d <= CASE WHEN l.m BETWEEN 1 AND 12
+                  THEN extract(day FROM make_date(l.y, l.m, 1) + interval '1 month - 1 day')
+                  ELSE 0 END;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE penduduk DROP CONSTRAINT chk_penduduk_status_perkawinan;
+ALTER TABLE penduduk DROP COLUMN status_perkawinan;
+ALTER TABLE penduduk DROP COLUMN tanggal_lahir;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Kartu Keluarga groups penduduk into a household. The head of household and
+-- their spouse act as guardians and may apply on behalf of the other members.
+CREATE TABLE kartu_keluarga (
+    no_kk CHAR(16) PRIMARY KEY,
+    kelurahan_id SMALLINT REFERENCES ref_kelurahan(id),
+    alamat TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE penduduk ADD COLUMN no_kk CHAR(16) REFERENCES kartu_keluarga(no_kk);
+ALTER TABLE penduduk ADD COLUMN hubungan_keluarga TEXT;
+ALTER TABLE penduduk ADD CONSTRAINT chk_penduduk_hubungan_keluarga
+    CHECK (hubungan_keluarga IN ('KEPALA_KELUARGA', 'SUAMI', 'ISTRI', 'ANAK', 'MENANTU', 'CUCU', 'ORANG_TUA', 'MERTUA', 'FAMILI_LAIN', 'LAINNYA'));
+ALTER TABLE penduduk ADD CONSTRAINT chk_penduduk_kk
+    CHECK ((no_kk IS NULL) = (hubungan_keluarga IS NULL));
+
+CREATE INDEX idx_penduduk_no_kk ON penduduk(no_kk);
+CREATE UNIQUE INDEX idx_penduduk_kepala_keluarga ON penduduk(no_kk) WHERE hubungan_keluarga = 'KEPALA_KELUARGA';
+
+-- nik is the citizen the KTP is for; pemohon_nik is the account that applied
+ALTER TABLE permohonan ADD COLUMN pemohon_nik CHAR(16) REFERENCES penduduk(nik);
+UPDATE permohonan SET pemohon_nik = nik;
+CREATE INDEX idx_permohonan_pemohon_nik ON permohonan(pemohon_nik);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_permohonan_pemohon_nik;
+ALTER TABLE permohonan DROP COLUMN pemohon_nik;
+
+DROP INDEX idx_penduduk_kepala_keluarga;
+DROP INDEX idx_penduduk_no_kk;
+ALTER TABLE penduduk DROP CONSTRAINT chk_penduduk_kk;
+ALTER TABLE penduduk DROP CONSTRAINT chk_penduduk_hubungan_keluarga;
+ALTER TABLE penduduk DROP COLUMN hubungan_keluarga;
+ALTER TABLE penduduk DROP COLUMN no_kk;
+
+DROP TABLE kartu_keluarga;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Lookups on the public tracking page, used for rate limiting per IP address
+-- and per booking code so that the second factor cannot be guessed
+CREATE TABLE percobaan_lacak (
+    id BIGSERIAL PRIMARY KEY,
+    kode_booking TEXT NOT NULL,
+    ip_address TEXT,
+    berhasil BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_percobaan_lacak_ip ON percobaan_lacak(ip_address, created_at);
+CREATE INDEX idx_percobaan_lacak_kode ON percobaan_lacak(kode_booking, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS percobaan_lacak;
+-- +goose StatementEnd
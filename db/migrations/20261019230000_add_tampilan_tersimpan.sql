@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Documents each jenis permohonan needs; must match the upload fields of the
+-- permohonan forms
+CREATE TABLE ref_syarat_dokumen (
+    jenis_permohonan TEXT NOT NULL,
+    jenis_dokumen TEXT NOT NULL,
+    PRIMARY KEY (jenis_permohonan, jenis_dokumen),
+    CONSTRAINT chk_syarat_jenis_dokumen CHECK (jenis_dokumen IN ('KTP', 'KK', 'SURAT_POLISI', 'KTP_RUSAK'))
+);
+
+INSERT INTO ref_syarat_dokumen (jenis_permohonan, jenis_dokumen) VALUES
+    ('BARU', 'KK'),
+    ('HILANG', 'SURAT_POLISI'),
+    ('RUSAK', 'KTP_RUSAK'),
+    ('RUSAK', 'KK'),
+    ('UPDATE', 'KTP'),
+    ('UPDATE', 'KK');
+
+-- Used to find when an application last changed status
+CREATE INDEX idx_riwayat_status_permohonan ON riwayat_status(permohonan_id, waktu_proses);
+
+-- Named filter combinations a petugas saved for an admin table. halaman names
+-- the table, query holds its query string without the page.
+CREATE TABLE tampilan_tersimpan (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    petugas_id UUID NOT NULL REFERENCES petugas(id) ON DELETE CASCADE,
+    halaman VARCHAR(20) NOT NULL,
+    nama TEXT NOT NULL,
+    query TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_tampilan_tersimpan UNIQUE (petugas_id, halaman, nama)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tampilan_tersimpan;
+DROP INDEX IF EXISTS idx_riwayat_status_permohonan;
+DROP TABLE IF EXISTS ref_syarat_dokumen;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Trigram indexes serve both ILIKE '%term%' and the typo-tolerant word
+-- similarity operators used by admin search. They replace the B-tree index
+-- on (nama_lengkap, email), which a leading wildcard could never use. NIK and
+-- booking code are CHAR columns, so they are indexed and searched as text.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+DROP INDEX IF EXISTS idx_penduduk_search;
+
+CREATE INDEX idx_penduduk_nama_trgm ON penduduk USING GIN (nama_lengkap gin_trgm_ops);
+CREATE INDEX idx_penduduk_nik_trgm ON penduduk USING GIN ((nik::text) gin_trgm_ops);
+CREATE INDEX idx_penduduk_alamat_trgm ON penduduk USING GIN (alamat gin_trgm_ops);
+CREATE INDEX idx_permohonan_nik_trgm ON permohonan USING GIN ((nik::text) gin_trgm_ops);
+CREATE INDEX idx_permohonan_kode_booking_trgm ON permohonan USING GIN ((kode_booking::text) gin_trgm_ops);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_permohonan_kode_booking_trgm;
+DROP INDEX IF EXISTS idx_permohonan_nik_trgm;
+DROP INDEX IF EXISTS idx_penduduk_alamat_trgm;
+DROP INDEX IF EXISTS idx_penduduk_nik_trgm;
+DROP INDEX IF EXISTS idx_penduduk_nama_trgm;
+CREATE INDEX idx_penduduk_search ON penduduk(nama_lengkap, email);
+DROP EXTENSION IF EXISTS pg_trgm;
+-- +goose StatementEnd
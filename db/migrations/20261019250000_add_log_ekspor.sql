@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Every CSV/XLSX export from the admin panel. filter keeps the query string
+-- the list was exported with; data_lengkap records whether personal data was
+-- exported unmasked.
+CREATE TABLE log_ekspor (
+    id BIGSERIAL PRIMARY KEY,
+    petugas_id UUID REFERENCES petugas(id) ON DELETE SET NULL,
+    nama_petugas TEXT NOT NULL,
+    jenis VARCHAR(20) NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    filter TEXT NOT NULL DEFAULT '',
+    jumlah_baris INT NOT NULL,
+    data_lengkap BOOLEAN NOT NULL,
+    ip_address TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_log_ekspor_petugas ON log_ekspor(petugas_id, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS log_ekspor;
+-- +goose StatementEnd
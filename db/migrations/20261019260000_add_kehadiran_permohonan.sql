@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Arrival at the session, recorded when a petugas scans the booking QR code.
+-- The first scan wins; later scans only look the booking up.
+ALTER TABLE permohonan
+    ADD COLUMN waktu_hadir TIMESTAMPTZ,
+    ADD COLUMN hadir_dicatat_oleh UUID REFERENCES petugas(id) ON DELETE SET NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE permohonan
+    DROP COLUMN IF EXISTS hadir_dicatat_oleh,
+    DROP COLUMN IF EXISTS waktu_hadir;
+-- +goose StatementEnd
@@ -0,0 +1,126 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Reason a permohonan was rejected, recorded with the DITOLAK history entry
+ALTER TABLE riwayat_status ADD COLUMN alasan_penolakan VARCHAR(30);
+ALTER TABLE riwayat_status ADD CONSTRAINT chk_riwayat_status_alasan CHECK (
+    alasan_penolakan IN ('DOKUMEN_TIDAK_LENGKAP', 'DOKUMEN_TIDAK_VALID', 'DATA_TIDAK_SESUAI', 'TIDAK_HADIR', 'DUPLIKAT', 'LAINNYA')
+);
+
+INSERT INTO ref_permission (kode, deskripsi) VALUES
+    ('laporan.view', 'Melihat laporan dan statistik layanan');
+
+INSERT INTO role_permission (role, permission) VALUES
+    ('ADMIN_KOTA', 'laporan.view'),
+    ('ADMIN_KECAMATAN', 'laporan.view');
+
+-- The report views below are refreshed by the server every hour. kecamatan_id
+-- and kelurahan_id locate the lokasi layanan, as in the admin scope, with 0
+-- for a lokasi that serves a whole kecamatan. Weeks start on Monday in
+-- Asia/Jakarta.
+
+-- Applications per week, jenis and the applicant's kelurahan
+CREATE MATERIALIZED VIEW mv_laporan_permohonan AS
+SELECT
+    date_trunc('week', p.created_at AT TIME ZONE 'Asia/Jakarta')::date AS minggu,
+    p.jenis_permohonan,
+    l.kecamatan_id,
+    COALESCE(l.kelurahan_id, 0)::smallint AS kelurahan_id,
+    COALESCE(pd.kelurahan_id, 0)::smallint AS kelurahan_pemohon_id,
+    COUNT(*)::int AS jumlah,
+    (COUNT(*) FILTER (WHERE p.status_terkini = 'DITOLAK'))::int AS ditolak
+FROM permohonan p
+JOIN penduduk pd ON p.nik = pd.nik
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+GROUP BY 1, 2, 3, 4, 5;
+
+CREATE UNIQUE INDEX idx_mv_laporan_permohonan
+    ON mv_laporan_permohonan(minggu, jenis_permohonan, kecamatan_id, kelurahan_id, kelurahan_pemohon_id);
+
+-- Hours from the first VERIFIKASI to the first SELESAI of each completed
+-- application; one row each so that percentiles can be taken over any scope
+CREATE MATERIALIZED VIEW mv_laporan_durasi AS
+SELECT
+    p.id AS permohonan_id,
+    date_trunc('week', r.selesai AT TIME ZONE 'Asia/Jakarta')::date AS minggu,
+    p.jenis_permohonan,
+    l.kecamatan_id,
+    COALESCE(l.kelurahan_id, 0)::smallint AS kelurahan_id,
+    (EXTRACT(EPOCH FROM r.selesai - COALESCE(r.verifikasi, p.created_at)) / 3600)::float8 AS durasi_jam
+FROM permohonan p
+JOIN (
+    SELECT
+        permohonan_id,
+        MIN(waktu_proses) FILTER (WHERE status_baru = 'VERIFIKASI') AS verifikasi,
+        MIN(waktu_proses) FILTER (WHERE status_baru = 'SELESAI') AS selesai
+    FROM riwayat_status
+    GROUP BY permohonan_id
+) r ON r.permohonan_id = p.id
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+WHERE r.selesai IS NOT NULL;
+
+CREATE UNIQUE INDEX idx_mv_laporan_durasi ON mv_laporan_durasi(permohonan_id);
+CREATE INDEX idx_mv_laporan_durasi_minggu ON mv_laporan_durasi(minggu);
+
+-- Rejections per week of rejection and reason; rejections from before
+-- reasons were recorded count as TIDAK_DICATAT
+CREATE MATERIALIZED VIEW mv_laporan_penolakan AS
+SELECT
+    date_trunc('week', t.waktu AT TIME ZONE 'Asia/Jakarta')::date AS minggu,
+    l.kecamatan_id,
+    COALESCE(l.kelurahan_id, 0)::smallint AS kelurahan_id,
+    COALESCE(t.alasan, 'TIDAK_DICATAT') AS alasan,
+    COUNT(*)::int AS jumlah
+FROM (
+    SELECT DISTINCT ON (permohonan_id)
+        permohonan_id,
+        waktu_proses AS waktu,
+        alasan_penolakan AS alasan
+    FROM riwayat_status
+    WHERE status_baru = 'DITOLAK'
+    ORDER BY permohonan_id, waktu_proses DESC, alasan_penolakan NULLS LAST
+) t
+JOIN permohonan p ON p.id = t.permohonan_id AND p.status_terkini = 'DITOLAK'
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+GROUP BY 1, 2, 3, 4;
+
+CREATE UNIQUE INDEX idx_mv_laporan_penolakan ON mv_laporan_penolakan(minggu, kecamatan_id, kelurahan_id, alasan);
+
+-- Quota use and attendance per session. Attendance is only known for
+-- sessions where check-in was used, so pencatatan_hadir marks those.
+CREATE MATERIALIZED VIEW mv_laporan_sesi AS
+SELECT
+    js.id AS jadwal_sesi_id,
+    js.tanggal,
+    date_trunc('week', js.tanggal::timestamp)::date AS minggu,
+    l.kecamatan_id,
+    COALESCE(l.kelurahan_id, 0)::smallint AS kelurahan_id,
+    js.kuota_maksimal::int AS kuota_maksimal,
+    js.kuota_terisi::int AS kuota_terisi,
+    (COUNT(p.id) FILTER (WHERE p.status_terkini IS DISTINCT FROM 'DITOLAK'))::int AS terdaftar,
+    (COUNT(p.id) FILTER (WHERE p.waktu_hadir IS NOT NULL))::int AS hadir,
+    COUNT(p.waktu_hadir) > 0 AS pencatatan_hadir
+FROM jadwal_sesi js
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+LEFT JOIN permohonan p ON p.jadwal_sesi_id = js.id
+GROUP BY js.id, l.kecamatan_id, l.kelurahan_id;
+
+CREATE UNIQUE INDEX idx_mv_laporan_sesi ON mv_laporan_sesi(jadwal_sesi_id);
+CREATE INDEX idx_mv_laporan_sesi_minggu ON mv_laporan_sesi(minggu);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP MATERIALIZED VIEW IF EXISTS mv_laporan_sesi;
+DROP MATERIALIZED VIEW IF EXISTS mv_laporan_penolakan;
+DROP MATERIALIZED VIEW IF EXISTS mv_laporan_durasi;
+DROP MATERIALIZED VIEW IF EXISTS mv_laporan_permohonan;
+DELETE FROM role_permission WHERE permission = 'laporan.view';
+DELETE FROM ref_permission WHERE kode = 'laporan.view';
+ALTER TABLE riwayat_status DROP CONSTRAINT IF EXISTS chk_riwayat_status_alasan;
+ALTER TABLE riwayat_status DROP COLUMN IF EXISTS alasan_penolakan;
+-- +goose StatementEnd
@@ -0,0 +1,61 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- How many days an application may stay in a status, per jenis. A status
+-- and jenis without a row has no target; SELESAI and DITOLAK are final.
+CREATE TABLE target_sla (
+    status TEXT NOT NULL,
+    jenis_permohonan TEXT NOT NULL,
+    target_hari SMALLINT NOT NULL,
+    updated_by UUID REFERENCES petugas(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (status, jenis_permohonan),
+    CONSTRAINT chk_target_sla_status CHECK (status IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')),
+    CONSTRAINT chk_target_sla_jenis CHECK (jenis_permohonan IN ('BARU', 'HILANG', 'RUSAK', 'UPDATE')),
+    CONSTRAINT chk_target_sla_hari CHECK (target_hari BETWEEN 1 AND 365)
+);
+
+INSERT INTO target_sla (status, jenis_permohonan, target_hari)
+SELECT s.status, j.jenis, s.hari
+FROM (VALUES ('VERIFIKASI', 3), ('PROSES', 14), ('SIAP_AMBIL', 30)) AS s(status, hari)
+CROSS JOIN (VALUES ('BARU'), ('HILANG'), ('RUSAK'), ('UPDATE')) AS j(jenis);
+
+-- Days on which the escalation digest went out, so that it is sent once
+-- across restarts and server instances
+CREATE TABLE digest_sla (
+    tanggal DATE PRIMARY KEY,
+    dikirim_pada TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO ref_permission (kode, deskripsi) VALUES
+    ('sla.manage', 'Mengatur target SLA permohonan');
+
+INSERT INTO role_permission (role, permission) VALUES
+    ('ADMIN_KOTA', 'sla.manage');
+
+-- When an application entered its current status: the first history entry
+-- with that status after the last entry with another one. Further entries
+-- with the same status, such as added notes, do not restart the clock.
+CREATE OR REPLACE FUNCTION status_sejak(p_id UUID, p_status TEXT, p_dibuat TIMESTAMPTZ)
+RETURNS TIMESTAMPTZ AS $$
+    SELECT COALESCE(MIN(rs.waktu_proses), p_dibuat)
+    FROM riwayat_status rs
+    WHERE rs.permohonan_id = p_id
+      AND rs.status_baru = p_status
+      AND rs.waktu_proses > COALESCE((
+          SELECT MAX(x.waktu_proses)
+          FROM riwayat_status x
+          WHERE x.permohonan_id = p_id AND x.status_baru IS DISTINCT FROM p_status
+      ), '-infinity');
+$$ LANGUAGE sql STABLE;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION IF EXISTS status_sejak;
+DELETE FROM role_permission WHERE permission = 'sla.manage';
+DELETE FROM ref_permission WHERE kode = 'sla.manage';
+DROP TABLE IF EXISTS digest_sla;
+DROP TABLE IF EXISTS target_sla;
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The petugas an application is assigned to, by claim, by an admin or by
+-- round-robin distribution. Only that petugas may change its status.
+ALTER TABLE permohonan
+    ADD COLUMN ditangani_oleh UUID REFERENCES petugas(id) ON DELETE SET NULL,
+    ADD COLUMN ditugaskan_pada TIMESTAMPTZ,
+    ADD COLUMN versi INTEGER NOT NULL DEFAULT 1;
+
+CREATE INDEX idx_permohonan_ditangani_oleh ON permohonan(ditangani_oleh) WHERE ditangani_oleh IS NOT NULL;
+
+INSERT INTO ref_permission (kode, deskripsi) VALUES
+    ('permohonan.assign', 'Menugaskan dan mengalihkan permohonan ke petugas');
+
+INSERT INTO role_permission (role, permission) VALUES
+    ('ADMIN_KOTA', 'permohonan.assign'),
+    ('ADMIN_KECAMATAN', 'permohonan.assign');
+
+-- Function: Optimistic Lock. versi changes whenever the status or the
+-- assignment does, so a form opened at an older versi is refused.
+CREATE OR REPLACE FUNCTION bump_permohonan_versi()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF (OLD.status_terkini IS DISTINCT FROM NEW.status_terkini)
+        OR (OLD.ditangani_oleh IS DISTINCT FROM NEW.ditangani_oleh) THEN
+        NEW.versi := OLD.versi + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_bump_permohonan_versi
+BEFORE UPDATE OF status_terkini, ditangani_oleh ON permohonan
+FOR EACH ROW EXECUTE FUNCTION bump_permohonan_versi();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trg_bump_permohonan_versi ON permohonan;
+DROP FUNCTION IF EXISTS bump_permohonan_versi;
+DELETE FROM role_permission WHERE permission = 'permohonan.assign';
+DELETE FROM ref_permission WHERE kode = 'permohonan.assign';
+DROP INDEX IF EXISTS idx_permohonan_ditangani_oleh;
+ALTER TABLE permohonan
+    DROP COLUMN IF EXISTS versi,
+    DROP COLUMN IF EXISTS ditugaskan_pada,
+    DROP COLUMN IF EXISTS ditangani_oleh;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Petugas lockouts were keyed by NIP, so accounts without a NIP shared one
+-- lockout and a change of NIP dropped it. They are now keyed by petugas ID.
+DELETE FROM kunci_login WHERE user_type = 'petugas' AND identifier = '';
+
+UPDATE kunci_login k
+SET identifier = pt.id::text
+FROM petugas pt
+WHERE k.user_type = 'petugas' AND k.identifier = pt.nip;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE kunci_login k
+SET identifier = pt.nip
+FROM petugas pt
+WHERE k.user_type = 'petugas' AND k.identifier = pt.id::text AND pt.nip IS NOT NULL;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Anyone can type a KK number, so claiming to be the head of household or a
+-- spouse no longer makes a citizen a guardian by itself. A petugas checks the
+-- claim against the KK first; until then the member has no guardian rights.
+-- Existing guardians were never checked and start out unapproved.
+ALTER TABLE penduduk
+    ADD COLUMN wali_disetujui_oleh UUID REFERENCES petugas(id) ON DELETE SET NULL,
+    ADD COLUMN wali_disetujui_at TIMESTAMPTZ;
+
+-- A KK has one husband and one wife, as it has one head of household. Extra
+-- claims made before this index are kept as other relatives.
+UPDATE penduduk p
+SET hubungan_keluarga = 'FAMILI_LAIN'
+WHERE p.hubungan_keluarga IN ('SUAMI', 'ISTRI')
+  AND EXISTS (
+      SELECT 1 FROM penduduk d
+      WHERE d.no_kk = p.no_kk
+        AND d.hubungan_keluarga = p.hubungan_keluarga
+        AND (d.created_at, d.nik) < (p.created_at, p.nik));
+
+CREATE UNIQUE INDEX idx_penduduk_suami ON penduduk(no_kk) WHERE hubungan_keluarga = 'SUAMI';
+CREATE UNIQUE INDEX idx_penduduk_istri ON penduduk(no_kk) WHERE hubungan_keluarga = 'ISTRI';
+
+CREATE INDEX idx_penduduk_wali_menunggu ON penduduk(kelurahan_id)
+    WHERE hubungan_keluarga IN ('KEPALA_KELUARGA', 'SUAMI', 'ISTRI') AND wali_disetujui_at IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_penduduk_wali_menunggu;
+DROP INDEX IF EXISTS idx_penduduk_istri;
+DROP INDEX IF EXISTS idx_penduduk_suami;
+ALTER TABLE penduduk
+    DROP COLUMN IF EXISTS wali_disetujui_at,
+    DROP COLUMN IF EXISTS wali_disetujui_oleh;
+-- +goose StatementEnd
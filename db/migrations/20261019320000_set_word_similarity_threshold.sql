@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Admin search matches names with nama_lengkap %> search, which compares
+-- word_similarity against pg_trgm.word_similarity_threshold. Pin it on the
+-- database instead of relying on the server default, so "Muhamad" finds
+-- "Muhammad" everywhere. The admin highlighter uses the same value.
+DO $$
+BEGIN
+    EXECUTE format('ALTER DATABASE %I SET pg_trgm.word_similarity_threshold = 0.6', current_database());
+END
+$$;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DO $$
+BEGIN
+    EXECUTE format('ALTER DATABASE %I RESET pg_trgm.word_similarity_threshold', current_database());
+END
+$$;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A day of the digest used to be marked as sent before anything was sent, so
+-- a failing mail server lost the whole day. A run now only holds the day for
+-- a while; it is done once every admin with breached applications got it.
+-- Days marked before this migration count as done.
+ALTER TABLE digest_sla RENAME COLUMN dikirim_pada TO diklaim_pada;
+ALTER TABLE digest_sla ADD COLUMN selesai_pada TIMESTAMPTZ;
+UPDATE digest_sla SET selesai_pada = diklaim_pada;
+
+-- Admins who received the digest of a day, so a retry skips them
+CREATE TABLE digest_sla_penerima (
+    tanggal DATE NOT NULL REFERENCES digest_sla(tanggal) ON DELETE CASCADE,
+    petugas_id UUID NOT NULL REFERENCES petugas(id) ON DELETE CASCADE,
+    dikirim_pada TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tanggal, petugas_id)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS digest_sla_penerima;
+ALTER TABLE digest_sla DROP COLUMN IF EXISTS selesai_pada;
+ALTER TABLE digest_sla RENAME COLUMN diklaim_pada TO dikirim_pada;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Where notifications such as the SLA digest go. Login stays by NIP; the
+-- username only doubled as the address when it happened to be one.
+ALTER TABLE petugas ADD COLUMN email TEXT UNIQUE;
+UPDATE petugas SET email = username WHERE username LIKE '%_@_%';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE petugas DROP COLUMN IF EXISTS email;
+-- +goose StatementEnd
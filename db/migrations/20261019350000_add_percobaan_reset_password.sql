@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Wrong reset codes are recorded like failed logins, so an address guessing
+-- codes for many NIKs can be throttled
+ALTER TABLE percobaan_login DROP CONSTRAINT chk_percobaan_login_alasan;
+ALTER TABLE percobaan_login ADD CONSTRAINT chk_percobaan_login_alasan CHECK (alasan IN ('BERHASIL', 'PASSWORD_SALAH', 'TIDAK_DITEMUKAN', 'AKUN_TERKUNCI', 'IP_DIBATASI', 'NONAKTIF', 'TANPA_PASSWORD', 'KODE_2FA_SALAH', 'KODE_RESET_SALAH'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM percobaan_login WHERE alasan = 'KODE_RESET_SALAH';
+ALTER TABLE percobaan_login DROP CONSTRAINT chk_percobaan_login_alasan;
+ALTER TABLE percobaan_login ADD CONSTRAINT chk_percobaan_login_alasan CHECK (alasan IN ('BERHASIL', 'PASSWORD_SALAH', 'TIDAK_DITEMUKAN', 'AKUN_TERKUNCI', 'IP_DIBATASI', 'NONAKTIF', 'TANPA_PASSWORD', 'KODE_2FA_SALAH'));
+-- +goose StatementEnd
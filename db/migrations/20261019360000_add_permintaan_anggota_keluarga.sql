@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A guardian who adds a citizen already on record (typically a walk-in
+-- created by a petugas, without a password) only asks for the link. The
+-- citizen joins the KK once a petugas has checked it against the KK, so a
+-- guardian cannot take over a stranger's record by typing their NIK.
+CREATE TABLE permintaan_anggota_keluarga (
+    nik CHAR(16) PRIMARY KEY REFERENCES penduduk(nik) ON DELETE CASCADE,
+    no_kk CHAR(16) NOT NULL REFERENCES kartu_keluarga(no_kk) ON DELETE CASCADE,
+    hubungan_keluarga TEXT NOT NULL,
+    diajukan_oleh CHAR(16) NOT NULL REFERENCES penduduk(nik) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_permintaan_anggota_keluarga_no_kk ON permintaan_anggota_keluarga(no_kk);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS permintaan_anggota_keluarga;
+-- +goose StatementEnd
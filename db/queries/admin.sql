@@ -8,15 +8,20 @@ SELECT
     COUNT(*) FILTER (WHERE status_terkini = 'DITOLAK') AS ditolak
 FROM permohonan p
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-WHERE (
-    -- Admin Kelurahan: filter by their kelurahan
-    (sqlc.narg('kelurahan_id')::smallint IS NOT NULL AND js.lokasi_kelurahan_id = sqlc.narg('kelurahan_id'))
-    OR
-    -- Admin Kecamatan: only see kecamatan-level data (NULL lokasi_kelurahan_id)
-    (sqlc.narg('kelurahan_id')::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-);
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
+-- Admin Kota: no filter, Admin Kecamatan: their kecamatan, Admin Kelurahan: their kelurahan
+WHERE (sqlc.narg('kecamatan_id')::smallint IS NULL OR l.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('kelurahan_id'));
 
 -- name: ListPermohonanAdmin :many
+-- sort_by is tanggal, nama, status, sesi or relevansi; status sorts in
+-- workflow order and relevansi ranks the closest matches to search first.
+-- Names also match search when they are similar rather than equal, so
+-- "Muhamad" finds "Muhammad".
+-- daftar_sampai is exclusive; tertahan_hari keeps applications whose status
+-- has not changed for that many days, and lewat_sla those past the SLA
+-- target of their status. ditangani_oleh is the queue of one petugas and
+-- belum_ditangani the unassigned applications; both keep open ones only.
 SELECT 
     p.id,
     p.kode_booking,
@@ -29,42 +34,100 @@ SELECT
     js.jam_mulai as jadwal_jam_mulai,
     js.jam_selesai as jadwal_jam_selesai,
     p.nomor_antrian_sesi as nomor_antrian,
-    k.id as lokasi_kelurahan_id,
-    k.nama_kelurahan as lokasi_nama_kelurahan
+    l.kelurahan_id as lokasi_kelurahan_id,
+    l.nama_lokasi,
+    status_sejak(p.id, p.status_terkini, p.created_at) as status_sejak,
+    t.target_hari as target_sla_hari,
+    pn.nama_petugas as nama_penangan
 FROM permohonan p
 JOIN penduduk pd ON p.nik = pd.nik
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
+LEFT JOIN target_sla t ON t.status = p.status_terkini AND t.jenis_permohonan = p.jenis_permohonan
+LEFT JOIN petugas pn ON p.ditangani_oleh = pn.id
 WHERE 
     (sqlc.narg('search')::text IS NULL OR 
-     p.nik ILIKE '%' || sqlc.narg('search') || '%' OR 
-     p.kode_booking ILIKE '%' || sqlc.narg('search') || '%' OR 
-     pd.nama_lengkap ILIKE '%' || sqlc.narg('search') || '%')
+     p.nik::text ILIKE '%' || sqlc.narg('search') || '%' OR 
+     p.kode_booking::text ILIKE '%' || sqlc.narg('search') || '%' OR 
+     pd.nama_lengkap ILIKE '%' || sqlc.narg('search') || '%' OR
+     pd.nama_lengkap %> sqlc.narg('search'))
     AND (sqlc.narg('status')::text IS NULL OR p.status_terkini = sqlc.narg('status'))
-    AND (
-        (sqlc.narg('kelurahan_id')::smallint IS NOT NULL AND js.lokasi_kelurahan_id = sqlc.narg('kelurahan_id'))
-        OR
-        (sqlc.narg('kelurahan_id')::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-    )
-ORDER BY p.created_at DESC
+    AND (sqlc.narg('jenis')::text IS NULL OR p.jenis_permohonan = sqlc.narg('jenis'))
+    AND (sqlc.narg('daftar_dari')::timestamptz IS NULL OR p.created_at >= sqlc.narg('daftar_dari'))
+    AND (sqlc.narg('daftar_sampai')::timestamptz IS NULL OR p.created_at < sqlc.narg('daftar_sampai'))
+    AND (sqlc.narg('tanggal_sesi')::date IS NULL OR js.tanggal = sqlc.narg('tanggal_sesi'))
+    AND (sqlc.narg('filter_kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('filter_kelurahan_id'))
+    AND (sqlc.narg('tertahan_hari')::int IS NULL OR COALESCE(
+            (SELECT MAX(rs.waktu_proses) FROM riwayat_status rs WHERE rs.permohonan_id = p.id),
+            p.created_at
+        ) < CURRENT_TIMESTAMP - make_interval(days => sqlc.narg('tertahan_hari')::int))
+    AND (NOT sqlc.arg('dokumen_kurang')::bool OR EXISTS (
+            SELECT 1 FROM ref_syarat_dokumen sd
+            WHERE sd.jenis_permohonan = p.jenis_permohonan
+              AND NOT EXISTS (
+                  SELECT 1 FROM dokumen_syarat d
+                  WHERE d.permohonan_id = p.id AND d.jenis_dokumen = sd.jenis_dokumen
+              )))
+    AND (NOT sqlc.arg('lewat_sla')::bool OR
+         status_sejak(p.id, p.status_terkini, p.created_at) + make_interval(days => t.target_hari::int) < CURRENT_TIMESTAMP)
+    AND (sqlc.narg('ditangani_oleh')::uuid IS NULL OR
+         (p.ditangani_oleh = sqlc.narg('ditangani_oleh') AND p.status_terkini IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')))
+    AND (NOT sqlc.arg('belum_ditangani')::bool OR
+         (p.ditangani_oleh IS NULL AND p.status_terkini IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')))
+    AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR l.kecamatan_id = sqlc.narg('kecamatan_id'))
+    AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('kelurahan_id'))
+ORDER BY
+    CASE WHEN sqlc.arg('sort_by')::text = 'relevansi' THEN word_similarity(sqlc.narg('search'), concat_ws(' ', pd.nama_lengkap, p.kode_booking, p.nik)) END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND NOT sqlc.arg('sort_desc')::bool THEN pd.nama_lengkap END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND sqlc.arg('sort_desc')::bool THEN pd.nama_lengkap END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'status' AND NOT sqlc.arg('sort_desc')::bool THEN array_position(ARRAY['VERIFIKASI', 'PROSES', 'SIAP_AMBIL', 'SELESAI', 'DITOLAK'], p.status_terkini::text) END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'status' AND sqlc.arg('sort_desc')::bool THEN array_position(ARRAY['VERIFIKASI', 'PROSES', 'SIAP_AMBIL', 'SELESAI', 'DITOLAK'], p.status_terkini::text) END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'sesi' AND NOT sqlc.arg('sort_desc')::bool THEN js.tanggal + js.jam_mulai END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'sesi' AND sqlc.arg('sort_desc')::bool THEN js.tanggal + js.jam_mulai END DESC NULLS LAST,
+    CASE WHEN sqlc.arg('sort_by')::text = 'tanggal' AND NOT sqlc.arg('sort_desc')::bool THEN p.created_at END ASC,
+    p.created_at DESC,
+    p.id
 LIMIT $1 OFFSET $2;
 
 -- name: CountPermohonanAdmin :one
+-- Takes the same filters as ListPermohonanAdmin
 SELECT COUNT(*) 
 FROM permohonan p
 JOIN penduduk pd ON p.nik = pd.nik
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
+LEFT JOIN target_sla t ON t.status = p.status_terkini AND t.jenis_permohonan = p.jenis_permohonan
 WHERE 
     (sqlc.narg('search')::text IS NULL OR 
-     p.nik ILIKE '%' || sqlc.narg('search') || '%' OR 
-     p.kode_booking ILIKE '%' || sqlc.narg('search') || '%' OR 
-     pd.nama_lengkap ILIKE '%' || sqlc.narg('search') || '%')
+     p.nik::text ILIKE '%' || sqlc.narg('search') || '%' OR 
+     p.kode_booking::text ILIKE '%' || sqlc.narg('search') || '%' OR 
+     pd.nama_lengkap ILIKE '%' || sqlc.narg('search') || '%' OR
+     pd.nama_lengkap %> sqlc.narg('search'))
     AND (sqlc.narg('status')::text IS NULL OR p.status_terkini = sqlc.narg('status'))
-    AND (
-        (sqlc.narg('kelurahan_id')::smallint IS NOT NULL AND js.lokasi_kelurahan_id = sqlc.narg('kelurahan_id'))
-        OR
-        (sqlc.narg('kelurahan_id')::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-    );
+    AND (sqlc.narg('jenis')::text IS NULL OR p.jenis_permohonan = sqlc.narg('jenis'))
+    AND (sqlc.narg('daftar_dari')::timestamptz IS NULL OR p.created_at >= sqlc.narg('daftar_dari'))
+    AND (sqlc.narg('daftar_sampai')::timestamptz IS NULL OR p.created_at < sqlc.narg('daftar_sampai'))
+    AND (sqlc.narg('tanggal_sesi')::date IS NULL OR js.tanggal = sqlc.narg('tanggal_sesi'))
+    AND (sqlc.narg('filter_kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('filter_kelurahan_id'))
+    AND (sqlc.narg('tertahan_hari')::int IS NULL OR COALESCE(
+            (SELECT MAX(rs.waktu_proses) FROM riwayat_status rs WHERE rs.permohonan_id = p.id),
+            p.created_at
+        ) < CURRENT_TIMESTAMP - make_interval(days => sqlc.narg('tertahan_hari')::int))
+    AND (NOT sqlc.arg('dokumen_kurang')::bool OR EXISTS (
+            SELECT 1 FROM ref_syarat_dokumen sd
+            WHERE sd.jenis_permohonan = p.jenis_permohonan
+              AND NOT EXISTS (
+                  SELECT 1 FROM dokumen_syarat d
+                  WHERE d.permohonan_id = p.id AND d.jenis_dokumen = sd.jenis_dokumen
+              )))
+    AND (NOT sqlc.arg('lewat_sla')::bool OR
+         status_sejak(p.id, p.status_terkini, p.created_at) + make_interval(days => t.target_hari::int) < CURRENT_TIMESTAMP)
+    AND (sqlc.narg('ditangani_oleh')::uuid IS NULL OR
+         (p.ditangani_oleh = sqlc.narg('ditangani_oleh') AND p.status_terkini IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')))
+    AND (NOT sqlc.arg('belum_ditangani')::bool OR
+         (p.ditangani_oleh IS NULL AND p.status_terkini IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')))
+    AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR l.kecamatan_id = sqlc.narg('kecamatan_id'))
+    AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('kelurahan_id'));
 
 -- name: GetPermohonanDetailAdmin :one
 SELECT 
@@ -83,32 +146,50 @@ SELECT
     js.jam_mulai as jadwal_jam_mulai,
     js.jam_selesai as jadwal_jam_selesai,
     p.nomor_antrian_sesi as nomor_antrian,
-    k_lokasi.nama_kelurahan as lokasi_permohonan
+    l.nama_lokasi as lokasi_permohonan,
+    kec.nama_kecamatan as kecamatan,
+    kec.kode_wilayah,
+    pd.tanggal_lahir,
+    pd.status_perkawinan,
+    pd.hubungan_keluarga,
+    p.pemohon_nik,
+    pm.nama_lengkap as nama_pemohon
 FROM permohonan p
 JOIN penduduk pd ON p.nik = pd.nik
+LEFT JOIN penduduk pm ON p.pemohon_nik = pm.nik
 LEFT JOIN ref_kelurahan k_penduduk ON pd.kelurahan_id = k_penduduk.id
+LEFT JOIN ref_kecamatan kec ON k_penduduk.kecamatan_id = kec.id
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-LEFT JOIN ref_kelurahan k_lokasi ON js.lokasi_kelurahan_id = k_lokasi.id
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
 WHERE p.id = $1
-  AND (
-    (sqlc.narg('kelurahan_id')::smallint IS NOT NULL AND js.lokasi_kelurahan_id = sqlc.narg('kelurahan_id'))
-    OR
-    (sqlc.narg('kelurahan_id')::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-  );
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR l.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('kelurahan_id'));
 
--- name: UpdatePermohonanStatusAdmin :exec
+-- name: GetPermohonanWilayah :one
+SELECT l.kecamatan_id, l.kelurahan_id
+FROM permohonan p
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+WHERE p.id = $1;
+
+-- name: UpdatePermohonanStatusAdmin :execrows
+-- Changes the status only for the petugas holding the application and only
+-- at the versi their form was loaded with; no rows means someone else holds
+-- it or it changed in the meantime.
 UPDATE permohonan p
 SET status_terkini = $2
-FROM jadwal_sesi js
+FROM jadwal_sesi js, lokasi_layanan l
 WHERE p.id = $1
+  AND p.ditangani_oleh = sqlc.arg('petugas_id')
+  AND p.versi = sqlc.arg('versi')
   AND p.jadwal_sesi_id = js.id
-  AND (
-    (sqlc.narg('kelurahan_id')::smallint IS NOT NULL AND js.lokasi_kelurahan_id = sqlc.narg('kelurahan_id'))
-    OR
-    (sqlc.narg('kelurahan_id')::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-  );
+  AND js.lokasi_id = l.id
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR l.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('kelurahan_id'));
 
 -- name: ListPendudukAdmin :many
+-- sort_by is tanggal, nama, kelurahan or relevansi, which ranks the closest
+-- matches to search first
 SELECT 
     p.nik,
     p.nama_lengkap,
@@ -116,16 +197,44 @@ SELECT
     p.alamat,
     p.email,
     p.no_hp,
-    k.nama_kelurahan
+    k.nama_kelurahan,
+    kc.kode_wilayah
 FROM penduduk p
 LEFT JOIN ref_kelurahan k ON p.kelurahan_id = k.id
+LEFT JOIN ref_kecamatan kc ON k.kecamatan_id = kc.id
 WHERE 
     (sqlc.narg('search')::text IS NULL OR 
-     p.nik ILIKE '%' || sqlc.narg('search') || '%' OR 
+     p.nik::text ILIKE '%' || sqlc.narg('search') || '%' OR 
      p.nama_lengkap ILIKE '%' || sqlc.narg('search') || '%' OR
-     p.alamat ILIKE '%' || sqlc.narg('search') || '%')
+     p.alamat ILIKE '%' || sqlc.narg('search') || '%' OR
+     p.nama_lengkap %> sqlc.narg('search'))
+    AND (sqlc.narg('jenis_kelamin')::text IS NULL OR p.jenis_kelamin = sqlc.narg('jenis_kelamin'))
+    AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR k.kecamatan_id = sqlc.narg('kecamatan_id'))
     AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR p.kelurahan_id = sqlc.narg('kelurahan_id'))
-ORDER BY p.created_at DESC;
+ORDER BY
+    CASE WHEN sqlc.arg('sort_by')::text = 'relevansi' THEN word_similarity(sqlc.narg('search'), concat_ws(' ', p.nama_lengkap, p.nik)) END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND NOT sqlc.arg('sort_desc')::bool THEN p.nama_lengkap END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND sqlc.arg('sort_desc')::bool THEN p.nama_lengkap END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'kelurahan' AND NOT sqlc.arg('sort_desc')::bool THEN k.nama_kelurahan END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'kelurahan' AND sqlc.arg('sort_desc')::bool THEN k.nama_kelurahan END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'tanggal' AND NOT sqlc.arg('sort_desc')::bool THEN p.created_at END ASC,
+    p.created_at DESC,
+    p.nik
+LIMIT $1 OFFSET $2;
+
+-- name: CountPendudukAdmin :one
+SELECT COUNT(*)
+FROM penduduk p
+LEFT JOIN ref_kelurahan k ON p.kelurahan_id = k.id
+WHERE 
+    (sqlc.narg('search')::text IS NULL OR 
+     p.nik::text ILIKE '%' || sqlc.narg('search') || '%' OR 
+     p.nama_lengkap ILIKE '%' || sqlc.narg('search') || '%' OR
+     p.alamat ILIKE '%' || sqlc.narg('search') || '%' OR
+     p.nama_lengkap %> sqlc.narg('search'))
+    AND (sqlc.narg('jenis_kelamin')::text IS NULL OR p.jenis_kelamin = sqlc.narg('jenis_kelamin'))
+    AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR k.kecamatan_id = sqlc.narg('kecamatan_id'))
+    AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR p.kelurahan_id = sqlc.narg('kelurahan_id'));
 
 -- name: GetPendudukStatsAdmin :one
 SELECT 
@@ -134,7 +243,9 @@ SELECT
     COUNT(*) FILTER (WHERE jenis_kelamin = 'PEREMPUAN') AS perempuan,
     COUNT(*) AS wajib_ktp
 FROM penduduk p
-WHERE (sqlc.narg('kelurahan_id')::smallint IS NULL OR p.kelurahan_id = sqlc.narg('kelurahan_id'));
+LEFT JOIN ref_kelurahan k ON p.kelurahan_id = k.id
+WHERE (sqlc.narg('kecamatan_id')::smallint IS NULL OR k.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR p.kelurahan_id = sqlc.narg('kelurahan_id'));
 
 -- name: GetDokumenByPermohonan :many
 SELECT 
@@ -154,26 +265,27 @@ SELECT
     js.kuota_terisi,
     js.kuota_maksimal,
     js.status_sesi,
-    COALESCE(k.nama_kelurahan, 'Kecamatan Pademangan')::text as nama_kelurahan
+    l.nama_lokasi
 FROM jadwal_sesi js
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
-WHERE js.tanggal = CURRENT_DATE
-  AND (
-    (sqlc.narg('kelurahan_id')::smallint IS NOT NULL AND js.lokasi_kelurahan_id = sqlc.narg('kelurahan_id'))
-    OR
-    (sqlc.narg('kelurahan_id')::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-  )
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+WHERE js.tanggal = sqlc.arg('tanggal')
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR l.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('kelurahan_id'))
 ORDER BY js.jam_mulai;
 
 -- name: GetPetugasStatsAdmin :one
 SELECT 
     COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE role = 'ADMIN_KOTA') AS admin_kota,
     COUNT(*) FILTER (WHERE role = 'ADMIN_KECAMATAN') AS admin_kecamatan,
     COUNT(*) FILTER (WHERE role = 'ADMIN_KELURAHAN') AS admin_kelurahan,
     COUNT(*) FILTER (WHERE is_active = TRUE) AS aktif
-FROM petugas;
+FROM petugas p
+WHERE (sqlc.narg('kecamatan_id')::smallint IS NULL OR p.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR p.kelurahan_id = sqlc.narg('kelurahan_id'));
 
 -- name: ListPetugasAdmin :many
+-- sort_by is tanggal, nama, role or status
 SELECT 
     p.id,
     p.username,
@@ -182,7 +294,88 @@ SELECT
     p.role,
     p.is_active,
     CASE WHEN p.is_active THEN 'AKTIF' ELSE 'NON_AKTIF' END AS status,
-    k.nama_kelurahan
+    k.nama_kelurahan,
+    kec.nama_kecamatan
 FROM petugas p
 LEFT JOIN ref_kelurahan k ON p.kelurahan_id = k.id
-ORDER BY p.created_at DESC;
+LEFT JOIN ref_kecamatan kec ON p.kecamatan_id = kec.id
+WHERE (sqlc.narg('search')::text IS NULL OR
+       p.username ILIKE '%' || sqlc.narg('search') || '%' OR
+       p.nama_petugas ILIKE '%' || sqlc.narg('search') || '%' OR
+       p.nip ILIKE '%' || sqlc.narg('search') || '%')
+  AND (sqlc.narg('role')::text IS NULL OR p.role = sqlc.narg('role'))
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR p.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR p.kelurahan_id = sqlc.narg('kelurahan_id'))
+ORDER BY
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND NOT sqlc.arg('sort_desc')::bool THEN p.nama_petugas END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND sqlc.arg('sort_desc')::bool THEN p.nama_petugas END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'role' AND NOT sqlc.arg('sort_desc')::bool THEN p.role END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'role' AND sqlc.arg('sort_desc')::bool THEN p.role END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'status' AND NOT sqlc.arg('sort_desc')::bool THEN p.is_active END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'status' AND sqlc.arg('sort_desc')::bool THEN p.is_active END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'tanggal' AND NOT sqlc.arg('sort_desc')::bool THEN p.created_at END ASC,
+    p.created_at DESC,
+    p.id
+LIMIT $1 OFFSET $2;
+
+-- name: CountPetugasAdmin :one
+SELECT COUNT(*)
+FROM petugas p
+WHERE (sqlc.narg('search')::text IS NULL OR
+       p.username ILIKE '%' || sqlc.narg('search') || '%' OR
+       p.nama_petugas ILIKE '%' || sqlc.narg('search') || '%' OR
+       p.nip ILIKE '%' || sqlc.narg('search') || '%')
+  AND (sqlc.narg('role')::text IS NULL OR p.role = sqlc.narg('role'))
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR p.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR p.kelurahan_id = sqlc.narg('kelurahan_id'));
+
+-- name: GetPetugasById :one
+SELECT * FROM petugas WHERE id = $1;
+
+-- name: UpdatePetugasProfil :exec
+UPDATE petugas
+SET nama_petugas = $2,
+    nip = $3,
+    email = $4,
+    updated_by = $5,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: UpdatePetugasWilayah :exec
+-- The role trigger derives the new role from the wilayah columns
+UPDATE petugas
+SET kecamatan_id = $2,
+    kelurahan_id = $3,
+    updated_by = $4,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: SetPetugasActive :exec
+UPDATE petugas
+SET is_active = $2,
+    updated_by = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: UpdatePetugasPassword :exec
+UPDATE petugas
+SET password_hash = $2,
+    updated_by = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: InsertRiwayatPetugas :exec
+INSERT INTO riwayat_petugas (petugas_id, aksi, dilakukan_oleh, keterangan)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListRiwayatPetugas :many
+SELECT
+    rp.aksi,
+    rp.keterangan,
+    rp.waktu,
+    p.nama_petugas AS dilakukan_oleh
+FROM riwayat_petugas rp
+LEFT JOIN petugas p ON rp.dilakukan_oleh = p.id
+WHERE rp.petugas_id = $1
+ORDER BY rp.waktu DESC
+LIMIT 20;
@@ -10,15 +10,59 @@ INSERT INTO penduduk (
     nama_lengkap,
     alamat,
     no_hp,
-    jenis_kelamin
-) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    jenis_kelamin,
+    tanggal_lahir,
+    status_perkawinan
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING *;
 
 -- name: GetPetugasByNIP :one
-SELECT * FROM petugas WHERE nip = $1 AND is_active = TRUE;
+SELECT * FROM petugas WHERE nip = $1;
 
 -- name: CheckPendudukExists :one
 SELECT EXISTS(SELECT 1 FROM penduduk WHERE nik = $1) AS exists;
 
 -- name: CheckEmailExists :one
 SELECT EXISTS(SELECT 1 FROM penduduk WHERE email = $1) AS exists;
+
+-- name: ListPermissionByRole :many
+SELECT permission FROM role_permission WHERE role = $1 ORDER BY permission;
+
+-- name: CountKodeResetPasswordSince :one
+SELECT COUNT(*) FROM kode_reset_password
+WHERE nik = $1 AND created_at > CURRENT_TIMESTAMP - make_interval(mins => sqlc.arg('minutes')::int);
+
+-- name: CreateKodeResetPassword :exec
+-- Issuing a new code spends any earlier one
+WITH spent AS (
+    UPDATE kode_reset_password
+    SET digunakan_at = CURRENT_TIMESTAMP
+    WHERE nik = $1 AND digunakan_at IS NULL
+)
+INSERT INTO kode_reset_password (nik, kode_hash, kanal, expires_at)
+VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(mins => sqlc.arg('ttl_minutes')::int));
+
+-- name: GetKodeResetPasswordAktif :one
+SELECT * FROM kode_reset_password
+WHERE nik = $1 AND digunakan_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: IncrementPercobaanKodeReset :one
+-- Counts a try before the code is compared; no row means the code is spent
+-- or out of tries, so parallel guesses cannot exceed max_tries
+UPDATE kode_reset_password
+SET percobaan = percobaan + 1
+WHERE id = $1
+  AND percobaan < sqlc.arg('max_tries')::int
+  AND digunakan_at IS NULL
+RETURNING percobaan;
+
+-- name: UseKodeResetPassword :execrows
+-- No row means another request used the code first
+UPDATE kode_reset_password
+SET digunakan_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND digunakan_at IS NULL;
+
+-- name: SetPendudukPassword :exec
+UPDATE penduduk SET password_hash = $2 WHERE nik = $1;
@@ -0,0 +1,3 @@
+-- name: InsertLogEkspor :exec
+INSERT INTO log_ekspor (petugas_id, nama_petugas, jenis, format, filter, jumlah_baris, data_lengkap, ip_address)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
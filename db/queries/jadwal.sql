@@ -7,15 +7,11 @@ SELECT
     js.kuota_terisi,
     js.kuota_maksimal,
     js.status_sesi,
-    COALESCE(k.nama_kelurahan, 'Kecamatan Pademangan')::text as nama_kelurahan
+    l.nama_lokasi
 FROM jadwal_sesi js
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
 WHERE js.tanggal >= $1 AND js.tanggal <= $2
-  AND (
-    (sqlc.narg('kelurahan_id')::integer IS NOT NULL AND js.lokasi_kelurahan_id = sqlc.narg('kelurahan_id'))
-    OR
-    (sqlc.narg('kelurahan_id')::integer IS NULL AND js.lokasi_kelurahan_id IS NULL)
-  )
+  AND js.lokasi_id = $3
 ORDER BY js.tanggal, js.jam_mulai;
 
 -- name: GetJadwalSesiById :one
@@ -27,15 +23,17 @@ SELECT
     js.kuota_terisi,
     js.kuota_maksimal,
     js.status_sesi,
-    js.lokasi_kelurahan_id,
-    COALESCE(k.nama_kelurahan, 'Kecamatan Pademangan')::text as nama_kelurahan
+    js.lokasi_id,
+    l.kelurahan_id as lokasi_kelurahan_id,
+    l.nama_lokasi,
+    l.kecamatan_id as lokasi_kecamatan_id
 FROM jadwal_sesi js
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
 WHERE js.id = $1;
 
 -- name: CreateJadwalSesi :one
 INSERT INTO jadwal_sesi (
-    lokasi_kelurahan_id,
+    lokasi_id,
     tanggal,
     jam_mulai,
     jam_selesai,
@@ -43,7 +41,7 @@ INSERT INTO jadwal_sesi (
     kuota_terisi,
     status_sesi
 ) VALUES ($1, $2, $3, $4, $5, 0, 'BUKA')
-ON CONFLICT (tanggal, jam_mulai, lokasi_kelurahan_id) DO NOTHING
+ON CONFLICT (tanggal, jam_mulai, lokasi_id) DO NOTHING
 RETURNING id;
 
 -- name: UpdateJadwalSesi :exec
@@ -66,8 +64,13 @@ SELECT id, nama_kelurahan
 FROM ref_kelurahan
 ORDER BY nama_kelurahan;
 
+-- name: GetKecamatanById :one
+SELECT id, nama_kecamatan
+FROM ref_kecamatan
+WHERE id = $1;
+
 -- name: GetKelurahanById :one
-SELECT id, nama_kelurahan
+SELECT id, nama_kelurahan, kecamatan_id
 FROM ref_kelurahan
 WHERE id = $1;
 
@@ -78,22 +81,71 @@ SELECT
     p.nik,
     pd.nama_lengkap,
     p.status_terkini,
-    p.nomor_antrian_sesi as nomor_antrian
+    p.nomor_antrian_sesi as nomor_antrian,
+    p.jenis_permohonan,
+    p.waktu_hadir
 FROM permohonan p
 JOIN penduduk pd ON p.nik = pd.nik
 WHERE p.jadwal_sesi_id = $1
 ORDER BY p.nomor_antrian_sesi ASC;
 
+-- name: ListAntrianJadwal :many
+-- One page of a session's queue. sort_by is antrian, nama or status.
+SELECT
+    p.id,
+    p.kode_booking,
+    p.nik,
+    pd.nama_lengkap,
+    p.status_terkini,
+    p.nomor_antrian_sesi AS nomor_antrian,
+    p.jenis_permohonan,
+    p.waktu_hadir
+FROM permohonan p
+JOIN penduduk pd ON p.nik = pd.nik
+WHERE p.jadwal_sesi_id = sqlc.arg('jadwal_sesi_id')
+ORDER BY
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND NOT sqlc.arg('sort_desc')::bool THEN pd.nama_lengkap END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND sqlc.arg('sort_desc')::bool THEN pd.nama_lengkap END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'status' AND NOT sqlc.arg('sort_desc')::bool THEN p.status_terkini END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'status' AND sqlc.arg('sort_desc')::bool THEN p.status_terkini END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'antrian' AND sqlc.arg('sort_desc')::bool THEN p.nomor_antrian_sesi END DESC,
+    p.nomor_antrian_sesi ASC,
+    p.id
+LIMIT $1 OFFSET $2;
+
 -- name: DeleteJadwalSesi :exec
-DELETE FROM jadwal_sesi
-WHERE id = $1
-  AND (
-    (sqlc.narg('kelurahan_id')::integer IS NOT NULL AND lokasi_kelurahan_id = sqlc.narg('kelurahan_id'))
-    OR
-    (sqlc.narg('kelurahan_id')::integer IS NULL AND lokasi_kelurahan_id IS NULL)
-  );
+DELETE FROM jadwal_sesi js
+USING lokasi_layanan l
+WHERE js.id = $1
+  AND js.lokasi_id = l.id
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR l.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('kelurahan_id'));
 
 -- name: CountPermohonanByJadwal :one
 SELECT COUNT(*) as count
 FROM permohonan
 WHERE jadwal_sesi_id = $1;
+
+-- name: GetAturanBooking :one
+SELECT * FROM aturan_booking
+WHERE lokasi_id = $1;
+
+-- name: UpsertAturanBooking :one
+INSERT INTO aturan_booking (
+    lokasi_id,
+    min_jam_pemberitahuan,
+    maks_hari_ke_depan,
+    batas_jam_hari_sama,
+    maks_booking_per_bulan,
+    updated_by,
+    updated_at
+) VALUES ($1, $2, $3, $4, $5, $6, NOW())
+ON CONFLICT (lokasi_id) DO UPDATE
+SET 
+    min_jam_pemberitahuan = EXCLUDED.min_jam_pemberitahuan,
+    maks_hari_ke_depan = EXCLUDED.maks_hari_ke_depan,
+    batas_jam_hari_sama = EXCLUDED.batas_jam_hari_sama,
+    maks_booking_per_bulan = EXCLUDED.maks_booking_per_bulan,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = EXCLUDED.updated_at
+RETURNING *;
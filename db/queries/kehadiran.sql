@@ -0,0 +1,72 @@
+-- name: GetPermohonanCheckIn :one
+-- Looks a booking up for the check-in counter
+SELECT
+    p.id,
+    p.kode_booking,
+    p.nik,
+    pd.nama_lengkap,
+    p.jenis_permohonan,
+    p.status_terkini,
+    p.nomor_antrian_sesi AS nomor_antrian,
+    p.waktu_hadir,
+    js.id AS jadwal_id,
+    js.tanggal AS jadwal_tanggal,
+    js.jam_mulai AS jadwal_jam_mulai,
+    js.jam_selesai AS jadwal_jam_selesai,
+    l.nama_lokasi
+FROM permohonan p
+JOIN penduduk pd ON p.nik = pd.nik
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+WHERE p.kode_booking = $1;
+
+-- name: RecordKehadiran :one
+-- Records arrival once; returns no rows when it was already recorded
+UPDATE permohonan
+SET waktu_hadir = NOW(), hadir_dicatat_oleh = $2
+WHERE id = $1 AND waktu_hadir IS NULL
+RETURNING waktu_hadir;
+
+-- name: ListKehadiranAdmin :many
+-- Arrivals recorded for the sessions of one day in the wilayah. sort_by is
+-- hadir, nama or antrian.
+SELECT
+    p.id,
+    p.kode_booking,
+    pd.nama_lengkap,
+    p.status_terkini,
+    p.nomor_antrian_sesi AS nomor_antrian,
+    p.waktu_hadir,
+    js.id AS jadwal_id,
+    js.jam_mulai AS jadwal_jam_mulai,
+    js.jam_selesai AS jadwal_jam_selesai,
+    l.nama_lokasi,
+    pt.nama_petugas AS dicatat_oleh
+FROM permohonan p
+JOIN penduduk pd ON p.nik = pd.nik
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+LEFT JOIN petugas pt ON p.hadir_dicatat_oleh = pt.id
+WHERE p.waktu_hadir IS NOT NULL
+  AND js.tanggal = sqlc.arg('tanggal')
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR l.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('kelurahan_id'))
+ORDER BY
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND NOT sqlc.arg('sort_desc')::bool THEN pd.nama_lengkap END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND sqlc.arg('sort_desc')::bool THEN pd.nama_lengkap END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'antrian' AND NOT sqlc.arg('sort_desc')::bool THEN p.nomor_antrian_sesi END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'antrian' AND sqlc.arg('sort_desc')::bool THEN p.nomor_antrian_sesi END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'hadir' AND NOT sqlc.arg('sort_desc')::bool THEN p.waktu_hadir END ASC,
+    p.waktu_hadir DESC,
+    p.id
+LIMIT $1 OFFSET $2;
+
+-- name: CountKehadiranAdmin :one
+SELECT COUNT(*)
+FROM permohonan p
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+WHERE p.waktu_hadir IS NOT NULL
+  AND js.tanggal = sqlc.arg('tanggal')
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR l.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('kelurahan_id'));
@@ -0,0 +1,147 @@
+-- name: GetKartuKeluarga :one
+SELECT * FROM kartu_keluarga WHERE no_kk = $1;
+
+-- name: CreateKartuKeluarga :exec
+-- A KK already registered by another household member is kept as-is
+INSERT INTO kartu_keluarga (no_kk, kelurahan_id, alamat)
+VALUES ($1, $2, $3)
+ON CONFLICT (no_kk) DO NOTHING;
+
+-- name: SetKeluargaPenduduk :exec
+-- A new place in a KK has to be verified again before it grants guardianship
+UPDATE penduduk
+SET no_kk = $2,
+    hubungan_keluarga = $3,
+    wali_disetujui_oleh = NULL,
+    wali_disetujui_at = NULL
+WHERE nik = $1;
+
+-- name: CreateAnggotaKeluarga :exec
+-- Members added by a guardian have no password until they claim the account
+INSERT INTO penduduk (
+    nik,
+    kelurahan_id,
+    nama_lengkap,
+    alamat,
+    jenis_kelamin,
+    tanggal_lahir,
+    status_perkawinan,
+    no_kk,
+    hubungan_keluarga
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: ListAnggotaKeluarga :many
+SELECT
+    nik,
+    nama_lengkap,
+    jenis_kelamin,
+    tanggal_lahir,
+    hubungan_keluarga,
+    (password_hash IS NOT NULL AND password_hash <> '')::boolean AS punya_akun,
+    (wali_disetujui_at IS NOT NULL)::boolean AS wali_disetujui
+FROM penduduk
+WHERE no_kk = $1
+ORDER BY hubungan_keluarga = 'KEPALA_KELUARGA' DESC, tanggal_lahir NULLS LAST, nama_lengkap;
+
+-- name: ListTanggunganKeluarga :many
+-- Household members the given citizen may apply for. The head of household
+-- and their spouse are the household's guardians once a petugas has verified
+-- their place in the KK.
+SELECT
+    a.nik,
+    a.nama_lengkap,
+    a.hubungan_keluarga
+FROM penduduk w
+JOIN penduduk a ON a.no_kk = w.no_kk AND a.nik <> w.nik
+WHERE w.nik = $1
+  AND w.hubungan_keluarga IN ('KEPALA_KELUARGA', 'SUAMI', 'ISTRI')
+  AND w.wali_disetujui_at IS NOT NULL
+ORDER BY a.tanggal_lahir NULLS LAST, a.nama_lengkap;
+
+-- name: ListWaliMenunggu :many
+-- Guardian claims waiting for a petugas to check them against the KK
+SELECT
+    p.nik,
+    p.nama_lengkap,
+    p.no_kk,
+    p.hubungan_keluarga,
+    p.created_at,
+    kel.nama_kelurahan
+FROM penduduk p
+LEFT JOIN ref_kelurahan kel ON p.kelurahan_id = kel.id
+WHERE p.hubungan_keluarga IN ('KEPALA_KELUARGA', 'SUAMI', 'ISTRI')
+  AND p.wali_disetujui_at IS NULL
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR kel.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR p.kelurahan_id = sqlc.narg('kelurahan_id'))
+ORDER BY p.no_kk, p.nama_lengkap;
+
+-- name: ApproveWaliPenduduk :execrows
+UPDATE penduduk
+SET wali_disetujui_oleh = sqlc.narg('disetujui_oleh'),
+    wali_disetujui_at = CURRENT_TIMESTAMP
+WHERE nik = sqlc.arg('nik')
+  AND hubungan_keluarga IN ('KEPALA_KELUARGA', 'SUAMI', 'ISTRI')
+  AND wali_disetujui_at IS NULL;
+
+-- name: RejectWaliPenduduk :execrows
+-- The citizen is unlinked from the KK and may link it again with the
+-- relationship printed on it
+UPDATE penduduk
+SET no_kk = NULL,
+    hubungan_keluarga = NULL
+WHERE nik = $1
+  AND hubungan_keluarga IN ('KEPALA_KELUARGA', 'SUAMI', 'ISTRI')
+  AND wali_disetujui_at IS NULL;
+
+-- name: CreatePermintaanAnggota :execrows
+-- A citizen has at most one link request open; a second guardian asking for
+-- the same citizen is refused
+INSERT INTO permintaan_anggota_keluarga (nik, no_kk, hubungan_keluarga, diajukan_oleh)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (nik) DO NOTHING;
+
+-- name: ListPermintaanAnggotaByKK :many
+SELECT
+    pa.nik,
+    p.nama_lengkap,
+    p.tanggal_lahir,
+    pa.hubungan_keluarga
+FROM permintaan_anggota_keluarga pa
+JOIN penduduk p ON p.nik = pa.nik
+WHERE pa.no_kk = $1
+ORDER BY pa.created_at;
+
+-- name: ListPermintaanAnggotaMenunggu :many
+-- Link requests waiting for a petugas, in the wilayah of the citizen to be
+-- linked
+SELECT
+    pa.nik,
+    p.nama_lengkap,
+    pa.no_kk,
+    pa.hubungan_keluarga,
+    pa.created_at,
+    w.nama_lengkap AS nama_pengaju,
+    kel.nama_kelurahan
+FROM permintaan_anggota_keluarga pa
+JOIN penduduk p ON p.nik = pa.nik
+JOIN penduduk w ON w.nik = pa.diajukan_oleh
+LEFT JOIN ref_kelurahan kel ON p.kelurahan_id = kel.id
+WHERE (sqlc.narg('kecamatan_id')::smallint IS NULL OR kel.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR p.kelurahan_id = sqlc.narg('kelurahan_id'))
+ORDER BY pa.no_kk, pa.created_at;
+
+-- name: ApprovePermintaanAnggota :execrows
+-- Links the citizen to the requested KK, provided they have not joined a KK
+-- meanwhile. The request is removed by the caller in the same transaction.
+UPDATE penduduk p
+SET no_kk = pa.no_kk,
+    hubungan_keluarga = pa.hubungan_keluarga,
+    wali_disetujui_oleh = NULL,
+    wali_disetujui_at = NULL
+FROM permintaan_anggota_keluarga pa
+WHERE pa.nik = p.nik
+  AND p.nik = $1
+  AND p.no_kk IS NULL;
+
+-- name: DeletePermintaanAnggota :execrows
+DELETE FROM permintaan_anggota_keluarga WHERE nik = $1;
@@ -0,0 +1,21 @@
+-- name: InsertPercobaanLacak :exec
+INSERT INTO percobaan_lacak (kode_booking, ip_address, berhasil)
+VALUES ($1, $2, $3);
+
+-- name: CountGagalLacakByIP :one
+SELECT COUNT(*) FROM percobaan_lacak
+WHERE ip_address = $1
+  AND NOT berhasil
+  AND created_at > CURRENT_TIMESTAMP - make_interval(mins => sqlc.arg('minutes')::int);
+
+-- name: GetGagalLacakByIPKode :one
+-- Failed lookups of one booking code from one address, for the backoff
+-- between guesses. Without failures terakhir is the zero time.Time.
+SELECT
+    COUNT(*) AS gagal,
+    COALESCE(MAX(created_at), '0001-01-01 00:00:00+00')::timestamptz AS terakhir
+FROM percobaan_lacak
+WHERE kode_booking = $1
+  AND ip_address = $2
+  AND NOT berhasil
+  AND created_at > CURRENT_TIMESTAMP - make_interval(mins => sqlc.arg('minutes')::int);
@@ -0,0 +1,96 @@
+-- name: RefreshLaporanPermohonan :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY mv_laporan_permohonan;
+
+-- name: RefreshLaporanDurasi :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY mv_laporan_durasi;
+
+-- name: RefreshLaporanPenolakan :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY mv_laporan_penolakan;
+
+-- name: RefreshLaporanSesi :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY mv_laporan_sesi;
+
+-- name: ListLaporanPermohonanMingguan :many
+-- Applications per week and jenis from dari onwards
+SELECT
+    minggu,
+    jenis_permohonan,
+    SUM(jumlah)::int AS jumlah,
+    SUM(ditolak)::int AS ditolak
+FROM mv_laporan_permohonan
+WHERE minggu >= sqlc.arg('dari')::date
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR kelurahan_id = sqlc.narg('kelurahan_id'))
+GROUP BY minggu, jenis_permohonan
+ORDER BY minggu, jenis_permohonan;
+
+-- name: ListLaporanPermohonanKelurahan :many
+-- Applications per kelurahan of the applicant from dari onwards, busiest first
+SELECT
+    m.kelurahan_pemohon_id,
+    COALESCE(k.nama_kelurahan, 'Tidak diketahui')::text AS nama_kelurahan,
+    SUM(m.jumlah)::int AS jumlah,
+    SUM(m.ditolak)::int AS ditolak
+FROM mv_laporan_permohonan m
+LEFT JOIN ref_kelurahan k ON k.id = m.kelurahan_pemohon_id
+WHERE m.minggu >= sqlc.arg('dari')::date
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR m.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR m.kelurahan_id = sqlc.narg('kelurahan_id'))
+GROUP BY m.kelurahan_pemohon_id, k.nama_kelurahan
+ORDER BY jumlah DESC, nama_kelurahan;
+
+-- name: ListLaporanDurasiMingguan :many
+-- Average and 90th percentile hours from VERIFIKASI to SELESAI per week of
+-- completion
+SELECT
+    minggu,
+    COUNT(*)::int AS jumlah,
+    AVG(durasi_jam)::float8 AS rata_rata_jam,
+    (percentile_cont(0.9) WITHIN GROUP (ORDER BY durasi_jam))::float8 AS p90_jam
+FROM mv_laporan_durasi
+WHERE minggu >= sqlc.arg('dari')::date
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR kelurahan_id = sqlc.narg('kelurahan_id'))
+GROUP BY minggu
+ORDER BY minggu;
+
+-- name: GetLaporanDurasi :one
+-- The same figures over the whole period
+SELECT
+    COUNT(*)::int AS jumlah,
+    COALESCE(AVG(durasi_jam), 0)::float8 AS rata_rata_jam,
+    COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY durasi_jam), 0)::float8 AS p90_jam
+FROM mv_laporan_durasi
+WHERE minggu >= sqlc.arg('dari')::date
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR kelurahan_id = sqlc.narg('kelurahan_id'));
+
+-- name: ListLaporanPenolakan :many
+-- Rejections per reason from dari onwards, most common first
+SELECT
+    alasan,
+    SUM(jumlah)::int AS jumlah
+FROM mv_laporan_penolakan
+WHERE minggu >= sqlc.arg('dari')::date
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR kelurahan_id = sqlc.narg('kelurahan_id'))
+GROUP BY alasan
+ORDER BY jumlah DESC, alasan;
+
+-- name: ListLaporanSesiMingguan :many
+-- Quota use of sessions up to hari_ini per week. Attendance counts only past
+-- sessions where check-in was used.
+SELECT
+    minggu,
+    COUNT(*)::int AS jumlah_sesi,
+    SUM(kuota_maksimal)::int AS kuota_maksimal,
+    SUM(kuota_terisi)::int AS kuota_terisi,
+    COALESCE(SUM(terdaftar) FILTER (WHERE pencatatan_hadir AND tanggal < sqlc.arg('hari_ini')::date), 0)::int AS terdaftar_tercatat,
+    COALESCE(SUM(hadir) FILTER (WHERE pencatatan_hadir AND tanggal < sqlc.arg('hari_ini')::date), 0)::int AS hadir
+FROM mv_laporan_sesi
+WHERE minggu >= sqlc.arg('dari')::date
+  AND tanggal <= sqlc.arg('hari_ini')::date
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR kelurahan_id = sqlc.narg('kelurahan_id'))
+GROUP BY minggu
+ORDER BY minggu;
@@ -0,0 +1,161 @@
+-- name: InsertPercobaanLogin :exec
+INSERT INTO percobaan_login (user_type, identifier, ip_address, user_agent, berhasil, alasan)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: CountGagalLoginByIP :one
+SELECT COUNT(*) FROM percobaan_login
+WHERE ip_address = $1
+  AND alasan IN ('PASSWORD_SALAH', 'TIDAK_DITEMUKAN')
+  AND created_at > CURRENT_TIMESTAMP - make_interval(mins => sqlc.arg('minutes')::int);
+
+-- name: CountGagalResetByIP :one
+SELECT COUNT(*) FROM percobaan_login
+WHERE ip_address = $1
+  AND alasan = 'KODE_RESET_SALAH'
+  AND created_at > CURRENT_TIMESTAMP - make_interval(mins => sqlc.arg('minutes')::int);
+
+-- name: GetKunciLogin :one
+SELECT * FROM kunci_login
+WHERE user_type = $1 AND identifier = $2;
+
+-- name: GetKunciLoginById :one
+SELECT * FROM kunci_login WHERE id = $1;
+
+-- name: RecordGagalLogin :one
+-- A failure more than a day after the previous one starts a new streak
+INSERT INTO kunci_login (user_type, identifier, gagal_beruntun, ip_terakhir)
+VALUES ($1, $2, 1, $3)
+ON CONFLICT (user_type, identifier) DO UPDATE
+SET gagal_beruntun = CASE
+        WHEN kunci_login.gagal_terakhir_at < CURRENT_TIMESTAMP - INTERVAL '1 day' THEN 1
+        ELSE kunci_login.gagal_beruntun + 1
+    END,
+    gagal_terakhir_at = CURRENT_TIMESTAMP,
+    ip_terakhir = EXCLUDED.ip_terakhir
+RETURNING gagal_beruntun;
+
+-- name: LockKunciLogin :one
+UPDATE kunci_login
+SET terkunci_sampai = CURRENT_TIMESTAMP + make_interval(secs => sqlc.arg('seconds')::int)
+WHERE user_type = $1 AND identifier = $2
+RETURNING terkunci_sampai;
+
+-- name: ResetKunciLogin :exec
+DELETE FROM kunci_login WHERE user_type = $1 AND identifier = $2;
+
+-- name: UnlockKunciLogin :exec
+UPDATE kunci_login
+SET gagal_beruntun = 0,
+    terkunci_sampai = NULL,
+    dibuka_oleh = $2,
+    dibuka_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: ListKunciLoginAdmin :many
+-- One page of the warga, and with with_petugas the petugas, locked out in the
+-- wilayah. The identifier is the NIK of a warga and the NIP, or else the
+-- username, of a petugas. Petugas lockouts are keyed by the petugas ID;
+-- guesses at unknown NIPs have no account and are not listed. sort_by is
+-- sampai, nama or gagal.
+WITH akun AS (
+    SELECT
+        k.id,
+        k.user_type,
+        k.identifier,
+        p.nama_lengkap AS nama,
+        kel.nama_kelurahan,
+        NULL::text AS role,
+        k.gagal_beruntun,
+        k.gagal_terakhir_at,
+        k.ip_terakhir,
+        k.terkunci_sampai
+    FROM kunci_login k
+    JOIN penduduk p ON p.nik = k.identifier
+    LEFT JOIN ref_kelurahan kel ON p.kelurahan_id = kel.id
+    WHERE k.user_type = 'warga'
+      AND k.terkunci_sampai > CURRENT_TIMESTAMP
+      AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR kel.kecamatan_id = sqlc.narg('kecamatan_id'))
+      AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR p.kelurahan_id = sqlc.narg('kelurahan_id'))
+    UNION ALL
+    SELECT
+        k.id,
+        k.user_type,
+        COALESCE(NULLIF(pt.nip, ''), pt.username),
+        pt.nama_petugas,
+        NULL::text,
+        pt.role,
+        k.gagal_beruntun,
+        k.gagal_terakhir_at,
+        k.ip_terakhir,
+        k.terkunci_sampai
+    FROM kunci_login k
+    JOIN petugas pt ON pt.id::text = k.identifier
+    WHERE k.user_type = 'petugas'
+      AND sqlc.arg('with_petugas')::bool
+      AND k.terkunci_sampai > CURRENT_TIMESTAMP
+      AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR pt.kecamatan_id = sqlc.narg('kecamatan_id'))
+      AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR pt.kelurahan_id = sqlc.narg('kelurahan_id'))
+)
+SELECT
+    id,
+    user_type,
+    identifier,
+    nama,
+    nama_kelurahan,
+    role,
+    gagal_beruntun,
+    gagal_terakhir_at,
+    ip_terakhir,
+    terkunci_sampai
+FROM akun
+ORDER BY
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND NOT sqlc.arg('sort_desc')::bool THEN nama END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'nama' AND sqlc.arg('sort_desc')::bool THEN nama END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'gagal' AND NOT sqlc.arg('sort_desc')::bool THEN gagal_beruntun END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'gagal' AND sqlc.arg('sort_desc')::bool THEN gagal_beruntun END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'sampai' AND NOT sqlc.arg('sort_desc')::bool THEN terkunci_sampai END ASC,
+    terkunci_sampai DESC,
+    id
+LIMIT $1 OFFSET $2;
+
+-- name: CountKunciLoginAdmin :one
+WITH akun AS (
+    SELECT
+        k.id,
+        k.user_type,
+        k.identifier,
+        p.nama_lengkap AS nama,
+        kel.nama_kelurahan,
+        NULL::text AS role,
+        k.gagal_beruntun,
+        k.gagal_terakhir_at,
+        k.ip_terakhir,
+        k.terkunci_sampai
+    FROM kunci_login k
+    JOIN penduduk p ON p.nik = k.identifier
+    LEFT JOIN ref_kelurahan kel ON p.kelurahan_id = kel.id
+    WHERE k.user_type = 'warga'
+      AND k.terkunci_sampai > CURRENT_TIMESTAMP
+      AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR kel.kecamatan_id = sqlc.narg('kecamatan_id'))
+      AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR p.kelurahan_id = sqlc.narg('kelurahan_id'))
+    UNION ALL
+    SELECT
+        k.id,
+        k.user_type,
+        COALESCE(NULLIF(pt.nip, ''), pt.username),
+        pt.nama_petugas,
+        NULL::text,
+        pt.role,
+        k.gagal_beruntun,
+        k.gagal_terakhir_at,
+        k.ip_terakhir,
+        k.terkunci_sampai
+    FROM kunci_login k
+    JOIN petugas pt ON pt.id::text = k.identifier
+    WHERE k.user_type = 'petugas'
+      AND sqlc.arg('with_petugas')::bool
+      AND k.terkunci_sampai > CURRENT_TIMESTAMP
+      AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR pt.kecamatan_id = sqlc.narg('kecamatan_id'))
+      AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR pt.kelurahan_id = sqlc.narg('kelurahan_id'))
+)
+SELECT COUNT(*) FROM akun;
@@ -0,0 +1,23 @@
+-- name: ListLokasiLayanan :many
+SELECT * FROM lokasi_layanan
+WHERE (sqlc.narg('kecamatan_id')::smallint IS NULL OR kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR kelurahan_id = sqlc.narg('kelurahan_id'))
+ORDER BY kecamatan_id, kelurahan_id NULLS FIRST, nama_lokasi;
+
+-- name: GetLokasiLayananById :one
+SELECT * FROM lokasi_layanan
+WHERE id = $1;
+
+-- name: GetLokasiLayananByWilayah :one
+-- NULL kelurahan_id returns the kantor kecamatan
+SELECT * FROM lokasi_layanan
+WHERE kecamatan_id = sqlc.arg('kecamatan_id')
+  AND kelurahan_id IS NOT DISTINCT FROM sqlc.narg('kelurahan_id')::smallint;
+
+-- name: UpdateLokasiLayanan :exec
+UPDATE lokasi_layanan
+SET 
+    alamat = $2,
+    jam_buka = $3,
+    jam_tutup = $4
+WHERE id = $1;
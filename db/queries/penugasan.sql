@@ -0,0 +1,82 @@
+-- name: GetPenugasanPermohonan :one
+SELECT
+    p.id,
+    p.status_terkini,
+    p.versi,
+    p.ditangani_oleh,
+    p.ditugaskan_pada,
+    pt.nama_petugas as nama_penangan
+FROM permohonan p
+LEFT JOIN petugas pt ON p.ditangani_oleh = pt.id
+WHERE p.id = $1;
+
+-- name: AssignPermohonan :execrows
+-- Assigns the application to petugas_id, or releases it when NULL, provided
+-- it is still at versi. The versi trigger then moves it on, so of two
+-- petugas claiming the same application only the first succeeds.
+UPDATE permohonan
+SET ditangani_oleh = sqlc.narg('petugas_id'),
+    ditugaskan_pada = CASE WHEN sqlc.narg('petugas_id')::uuid IS NULL THEN NULL ELSE CURRENT_TIMESTAMP END
+WHERE id = $1 AND versi = $2;
+
+-- name: ListPetugasPenangan :many
+-- Active petugas who may verify the application and whose wilayah covers
+-- its lokasi, kelurahan staff first. beban counts the open applications
+-- each one holds.
+SELECT
+    pt.id,
+    pt.nama_petugas,
+    pt.role,
+    (SELECT COUNT(*) FROM permohonan x
+     WHERE x.ditangani_oleh = pt.id
+       AND x.status_terkini IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')) AS beban
+FROM permohonan p
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+JOIN petugas pt ON pt.is_active AND (
+        pt.role = 'ADMIN_KOTA' OR
+        (pt.role = 'ADMIN_KECAMATAN' AND pt.kecamatan_id = l.kecamatan_id) OR
+        (pt.role = 'ADMIN_KELURAHAN' AND pt.kelurahan_id = l.kelurahan_id))
+WHERE p.id = $1
+  AND EXISTS (
+      SELECT 1 FROM role_permission rp
+      WHERE rp.role = pt.role AND rp.permission = 'permohonan.verify')
+ORDER BY array_position(ARRAY['ADMIN_KELURAHAN', 'ADMIN_KECAMATAN', 'ADMIN_KOTA'], pt.role::text), pt.nama_petugas;
+
+-- name: PickPetugasBergilir :one
+-- Picks the next petugas for round-robin distribution: among the staff of
+-- the lokasi's kelurahan, or its admin kecamatan when the kelurahan has
+-- none, the one holding the fewest open applications, then the one who was
+-- assigned one longest ago. Admin kota never receive work this way.
+SELECT pt.id AS petugas_id
+FROM permohonan p
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+JOIN petugas pt ON pt.is_active AND (
+        (pt.role = 'ADMIN_KECAMATAN' AND pt.kecamatan_id = l.kecamatan_id) OR
+        (pt.role = 'ADMIN_KELURAHAN' AND pt.kelurahan_id = l.kelurahan_id))
+WHERE p.id = $1
+  AND EXISTS (
+      SELECT 1 FROM role_permission rp
+      WHERE rp.role = pt.role AND rp.permission = 'permohonan.verify')
+ORDER BY
+    pt.role = 'ADMIN_KELURAHAN' DESC,
+    (SELECT COUNT(*) FROM permohonan x
+     WHERE x.ditangani_oleh = pt.id
+       AND x.status_terkini IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')) ASC,
+    (SELECT MAX(x.ditugaskan_pada) FROM permohonan x WHERE x.ditangani_oleh = pt.id) ASC NULLS FIRST,
+    pt.id
+LIMIT 1;
+
+-- name: ListPermohonanBelumDitangani :many
+-- Open applications within the scope that nobody holds, oldest first
+SELECT p.id, p.versi
+FROM permohonan p
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+WHERE p.ditangani_oleh IS NULL
+  AND p.status_terkini IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')
+  AND (sqlc.narg('kecamatan_id')::smallint IS NULL OR l.kecamatan_id = sqlc.narg('kecamatan_id'))
+  AND (sqlc.narg('kelurahan_id')::smallint IS NULL OR l.kelurahan_id = sqlc.narg('kelurahan_id'))
+ORDER BY p.created_at, p.id
+LIMIT $1;
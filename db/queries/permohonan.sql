@@ -16,8 +16,9 @@ INSERT INTO riwayat_status (
     petugas_id,
     status_baru,
     catatan_proses,
+    alasan_penolakan,
     waktu_proses
-) VALUES ($1, $2, $3, $4, NOW());
+) VALUES ($1, $2, $3, $4, $5, NOW());
 
 -- name: ListPermohonanByStatus :many
 SELECT 
@@ -61,14 +62,15 @@ SELECT
     pd.jenis_kelamin,
     pd.alamat,
     pd.no_hp,
-    COALESCE(k.nama_kelurahan, 'Kecamatan Pademangan')::text as nama_kelurahan,
+    l.nama_lokasi,
     js.tanggal as jadwal_tanggal,
     js.jam_mulai as jadwal_jam_mulai,
-    js.jam_selesai as jadwal_jam_selesai
+    js.jam_selesai as jadwal_jam_selesai,
+    p.pemohon_nik
 FROM permohonan p
 JOIN penduduk pd ON p.nik = pd.nik
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
 WHERE p.id = $1;
 
 -- name: GetRiwayatStatusByPermohonan :many
@@ -86,8 +88,9 @@ ORDER BY rs.waktu_proses DESC;
 INSERT INTO permohonan (
     nik,
     jadwal_sesi_id,
-    jenis_permohonan
-) VALUES ($1, $2, $3)
+    jenis_permohonan,
+    pemohon_nik
+) VALUES ($1, $2, $3, $4)
 RETURNING id;
 
 -- name: CreateDokumenSyarat :exec
@@ -96,3 +99,29 @@ INSERT INTO dokumen_syarat (
     file_path,
     jenis_dokumen
 ) VALUES ($1, $2, $3);
+
+-- name: LockPermohonanNIK :exec
+-- Serialises new applications for one NIK until the transaction ends, so
+-- the active and monthly counts cannot be raced by parallel submits
+SELECT pg_advisory_xact_lock(hashtextextended('permohonan:' || sqlc.arg('nik')::text, 0));
+
+-- name: CountPermohonanByNIKInPeriod :one
+SELECT COUNT(*) as count
+FROM permohonan
+WHERE nik = $1
+  AND created_at >= sqlc.arg('period_start')
+  AND created_at < sqlc.arg('period_end');
+
+-- name: HasPermohonanSelesai :one
+-- Whether the citizen has a completed application of the given type
+SELECT EXISTS(
+    SELECT 1 FROM permohonan
+    WHERE nik = $1 AND jenis_permohonan = $2 AND status_terkini = 'SELESAI'
+) AS exists;
+
+-- name: ListSyaratDokumen :many
+-- Documents an application of the given type needs, whose originals the
+-- citizen brings to the session
+SELECT jenis_dokumen FROM ref_syarat_dokumen
+WHERE jenis_permohonan = $1
+ORDER BY jenis_dokumen;
@@ -1,14 +1,27 @@
+-- name: CreateKecamatan :one
+INSERT INTO ref_kecamatan (nama_kecamatan, nama_kota, kode_wilayah)
+VALUES ($1, $2, $3)
+RETURNING *;
+
 -- name: CreateKelurahan :one
-INSERT INTO ref_kelurahan (nama_kelurahan, kode_area)
-VALUES ($1, $2)
+INSERT INTO ref_kelurahan (kecamatan_id, nama_kelurahan, kode_area)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: CreateLokasiLayanan :one
+INSERT INTO lokasi_layanan (kecamatan_id, kelurahan_id, kode, nama_lokasi, alamat, jam_buka, jam_tutup)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
+-- name: GetKecamatanByKodeWilayah :one
+SELECT * FROM ref_kecamatan WHERE kode_wilayah = $1;
+
 -- name: GetKelurahanByKodeArea :one
 SELECT * FROM ref_kelurahan WHERE kode_area = $1;
 
 -- name: CreatePetugas :one
-INSERT INTO petugas (kelurahan_id, nip, nama_petugas, created_by, username, password_hash)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO petugas (kecamatan_id, kelurahan_id, nip, nama_petugas, created_by, username, password_hash, email)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING *;
 
 -- name: GetPetugasByUsername :one
@@ -17,5 +30,8 @@ SELECT * FROM petugas WHERE username = $1;
 -- name: ListKelurahan :many
 SELECT * FROM ref_kelurahan ORDER BY id;
 
+-- name: ListKecamatan :many
+SELECT * FROM ref_kecamatan ORDER BY nama_kota, nama_kecamatan;
+
 -- name: TruncateSeedTables :exec
-TRUNCATE riwayat_status, dokumen_syarat, permohonan, jadwal_sesi, petugas, penduduk, ref_kelurahan RESTART IDENTITY CASCADE;
+TRUNCATE riwayat_status, dokumen_syarat, permohonan, jadwal_sesi, aturan_booking, lokasi_layanan, petugas, penduduk, ref_kelurahan, ref_kecamatan RESTART IDENTITY CASCADE;
@@ -0,0 +1,53 @@
+-- name: CreateSesiLogin :exec
+INSERT INTO sesi_login (
+    token_hash,
+    user_type,
+    user_id,
+    user_name,
+    user_role,
+    kecamatan_id,
+    kelurahan_id,
+    permissions,
+    user_agent,
+    ip_address,
+    tahap_2fa,
+    ingat_saya,
+    csrf_token,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+    CURRENT_TIMESTAMP + make_interval(secs => sqlc.arg('max_age')::int)
+);
+
+-- name: GetSesiLogin :one
+SELECT * FROM sesi_login
+WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP;
+
+-- name: CompleteSesi2FA :exec
+-- Rotates the token so that the pre-2FA cookie cannot be reused
+UPDATE sesi_login
+SET token_hash = $2,
+    tahap_2fa = '',
+    expires_at = CURRENT_TIMESTAMP + make_interval(secs => sqlc.arg('max_age')::int)
+WHERE id = $1;
+
+-- name: TouchSesiLogin :exec
+-- Throttled so that busy pages do not write on every request
+UPDATE sesi_login
+SET last_seen_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 minute';
+
+-- name: DeleteSesiLogin :exec
+DELETE FROM sesi_login WHERE token_hash = $1;
+
+-- name: DeleteSesiLoginByUser :exec
+DELETE FROM sesi_login WHERE user_type = $1 AND user_id = $2;
+
+-- name: DeleteExpiredSesiLogin :exec
+DELETE FROM sesi_login WHERE expires_at <= CURRENT_TIMESTAMP;
+
+-- name: ListSesiLoginByUser :many
+SELECT id, user_agent, ip_address, created_at, last_seen_at, expires_at
+FROM sesi_login
+WHERE user_type = $1 AND user_id = $2 AND tahap_2fa <> 'VERIFIKASI' AND expires_at > CURRENT_TIMESTAMP
+ORDER BY last_seen_at DESC;
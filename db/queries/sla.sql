@@ -0,0 +1,74 @@
+-- name: ListTargetSLA :many
+SELECT * FROM target_sla
+ORDER BY array_position(ARRAY['VERIFIKASI', 'PROSES', 'SIAP_AMBIL'], status), jenis_permohonan;
+
+-- name: UpsertTargetSLA :exec
+INSERT INTO target_sla (status, jenis_permohonan, target_hari, updated_by, updated_at)
+VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+ON CONFLICT (status, jenis_permohonan) DO UPDATE
+SET target_hari = EXCLUDED.target_hari,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: DeleteTargetSLA :exec
+DELETE FROM target_sla
+WHERE status = $1 AND jenis_permohonan = $2;
+
+-- name: ListPermohonanLewatSLA :many
+-- Applications past the SLA target of their status, by kecamatan and
+-- kelurahan of the lokasi, longest waiting first
+SELECT
+    p.id,
+    p.kode_booking,
+    pd.nama_lengkap,
+    p.jenis_permohonan,
+    p.status_terkini,
+    s.sejak AS status_sejak,
+    t.target_hari,
+    l.kecamatan_id,
+    COALESCE(k.nama_kelurahan, l.nama_lokasi)::text AS nama_kelurahan
+FROM permohonan p
+JOIN penduduk pd ON p.nik = pd.nik
+JOIN target_sla t ON t.status = p.status_terkini AND t.jenis_permohonan = p.jenis_permohonan
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+LEFT JOIN ref_kelurahan k ON k.id = l.kelurahan_id
+CROSS JOIN LATERAL (SELECT status_sejak(p.id, p.status_terkini, p.created_at) AS sejak) s
+WHERE s.sejak + make_interval(days => t.target_hari::int) < CURRENT_TIMESTAMP
+ORDER BY l.kecamatan_id, nama_kelurahan, s.sejak;
+
+-- name: ListAdminKecamatanAktif :many
+-- Recipients of the SLA digest; admins without an email are reported and
+-- skipped
+SELECT
+    p.id,
+    p.kecamatan_id,
+    p.nama_petugas,
+    p.username,
+    p.email,
+    k.nama_kecamatan
+FROM petugas p
+JOIN ref_kecamatan k ON k.id = p.kecamatan_id
+WHERE p.role = 'ADMIN_KECAMATAN' AND p.is_active
+ORDER BY p.kecamatan_id, p.nama_petugas;
+
+-- name: ClaimDigestSLA :execrows
+-- Claims the digest of a day for ten minutes; no rows means it is done or
+-- another run holds it
+INSERT INTO digest_sla (tanggal) VALUES ($1)
+ON CONFLICT (tanggal) DO UPDATE SET diklaim_pada = CURRENT_TIMESTAMP
+WHERE digest_sla.selesai_pada IS NULL
+  AND digest_sla.diklaim_pada < CURRENT_TIMESTAMP - INTERVAL '10 minutes';
+
+-- name: CompleteDigestSLA :exec
+UPDATE digest_sla SET selesai_pada = CURRENT_TIMESTAMP
+WHERE tanggal = $1;
+
+-- name: ListPenerimaDigestSLA :many
+SELECT petugas_id FROM digest_sla_penerima
+WHERE tanggal = $1;
+
+-- name: CreatePenerimaDigestSLA :exec
+INSERT INTO digest_sla_penerima (tanggal, petugas_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
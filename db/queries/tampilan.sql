@@ -0,0 +1,15 @@
+-- name: ListTampilanTersimpan :many
+SELECT * FROM tampilan_tersimpan
+WHERE petugas_id = $1 AND halaman = $2
+ORDER BY nama;
+
+-- name: SaveTampilanTersimpan :exec
+-- Saving under an existing name replaces that view
+INSERT INTO tampilan_tersimpan (petugas_id, halaman, nama, query)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (petugas_id, halaman, nama) DO UPDATE
+SET query = EXCLUDED.query;
+
+-- name: DeleteTampilanTersimpan :exec
+DELETE FROM tampilan_tersimpan
+WHERE id = $1 AND petugas_id = $2;
@@ -0,0 +1,46 @@
+-- name: GetPetugasTotp :one
+SELECT * FROM petugas_totp WHERE petugas_id = $1;
+
+-- name: UpsertPetugasTotpPending :exec
+-- Starts or restarts an enrolment; an active secret is never replaced here
+INSERT INTO petugas_totp (petugas_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (petugas_id) DO UPDATE
+SET secret = EXCLUDED.secret,
+    terakhir_step = 0,
+    created_at = CURRENT_TIMESTAMP
+WHERE petugas_totp.aktif = false;
+
+-- name: ActivatePetugasTotp :exec
+UPDATE petugas_totp
+SET aktif = true,
+    terakhir_step = $2,
+    aktif_at = CURRENT_TIMESTAMP
+WHERE petugas_id = $1;
+
+-- name: UpdatePetugasTotpStep :execrows
+-- Affects no row when the step was already used, which rejects replays
+UPDATE petugas_totp
+SET terakhir_step = $2
+WHERE petugas_id = $1 AND terakhir_step < $2;
+
+-- name: DeletePetugasTotp :exec
+DELETE FROM petugas_totp WHERE petugas_id = $1;
+
+-- name: InsertKodePemulihan :exec
+INSERT INTO kode_pemulihan (petugas_id, kode_hash) VALUES ($1, $2);
+
+-- name: DeleteKodePemulihan :exec
+DELETE FROM kode_pemulihan WHERE petugas_id = $1;
+
+-- name: UseKodePemulihan :execrows
+UPDATE kode_pemulihan
+SET digunakan_at = CURRENT_TIMESTAMP
+WHERE petugas_id = $1 AND kode_hash = $2 AND digunakan_at IS NULL;
+
+-- name: CountKodePemulihanTersisa :one
+SELECT COUNT(*) FROM kode_pemulihan
+WHERE petugas_id = $1 AND digunakan_at IS NULL;
+
+-- name: GetWajib2FA :one
+SELECT EXISTS(SELECT 1 FROM kebijakan_2fa WHERE role = $1 AND wajib) AS wajib;
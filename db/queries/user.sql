@@ -1,4 +1,5 @@
 -- name: GetPermohonanByNIK :many
+-- Applications for the citizen and those they submitted for household members
 SELECT 
     p.id,
     p.kode_booking,
@@ -9,11 +10,15 @@ SELECT
     js.tanggal as jadwal_tanggal,
     js.jam_mulai as jadwal_jam_mulai,
     js.jam_selesai as jadwal_jam_selesai,
-    k.nama_kelurahan as lokasi_kelurahan
+    l.nama_lokasi,
+    p.nik,
+    pd.nama_lengkap,
+    p.waktu_hadir
 FROM permohonan p
+LEFT JOIN penduduk pd ON p.nik = pd.nik
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
-WHERE p.nik = $1
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
+WHERE p.nik = $1 OR p.pemohon_nik = $1
 ORDER BY p.created_at DESC
 LIMIT $2 OFFSET $3;
 
@@ -28,6 +33,19 @@ SELECT
 FROM permohonan 
 WHERE nik = $1;
 
+-- name: CountPermohonanByPemohon :one
+-- Counts applications for the citizen together with those they submitted
+-- for household members
+SELECT 
+    COUNT(*) as total,
+    COUNT(*) FILTER (WHERE status_terkini = 'VERIFIKASI') as verifikasi,
+    COUNT(*) FILTER (WHERE status_terkini = 'PROSES') as proses,
+    COUNT(*) FILTER (WHERE status_terkini = 'SIAP_AMBIL') as siap_ambil,
+    COUNT(*) FILTER (WHERE status_terkini = 'SELESAI') as selesai,
+    COUNT(*) FILTER (WHERE status_terkini = 'DITOLAK') as ditolak
+FROM permohonan 
+WHERE nik = $1 OR pemohon_nik = $1;
+
 -- name: GetPendudukProfile :one
 SELECT 
     p.nik,
@@ -37,7 +55,9 @@ SELECT
     p.no_hp,
     p.jenis_kelamin,
     p.created_at,
-    k.nama_kelurahan
+    k.nama_kelurahan,
+    p.tanggal_lahir,
+    p.status_perkawinan
 FROM penduduk p
 LEFT JOIN ref_kelurahan k ON p.kelurahan_id = k.id
 WHERE p.nik = $1;
@@ -53,10 +73,13 @@ SELECT
     js.tanggal as jadwal_tanggal,
     js.jam_mulai as jadwal_jam_mulai,
     js.jam_selesai as jadwal_jam_selesai,
-    k.nama_kelurahan as lokasi_kelurahan,
-    pd.nama_lengkap
+    l.nama_lokasi,
+    pd.nama_lengkap,
+    p.nik,
+    p.pemohon_nik,
+    pd.tanggal_lahir
 FROM permohonan p
 LEFT JOIN penduduk pd ON p.nik = pd.nik
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
 WHERE p.kode_booking = $1;
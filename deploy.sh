@@ -4,7 +4,7 @@ set -e
 # ensure .env exists
 if [ ! -f .env ]; then
     echo "Error: .env file is missing!"
-    echo "Please create one with DATABASE_URL and SESSION_SECRET"
+    echo "Please create one with DATABASE_URL"
     exit 1
 fi
 
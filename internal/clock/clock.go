@@ -0,0 +1,57 @@
+package clock
+
+import (
+	"time"
+)
+
+// Location is the time zone every office operates in. Session dates and
+// "today" are always evaluated here, never in the server's local zone.
+var Location = loadLocation()
+
+func loadLocation() *time.Location {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		// WIB has no daylight saving, so a fixed offset is equivalent
+		return time.FixedZone("WIB", 7*60*60)
+	}
+	return loc
+}
+
+// Clock provides the current time to services that make date-based decisions
+type Clock interface {
+	Now() time.Time
+}
+
+type jakartaClock struct{}
+
+// New returns the system clock expressed in Asia/Jakarta
+func New() Clock {
+	return jakartaClock{}
+}
+
+func (jakartaClock) Now() time.Time {
+	return time.Now().In(Location)
+}
+
+// Fixed is a Clock that always returns the same instant, for tests and tooling
+type Fixed time.Time
+
+func (f Fixed) Now() time.Time {
+	return time.Time(f).In(Location)
+}
+
+// Today returns midnight of the current day in Asia/Jakarta
+func Today(c Clock) time.Time {
+	return StartOfDay(c.Now())
+}
+
+// StartOfDay truncates t to midnight of its day in Asia/Jakarta
+func StartOfDay(t time.Time) time.Time {
+	t = t.In(Location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, Location)
+}
+
+// Local converts t to Asia/Jakarta for display
+func Local(t time.Time) time.Time {
+	return t.In(Location)
+}
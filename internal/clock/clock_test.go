@@ -0,0 +1,57 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTodayAroundMidnightWIB(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"last second of the day in WIB", time.Date(2026, 3, 9, 16, 59, 59, 0, time.UTC), "2026-03-09"},
+		{"midnight WIB is still yesterday in UTC", time.Date(2026, 3, 9, 17, 0, 0, 0, time.UTC), "2026-03-10"},
+		{"early morning WIB", time.Date(2026, 3, 9, 23, 30, 0, 0, time.UTC), "2026-03-10"},
+		{"new year in WIB before UTC", time.Date(2026, 12, 31, 17, 0, 0, 0, time.UTC), "2027-01-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Today(Fixed(tt.now))
+			if got.Format("2006-01-02") != tt.want {
+				t.Errorf("Today() = %s, want %s", got.Format(time.RFC3339), tt.want)
+			}
+			if got.Hour() != 0 || got.Minute() != 0 || got.Second() != 0 {
+				t.Errorf("Today() = %s, want midnight", got.Format(time.RFC3339))
+			}
+			if got.Location() != Location {
+				t.Errorf("Today() location = %s, want %s", got.Location(), Location)
+			}
+		})
+	}
+}
+
+func TestStartOfDayIgnoresInputZone(t *testing.T) {
+	// 23:30 in UTC-5 on the 9th is 11:30 WIB on the 10th
+	ny := time.FixedZone("UTC-5", -5*60*60)
+	got := StartOfDay(time.Date(2026, 3, 9, 23, 30, 0, 0, ny))
+	want := time.Date(2026, 3, 10, 0, 0, 0, 0, Location)
+	if !got.Equal(want) {
+		t.Errorf("StartOfDay() = %s, want %s", got, want)
+	}
+}
+
+func TestFixedReturnsWIB(t *testing.T) {
+	instant := time.Date(2026, 3, 9, 17, 0, 0, 0, time.UTC)
+	got := Fixed(instant).Now()
+	if !got.Equal(instant) {
+		t.Errorf("Fixed.Now() = %s, want %s", got, instant)
+	}
+	if got.Location() != Location {
+		t.Errorf("Fixed.Now() location = %s, want %s", got.Location(), Location)
+	}
+	if got.Hour() != 0 {
+		t.Errorf("Fixed.Now() hour = %d, want 0 WIB", got.Hour())
+	}
+}
@@ -0,0 +1,75 @@
+// Package export streams tables to CSV or XLSX files row by row, so that
+// large exports never have to be held in memory.
+package export
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Format is the file format of an export
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+// ParseFormat reads the format query parameter, defaulting to CSV
+func ParseFormat(s string) (Format, bool) {
+	switch Format(strings.ToLower(s)) {
+	case "", CSV:
+		return CSV, true
+	case XLSX:
+		return XLSX, true
+	}
+	return "", false
+}
+
+func (f Format) ContentType() string {
+	if f == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Writer writes one table. Rows are written as they come; Flush sends what
+// has been written so far and Close finishes the file.
+type Writer interface {
+	WriteRow(cells []string) error
+	Flush() error
+	Close() error
+}
+
+// NewWriter starts a file in the given format. sheet names the worksheet of
+// an XLSX file and is ignored for CSV.
+func NewWriter(w io.Writer, f Format, sheet string) (Writer, error) {
+	if f == XLSX {
+		return newXLSXWriter(w, sheet)
+	}
+	return newCSVWriter(w)
+}
+
+// Start sets the download headers for name (without extension) and returns
+// the writer for the response body
+func Start(w http.ResponseWriter, f Format, name, sheet string) (Writer, error) {
+	w.Header().Set("Content-Type", f.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+string(f)))
+	w.Header().Set("Cache-Control", "no-store")
+	return NewWriter(flushWriter{w}, f, sheet)
+}
+
+// flushWriter pushes every write to the client so downloads start at once
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if f, ok := fw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
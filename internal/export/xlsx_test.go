@@ -0,0 +1,195 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readXLSX opens a workbook the way a spreadsheet application does: it finds
+// the workbook through the package relationships, the worksheet through the
+// workbook relationships, and checks every part has a declared content type.
+func readXLSX(t *testing.T, b []byte) (sheet string, rows [][]string) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+	parts := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		parts[f.Name] = body
+	}
+	part := func(name string, v any) {
+		t.Helper()
+		body, ok := parts[name]
+		if !ok {
+			t.Fatalf("missing part %s", name)
+		}
+		if err := xml.Unmarshal(body, v); err != nil {
+			t.Fatalf("parse %s: %v", name, err)
+		}
+	}
+
+	var types struct {
+		Defaults []struct {
+			Extension string `xml:",attr"`
+		} `xml:"Default"`
+		Overrides []struct {
+			PartName    string `xml:",attr"`
+			ContentType string `xml:",attr"`
+		} `xml:"Override"`
+	}
+	part("[Content_Types].xml", &types)
+	declared := map[string]string{}
+	for _, o := range types.Overrides {
+		declared[o.PartName[1:]] = o.ContentType
+	}
+	for name := range parts {
+		if name == "[Content_Types].xml" {
+			continue
+		}
+		if _, ok := declared[name]; ok {
+			continue
+		}
+		found := false
+		for _, d := range types.Defaults {
+			if path.Ext(name) == "."+d.Extension {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("part %s has no content type", name)
+		}
+	}
+
+	type rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Type   string `xml:",attr"`
+			Target string `xml:",attr"`
+		} `xml:"Relationship"`
+	}
+	var root rels
+	part("_rels/.rels", &root)
+	if len(root.Rels) != 1 || !strings.HasSuffix(root.Rels[0].Type, "/officeDocument") {
+		t.Fatalf("package relationships = %+v, want one officeDocument", root.Rels)
+	}
+	workbookPath := root.Rels[0].Target
+	if declared[workbookPath] != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml" {
+		t.Errorf("workbook content type = %q", declared[workbookPath])
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	part(workbookPath, &workbook)
+	if len(workbook.Sheets) != 1 {
+		t.Fatalf("workbook has %d sheets, want 1", len(workbook.Sheets))
+	}
+
+	var wbRels rels
+	dir := path.Dir(workbookPath)
+	part(path.Join(dir, "_rels", path.Base(workbookPath)+".rels"), &wbRels)
+	sheetPath := ""
+	for _, r := range wbRels.Rels {
+		if r.ID == workbook.Sheets[0].RID {
+			sheetPath = path.Join(dir, r.Target)
+		}
+	}
+	if sheetPath == "" {
+		t.Fatalf("sheet relationship %q not found", workbook.Sheets[0].RID)
+	}
+
+	var ws struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				T    string `xml:"t,attr"`
+				Text string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	part(sheetPath, &ws)
+	for i, row := range ws.Rows {
+		if row.R != i+1 {
+			t.Errorf("row %d numbered %d", i+1, row.R)
+		}
+		cells := []string{}
+		for _, c := range row.Cells {
+			if c.T != "inlineStr" {
+				t.Errorf("row %d has a cell of type %q", row.R, c.T)
+			}
+			cells = append(cells, c.Text)
+		}
+		rows = append(rows, cells)
+	}
+	return workbook.Sheets[0].Name, rows
+}
+
+func TestXLSXRoundTrip(t *testing.T) {
+	want := [][]string{
+		{"NIK", "Nama", "Alamat"},
+		{"0101010101010001", "Siti <Aminah> & Putri", "  Jl. Merdeka \"5\"  "},
+		{"3201010101010002", "Dewi Ayu Lestari", ""},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, XLSX, "Penduduk: 2026/10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range want {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+		// Exports flush as they go; the file must still be whole
+		if i == 0 {
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sheet, got := readXLSX(t, buf.Bytes())
+	if sheet != "Penduduk- 2026-10" {
+		t.Errorf("sheet name = %q, want %q", sheet, "Penduduk- 2026-10")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+}
+
+func TestXLSXEmptySheetName(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, XLSX, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sheet, rows := readXLSX(t, buf.Bytes())
+	if sheet != "Sheet1" || len(rows) != 0 {
+		t.Errorf("got sheet %q with %d rows, want Sheet1 with none", sheet, len(rows))
+	}
+}
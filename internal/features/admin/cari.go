@@ -0,0 +1,195 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/policy"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// Results shown per group on the global search page
+const cariPerGrup = 5
+
+// SearchHandler searches penduduk and permohonan at once from the search box in
+// the sidebar. Each group shows its best matches and links to the full table
+// with the same search.
+func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	scope := policy.ScopeOf(user)
+	kata := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	data := CariPageData{
+		UserName:   user.UserName,
+		UserRole:   common.FormatRole(user.UserRole),
+		ActivePage: "cari",
+		Kata:       kata,
+	}
+	if kata == "" {
+		CariPage(data).Render(ctx, w)
+		return
+	}
+	search := pgtype.Text{String: kata, Valid: true}
+
+	pendudukTotal, err := h.store.CountPendudukAdmin(ctx, pg_store.CountPendudukAdminParams{
+		Search:      search,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err == nil && pendudukTotal > 0 {
+		rows, err := h.store.ListPendudukAdmin(ctx, pg_store.ListPendudukAdminParams{
+			Limit:       cariPerGrup,
+			Search:      search,
+			KecamatanID: scope.KecamatanID,
+			KelurahanID: scope.KelurahanID,
+			SortBy:      "relevansi",
+			SortDesc:    true,
+		})
+		if err == nil {
+			data.Penduduk = pendudukItems(rows, user, h.clock.Now())
+			data.PendudukTotal = int(pendudukTotal)
+		}
+	}
+
+	permohonanTotal, err := h.store.CountPermohonanAdmin(ctx, pg_store.CountPermohonanAdminParams{
+		Search:      search,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err == nil && permohonanTotal > 0 {
+		rows, err := h.store.ListPermohonanAdmin(ctx, pg_store.ListPermohonanAdminParams{
+			Limit:       cariPerGrup,
+			Search:      search,
+			KecamatanID: scope.KecamatanID,
+			KelurahanID: scope.KelurahanID,
+			SortBy:      "relevansi",
+			SortDesc:    true,
+		})
+		if err == nil {
+			data.Permohonan = permohonanItems(rows, user, h.clock.Now())
+			data.PermohonanTotal = int(permohonanTotal)
+		}
+	}
+
+	CariPage(data).Render(ctx, w)
+}
+
+// Potongan is a piece of a search result, marked when it matches the search
+type Potongan struct {
+	Teks  string `json:"teks"`
+	Cocok bool   `json:"cocok"`
+}
+
+// kemiripanKataMin is the pg_trgm.word_similarity_threshold the database is
+// set to, so a name the search found by similarity is also highlighted.
+const kemiripanKataMin = 0.6
+
+// highlight splits text into pieces that do and do not match the search. A word
+// of the search matches wherever it appears in the text; a word that only
+// resembles one, like "Muhamad" for "Muhammad", marks the whole word when
+// their trigram similarity reaches the threshold the database searches with.
+// Numbers such as a NIK are only matched exactly.
+func highlight(teks, search string) []Potongan {
+	lower := strings.ToLower(teks)
+	kataCari := strings.Fields(strings.ToLower(search))
+	// Case folding that changes byte lengths would shift the offsets
+	if len(lower) != len(teks) || len(kataCari) == 0 {
+		return []Potongan{{Teks: teks}}
+	}
+
+	cocok := make([]bool, len(teks))
+	for _, k := range kataCari {
+		if len(k) < 2 {
+			continue
+		}
+		for from := 0; ; {
+			i := strings.Index(lower[from:], k)
+			if i < 0 {
+				break
+			}
+			for j := from + i; j < from+i+len(k); j++ {
+				cocok[j] = true
+			}
+			from += i + len(k)
+		}
+	}
+	start := -1
+	for i := 0; i <= len(lower); i++ {
+		if i < len(lower) && lower[i] != ' ' {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			kata := lower[start:i]
+			for _, k := range kataCari {
+				if hasHuruf(k) && hasHuruf(kata) && kemiripanKata(k, kata) >= kemiripanKataMin {
+					for j := start; j < i; j++ {
+						cocok[j] = true
+					}
+				}
+			}
+			start = -1
+		}
+	}
+
+	var hasil []Potongan
+	for i := 0; i < len(teks); {
+		j := i
+		for j < len(teks) && cocok[j] == cocok[i] {
+			j++
+		}
+		hasil = append(hasil, Potongan{Teks: teks[i:j], Cocok: cocok[i]})
+		i = j
+	}
+	return hasil
+}
+
+func hasHuruf(s string) bool {
+	return strings.IndexFunc(s, unicode.IsLetter) >= 0
+}
+
+// kemiripanKata is the share of the trigrams of a search word that also occur
+// in a word of the text, which is how pg_trgm's word_similarity scores a
+// search word against the best matching word.
+func kemiripanKata(cari, kata string) float64 {
+	tCari := trigram(cari)
+	if len(tCari) == 0 {
+		return 0
+	}
+	tKata := trigram(kata)
+	sama := 0
+	for t := range tCari {
+		if tKata[t] {
+			sama++
+		}
+	}
+	return float64(sama) / float64(len(tCari))
+}
+
+// trigram returns the trigrams of a word the way pg_trgm builds them: letters
+// and digits only, padded with two spaces in front and one behind.
+func trigram(kata string) map[string]bool {
+	var b strings.Builder
+	b.WriteString("  ")
+	for _, r := range kata {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(" ")
+	runes := []rune(b.String())
+	hasil := make(map[string]bool, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		hasil[string(runes[i:i+3])] = true
+	}
+	return hasil
+}
@@ -0,0 +1,37 @@
+package admin
+
+import "testing"
+
+func markCocok(ps []Potongan) string {
+	var s string
+	for _, p := range ps {
+		if p.Cocok {
+			s += "[" + p.Teks + "]"
+		} else {
+			s += p.Teks
+		}
+	}
+	return s
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name   string
+		teks   string
+		search string
+		want   string
+	}{
+		{"exact substring", "Jl. Merdeka No. 5", "merdeka", "Jl. [Merdeka] No. 5"},
+		{"misspelled name", "Muhammad Rizki", "Muhamad", "[Muhammad] Rizki"},
+		{"unrelated name", "Budi Santoso", "Muhamad", "Budi Santoso"},
+		{"nik prefix only", "3201010101010001", "3201", "[3201]010101010001"},
+		{"no search", "Siti Aminah", "", "Siti Aminah"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markCocok(highlight(tt.teks, tt.search)); got != tt.want {
+				t.Errorf("highlight(%q, %q) = %q, want %q", tt.teks, tt.search, got, tt.want)
+			}
+		})
+	}
+}
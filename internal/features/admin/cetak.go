@@ -0,0 +1,146 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/pdf"
+	"github.com/nobuww/simpel-ktp/internal/policy"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// kolomDaftarHadir is one column of the printed roster
+type kolomDaftarHadir struct {
+	judul string
+	lebar float64
+}
+
+var kolomAntrian = []kolomDaftarHadir{
+	{"No", 30},
+	{"Kode Booking", 78},
+	{"NIK", 100},
+	{"Nama", 140},
+	{"Jenis", 50},
+	{"Status", 62},
+	{"Paraf", 0}, // takes the rest of the row
+}
+
+// Layout of the roster in points
+const (
+	cetakMargin    = 40.0
+	cetakBaris     = 22.0
+	cetakHeaderTop = 128.0
+	cetakFooter    = 60.0
+)
+
+// PrintAntrianHandler prints the queue of one session as a PDF roster for the
+// counter, with an empty column for the citizen's initials
+func (h *Handler) PrintAntrianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	jadwal, err := h.policy.Jadwal(ctx, user, id)
+	if err != nil {
+		writePolicyError(w, err, "Jadwal tidak ditemukan")
+		return
+	}
+	list, err := h.store.ListPermohonanByJadwal(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat antrian")
+		return
+	}
+
+	doc := daftarHadirPDF(jadwal, list, policy.Can(user, policy.PendudukViewPII, nil), clock.Local(h.clock.Now()))
+	nama := fmt.Sprintf("antrian-%s-%s.pdf", jadwal.Tanggal.Time.Format("20060102"), convertMicrosToTime(jadwal.JamMulai.Microseconds))
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", nama))
+	w.Header().Set("Cache-Control", "no-store")
+	doc.WriteTo(w)
+}
+
+// daftarHadirPDF lays out the roster, repeating the session heading and the
+// column titles on every page
+func daftarHadirPDF(jadwal pg_store.GetJadwalSesiByIdRow, list []pg_store.ListPermohonanByJadwalRow, lengkap bool, dicetak time.Time) *pdf.Document {
+	sesi := fmt.Sprintf("%s, %s - %s", jadwal.Tanggal.Time.Format("02 Jan 2006"),
+		convertMicrosToTime(jadwal.JamMulai.Microseconds), convertMicrosToTime(jadwal.JamSelesai.Microseconds))
+	doc := pdf.New("Daftar Antrian " + sesi)
+
+	// Rows that fit between the column titles and the footer
+	tabel := pdf.PageHeight - cetakFooter - cetakHeaderTop - cetakBaris
+	perHalaman := int(tabel / cetakBaris)
+	halaman := (len(list) + perHalaman - 1) / perHalaman
+	if halaman == 0 {
+		halaman = 1
+	}
+	right := pdf.PageWidth - cetakMargin
+
+	for n := 0; n < halaman; n++ {
+		p := doc.AddPage()
+		p.Text(cetakMargin, 60, pdf.Bold, 14, "DAFTAR ANTRIAN PEREKAMAN KTP-EL")
+		p.Text(cetakMargin, 80, pdf.Regular, 10, "Sesi: "+sesi)
+		p.Text(cetakMargin, 95, pdf.Regular, 10, "Lokasi: "+jadwal.NamaLokasi)
+		p.TextRight(right, 80, pdf.Regular, 10, fmt.Sprintf("Terisi %d dari %d", jadwal.KuotaTerisi, jadwal.KuotaMaksimal))
+
+		y := cetakHeaderTop
+		p.Box(cetakMargin, y, right-cetakMargin, cetakBaris, 0.9)
+		x := cetakMargin
+		for _, k := range kolomAntrian {
+			p.Text(x+4, y+15, pdf.Bold, 9, k.judul)
+			x += k.lebar
+		}
+		y += cetakBaris
+
+		akhir := min((n+1)*perHalaman, len(list))
+		for _, a := range list[n*perHalaman : akhir] {
+			nik := a.Nik.String
+			if !lengkap {
+				nik = common.MaskNIK(nik)
+			}
+			antrian := "-"
+			if a.NomorAntrian.Valid {
+				antrian = strconv.Itoa(int(a.NomorAntrian.Int16))
+			}
+			sel := []string{antrian, a.KodeBooking.String, nik, a.NamaLengkap, a.JenisPermohonan, a.StatusTerkini.String, ""}
+			x = cetakMargin
+			for i, k := range kolomAntrian {
+				if sel[i] != "" {
+					p.Text(x+4, y+15, pdf.Regular, 9, pdf.Fit(sel[i], pdf.Regular, 9, k.lebar-8))
+				}
+				x += k.lebar
+			}
+			y += cetakBaris
+			p.Line(cetakMargin, y, right, y, 0.5)
+		}
+		if len(list) == 0 {
+			p.Text(cetakMargin+4, y+15, pdf.Regular, 9, "Belum ada permohonan pada sesi ini")
+			y += cetakBaris
+		}
+
+		// Vertical rules of the table
+		x = cetakMargin
+		for _, k := range kolomAntrian {
+			p.Line(x, cetakHeaderTop, x, y, 0.5)
+			x += k.lebar
+		}
+		p.Line(right, cetakHeaderTop, right, y, 0.5)
+		p.Line(cetakMargin, cetakHeaderTop, right, cetakHeaderTop, 0.5)
+
+		p.Text(cetakMargin, pdf.PageHeight-40, pdf.Regular, 8, "Dicetak "+dicetak.Format("02 Jan 2006 15:04")+" WIB")
+		p.TextRight(right, pdf.PageHeight-40, pdf.Regular, 8, fmt.Sprintf("Halaman %d dari %d", n+1, halaman))
+	}
+	return doc
+}
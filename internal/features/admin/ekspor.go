@@ -0,0 +1,253 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/export"
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/policy"
+	"github.com/nobuww/simpel-ktp/internal/session"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// Rows fetched per query while streaming an export
+const eksporBatch = 500
+
+// eksporFetch returns the rows of one batch, already converted to cells
+type eksporFetch func(ctx context.Context, limit, offset int32) ([][]string, error)
+
+// ekspor streams a table as CSV or XLSX, batch by batch, and records the
+// export in log_ekspor. jenis names the table in the file name and the log.
+// Personal data must already be masked by fetch unless dataLengkap is set.
+func (h *Handler) ekspor(w http.ResponseWriter, r *http.Request, user *session.UserSession, jenis string, header []string, dataLengkap bool, fetch eksporFetch) {
+	ctx := r.Context()
+	format, ok := export.ParseFormat(r.URL.Query().Get("format"))
+	if !ok {
+		common.WriteError(w, http.StatusBadRequest, "Format ekspor tidak dikenal")
+		return
+	}
+
+	// Errors on the first batch can still be reported; after that the
+	// download has started and a failure can only cut it short
+	rows, err := fetch(ctx, eksporBatch, 0)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal mengekspor data")
+		return
+	}
+	nama := fmt.Sprintf("%s-%s", jenis, h.clock.Now().In(clock.Location).Format("20060102-1504"))
+	out, err := export.Start(w, format, nama, jenis)
+	if err != nil {
+		return
+	}
+
+	jumlah := 0
+	err = out.WriteRow(header)
+	for offset := int32(0); err == nil; {
+		for _, row := range rows {
+			if err = out.WriteRow(row); err != nil {
+				break
+			}
+		}
+		jumlah += len(rows)
+		if err != nil || len(rows) < eksporBatch {
+			break
+		}
+		if err = out.Flush(); err != nil {
+			break
+		}
+		offset += eksporBatch
+		rows, err = fetch(ctx, eksporBatch, offset)
+	}
+	if err == nil {
+		out.Close()
+	}
+
+	h.recordEkspor(context.WithoutCancel(ctx), r, user, jenis, format, jumlah, dataLengkap)
+}
+
+// recordEkspor writes the audit record of an export. It is best effort, like
+// the login audit.
+func (h *Handler) recordEkspor(ctx context.Context, r *http.Request, user *session.UserSession, jenis string, format export.Format, jumlah int, dataLengkap bool) {
+	var petugasID pgtype.UUID
+	if uid, err := uuid.Parse(user.UserID); err == nil {
+		petugasID = pgtype.UUID{Bytes: uid, Valid: true}
+	}
+	ip := session.ClientIP(r)
+	_ = h.store.InsertLogEkspor(ctx, pg_store.InsertLogEksporParams{
+		PetugasID:   petugasID,
+		NamaPetugas: user.UserName,
+		Jenis:       jenis,
+		Format:      string(format),
+		Filter:      r.URL.RawQuery,
+		JumlahBaris: int32(jumlah),
+		DataLengkap: dataLengkap,
+		IpAddress:   pgtype.Text{String: ip, Valid: ip != ""},
+	})
+}
+
+// ExportPermohonanHandler exports the permohonan table with the same filters
+// and order as the page it was started from
+func (h *Handler) ExportPermohonanHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	q := rankBySearch(common.ParseListQuery(r, perPage, "tanggal", "nama", "status", "sesi"))
+	filter := permohonanFilter(q, policy.ScopeOf(user), actorID(user))
+	lengkap := policy.Can(user, policy.PendudukViewPII, nil)
+
+	header := []string{"Kode Booking", "NIK", "Nama", "Jenis", "Status", "Tanggal Daftar", "Jadwal Sesi", "Lokasi", "No. Antrian", "Lewat SLA (hari)", "Ditangani Oleh"}
+	now := h.clock.Now()
+	h.ekspor(w, r, user, "permohonan", header, lengkap, func(ctx context.Context, limit, offset int32) ([][]string, error) {
+		list, err := h.store.ListPermohonanAdmin(ctx, listPermohonanParams(filter, q, limit, offset))
+		if err != nil {
+			return nil, err
+		}
+		rows := make([][]string, len(list))
+		for i, p := range list {
+			nik := p.Nik.String
+			if !lengkap {
+				nik = common.MaskNIK(nik)
+			}
+			var daftar, jadwal, antrian, lewat string
+			if p.TanggalDaftar.Valid {
+				daftar = clock.Local(p.TanggalDaftar.Time).Format("2006-01-02 15:04")
+			}
+			if p.JadwalTanggal.Valid && p.JadwalJamMulai.Valid {
+				jadwal = p.JadwalTanggal.Time.Format("2006-01-02") + " " + convertMicrosToTime(p.JadwalJamMulai.Microseconds)
+			}
+			if p.NomorAntrian.Valid {
+				antrian = strconv.Itoa(int(p.NomorAntrian.Int16))
+			}
+			if hari := hariLewatSLA(p, now); hari > 0 {
+				lewat = strconv.Itoa(hari)
+			}
+			rows[i] = []string{
+				p.KodeBooking.String, nik, p.NamaLengkap, p.JenisPermohonan, p.StatusTerkini.String,
+				daftar, jadwal, p.NamaLokasi.String, antrian, lewat, p.NamaPenangan.String,
+			}
+		}
+		return rows, nil
+	})
+}
+
+// ExportPendudukHandler exports the penduduk table with its current filters
+func (h *Handler) ExportPendudukHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	q := rankBySearch(common.ParseListQuery(r, perPage, "tanggal", "nama", "kelurahan"))
+	scope := policy.ScopeOf(user)
+	today := h.clock.Now()
+
+	header := []string{"NIK", "Nama", "Jenis Kelamin", "Tanggal Lahir", "Alamat", "Kelurahan", "Email", "No. HP"}
+	lengkap := policy.Can(user, policy.PendudukViewPII, nil)
+	h.ekspor(w, r, user, "penduduk", header, lengkap, func(ctx context.Context, limit, offset int32) ([][]string, error) {
+		list, err := h.store.ListPendudukAdmin(ctx, pg_store.ListPendudukAdminParams{
+			Limit:        limit,
+			Offset:       offset,
+			Search:       q.Text("search"),
+			JenisKelamin: q.Text("jenis_kelamin"),
+			KecamatanID:  scope.KecamatanID,
+			KelurahanID:  scope.KelurahanID,
+			SortBy:       q.Sort,
+			SortDesc:     q.Desc,
+		})
+		if err != nil {
+			return nil, err
+		}
+		rows := make([][]string, len(list))
+		for i, p := range pendudukItems(list, user, today) {
+			rows[i] = []string{p.NIK, p.NamaLengkap, p.JenisKelamin, p.TanggalLahir, p.Alamat, p.Kelurahan, p.Email, p.NoHP}
+		}
+		return rows, nil
+	})
+}
+
+// ExportPetugasHandler exports the petugas table with its current filters
+func (h *Handler) ExportPetugasHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	q := common.ParseListQuery(r, perPage, "tanggal", "nama", "role", "status")
+	scope := policy.ScopeOf(user)
+
+	header := []string{"Username", "Nama", "NIP", "Role", "Kecamatan", "Kelurahan", "Status"}
+	h.ekspor(w, r, user, "petugas", header, true, func(ctx context.Context, limit, offset int32) ([][]string, error) {
+		list, err := h.store.ListPetugasAdmin(ctx, pg_store.ListPetugasAdminParams{
+			Limit:       limit,
+			Offset:      offset,
+			Search:      q.Text("search"),
+			Role:        q.Text("role"),
+			KecamatanID: scope.KecamatanID,
+			KelurahanID: scope.KelurahanID,
+			SortBy:      q.Sort,
+			SortDesc:    q.Desc,
+		})
+		if err != nil {
+			return nil, err
+		}
+		rows := make([][]string, len(list))
+		for i, p := range list {
+			rows[i] = []string{
+				p.Username, p.NamaPetugas, p.Nip.String, common.FormatRole(p.Role),
+				p.NamaKecamatan.String, p.NamaKelurahan.String, p.Status,
+			}
+		}
+		return rows, nil
+	})
+}
+
+// ExportAntrianHandler exports the queue of one session, in queue order
+func (h *Handler) ExportAntrianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	jadwal, err := h.policy.Jadwal(r.Context(), user, id)
+	if err != nil {
+		writePolicyError(w, err, "Jadwal tidak ditemukan")
+		return
+	}
+	lengkap := policy.Can(user, policy.PendudukViewPII, nil)
+	sesi := jadwal.Tanggal.Time.Format("2006-01-02") + " " + convertMicrosToTime(jadwal.JamMulai.Microseconds)
+
+	header := []string{"No. Antrian", "Kode Booking", "NIK", "Nama", "Status", "Sesi", "Lokasi"}
+	h.ekspor(w, r, user, "antrian", header, lengkap, func(ctx context.Context, limit, offset int32) ([][]string, error) {
+		// A session holds at most its quota, so it comes in one batch
+		if offset > 0 {
+			return nil, nil
+		}
+		list, err := h.store.ListPermohonanByJadwal(ctx, pgtype.UUID{Bytes: id, Valid: true})
+		if err != nil {
+			return nil, err
+		}
+		rows := make([][]string, len(list))
+		for i, p := range list {
+			nik := p.Nik.String
+			if !lengkap {
+				nik = common.MaskNIK(nik)
+			}
+			var antrian string
+			if p.NomorAntrian.Valid {
+				antrian = strconv.Itoa(int(p.NomorAntrian.Int16))
+			}
+			rows[i] = []string{antrian, p.KodeBooking.String, nik, p.NamaLengkap, p.StatusTerkini.String, sesi, jadwal.NamaLokasi}
+		}
+		return rows, nil
+	})
+}
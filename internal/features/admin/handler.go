@@ -1,41 +1,48 @@
 package admin
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
 	"github.com/nobuww/simpel-ktp/internal/features/common"
-	"github.com/nobuww/simpel-ktp/internal/middleware"
+	"github.com/nobuww/simpel-ktp/internal/nik"
+	"github.com/nobuww/simpel-ktp/internal/policy"
 	"github.com/nobuww/simpel-ktp/internal/session"
+	"github.com/nobuww/simpel-ktp/internal/sla"
 	"github.com/nobuww/simpel-ktp/internal/store"
 	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+	"github.com/nobuww/simpel-ktp/internal/tiket"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // Handler manages admin-related HTTP handlers
 type Handler struct {
-	store *store.Store
+	store  store.Repository
+	clock  clock.Clock
+	policy *policy.Service
+	tiket  *tiket.Signer
 }
 
 // New creates a new admin handler with the required dependencies
-func New(s *store.Store) *Handler {
+func New(s store.Repository, clk clock.Clock, pol *policy.Service, signer *tiket.Signer) *Handler {
 	return &Handler{
-		store: s,
+		store:  s,
+		clock:  clk,
+		policy: pol,
+		tiket:  signer,
 	}
 }
 
-func getKelurahanID(user *session.UserSession) pgtype.Int2 {
-	if user == nil || user.KelurahanID == nil {
-		return pgtype.Int2{Valid: false}
-	}
-	return pgtype.Int2{Int16: *user.KelurahanID, Valid: true}
-}
-
 func (h *Handler) DashboardHandler(w http.ResponseWriter, r *http.Request) {
 	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
 	if !ok {
@@ -43,10 +50,13 @@ func (h *Handler) DashboardHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx := r.Context()
 
-	scopeID := getKelurahanID(user)
+	scope := policy.ScopeOf(user)
 
 	// Get Stats
-	statsRow, err := h.store.GetAdminDashboardStats(ctx, scopeID)
+	statsRow, err := h.store.GetAdminDashboardStats(ctx, pg_store.GetAdminDashboardStatsParams{
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
 	if err != nil {
 		statsRow = pg_store.GetAdminDashboardStatsRow{}
 	}
@@ -66,15 +76,20 @@ func (h *Handler) DashboardHandler(w http.ResponseWriter, r *http.Request) {
 		Offset:      0,
 		Search:      pgtype.Text{Valid: false},
 		Status:      pgtype.Text{Valid: false},
-		KelurahanID: scopeID,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
 	})
 	if err != nil {
 		recentRows = nil
 	}
-	recent := convertPermohonanList(recentRows)
+	recent := permohonanItems(recentRows, user, h.clock.Now())
 
 	// Get Today's Jadwal
-	todayJadwalRows, err := h.store.ListTodayJadwal(ctx, scopeID)
+	todayJadwalRows, err := h.store.ListTodayJadwal(ctx, pg_store.ListTodayJadwalParams{
+		Tanggal:     pgtype.Date{Time: clock.Today(h.clock), Valid: true},
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
 	if err != nil {
 		todayJadwalRows = nil
 	}
@@ -99,24 +114,14 @@ func (h *Handler) PermohonanHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx := r.Context()
 
-	// Pagination
-	pageStr := r.URL.Query().Get("page")
-	page, _ := strconv.Atoi(pageStr)
-	if page < 1 {
-		page = 1
-	}
-	limit := 20
-	offset := (page - 1) * limit
-
-	// Filters
-	search := r.URL.Query().Get("search")
-	statusFilter := r.URL.Query().Get("status")
-
-	// Stats for top cards
-	scopeID := getKelurahanID(user)
+	q := rankBySearch(common.ParseListQuery(r, perPage, "tanggal", "nama", "status", "sesi"))
+	scope := policy.ScopeOf(user)
 
 	// Stats for top cards
-	statsRow, err := h.store.GetAdminDashboardStats(ctx, scopeID)
+	statsRow, err := h.store.GetAdminDashboardStats(ctx, pg_store.GetAdminDashboardStatsParams{
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
 	if err != nil {
 		statsRow = pg_store.GetAdminDashboardStatsRow{}
 	}
@@ -130,19 +135,17 @@ func (h *Handler) PermohonanHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// List
-	params := pg_store.ListPermohonanAdminParams{
-		Limit:       int32(limit),
-		Offset:      int32(offset),
-		Search:      pgtype.Text{String: search, Valid: search != ""},
-		Status:      pgtype.Text{String: statusFilter, Valid: statusFilter != ""},
-		KelurahanID: scopeID,
+	filter := permohonanFilter(q, scope, actorID(user))
+	total, err := h.store.CountPermohonanAdmin(ctx, filter)
+	if err != nil {
+		total = 0
 	}
-
-	listRows, err := h.store.ListPermohonanAdmin(ctx, params)
+	q = q.ClampPage(total)
+	listRows, err := h.store.ListPermohonanAdmin(ctx, listPermohonanParams(filter, q, q.Limit(), q.Offset()))
 	if err != nil {
 		listRows = nil
 	}
-	list := convertPermohonanList(listRows)
+	list := permohonanItems(listRows, user, h.clock.Now())
 
 	data := PermohonanPageData{
 		UserName:   user.UserName,
@@ -150,12 +153,48 @@ func (h *Handler) PermohonanHandler(w http.ResponseWriter, r *http.Request) {
 		ActivePage: "permohonan",
 		Stats:      stats,
 		List:       list,
+		Query:      q,
+		Total:      int(total),
+		Tampilan:   h.loadTampilan(r, user.UserID),
 	}
+	// Kelurahan can only be picked by admins who see more than one
+	if !scope.KelurahanID.Valid {
+		_, data.KelurahanList = h.wilayahOptions(ctx, scope)
+	}
+	if q.Get("penanganan") == "saya" {
+		data.ActivePage = "antrian"
+	}
+	// The round-robin notice shows once; links built from the query drop it
+	if n, err := strconv.Atoi(q.Get("dibagikan")); err == nil {
+		data.Dibagikan = strconv.Itoa(n)
+	}
+	q.Values.Del("dibagikan")
 
 	PermohonanPage(data).Render(ctx, w)
 }
 
-func convertPermohonanList(list []pg_store.ListPermohonanAdminRow) []PermohonanItem {
+// permohonanItems converts permohonan rows for display, masking the NIK for
+// petugas without access to personal data
+func permohonanItems(rows []pg_store.ListPermohonanAdminRow, user *session.UserSession, now time.Time) []PermohonanItem {
+	list := convertPermohonanList(rows, now)
+	if !policy.Can(user, policy.PendudukViewPII, nil) {
+		for i := range list {
+			list[i].NIK = common.MaskNIK(list[i].NIK)
+		}
+	}
+	return list
+}
+
+// hariLewatSLA returns how many days an application is past the SLA target
+// of its status, or 0 when it is within target or has none
+func hariLewatSLA(p pg_store.ListPermohonanAdminRow, now time.Time) int {
+	if !p.TargetSlaHari.Valid {
+		return 0
+	}
+	return sla.Hari(sla.Terlambat(p.StatusSejak, int(p.TargetSlaHari.Int16), now))
+}
+
+func convertPermohonanList(list []pg_store.ListPermohonanAdminRow, now time.Time) []PermohonanItem {
 	items := make([]PermohonanItem, 0, len(list))
 	for _, p := range list {
 		item := PermohonanItem{
@@ -164,13 +203,14 @@ func convertPermohonanList(list []pg_store.ListPermohonanAdminRow) []PermohonanI
 			NamaLengkap:     p.NamaLengkap,
 			JenisPermohonan: p.JenisPermohonan,
 			StatusTerkini:   p.StatusTerkini.String,
+			Penangan:        p.NamaPenangan.String,
 		}
 
 		if p.Nik.Valid {
 			item.NIK = p.Nik.String
 		}
 		if p.TanggalDaftar.Valid {
-			item.TanggalDaftar = p.TanggalDaftar.Time.Format("2006-01-02")
+			item.TanggalDaftar = clock.Local(p.TanggalDaftar.Time).Format("2006-01-02")
 		}
 
 		if p.JadwalTanggal.Valid && p.JadwalJamMulai.Valid {
@@ -189,6 +229,14 @@ func convertPermohonanList(list []pg_store.ListPermohonanAdminRow) []PermohonanI
 			item.NomorAntrian = int(p.NomorAntrian.Int16)
 		}
 
+		if p.TargetSlaHari.Valid {
+			item.SLA = fmt.Sprintf("%s sejak %s, target %d hari", sla.Label(item.StatusTerkini),
+				clock.Local(p.StatusSejak).Format("2 Jan 2006"), p.TargetSlaHari.Int16)
+			if hari := hariLewatSLA(p, now); hari > 0 {
+				item.LewatSLA = fmt.Sprintf("Lewat SLA %d hari", hari)
+			}
+		}
+
 		items = append(items, item)
 	}
 	return items
@@ -202,7 +250,7 @@ func convertTodayJadwalList(rows []pg_store.ListTodayJadwalRow) []TodayJadwalIte
 
 		items[i] = TodayJadwalItem{
 			ID:            r.ID.String(),
-			SessionName:   r.NamaKelurahan,
+			SessionName:   r.NamaLokasi,
 			Time:          fmt.Sprintf("%s - %s", jamMulai, jamSelesai),
 			KuotaTerisi:   int(r.KuotaTerisi),
 			KuotaMaksimal: int(r.KuotaMaksimal),
@@ -226,11 +274,17 @@ func (h *Handler) PermohonanDetailHandler(w http.ResponseWriter, r *http.Request
 	if !ok {
 		return
 	}
-	scopeID := getKelurahanID(user)
+	wilayah, err := h.policy.Permohonan(ctx, user, permohonanID)
+	if err != nil {
+		writePolicyError(w, err, "Permohonan tidak ditemukan")
+		return
+	}
+	scope := policy.ScopeOf(user)
 
 	detailRow, err := h.store.GetPermohonanDetailAdmin(ctx, pg_store.GetPermohonanDetailAdminParams{
 		ID:          permohonanID,
-		KelurahanID: scopeID,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
 	})
 	if err != nil {
 		common.WriteNotFound(w, "Permohonan tidak ditemukan")
@@ -260,12 +314,13 @@ func (h *Handler) PermohonanDetailHandler(w http.ResponseWriter, r *http.Request
 		JenisPermohonan: detailRow.JenisPermohonan,
 		StatusTerkini:   detailRow.StatusTerkini.String,
 		Kelurahan:       detailRow.Kelurahan.String,
+		Kecamatan:       detailRow.Kecamatan.String,
 		RiwayatStatus:   convertHistoryList(historyRows),
 		Dokumen:         convertDokumenList(dokumenRows),
 	}
 
 	if detailRow.TanggalDaftar.Valid {
-		detail.TanggalDaftar = detailRow.TanggalDaftar.Time.Format("2 Jan 2006")
+		detail.TanggalDaftar = clock.Local(detailRow.TanggalDaftar.Time).Format("2 Jan 2006")
 	}
 	if detailRow.JadwalTanggal.Valid && detailRow.JadwalJamMulai.Valid {
 		d := detailRow.JadwalTanggal.Time.Format("Mon, 2 Jan 2006")
@@ -277,6 +332,30 @@ func (h *Handler) PermohonanDetailHandler(w http.ResponseWriter, r *http.Request
 	if detailRow.NomorAntrian.Valid {
 		detail.NomorAntrian = int(detailRow.NomorAntrian.Int16)
 	}
+	if detailRow.TanggalLahir.Valid {
+		detail.TanggalLahir = detailRow.TanggalLahir.Time.Format("2 Jan 2006")
+	}
+	detail.StatusPerkawinan = formatStatusPerkawinan(detailRow.StatusPerkawinan)
+	// Citizens who moved in keep the NIK of their old kecamatan; the petugas
+	// checks their moving documents
+	if parsed, err := nik.Parse(detailRow.Nik.String, h.clock.Now()); err == nil {
+		if err := parsed.CheckWilayah(detailRow.KodeWilayah.String); err != nil {
+			detail.PeringatanNIK = nik.Message(err)
+		}
+	}
+	pemohonNIK := ""
+	if detailRow.PemohonNik.Valid && detailRow.PemohonNik != detailRow.Nik {
+		pemohonNIK = detailRow.PemohonNik.String
+	}
+	if !policy.Can(user, policy.PendudukViewPII, &wilayah) {
+		pemohonNIK = common.MaskNIK(pemohonNIK)
+		detail.NIK = common.MaskNIK(detail.NIK)
+		detail.Alamat = "Disembunyikan"
+		detail.NoTelp = common.MaskPII(detail.NoTelp, 4)
+	}
+	if pemohonNIK != "" {
+		detail.DiajukanOleh = fmt.Sprintf("%s (%s)", detailRow.NamaPemohon.String, pemohonNIK)
+	}
 
 	PermohonanDetailContent(detail).Render(ctx, w)
 }
@@ -290,7 +369,7 @@ func convertDokumenList(rows []pg_store.GetDokumenByPermohonanRow) []DokumenItem
 			FilePath:     r.FilePath,
 		}
 		if r.UploadedAt.Valid {
-			item.UploadedAt = r.UploadedAt.Time.Format("2 Jan 2006, 15:04")
+			item.UploadedAt = clock.Local(r.UploadedAt.Time).Format("2 Jan 2006, 15:04")
 		}
 		items[i] = item
 	}
@@ -305,7 +384,7 @@ func convertHistoryList(rows []pg_store.GetRiwayatStatusByPermohonanRow) []Riway
 			Catatan: r.CatatanProses.String,
 		}
 		if r.WaktuProses.Valid {
-			item.Waktu = r.WaktuProses.Time.Format("2 Jan 2006, 15:04")
+			item.Waktu = clock.Local(r.WaktuProses.Time).Format("2 Jan 2006, 15:04")
 		}
 		if r.NamaPetugas.Valid {
 			item.Petugas = r.NamaPetugas.String
@@ -323,13 +402,12 @@ func (h *Handler) JadwalHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ctx := r.Context()
-	scopeID := getKelurahanID(user)
 
 	// Determine date range
 	refDateStr := r.URL.Query().Get("ref_date")
-	refDate := time.Now()
+	refDate := clock.Today(h.clock)
 	if refDateStr != "" {
-		if parsed, err := time.Parse("2006-01-02", refDateStr); err == nil {
+		if parsed, err := time.ParseInLocation("2006-01-02", refDateStr, clock.Location); err == nil {
 			refDate = parsed
 		}
 	}
@@ -344,29 +422,18 @@ func (h *Handler) JadwalHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	endOfWeek := startOfWeek.AddDate(0, 0, 6)
 
-	// Determine if admin is kecamatan level
-	isKecamatan := !scopeID.Valid
-
-	// Get kelurahan name for display (for kelurahan admin)
-	kelurahanName := ""
-	if scopeID.Valid {
-		kelRow, err := h.store.GetKelurahanById(ctx, scopeID.Int16)
-		if err == nil {
-			kelurahanName = kelRow.NamaKelurahan
-		}
-	}
-
-	// Filter by user's kelurahan (kecamatan sees only kecamatan schedules - NULL kelurahan_id)
-	var filterKelurahanID pgtype.Int4
-	if scopeID.Valid {
-		filterKelurahanID = pgtype.Int4{Int32: int32(scopeID.Int16), Valid: true}
+	// The admin manages the schedules of a lokasi layanan within their scope
+	lokasi, lokasiList, err := h.resolveLokasi(ctx, r, user)
+	if err != nil {
+		writeLokasiError(w, err)
+		return
 	}
 
 	// Fetch Jadwal
 	listParams := pg_store.ListJadwalSesiParams{
-		Tanggal:     pgtype.Date{Time: startOfWeek, Valid: true},
-		Tanggal_2:   pgtype.Date{Time: endOfWeek, Valid: true},
-		KelurahanID: filterKelurahanID,
+		Tanggal:   pgtype.Date{Time: startOfWeek, Valid: true},
+		Tanggal_2: pgtype.Date{Time: endOfWeek, Valid: true},
+		LokasiID:  lokasi.ID,
 	}
 
 	rows, err := h.store.ListJadwalSesi(ctx, listParams)
@@ -382,7 +449,7 @@ func (h *Handler) JadwalHandler(w http.ResponseWriter, r *http.Request) {
 			TanggalFormat: r.Tanggal.Time.Format("Mon, 2 Jan 2006"),
 			JamMulai:      convertMicrosToTime(r.JamMulai.Microseconds),
 			JamSelesai:    convertMicrosToTime(r.JamSelesai.Microseconds),
-			NamaKelurahan: r.NamaKelurahan,
+			NamaLokasi:    r.NamaLokasi,
 			KuotaTerisi:   int(r.KuotaTerisi),
 			KuotaMaksimal: int(r.KuotaMaksimal),
 			StatusSesi:    r.StatusSesi.String,
@@ -395,9 +462,10 @@ func (h *Handler) JadwalHandler(w http.ResponseWriter, r *http.Request) {
 		ActivePage:      "jadwal",
 		CurrentWeek:     fmt.Sprintf("%s - %s", startOfWeek.Format("2 Jan"), endOfWeek.Format("2 Jan 2006")),
 		StartOfWeekDate: startOfWeek.Format("2006-01-02"),
-		KelurahanName:   kelurahanName,
-		IsKecamatan:     isKecamatan,
+		Lokasi:          lokasiInfo(lokasi),
+		LokasiList:      lokasiOptions(lokasiList),
 		List:            items,
+		Aturan:          h.loadAturanBooking(ctx, lokasi.ID),
 	}
 
 	// Determine if we should render partials (week navigation) or full page
@@ -422,16 +490,11 @@ func (h *Handler) CreateJadwalHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ctx := r.Context()
-	scopeID := getKelurahanID(user)
 
-	// Admin Kelurahan: use their kelurahan ID
-	// Admin Kecamatan: use NULL (for Kantor Kecamatan)
-	var lokasiKelurahanID pgtype.Int2
-	if scopeID.Valid {
-		lokasiKelurahanID = scopeID
-	} else {
-		// Admin Kecamatan - lokasi is Kantor Kecamatan (NULL)
-		lokasiKelurahanID = pgtype.Int2{Valid: false}
+	lokasi, _, err := h.resolveLokasi(ctx, r, user)
+	if err != nil {
+		writeLokasiError(w, err)
+		return
 	}
 
 	tanggalStr := r.FormValue("tanggal")
@@ -440,7 +503,7 @@ func (h *Handler) CreateJadwalHandler(w http.ResponseWriter, r *http.Request) {
 	kuotaStr := r.FormValue("kuota_maksimal")
 
 	// Parse inputs
-	tanggal, err := time.Parse("2006-01-02", tanggalStr)
+	tanggal, err := time.ParseInLocation("2006-01-02", tanggalStr, clock.Location)
 	if err != nil {
 		common.WriteError(w, http.StatusBadRequest, "Format tanggal salah")
 		return
@@ -464,11 +527,11 @@ func (h *Handler) CreateJadwalHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	id, err := h.store.CreateJadwalSesi(ctx, pg_store.CreateJadwalSesiParams{
-		LokasiKelurahanID: lokasiKelurahanID,
-		Tanggal:           pgtype.Date{Time: tanggal, Valid: true},
-		JamMulai:          pgtype.Time{Microseconds: jamMulai, Valid: true},
-		JamSelesai:        pgtype.Time{Microseconds: jamSelesai, Valid: true},
-		KuotaMaksimal:     int16(kuota),
+		LokasiID:      lokasi.ID,
+		Tanggal:       pgtype.Date{Time: tanggal, Valid: true},
+		JamMulai:      pgtype.Time{Microseconds: jamMulai, Valid: true},
+		JamSelesai:    pgtype.Time{Microseconds: jamSelesai, Valid: true},
+		KuotaMaksimal: int16(kuota),
 	})
 	if err != nil {
 		common.WriteError(w, http.StatusInternalServerError, "Gagal membuat jadwal: "+err.Error())
@@ -477,7 +540,234 @@ func (h *Handler) CreateJadwalHandler(w http.ResponseWriter, r *http.Request) {
 	_ = id
 
 	common.HXTrigger(w, `{"closeDialog": "create-jadwal-dialog", "refreshJadwal": true}`)
-	common.HXRedirect(w, "/admin/jadwal")
+	common.HXRedirect(w, jadwalURL(lokasi.ID))
+}
+
+// resolveLokasi returns the lokasi layanan a jadwal request works on, along with
+// every lokasi in the admin's scope. The lokasi_id parameter selects one of them;
+// without it the admin gets their own office (the kantor kecamatan for Admin Kecamatan)
+func (h *Handler) resolveLokasi(ctx context.Context, r *http.Request, user *session.UserSession) (pg_store.LokasiLayanan, []pg_store.LokasiLayanan, error) {
+	scope := policy.ScopeOf(user)
+	list, err := h.store.ListLokasiLayanan(ctx, pg_store.ListLokasiLayananParams{
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err != nil {
+		return pg_store.LokasiLayanan{}, nil, err
+	}
+	if len(list) == 0 {
+		return pg_store.LokasiLayanan{}, nil, policy.ErrNotFound
+	}
+
+	if v := r.FormValue("lokasi_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 16)
+		if err != nil {
+			return pg_store.LokasiLayanan{}, nil, policy.ErrNotFound
+		}
+		lokasi, err := h.policy.Lokasi(ctx, user, int16(id))
+		if err != nil {
+			return pg_store.LokasiLayanan{}, nil, err
+		}
+		return lokasi, list, nil
+	}
+
+	// The list puts each kantor kecamatan before its kelurahan
+	return list[0], list, nil
+}
+
+// writePolicyError answers out-of-scope and missing records alike with 404
+func writePolicyError(w http.ResponseWriter, err error, notFoundMsg string) {
+	if errors.Is(err, policy.ErrNotFound) {
+		common.WriteNotFound(w, notFoundMsg)
+		return
+	}
+	common.WriteError(w, http.StatusInternalServerError, "Gagal memuat data")
+}
+
+func writeLokasiError(w http.ResponseWriter, err error) {
+	writePolicyError(w, err, "Lokasi layanan tidak ditemukan")
+}
+
+func formatStatusPerkawinan(status string) string {
+	switch status {
+	case "BELUM_KAWIN":
+		return "Belum Kawin"
+	case "KAWIN":
+		return "Kawin"
+	case "CERAI_HIDUP":
+		return "Cerai Hidup"
+	case "CERAI_MATI":
+		return "Cerai Mati"
+	default:
+		return status
+	}
+}
+
+func jadwalURL(lokasiID int16) string {
+	return "/admin/jadwal?lokasi_id=" + strconv.Itoa(int(lokasiID))
+}
+
+func lokasiOptions(list []pg_store.LokasiLayanan) []LokasiOption {
+	options := make([]LokasiOption, len(list))
+	for i, l := range list {
+		options[i] = LokasiOption{ID: l.ID, Nama: l.NamaLokasi}
+	}
+	return options
+}
+
+func lokasiInfo(l pg_store.LokasiLayanan) LokasiInfo {
+	return LokasiInfo{
+		ID:       l.ID,
+		Nama:     l.NamaLokasi,
+		Alamat:   l.Alamat.String,
+		JamBuka:  convertMicrosToTime(l.JamBuka.Microseconds),
+		JamTutup: convertMicrosToTime(l.JamTutup.Microseconds),
+	}
+}
+
+func (h *Handler) UpdateLokasiLayananHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	lokasi, _, err := h.resolveLokasi(ctx, r, user)
+	if err != nil {
+		writeLokasiError(w, err)
+		return
+	}
+
+	jamBuka, err := parseTime(r.FormValue("jam_buka"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Format jam buka salah")
+		return
+	}
+
+	jamTutup, err := parseTime(r.FormValue("jam_tutup"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Format jam tutup salah")
+		return
+	}
+
+	if jamBuka >= jamTutup {
+		common.WriteError(w, http.StatusBadRequest, "Jam tutup harus setelah jam buka")
+		return
+	}
+
+	alamat := strings.TrimSpace(r.FormValue("alamat"))
+
+	err = h.store.UpdateLokasiLayanan(ctx, pg_store.UpdateLokasiLayananParams{
+		ID:       lokasi.ID,
+		Alamat:   pgtype.Text{String: alamat, Valid: alamat != ""},
+		JamBuka:  pgtype.Time{Microseconds: jamBuka, Valid: true},
+		JamTutup: pgtype.Time{Microseconds: jamTutup, Valid: true},
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan lokasi layanan")
+		return
+	}
+
+	common.HXTrigger(w, `{"closeDialog": "lokasi-layanan-dialog"}`)
+	common.HXRedirect(w, jadwalURL(lokasi.ID))
+}
+
+// loadAturanBooking returns the booking rules of the given location as form values,
+// falling back to the defaults when the location has none configured
+func (h *Handler) loadAturanBooking(ctx context.Context, lokasiID int16) AturanBookingForm {
+	form := AturanBookingForm{MinJamPemberitahuan: "0", MaksHariKeDepan: "30"}
+
+	row, err := h.store.GetAturanBooking(ctx, lokasiID)
+	if err != nil {
+		return form
+	}
+
+	form.MinJamPemberitahuan = strconv.Itoa(int(row.MinJamPemberitahuan))
+	form.MaksHariKeDepan = strconv.Itoa(int(row.MaksHariKeDepan))
+	if row.BatasJamHariSama.Valid {
+		form.BatasJamHariSama = convertMicrosToTime(row.BatasJamHariSama.Microseconds)
+	}
+	if row.MaksBookingPerBulan.Valid {
+		form.MaksBookingPerBulan = strconv.Itoa(int(row.MaksBookingPerBulan.Int16))
+	}
+	return form
+}
+
+func (h *Handler) UpdateAturanBookingHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	lokasi, _, err := h.resolveLokasi(ctx, r, user)
+	if err != nil {
+		writeLokasiError(w, err)
+		return
+	}
+
+	minJam, err := strconv.Atoi(r.FormValue("min_jam_pemberitahuan"))
+	if err != nil || minJam < 0 || minJam > 720 {
+		common.WriteError(w, http.StatusBadRequest, "Minimal jam pemberitahuan harus antara 0 dan 720")
+		return
+	}
+
+	maksHari, err := strconv.Atoi(r.FormValue("maks_hari_ke_depan"))
+	if err != nil || maksHari < 1 || maksHari > 365 {
+		common.WriteError(w, http.StatusBadRequest, "Maksimal hari ke depan harus antara 1 dan 365")
+		return
+	}
+
+	var batasJam pgtype.Time
+	if v := r.FormValue("batas_jam_hari_sama"); v != "" {
+		micros, err := parseTime(v)
+		if err != nil {
+			common.WriteError(w, http.StatusBadRequest, "Format batas jam salah")
+			return
+		}
+		batasJam = pgtype.Time{Microseconds: micros, Valid: true}
+	}
+
+	var maksBooking pgtype.Int2
+	if v := r.FormValue("maks_booking_per_bulan"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			common.WriteError(w, http.StatusBadRequest, "Maksimal booking per bulan harus antara 1 dan 100")
+			return
+		}
+		maksBooking = pgtype.Int2{Int16: int16(n), Valid: true}
+	}
+
+	var updatedBy pgtype.UUID
+	if uid, err := uuid.Parse(user.UserID); err == nil {
+		updatedBy = pgtype.UUID{Bytes: uid, Valid: true}
+	}
+
+	_, err = h.store.UpsertAturanBooking(ctx, pg_store.UpsertAturanBookingParams{
+		LokasiID:            lokasi.ID,
+		MinJamPemberitahuan: int16(minJam),
+		MaksHariKeDepan:     int16(maksHari),
+		BatasJamHariSama:    batasJam,
+		MaksBookingPerBulan: maksBooking,
+		UpdatedBy:           updatedBy,
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan aturan booking")
+		return
+	}
+
+	common.HXTrigger(w, `{"closeDialog": "aturan-booking-dialog"}`)
+	common.HXRedirect(w, jadwalURL(lokasi.ID))
 }
 
 func parseTime(t string) (int64, error) {
@@ -495,29 +785,21 @@ func (h *Handler) GenerateJadwalHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	ctx := r.Context()
-	scopeID := getKelurahanID(user)
 
-	// Admin Kelurahan: use their kelurahan ID
-	// Admin Kecamatan: use NULL (for Kantor Kecamatan)
-	var lokasiKelurahanID pgtype.Int2
-	searchKelurahanID := pgtype.Int4{Valid: false}
-
-	if scopeID.Valid {
-		lokasiKelurahanID = scopeID
-		searchKelurahanID = pgtype.Int4{Int32: int32(scopeID.Int16), Valid: true}
-	} else {
-		// Admin Kecamatan - lokasi is Kantor Kecamatan (NULL)
-		lokasiKelurahanID = pgtype.Int2{Valid: false}
+	lokasi, _, err := h.resolveLokasi(ctx, r, user)
+	if err != nil {
+		writeLokasiError(w, err)
+		return
 	}
 
-	startDate := time.Now() // Start today
+	startDate := clock.Today(h.clock)
 	endDate := startDate.AddDate(0, 0, 30)
 
 	// Fetch existing schedules to prevent duplicates
 	existingSchedules, err := h.store.ListJadwalSesi(ctx, pg_store.ListJadwalSesiParams{
-		Tanggal:     pgtype.Date{Time: startDate, Valid: true},
-		Tanggal_2:   pgtype.Date{Time: endDate, Valid: true},
-		KelurahanID: searchKelurahanID,
+		Tanggal:   pgtype.Date{Time: startDate, Valid: true},
+		Tanggal_2: pgtype.Date{Time: endDate, Valid: true},
+		LokasiID:  lokasi.ID,
 	})
 
 	if err != nil {
@@ -550,11 +832,11 @@ func (h *Handler) GenerateJadwalHandler(w http.ResponseWriter, r *http.Request)
 
 		if !existingMap[key1] {
 			h.store.CreateJadwalSesi(ctx, pg_store.CreateJadwalSesiParams{
-				LokasiKelurahanID: lokasiKelurahanID,
-				Tanggal:           pgDate,
-				JamMulai:          pgtype.Time{Microseconds: jamMulai1, Valid: true},
-				JamSelesai:        pgtype.Time{Microseconds: 12 * 3600 * 1000000, Valid: true},
-				KuotaMaksimal:     50,
+				LokasiID:      lokasi.ID,
+				Tanggal:       pgDate,
+				JamMulai:      pgtype.Time{Microseconds: jamMulai1, Valid: true},
+				JamSelesai:    pgtype.Time{Microseconds: 12 * 3600 * 1000000, Valid: true},
+				KuotaMaksimal: 50,
 			})
 		}
 
@@ -564,16 +846,16 @@ func (h *Handler) GenerateJadwalHandler(w http.ResponseWriter, r *http.Request)
 
 		if !existingMap[key2] {
 			h.store.CreateJadwalSesi(ctx, pg_store.CreateJadwalSesiParams{
-				LokasiKelurahanID: lokasiKelurahanID,
-				Tanggal:           pgDate,
-				JamMulai:          pgtype.Time{Microseconds: jamMulai2, Valid: true},
-				JamSelesai:        pgtype.Time{Microseconds: 15 * 3600 * 1000000, Valid: true},
-				KuotaMaksimal:     50,
+				LokasiID:      lokasi.ID,
+				Tanggal:       pgDate,
+				JamMulai:      pgtype.Time{Microseconds: jamMulai2, Valid: true},
+				JamSelesai:    pgtype.Time{Microseconds: 15 * 3600 * 1000000, Valid: true},
+				KuotaMaksimal: 50,
 			})
 		}
 	}
 
-	common.HXRedirect(w, "/admin/jadwal")
+	common.HXRedirect(w, jadwalURL(lokasi.ID))
 }
 
 func getStartOfWeek(t time.Time) time.Time {
@@ -590,10 +872,13 @@ func (h *Handler) PendudukHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ctx := r.Context()
-	scopeID := getKelurahanID(user)
+	scope := policy.ScopeOf(user)
 
 	// Fetch Stats
-	statsRow, err := h.store.GetPendudukStatsAdmin(ctx, scopeID)
+	statsRow, err := h.store.GetPendudukStatsAdmin(ctx, pg_store.GetPendudukStatsAdminParams{
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
 	if err != nil {
 		statsRow = pg_store.GetPendudukStatsAdminRow{}
 	}
@@ -605,17 +890,49 @@ func (h *Handler) PendudukHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch List
-	search := r.URL.Query().Get("search")
-	listParams := pg_store.ListPendudukAdminParams{
-		Search:      pgtype.Text{String: search, Valid: search != ""},
-		KelurahanID: scopeID,
-	}
-
-	rows, err := h.store.ListPendudukAdmin(ctx, listParams)
+	q := rankBySearch(common.ParseListQuery(r, perPage, "tanggal", "nama", "kelurahan"))
+	total, err := h.store.CountPendudukAdmin(ctx, pg_store.CountPendudukAdminParams{
+		Search:       q.Text("search"),
+		JenisKelamin: q.Text("jenis_kelamin"),
+		KecamatanID:  scope.KecamatanID,
+		KelurahanID:  scope.KelurahanID,
+	})
+	if err != nil {
+		total = 0
+	}
+	q = q.ClampPage(total)
+	rows, err := h.store.ListPendudukAdmin(ctx, pg_store.ListPendudukAdminParams{
+		Limit:        q.Limit(),
+		Offset:       q.Offset(),
+		Search:       q.Text("search"),
+		JenisKelamin: q.Text("jenis_kelamin"),
+		KecamatanID:  scope.KecamatanID,
+		KelurahanID:  scope.KelurahanID,
+		SortBy:       q.Sort,
+		SortDesc:     q.Desc,
+	})
 	if err != nil {
 		rows = []pg_store.ListPendudukAdminRow{}
 	}
 
+	list := pendudukItems(rows, user, h.clock.Now())
+
+	data := PendudukPageData{
+		UserName:   user.UserName,
+		UserRole:   common.FormatRole(user.UserRole),
+		ActivePage: "penduduk",
+		Stats:      stats,
+		List:       list,
+		Query:      q,
+		Total:      int(total),
+	}
+
+	PendudukPage(data).Render(r.Context(), w)
+}
+
+// pendudukItems converts penduduk rows for display, hiding personal data from
+// petugas without access to it
+func pendudukItems(rows []pg_store.ListPendudukAdminRow, user *session.UserSession, today time.Time) []PendudukItem {
 	list := make([]PendudukItem, len(rows))
 	for i, r := range rows {
 		list[i] = PendudukItem{
@@ -628,17 +945,29 @@ func (h *Handler) PendudukHandler(w http.ResponseWriter, r *http.Request) {
 			Email:        r.Email.String,
 			NoHP:         r.NoHp.String,
 		}
+		// Records entered before NIK validation may not pass it; flag them for correction
+		parsed, err := nik.Parse(r.Nik, today)
+		if err == nil {
+			list[i].TanggalLahir = parsed.TanggalLahir.Format("2 Jan 2006")
+			err = parsed.Check(r.JenisKelamin)
+		}
+		if err == nil {
+			err = parsed.CheckWilayah(r.KodeWilayah.String)
+		}
+		if err != nil {
+			list[i].PeringatanNIK = nik.Message(err)
+		}
 	}
-
-	data := PendudukPageData{
-		UserName:   user.UserName,
-		UserRole:   common.FormatRole(user.UserRole),
-		ActivePage: "penduduk",
-		Stats:      stats,
-		List:       list,
+	if !policy.Can(user, policy.PendudukViewPII, nil) {
+		for i := range list {
+			list[i].NIK = common.MaskNIK(list[i].NIK)
+			list[i].Alamat = "Disembunyikan"
+			list[i].Email = common.MaskPII(list[i].Email, 2)
+			list[i].NoHP = common.MaskPII(list[i].NoHP, 4)
+			list[i].TanggalLahir = ""
+		}
 	}
-
-	PendudukPage(data).Render(r.Context(), w)
+	return list
 }
 
 func (h *Handler) PetugasHandler(w http.ResponseWriter, r *http.Request) {
@@ -647,25 +976,49 @@ func (h *Handler) PetugasHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ctx := r.Context()
+	scope := policy.ScopeOf(user)
 
-	// Check if user is admin kecamatan (no kelurahan_id)
-	isKecamatan := user.KelurahanID == nil
+	canManage := policy.Can(user, policy.PetugasManage, nil)
 
 	// Fetch Stats
-	statsRow, err := h.store.GetPetugasStatsAdmin(ctx)
+	statsRow, err := h.store.GetPetugasStatsAdmin(ctx, pg_store.GetPetugasStatsAdminParams{
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
 	if err != nil {
 		statsRow = pg_store.GetPetugasStatsAdminRow{}
 	}
 
 	stats := PetugasStats{
 		Total:          int(statsRow.Total),
+		AdminKota:      int(statsRow.AdminKota),
 		AdminKecamatan: int(statsRow.AdminKecamatan),
 		AdminKelurahan: int(statsRow.AdminKelurahan),
 		Aktif:          int(statsRow.Aktif),
 	}
 
 	// Fetch List
-	rows, err := h.store.ListPetugasAdmin(ctx)
+	q := common.ParseListQuery(r, perPage, "tanggal", "nama", "role", "status")
+	total, err := h.store.CountPetugasAdmin(ctx, pg_store.CountPetugasAdminParams{
+		Search:      q.Text("search"),
+		Role:        q.Text("role"),
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err != nil {
+		total = 0
+	}
+	q = q.ClampPage(total)
+	rows, err := h.store.ListPetugasAdmin(ctx, pg_store.ListPetugasAdminParams{
+		Limit:       q.Limit(),
+		Offset:      q.Offset(),
+		Search:      q.Text("search"),
+		Role:        q.Text("role"),
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+		SortBy:      q.Sort,
+		SortDesc:    q.Desc,
+	})
 	if err != nil {
 		rows = []pg_store.ListPetugasAdminRow{}
 	}
@@ -678,23 +1031,17 @@ func (h *Handler) PetugasHandler(w http.ResponseWriter, r *http.Request) {
 			NamaLengkap: r.NamaPetugas,
 			Role:        r.Role,
 			Kelurahan:   r.NamaKelurahan.String,
+			Kecamatan:   r.NamaKecamatan.String,
 			Status:      r.Status,
+			IsSelf:      r.ID.String() == user.UserID,
 		}
 	}
 
-	// Load kelurahan list for the create form (only for admin kecamatan)
+	// Load wilayah options for the create form
+	var kecamatanList []KecamatanOption
 	var kelurahanList []KelurahanOption
-	if isKecamatan {
-		kelRows, err := h.store.ListKelurahan(ctx)
-		if err == nil {
-			kelurahanList = make([]KelurahanOption, len(kelRows))
-			for i, k := range kelRows {
-				kelurahanList[i] = KelurahanOption{
-					ID:   k.ID,
-					Nama: k.NamaKelurahan,
-				}
-			}
-		}
+	if canManage {
+		kecamatanList, kelurahanList = h.wilayahOptions(ctx, scope)
 	}
 
 	data := PetugasPageData{
@@ -703,8 +1050,12 @@ func (h *Handler) PetugasHandler(w http.ResponseWriter, r *http.Request) {
 		ActivePage:    "petugas",
 		Stats:         stats,
 		List:          list,
-		IsKecamatan:   isKecamatan,
+		CanManage:     canManage,
+		IsKota:        scope.IsKota(),
+		KecamatanList: kecamatanList,
 		KelurahanList: kelurahanList,
+		Query:         q,
+		Total:         int(total),
 	}
 
 	PetugasPage(data).Render(r.Context(), w)
@@ -721,19 +1072,14 @@ func (h *Handler) CreatePetugasHandler(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
-
-	// Only admin kecamatan can create petugas
-	if user.KelurahanID != nil {
-		common.WriteError(w, http.StatusForbidden, "Anda tidak memiliki akses untuk menambah petugas")
-		return
-	}
-
+	scope := policy.ScopeOf(user)
 	ctx := r.Context()
 
 	nip := r.FormValue("nip")
 	nama := r.FormValue("nama")
 	email := r.FormValue("email")
 	password := r.FormValue("password")
+	kecamatanIDStr := r.FormValue("kecamatan_id")
 	kelurahanIDStr := r.FormValue("kelurahan_id")
 
 	// Validate required fields
@@ -747,6 +1093,12 @@ func (h *Handler) CreatePetugasHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	kecamatanID, kelurahanID, wilayah, err := h.resolvePetugasWilayah(ctx, scope, kecamatanIDStr, kelurahanIDStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -754,31 +1106,24 @@ func (h *Handler) CreatePetugasHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse kelurahan ID (optional - empty means admin kecamatan)
-	var kelurahanID pgtype.Int2
-	if kelurahanIDStr != "" {
-		kelID, err := strconv.Atoi(kelurahanIDStr)
+	createdBy := actorID(user)
+
+	// Create petugas; the role follows from the wilayah columns
+	err = h.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		petugas, err := q.CreatePetugas(ctx, pg_store.CreatePetugasParams{
+			KecamatanID:  kecamatanID,
+			KelurahanID:  kelurahanID,
+			Nip:          pgtype.Text{String: nip, Valid: nip != ""},
+			NamaPetugas:  nama,
+			CreatedBy:    createdBy,
+			Username:     email,
+			PasswordHash: string(hashedPassword),
+			Email:        pgtype.Text{String: email, Valid: true},
+		})
 		if err != nil {
-			common.WriteError(w, http.StatusBadRequest, "Kelurahan ID tidak valid")
-			return
+			return err
 		}
-		kelurahanID = pgtype.Int2{Int16: int16(kelID), Valid: true}
-	}
-
-	// Get current user ID for created_by
-	var createdBy pgtype.UUID
-	if uid, err := uuid.Parse(user.UserID); err == nil {
-		createdBy = pgtype.UUID{Bytes: uid, Valid: true}
-	}
-
-	// Create petugas
-	_, err = h.store.CreatePetugas(ctx, pg_store.CreatePetugasParams{
-		KelurahanID:  kelurahanID,
-		Nip:          pgtype.Text{String: nip, Valid: nip != ""},
-		NamaPetugas:  nama,
-		CreatedBy:    createdBy,
-		Username:     email,
-		PasswordHash: string(hashedPassword),
+		return logPetugas(ctx, q, petugas.ID, aksiDibuat, createdBy, wilayah)
 	})
 	if err != nil {
 		common.WriteError(w, http.StatusInternalServerError, "Gagal membuat petugas: "+err.Error())
@@ -789,34 +1134,538 @@ func (h *Handler) CreatePetugasHandler(w http.ResponseWriter, r *http.Request) {
 	common.HXRedirect(w, "/admin/petugas")
 }
 
-// PermohonanStatusFormHandler returns the status update form partial
-func (h *Handler) PermohonanStatusFormHandler(w http.ResponseWriter, r *http.Request) {
-	permohonanIDStr := chi.URLParam(r, "id")
-	permohonanID, err := uuid.Parse(permohonanIDStr)
-	if err != nil {
-		common.WriteNotFound(w, "ID tidak valid")
-		return
+// Actions recorded in riwayat_petugas
+const (
+	aksiDibuat        = "DIBUAT"
+	aksiDiubah        = "DIUBAH"
+	aksiDipindah      = "DIPINDAH"
+	aksiDinonaktifkan = "DINONAKTIFKAN"
+	aksiDiaktifkan    = "DIAKTIFKAN"
+	aksiResetPassword = "RESET_PASSWORD"
+	aksiSesiDiakhiri  = "SESI_DIAKHIRI"
+	aksiReset2FA      = "RESET_2FA"
+)
+
+// actorID returns the petugas performing an action, for created_by/updated_by columns
+func actorID(user *session.UserSession) pgtype.UUID {
+	if uid, err := uuid.Parse(user.UserID); err == nil {
+		return pgtype.UUID{Bytes: uid, Valid: true}
 	}
+	return pgtype.UUID{}
+}
 
-	ctx := r.Context()
+func logPetugas(ctx context.Context, q *pg_store.Queries, petugasID uuid.UUID, aksi string, actor pgtype.UUID, keterangan string) error {
+	return q.InsertRiwayatPetugas(ctx, pg_store.InsertRiwayatPetugasParams{
+		PetugasID:     petugasID,
+		Aksi:          aksi,
+		DilakukanOleh: actor,
+		Keterangan:    pgtype.Text{String: keterangan, Valid: keterangan != ""},
+	})
+}
 
-	// Get current status
-	statusRow, err := h.store.GetPermohonanStatusById(ctx, permohonanID)
-	if err != nil {
-		common.WriteError(w, http.StatusNotFound, "Permohonan tidak ditemukan")
-		return
+// revokePetugasSessions signs the petugas out on every device, so that
+// account changes take effect on their next request
+func revokePetugasSessions(ctx context.Context, q *pg_store.Queries, petugasID uuid.UUID) error {
+	return q.DeleteSesiLoginByUser(ctx, pg_store.DeleteSesiLoginByUserParams{
+		UserType: session.UserTypePetugas,
+		UserID:   petugasID.String(),
+	})
+}
+
+// resolvePetugasWilayah validates the wilayah a petugas is assigned to and
+// returns it with a description for the audit trail. Admin Kecamatan can only
+// assign within their own kecamatan; Admin Kota picks one (empty means another
+// admin kota). An empty kelurahan means the admin of the kecamatan.
+func (h *Handler) resolvePetugasWilayah(ctx context.Context, scope policy.Scope, kecamatanIDStr, kelurahanIDStr string) (pgtype.Int2, pgtype.Int2, string, error) {
+	var kelurahanID pgtype.Int2
+	kecamatanID := scope.KecamatanID
+	if scope.IsKota() && kecamatanIDStr != "" {
+		kecID, err := strconv.ParseInt(kecamatanIDStr, 10, 16)
+		if err != nil {
+			return kecamatanID, kelurahanID, "", errors.New("Kecamatan ID tidak valid")
+		}
+		kecamatanID = pgtype.Int2{Int16: int16(kecID), Valid: true}
 	}
 
-	currentStatus := ""
-	if statusRow.StatusTerkini.Valid {
-		currentStatus = statusRow.StatusTerkini.String
+	if kelurahanIDStr != "" {
+		kelID, err := strconv.ParseInt(kelurahanIDStr, 10, 16)
+		if err != nil {
+			return kecamatanID, kelurahanID, "", errors.New("Kelurahan ID tidak valid")
+		}
+		kel, err := h.store.GetKelurahanById(ctx, int16(kelID))
+		if err != nil {
+			return kecamatanID, kelurahanID, "", errors.New("Kelurahan tidak ditemukan")
+		}
+		if kecamatanID.Valid && kel.KecamatanID != kecamatanID.Int16 {
+			return kecamatanID, kelurahanID, "", errors.New("Kelurahan tidak berada di kecamatan yang dipilih")
+		}
+		kelurahanID = pgtype.Int2{Int16: kel.ID, Valid: true}
+		kecamatanID = pgtype.Int2{Int16: kel.KecamatanID, Valid: true}
+		if !scope.Covers(kecamatanID, kelurahanID) {
+			return kecamatanID, kelurahanID, "", errors.New("Kelurahan di luar wilayah Anda")
+		}
+		return kecamatanID, kelurahanID, "Kelurahan " + kel.NamaKelurahan, nil
 	}
 
-	StatusUpdateForm(permohonanIDStr, currentStatus).Render(ctx, w)
+	if !kecamatanID.Valid {
+		return kecamatanID, kelurahanID, "Disdukcapil", nil
+	}
+	if scope.KelurahanID.Valid {
+		return kecamatanID, kelurahanID, "", errors.New("Kelurahan wajib dipilih")
+	}
+	kec, err := h.store.GetKecamatanById(ctx, kecamatanID.Int16)
+	if err != nil {
+		return kecamatanID, kelurahanID, "", errors.New("Kecamatan tidak ditemukan")
+	}
+	return kecamatanID, kelurahanID, "Kecamatan " + kec.NamaKecamatan, nil
 }
 
-// UpdateStatusHandler handles the status update submission
-func (h *Handler) UpdateStatusHandler(w http.ResponseWriter, r *http.Request) {
+// wilayahOptions lists the kecamatan (Admin Kota only) and kelurahan within
+// the admin's scope, for assigning petugas and for filtering by area
+func (h *Handler) wilayahOptions(ctx context.Context, scope policy.Scope) ([]KecamatanOption, []KelurahanOption) {
+	var kecamatanList []KecamatanOption
+	if scope.IsKota() {
+		kecRows, err := h.store.ListKecamatan(ctx)
+		if err == nil {
+			kecamatanList = make([]KecamatanOption, len(kecRows))
+			for i, k := range kecRows {
+				kecamatanList[i] = KecamatanOption{
+					ID:   k.ID,
+					Nama: k.NamaKecamatan,
+				}
+			}
+		}
+	}
+
+	var kelurahanList []KelurahanOption
+	kelRows, err := h.store.ListKelurahan(ctx)
+	if err == nil {
+		for _, k := range kelRows {
+			if !scope.Covers(pgtype.Int2{Int16: k.KecamatanID, Valid: true}, pgtype.Int2{Int16: k.ID, Valid: true}) {
+				continue
+			}
+			kelurahanList = append(kelurahanList, KelurahanOption{
+				ID:          k.ID,
+				KecamatanID: k.KecamatanID,
+				Nama:        k.NamaKelurahan,
+			})
+		}
+	}
+	return kecamatanList, kelurahanList
+}
+
+// loadPetugas resolves a petugas ID to a petugas within the admin's scope
+func (h *Handler) loadPetugas(w http.ResponseWriter, r *http.Request, user *session.UserSession, idStr string) (pg_store.Petugas, bool) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteNotFound(w, "ID petugas tidak valid")
+		return pg_store.Petugas{}, false
+	}
+	petugas, err := h.policy.Petugas(r.Context(), user, id)
+	if err != nil {
+		writePolicyError(w, err, "Petugas tidak ditemukan")
+		return pg_store.Petugas{}, false
+	}
+	return petugas, true
+}
+
+// EditPetugasFormHandler returns the edit form partial with the account's audit trail
+func (h *Handler) EditPetugasFormHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	petugas, ok := h.loadPetugas(w, r, user, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	scope := policy.ScopeOf(user)
+
+	sesiRows, err := h.store.ListSesiLoginByUser(ctx, pg_store.ListSesiLoginByUserParams{
+		UserType: session.UserTypePetugas,
+		UserID:   petugas.ID.String(),
+	})
+	if err != nil {
+		sesiRows = []pg_store.ListSesiLoginByUserRow{}
+	}
+	sesi := make([]SesiPetugasItem, len(sesiRows))
+	for i, sr := range sesiRows {
+		sesi[i] = SesiPetugasItem{
+			UserAgent:  sr.UserAgent.String,
+			IPAddress:  sr.IpAddress.String,
+			LoginAt:    clock.Local(sr.CreatedAt).Format("2 Jan 2006 15:04"),
+			LastSeenAt: clock.Local(sr.LastSeenAt).Format("2 Jan 2006 15:04"),
+			IsCurrent:  sr.ID.String() == user.SessionID,
+		}
+	}
+
+	riwayatRows, err := h.store.ListRiwayatPetugas(ctx, petugas.ID)
+	if err != nil {
+		riwayatRows = []pg_store.ListRiwayatPetugasRow{}
+	}
+	riwayat := make([]RiwayatPetugasItem, len(riwayatRows))
+	for i, rp := range riwayatRows {
+		riwayat[i] = RiwayatPetugasItem{
+			Aksi:          rp.Aksi,
+			Keterangan:    rp.Keterangan.String,
+			DilakukanOleh: rp.DilakukanOleh.String,
+			Waktu:         clock.Local(rp.Waktu).Format("2 Jan 2006 15:04"),
+		}
+	}
+
+	totpAktif := false
+	if t, err := h.store.GetPetugasTotp(ctx, petugas.ID); err == nil {
+		totpAktif = t.Aktif
+	}
+
+	kecamatanList, kelurahanList := h.wilayahOptions(ctx, scope)
+
+	data := EditPetugasData{
+		ID:            petugas.ID.String(),
+		NIP:           petugas.Nip.String,
+		NamaLengkap:   petugas.NamaPetugas,
+		Email:         petugas.Email.String,
+		KecamatanID:   petugas.KecamatanID.Int16,
+		KelurahanID:   petugas.KelurahanID.Int16,
+		IsActive:      petugas.IsActive,
+		IsSelf:        petugas.ID.String() == user.UserID,
+		IsKota:        scope.IsKota(),
+		KecamatanList: kecamatanList,
+		KelurahanList: kelurahanList,
+		Riwayat:       riwayat,
+		Sesi:          sesi,
+		TOTPAktif:     totpAktif,
+	}
+
+	EditPetugasContent(data).Render(ctx, w)
+}
+
+// UpdatePetugasHandler saves profile changes and moves the petugas to another
+// wilayah; the role trigger derives the new role
+func (h *Handler) UpdatePetugasHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	petugas, ok := h.loadPetugas(w, r, user, r.FormValue("id"))
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	scope := policy.ScopeOf(user)
+
+	nip := strings.TrimSpace(r.FormValue("nip"))
+	nama := strings.TrimSpace(r.FormValue("nama"))
+	email := strings.TrimSpace(r.FormValue("email"))
+	if nama == "" || email == "" {
+		common.WriteError(w, http.StatusBadRequest, "Nama dan email wajib diisi")
+		return
+	}
+
+	kecamatanID, kelurahanID, wilayah, err := h.resolvePetugasWilayah(ctx, scope, r.FormValue("kecamatan_id"), r.FormValue("kelurahan_id"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	moved := kecamatanID != petugas.KecamatanID || kelurahanID != petugas.KelurahanID
+	if moved && petugas.ID.String() == user.UserID {
+		common.WriteError(w, http.StatusBadRequest, "Anda tidak dapat memindahkan wilayah akun sendiri")
+		return
+	}
+
+	actor := actorID(user)
+	err = h.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		if err := q.UpdatePetugasProfil(ctx, pg_store.UpdatePetugasProfilParams{
+			ID:          petugas.ID,
+			NamaPetugas: nama,
+			Nip:         pgtype.Text{String: nip, Valid: nip != ""},
+			Email:       pgtype.Text{String: email, Valid: true},
+			UpdatedBy:   actor,
+		}); err != nil {
+			return err
+		}
+		if nama != petugas.NamaPetugas || nip != petugas.Nip.String || email != petugas.Email.String {
+			if err := logPetugas(ctx, q, petugas.ID, aksiDiubah, actor, ""); err != nil {
+				return err
+			}
+		}
+
+		if !moved {
+			return nil
+		}
+		if err := q.UpdatePetugasWilayah(ctx, pg_store.UpdatePetugasWilayahParams{
+			ID:          petugas.ID,
+			KecamatanID: kecamatanID,
+			KelurahanID: kelurahanID,
+			UpdatedBy:   actor,
+		}); err != nil {
+			return err
+		}
+		if err := revokePetugasSessions(ctx, q, petugas.ID); err != nil {
+			return err
+		}
+		return logPetugas(ctx, q, petugas.ID, aksiDipindah, actor, wilayah)
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan petugas: "+err.Error())
+		return
+	}
+
+	common.HXTrigger(w, `{"closeDialog": "edit-petugas-dialog", "refreshPetugas": true}`)
+	common.HXRedirect(w, "/admin/petugas")
+}
+
+// SetPetugasStatusHandler deactivates or reactivates a petugas. Deactivation
+// ends the petugas's sessions in the same transaction.
+func (h *Handler) SetPetugasStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	petugas, ok := h.loadPetugas(w, r, user, r.FormValue("id"))
+	if !ok {
+		return
+	}
+	if petugas.ID.String() == user.UserID {
+		common.WriteError(w, http.StatusBadRequest, "Anda tidak dapat menonaktifkan akun sendiri")
+		return
+	}
+	ctx := r.Context()
+
+	aktif := r.FormValue("aktif") == "true"
+	aksi := aksiDinonaktifkan
+	if aktif {
+		aksi = aksiDiaktifkan
+	}
+	keterangan := strings.TrimSpace(r.FormValue("keterangan"))
+
+	actor := actorID(user)
+	err := h.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		if err := q.SetPetugasActive(ctx, pg_store.SetPetugasActiveParams{
+			ID:        petugas.ID,
+			IsActive:  aktif,
+			UpdatedBy: actor,
+		}); err != nil {
+			return err
+		}
+		if !aktif {
+			if err := revokePetugasSessions(ctx, q, petugas.ID); err != nil {
+				return err
+			}
+		}
+		return logPetugas(ctx, q, petugas.ID, aksi, actor, keterangan)
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal mengubah status petugas")
+		return
+	}
+
+	common.HXTrigger(w, `{"closeDialog": "status-petugas-dialog", "refreshPetugas": true}`)
+	common.HXRedirect(w, "/admin/petugas")
+}
+
+// ResetPetugasPasswordHandler replaces the password with a generated temporary
+// one, shown once to the admin, and signs the petugas out everywhere
+func (h *Handler) ResetPetugasPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	petugas, ok := h.loadPetugas(w, r, user, r.FormValue("id"))
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	password, err := generateTempPassword()
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal membuat password sementara")
+		return
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memproses password")
+		return
+	}
+
+	actor := actorID(user)
+	err = h.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		if err := q.UpdatePetugasPassword(ctx, pg_store.UpdatePetugasPasswordParams{
+			ID:           petugas.ID,
+			PasswordHash: string(hashedPassword),
+			UpdatedBy:    actor,
+		}); err != nil {
+			return err
+		}
+		if err := revokePetugasSessions(ctx, q, petugas.ID); err != nil {
+			return err
+		}
+		return logPetugas(ctx, q, petugas.ID, aksiResetPassword, actor, "")
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal mereset password")
+		return
+	}
+
+	ResetPasswordResult(petugas.NamaPetugas, password).Render(ctx, w)
+}
+
+// RevokePetugasSessionsHandler signs a petugas out on every device
+func (h *Handler) RevokePetugasSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	petugas, ok := h.loadPetugas(w, r, user, r.FormValue("id"))
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	actor := actorID(user)
+	err := h.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		if err := revokePetugasSessions(ctx, q, petugas.ID); err != nil {
+			return err
+		}
+		return logPetugas(ctx, q, petugas.ID, aksiSesiDiakhiri, actor, "")
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal mengakhiri sesi petugas")
+		return
+	}
+
+	if petugas.ID.String() == user.UserID {
+		common.HXRedirect(w, "/petugas/login")
+		return
+	}
+	common.HXTrigger(w, `{"closeDialog": "edit-petugas-dialog", "refreshPetugas": true}`)
+	common.HXRedirect(w, "/admin/petugas")
+}
+
+// ResetPetugas2FAHandler removes a petugas' TOTP secret and recovery codes,
+// for example after a lost phone. The petugas is signed out and enrols again
+// on the next login if the role requires 2FA.
+func (h *Handler) ResetPetugas2FAHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	petugas, ok := h.loadPetugas(w, r, user, r.FormValue("id"))
+	if !ok {
+		return
+	}
+	if petugas.ID.String() == user.UserID {
+		common.WriteError(w, http.StatusBadRequest, "Atur verifikasi dua langkah Anda sendiri melalui halaman Keamanan Akun")
+		return
+	}
+	ctx := r.Context()
+
+	actor := actorID(user)
+	err := h.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		if err := q.DeleteKodePemulihan(ctx, petugas.ID); err != nil {
+			return err
+		}
+		if err := q.DeletePetugasTotp(ctx, petugas.ID); err != nil {
+			return err
+		}
+		if err := revokePetugasSessions(ctx, q, petugas.ID); err != nil {
+			return err
+		}
+		return logPetugas(ctx, q, petugas.ID, aksiReset2FA, actor, "")
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal mereset verifikasi dua langkah")
+		return
+	}
+
+	common.HXTrigger(w, `{"closeDialog": "edit-petugas-dialog", "refreshPetugas": true}`)
+	common.HXRedirect(w, "/admin/petugas")
+}
+
+// generateTempPassword returns a random password without look-alike characters
+func generateTempPassword() (string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = alphabet[int(b)%len(alphabet)]
+	}
+	return string(buf), nil
+}
+
+// PermohonanStatusFormHandler returns the status update form partial, which
+// also shows who handles the permohonan
+func (h *Handler) PermohonanStatusFormHandler(w http.ResponseWriter, r *http.Request) {
+	permohonanIDStr := chi.URLParam(r, "id")
+	permohonanID, err := uuid.Parse(permohonanIDStr)
+	if err != nil {
+		common.WriteNotFound(w, "ID tidak valid")
+		return
+	}
+
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	wilayah, err := h.policy.Permohonan(ctx, user, permohonanID)
+	if err != nil {
+		writePolicyError(w, err, "Permohonan tidak ditemukan")
+		return
+	}
+	if !policy.Can(user, policy.PermohonanVerify, &wilayah) && !policy.Can(user, policy.PermohonanReject, &wilayah) &&
+		!policy.Can(user, policy.PermohonanAssign, &wilayah) {
+		common.WriteError(w, http.StatusForbidden, "Anda tidak memiliki akses untuk mengubah status permohonan")
+		return
+	}
+
+	h.renderStatusForm(w, r, user, permohonanID, wilayah, http.StatusOK, "")
+}
+
+// AlasanPenolakan is a reason for rejecting a permohonan
+type AlasanPenolakan struct {
+	Kode  string
+	Label string
+}
+
+// alasanPenolakan lists the reasons a petugas can pick when rejecting, in
+// the order shown on the status form
+var alasanPenolakan = []AlasanPenolakan{
+	{"DOKUMEN_TIDAK_LENGKAP", "Dokumen tidak lengkap"},
+	{"DOKUMEN_TIDAK_VALID", "Dokumen tidak valid"},
+	{"DATA_TIDAK_SESUAI", "Data tidak sesuai"},
+	{"TIDAK_HADIR", "Tidak hadir pada sesi"},
+	{"DUPLIKAT", "Permohonan ganda"},
+	{"LAINNYA", "Lainnya"},
+}
+
+func labelAlasanPenolakan(kode string) string {
+	for _, a := range alasanPenolakan {
+		if a.Kode == kode {
+			return a.Label
+		}
+	}
+	if kode == "TIDAK_DICATAT" {
+		return "Tidak dicatat"
+	}
+	return kode
+}
+
+// UpdateStatusHandler handles the status update submission
+func (h *Handler) UpdateStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -844,38 +1693,90 @@ func (h *Handler) UpdateStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A rejection needs a reason for the reports; other statuses carry none
+	var alasan pgtype.Text
+	if newStatus == "DITOLAK" {
+		kode := r.FormValue("alasan")
+		for _, a := range alasanPenolakan {
+			if a.Kode == kode {
+				alasan = pgtype.Text{String: kode, Valid: true}
+			}
+		}
+		if !alasan.Valid {
+			common.WriteError(w, http.StatusBadRequest, "Pilih alasan penolakan")
+			return
+		}
+	}
+
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
 	ctx := r.Context()
 
-	user := middleware.GetUserFromContext(ctx)
 	var petugasID pgtype.UUID
-	if user != nil {
-		if uid, err := uuid.Parse(user.UserID); err == nil {
-			petugasID = pgtype.UUID{Bytes: uid, Valid: true}
-		}
+	if uid, err := uuid.Parse(user.UserID); err == nil {
+		petugasID = pgtype.UUID{Bytes: uid, Valid: true}
 	}
 
-	scopeID := getKelurahanID(user)
+	wilayah, err := h.policy.Permohonan(ctx, user, permohonanID)
+	if err != nil {
+		writePolicyError(w, err, "Permohonan tidak ditemukan")
+		return
+	}
+	perm := policy.PermohonanVerify
+	if newStatus == "DITOLAK" {
+		perm = policy.PermohonanReject
+	}
+	if !policy.Can(user, perm, &wilayah) {
+		common.WriteError(w, http.StatusForbidden, "Anda tidak memiliki akses untuk mengubah status ini")
+		return
+	}
+	scope := policy.ScopeOf(user)
 
+	// Only the holder of the permohonan may change it, and only from an up
+	// to date form; a claim or status change by someone else moves the versi
+	versi, ok := formVersi(r)
+	if !ok {
+		common.WriteError(w, http.StatusBadRequest, "Versi permohonan tidak valid")
+		return
+	}
 	err = h.store.ExecTx(ctx, func(q *pg_store.Queries) error {
-		if err := q.UpdatePermohonanStatusAdmin(ctx, pg_store.UpdatePermohonanStatusAdminParams{
+		n, err := q.UpdatePermohonanStatusAdmin(ctx, pg_store.UpdatePermohonanStatusAdminParams{
 			ID:            permohonanID,
 			StatusTerkini: pgtype.Text{String: newStatus, Valid: true},
-			KelurahanID:   scopeID,
-		}); err != nil {
+			PetugasID:     petugasID,
+			Versi:         versi,
+			KecamatanID:   scope.KecamatanID,
+			KelurahanID:   scope.KelurahanID,
+		})
+		if err != nil {
 			return err
 		}
+		if n == 0 {
+			return errPenugasanBerubah
+		}
 
 		if err := q.InsertRiwayatStatus(ctx, pg_store.InsertRiwayatStatusParams{
-			PermohonanID:  pgtype.UUID{Bytes: permohonanID, Valid: true},
-			PetugasID:     petugasID,
-			StatusBaru:    newStatus,
-			CatatanProses: pgtype.Text{String: catatan, Valid: true},
+			PermohonanID:    pgtype.UUID{Bytes: permohonanID, Valid: true},
+			PetugasID:       petugasID,
+			StatusBaru:      newStatus,
+			CatatanProses:   pgtype.Text{String: catatan, Valid: true},
+			AlasanPenolakan: alasan,
 		}); err != nil {
 			return err
 		}
 		return nil
 	})
 
+	if errors.Is(err, errPenugasanBerubah) {
+		pesan := pesanVersiBerubah
+		if row, err := h.store.GetPenugasanPermohonan(ctx, permohonanID); err == nil && row.DitanganiOleh != petugasID {
+			pesan = "Status hanya dapat diubah oleh petugas yang menangani permohonan ini."
+		}
+		h.renderStatusForm(w, r, user, permohonanID, wilayah, http.StatusConflict, pesan)
+		return
+	}
 	if err != nil {
 		common.WriteError(w, http.StatusInternalServerError, "Gagal update status: "+err.Error())
 		return
@@ -908,9 +1809,9 @@ func (h *Handler) JadwalAntrianHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Get Jadwal Info
-	item, err := h.store.GetJadwalSesiById(ctx, id)
+	item, err := h.policy.Jadwal(ctx, user, id)
 	if err != nil {
-		common.WriteNotFound(w, "Jadwal not found")
+		writePolicyError(w, err, "Jadwal tidak ditemukan")
 		return
 	}
 
@@ -920,17 +1821,33 @@ func (h *Handler) JadwalAntrianHandler(w http.ResponseWriter, r *http.Request) {
 		TanggalFormat: item.Tanggal.Time.Format("Mon, 2 Jan 2006"), // Formatted for display
 		JamMulai:      convertMicrosToTime(item.JamMulai.Microseconds),
 		JamSelesai:    convertMicrosToTime(item.JamSelesai.Microseconds),
-		NamaKelurahan: item.NamaKelurahan,
+		NamaLokasi:    item.NamaLokasi,
 		KuotaMaksimal: int(item.KuotaMaksimal),
 		KuotaTerisi:   int(item.KuotaTerisi),
 		StatusSesi:    item.StatusSesi.String,
 	}
 
 	// Get Antrian List
-	listRows, err := h.store.ListPermohonanByJadwal(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	q := common.ParseListQuery(r, perPage, "antrian", "nama", "status")
+	if q.Get("sort") == "" {
+		// A queue reads from the first number unless a column was picked
+		q.Desc = false
+	}
+	jadwalID := pgtype.UUID{Bytes: id, Valid: true}
+	total, err := h.store.CountPermohonanByJadwal(ctx, jadwalID)
+	if err != nil {
+		total = 0
+	}
+	q = q.ClampPage(total)
+	listRows, err := h.store.ListAntrianJadwal(ctx, pg_store.ListAntrianJadwalParams{
+		Limit:        q.Limit(),
+		Offset:       q.Offset(),
+		JadwalSesiID: jadwalID,
+		SortBy:       q.Sort,
+		SortDesc:     q.Desc,
+	})
 	if err != nil {
-		// handle error or empty
-		listRows = []pg_store.ListPermohonanByJadwalRow{}
+		listRows = []pg_store.ListAntrianJadwalRow{}
 	}
 
 	antrianList := make([]AntrianItem, len(listRows))
@@ -943,11 +1860,21 @@ func (h *Handler) JadwalAntrianHandler(w http.ResponseWriter, r *http.Request) {
 			Status:      r.StatusTerkini.String,
 			NoAntrian:   int(r.NomorAntrian.Int16),
 		}
+		if r.WaktuHadir.Valid {
+			antrianList[i].Hadir = clock.Local(r.WaktuHadir.Time).Format("15:04")
+		}
+	}
+	if !policy.Can(user, policy.PendudukViewPII, nil) {
+		for i := range antrianList {
+			antrianList[i].NIK = common.MaskNIK(antrianList[i].NIK)
+		}
 	}
 
 	data := JadwalAntrianData{
 		JadwalInfo:  jadwalInfo,
 		AntrianList: antrianList,
+		Query:       q,
+		Total:       int(total),
 		UserName:    user.UserName,
 		UserRole:    common.FormatRole(user.UserRole),
 		ActivePage:  "jadwal",
@@ -965,16 +1892,16 @@ func (h *Handler) DeleteJadwalConfirmHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	_, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
 	if !ok {
 		return
 	}
 	ctx := r.Context()
 
 	// Get Jadwal Info
-	item, err := h.store.GetJadwalSesiById(ctx, id)
+	item, err := h.policy.Jadwal(ctx, user, id)
 	if err != nil {
-		common.WriteNotFound(w, "Jadwal not found")
+		writePolicyError(w, err, "Jadwal tidak ditemukan")
 		return
 	}
 
@@ -984,7 +1911,7 @@ func (h *Handler) DeleteJadwalConfirmHandler(w http.ResponseWriter, r *http.Requ
 		TanggalFormat: item.Tanggal.Time.Format("Mon, 2 Jan 2006"),
 		JamMulai:      convertMicrosToTime(item.JamMulai.Microseconds),
 		JamSelesai:    convertMicrosToTime(item.JamSelesai.Microseconds),
-		NamaKelurahan: item.NamaKelurahan,
+		NamaLokasi:    item.NamaLokasi,
 		KuotaMaksimal: int(item.KuotaMaksimal),
 		KuotaTerisi:   int(item.KuotaTerisi),
 		StatusSesi:    item.StatusSesi.String,
@@ -1020,17 +1947,16 @@ func (h *Handler) DeleteJadwalHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ctx := r.Context()
-	scopeID := getKelurahanID(user)
-
-	// Convert scope ID for the delete query
-	var filterKelurahanID pgtype.Int4
-	if scopeID.Valid {
-		filterKelurahanID = pgtype.Int4{Int32: int32(scopeID.Int16), Valid: true}
+	if _, err := h.policy.Jadwal(ctx, user, id); err != nil {
+		writePolicyError(w, err, "Jadwal tidak ditemukan")
+		return
 	}
+	scope := policy.ScopeOf(user)
 
 	err = h.store.DeleteJadwalSesi(ctx, pg_store.DeleteJadwalSesiParams{
 		ID:          id,
-		KelurahanID: filterKelurahanID,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
 	})
 	if err != nil {
 		common.WriteError(w, http.StatusInternalServerError, "Gagal menghapus jadwal: "+err.Error())
@@ -1040,3 +1966,131 @@ func (h *Handler) DeleteJadwalHandler(w http.ResponseWriter, r *http.Request) {
 	common.HXTrigger(w, `{"closeDialog": "delete-jadwal-dialog", "refreshJadwal": true}`)
 	common.HXRedirect(w, "/admin/jadwal")
 }
+
+// AkunTerkunciHandler lists accounts locked after repeated failed logins.
+// Locked petugas accounts are only listed for petugas who manage accounts.
+func (h *Handler) AkunTerkunciHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	scope := policy.ScopeOf(user)
+	canViewPII := policy.Can(user, policy.PendudukViewPII, nil)
+	withPetugas := policy.Can(user, policy.PetugasManage, nil)
+
+	q := common.ParseListQuery(r, perPage, "sampai", "nama", "gagal")
+	total, err := h.store.CountKunciLoginAdmin(ctx, pg_store.CountKunciLoginAdminParams{
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+		WithPetugas: withPetugas,
+	})
+	if err != nil {
+		total = 0
+	}
+	q = q.ClampPage(total)
+	rows, err := h.store.ListKunciLoginAdmin(ctx, pg_store.ListKunciLoginAdminParams{
+		Limit:       q.Limit(),
+		Offset:      q.Offset(),
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+		WithPetugas: withPetugas,
+		SortBy:      q.Sort,
+		SortDesc:    q.Desc,
+	})
+	if err != nil {
+		rows = []pg_store.ListKunciLoginAdminRow{}
+	}
+
+	list := make([]AkunTerkunciItem, 0, len(rows))
+	for _, row := range rows {
+		item := AkunTerkunciItem{
+			ID:             row.ID.String(),
+			Identifier:     row.Identifier,
+			Nama:           row.Nama,
+			GagalBeruntun:  int(row.GagalBeruntun),
+			GagalTerakhir:  clock.Local(row.GagalTerakhirAt).Format("2 Jan 2006 15:04"),
+			IPTerakhir:     row.IpTerakhir.String,
+			TerkunciSampai: clock.Local(row.TerkunciSampai.Time).Format("2 Jan 2006 15:04"),
+		}
+		if row.UserType == session.UserTypePetugas {
+			item.Keterangan = common.FormatRole(row.Role.String)
+		} else {
+			if !canViewPII {
+				item.Identifier = common.MaskPII(item.Identifier, 6)
+			}
+			item.Keterangan = "Warga"
+			if row.NamaKelurahan.Valid {
+				item.Keterangan = "Warga · Kel. " + row.NamaKelurahan.String
+			}
+		}
+		list = append(list, item)
+	}
+
+	data := AkunTerkunciPageData{
+		UserName:   user.UserName,
+		UserRole:   common.FormatRole(user.UserRole),
+		ActivePage: "akun-terkunci",
+		List:       list,
+		Query:      q,
+		Total:      int(total),
+	}
+
+	AkunTerkunciPage(data).Render(ctx, w)
+}
+
+// UnlockAkunHandler lifts a login lockout. Any petugas may unlock warga in
+// their wilayah; unlocking petugas requires petugas.manage.
+func (h *Handler) UnlockAkunHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	id, err := uuid.Parse(r.FormValue("id"))
+	if err != nil {
+		common.WriteNotFound(w, "Akun tidak ditemukan")
+		return
+	}
+	kunci, err := h.store.GetKunciLoginById(ctx, id)
+	if err != nil {
+		common.WriteNotFound(w, "Akun tidak ditemukan")
+		return
+	}
+
+	switch kunci.UserType {
+	case session.UserTypeWarga:
+		_, err = h.policy.Penduduk(ctx, user, kunci.Identifier)
+	case session.UserTypePetugas:
+		if !policy.Can(user, policy.PetugasManage, nil) {
+			common.WriteError(w, http.StatusForbidden, "Anda tidak memiliki akses untuk tindakan ini")
+			return
+		}
+		// Petugas lockouts are keyed by ID; a lockout of an unknown NIP
+		// has no account to unlock
+		var petugasID uuid.UUID
+		petugasID, err = uuid.Parse(kunci.Identifier)
+		if err == nil {
+			_, err = h.policy.Petugas(ctx, user, petugasID)
+		} else {
+			err = policy.ErrNotFound
+		}
+	default:
+		err = policy.ErrNotFound
+	}
+	if err != nil {
+		writePolicyError(w, err, "Akun tidak ditemukan")
+		return
+	}
+
+	if err := h.store.UnlockKunciLogin(ctx, pg_store.UnlockKunciLoginParams{
+		ID:         kunci.ID,
+		DibukaOleh: actorID(user),
+	}); err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal membuka kunci akun")
+		return
+	}
+
+	common.HXRedirect(w, "/admin/akun-terkunci")
+}
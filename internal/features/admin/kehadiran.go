@@ -0,0 +1,176 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/policy"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// KehadiranHandler shows the check-in page. The booking QR code is read by
+// the device camera, or typed into the input by a USB scanner. Below it are
+// the arrivals already recorded today in the petugas' wilayah.
+func (h *Handler) KehadiranHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	scope := policy.ScopeOf(user)
+	today := pgtype.Date{Time: clock.Today(h.clock), Valid: true}
+
+	q := common.ParseListQuery(r, perPage, "hadir", "nama", "antrian")
+	total, err := h.store.CountKehadiranAdmin(ctx, pg_store.CountKehadiranAdminParams{
+		Tanggal:     today,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err != nil {
+		total = 0
+	}
+	q = q.ClampPage(total)
+	rows, err := h.store.ListKehadiranAdmin(ctx, pg_store.ListKehadiranAdminParams{
+		Limit:       q.Limit(),
+		Offset:      q.Offset(),
+		Tanggal:     today,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+		SortBy:      q.Sort,
+		SortDesc:    q.Desc,
+	})
+	if err != nil {
+		rows = []pg_store.ListKehadiranAdminRow{}
+	}
+
+	list := make([]KehadiranItem, 0, len(rows))
+	for _, row := range rows {
+		item := KehadiranItem{
+			PermohonanID: row.ID.String(),
+			JadwalID:     row.JadwalID.String(),
+			KodeBooking:  row.KodeBooking.String,
+			NamaLengkap:  row.NamaLengkap,
+			Status:       row.StatusTerkini.String,
+			Hadir:        clock.Local(row.WaktuHadir.Time).Format("15:04"),
+			Sesi:         convertMicrosToTime(row.JadwalJamMulai.Microseconds) + " - " + convertMicrosToTime(row.JadwalJamSelesai.Microseconds),
+			NamaLokasi:   row.NamaLokasi,
+			DicatatOleh:  row.DicatatOleh.String,
+		}
+		if row.NomorAntrian.Valid {
+			item.NomorAntrian = int(row.NomorAntrian.Int16)
+		}
+		list = append(list, item)
+	}
+
+	KehadiranPage(KehadiranPageData{
+		UserName:   user.UserName,
+		UserRole:   common.FormatRole(user.UserRole),
+		ActivePage: "kehadiran",
+		List:       list,
+		Query:      q,
+		Total:      int(total),
+	}).Render(ctx, w)
+}
+
+// CreateKehadiranHandler checks a citizen in from the payload of a scanned
+// booking QR code. The signature shows the code was issued by the app; the
+// booking must be in the petugas' scope, still awaiting its session, and
+// booked for today. Scanning a booking that has already arrived only shows
+// it again, so the scanner doubles as the queue lookup.
+func (h *Handler) CreateKehadiranHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	kode, err := h.tiket.Verify(r.FormValue("payload"))
+	if err != nil {
+		KehadiranHasil(HasilKehadiran{Pesan: "Kode QR tidak dikenali. Pindai QR pada bukti booking atau dashboard warga."}).Render(ctx, w)
+		return
+	}
+	row, err := h.store.GetPermohonanCheckIn(ctx, pgtype.Text{String: kode, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		KehadiranHasil(HasilKehadiran{Pesan: "Booking " + kode + " tidak ditemukan."}).Render(ctx, w)
+		return
+	}
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat booking")
+		return
+	}
+	if _, err := h.policy.Permohonan(ctx, user, row.ID); err != nil {
+		if errors.Is(err, policy.ErrNotFound) {
+			KehadiranHasil(HasilKehadiran{Pesan: "Booking " + kode + " tidak terdaftar di wilayah Anda."}).Render(ctx, w)
+			return
+		}
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat booking")
+		return
+	}
+
+	hasil := hasilKehadiran(row, policy.Can(user, policy.PendudukViewPII, nil))
+	status := row.StatusTerkini.String
+	sesiHariIni := row.JadwalTanggal.Time.Format("2006-01-02") == clock.Today(h.clock).Format("2006-01-02")
+	switch {
+	case row.WaktuHadir.Valid:
+		hasil.Pesan = "Sudah tercatat hadir pukul " + clock.Local(row.WaktuHadir.Time).Format("15:04") + "."
+	case status != "VERIFIKASI" && status != "PROSES":
+		hasil.Pesan = "Permohonan ini tidak sedang menunggu sesi, kehadiran tidak dicatat."
+	case !sesiHariIni:
+		hasil.Pesan = "Sesi booking ini bukan hari ini, kehadiran tidak dicatat."
+	default:
+		var petugasID pgtype.UUID
+		if uid, err := uuid.Parse(user.UserID); err == nil {
+			petugasID = pgtype.UUID{Bytes: uid, Valid: true}
+		}
+		waktu, err := h.store.RecordKehadiran(ctx, pg_store.RecordKehadiranParams{
+			ID:               row.ID,
+			HadirDicatatOleh: petugasID,
+		})
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			// Another counter scanned it a moment earlier
+			hasil.Pesan = "Sudah tercatat hadir."
+		case err != nil:
+			common.WriteError(w, http.StatusInternalServerError, "Gagal mencatat kehadiran")
+			return
+		default:
+			hasil.Tercatat = true
+			hasil.Pesan = "Kehadiran tercatat pukul " + clock.Local(waktu.Time).Format("15:04") + "."
+		}
+	}
+	KehadiranHasil(hasil).Render(ctx, w)
+}
+
+// hasilKehadiran maps the booking shown after a scan, masking the NIK for
+// petugas who may not see it
+func hasilKehadiran(row pg_store.GetPermohonanCheckInRow, lengkap bool) HasilKehadiran {
+	nik := row.Nik.String
+	if !lengkap {
+		nik = common.MaskNIK(nik)
+	}
+	hasil := HasilKehadiran{
+		Ditemukan:       true,
+		PermohonanID:    row.ID.String(),
+		JadwalID:        row.JadwalID.String(),
+		KodeBooking:     row.KodeBooking.String,
+		NamaLengkap:     row.NamaLengkap,
+		NIK:             nik,
+		JenisPermohonan: row.JenisPermohonan,
+		Status:          row.StatusTerkini.String,
+		Sesi:            row.JadwalTanggal.Time.Format("02 Jan 2006") + " " + convertMicrosToTime(row.JadwalJamMulai.Microseconds) + " - " + convertMicrosToTime(row.JadwalJamSelesai.Microseconds),
+		NamaLokasi:      row.NamaLokasi,
+	}
+	if row.NomorAntrian.Valid {
+		hasil.NomorAntrian = int(row.NomorAntrian.Int16)
+	}
+	return hasil
+}
@@ -0,0 +1,376 @@
+package admin
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/policy"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// Periods offered on the reports page, in weeks
+var periodeLaporan = []int{4, 12, 26, 52}
+
+const periodeLaporanDefault = 12
+
+// Chart area in SVG units; the SVG scales to the card width
+const (
+	grafikLebar  = 640.0
+	grafikTinggi = 220.0
+	grafikKiri   = 40.0
+	grafikKanan  = 8.0
+	grafikAtas   = 8.0
+	grafikBawah  = 24.0
+)
+
+// warnaJenis colours each jenis permohonan in the weekly chart
+var warnaJenis = []struct {
+	Jenis string
+	Warna string
+}{
+	{"BARU", "#3b82f6"},
+	{"HILANG", "#f59e0b"},
+	{"RUSAK", "#ef4444"},
+	{"UPDATE", "#10b981"},
+}
+
+// LaporanHandler shows the trend reports for the user's wilayah. The figures
+// come from materialized views refreshed every hour, so they may lag the
+// dashboard slightly.
+func (h *Handler) LaporanHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	scope := policy.ScopeOf(user)
+
+	periode, _ := strconv.Atoi(r.URL.Query().Get("minggu"))
+	if !slices.Contains(periodeLaporan, periode) {
+		periode = periodeLaporanDefault
+	}
+	hariIni := clock.Today(h.clock)
+	minggu := daftarMinggu(hariIni, periode)
+	dari := pgtype.Date{Time: minggu[0], Valid: true}
+
+	permohonan, err := h.store.ListLaporanPermohonanMingguan(ctx, pg_store.ListLaporanPermohonanMingguanParams{
+		Dari:        dari,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat laporan")
+		return
+	}
+	kelurahan, err := h.store.ListLaporanPermohonanKelurahan(ctx, pg_store.ListLaporanPermohonanKelurahanParams{
+		Dari:        dari,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat laporan")
+		return
+	}
+	durasi, err := h.store.ListLaporanDurasiMingguan(ctx, pg_store.ListLaporanDurasiMingguanParams{
+		Dari:        dari,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat laporan")
+		return
+	}
+	durasiTotal, err := h.store.GetLaporanDurasi(ctx, pg_store.GetLaporanDurasiParams{
+		Dari:        dari,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat laporan")
+		return
+	}
+	penolakan, err := h.store.ListLaporanPenolakan(ctx, pg_store.ListLaporanPenolakanParams{
+		Dari:        dari,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat laporan")
+		return
+	}
+	sesi, err := h.store.ListLaporanSesiMingguan(ctx, pg_store.ListLaporanSesiMingguanParams{
+		HariIni:     pgtype.Date{Time: hariIni, Valid: true},
+		Dari:        dari,
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat laporan")
+		return
+	}
+
+	data := LaporanPageData{
+		UserName:   user.UserName,
+		UserRole:   common.FormatRole(user.UserRole),
+		ActivePage: "laporan",
+		Periode:    periode,
+		Pilihan:    periodeLaporan,
+		Dari:       minggu[0].Format("02 Jan 2006"),
+		Permohonan: grafikPermohonan(minggu, permohonan),
+		Durasi:     grafikDurasi(minggu, durasi),
+		Sesi:       grafikSesi(minggu, sesi),
+	}
+	for _, j := range warnaJenis {
+		data.Legenda = append(data.Legenda, LegendaGrafik{Label: j.Jenis, Warna: j.Warna})
+	}
+
+	for _, row := range permohonan {
+		data.TotalPermohonan += int(row.Jumlah)
+		data.TotalDitolak += int(row.Ditolak)
+	}
+	data.RasioDitolak = formatPersen(data.TotalDitolak, data.TotalPermohonan)
+	// Rejections are counted by the week of rejection, applications by the
+	// week they were made, so each reason is a share of the rejections
+	totalPenolakan := 0
+	for _, row := range penolakan {
+		totalPenolakan += int(row.Jumlah)
+	}
+	for _, row := range penolakan {
+		data.Penolakan = append(data.Penolakan, BarisPenolakan{
+			Alasan: labelAlasanPenolakan(row.Alasan),
+			Jumlah: int(row.Jumlah),
+			Persen: calculatePercent(int(row.Jumlah), totalPenolakan),
+			Rasio:  formatPersen(int(row.Jumlah), totalPenolakan),
+		})
+	}
+
+	data.JumlahSelesai = int(durasiTotal.Jumlah)
+	if durasiTotal.Jumlah > 0 {
+		data.RataRataDurasi = formatDurasi(durasiTotal.RataRataJam)
+		data.P90Durasi = formatDurasi(durasiTotal.P90Jam)
+	}
+
+	var kuota, terisi, terdaftar, hadir int
+	for _, row := range sesi {
+		kuota += int(row.KuotaMaksimal)
+		terisi += int(row.KuotaTerisi)
+		terdaftar += int(row.TerdaftarTercatat)
+		hadir += int(row.Hadir)
+	}
+	data.Utilisasi = formatPersen(terisi, kuota)
+	data.TidakHadir = formatPersen(terdaftar-hadir, terdaftar)
+
+	maks := 0
+	for _, row := range kelurahan {
+		maks = max(maks, int(row.Jumlah))
+	}
+	for _, row := range kelurahan {
+		data.Kelurahan = append(data.Kelurahan, BarisKelurahan{
+			Nama:    row.NamaKelurahan,
+			Jumlah:  int(row.Jumlah),
+			Ditolak: int(row.Ditolak),
+			Persen:  calculatePercent(int(row.Jumlah), maks),
+		})
+	}
+
+	LaporanPage(data).Render(ctx, w)
+}
+
+// daftarMinggu returns the Mondays of the last n weeks up to the week of
+// today, oldest first
+func daftarMinggu(hariIni time.Time, n int) []time.Time {
+	senin := hariIni.AddDate(0, 0, -((int(hariIni.Weekday()) + 6) % 7))
+	minggu := make([]time.Time, n)
+	for i := range minggu {
+		minggu[i] = senin.AddDate(0, 0, -7*(n-1-i))
+	}
+	return minggu
+}
+
+// indeksMinggu finds the position of a report week among the chart weeks
+func indeksMinggu(minggu []time.Time, d pgtype.Date) int {
+	kunci := d.Time.Format("2006-01-02")
+	for i, m := range minggu {
+		if m.Format("2006-01-02") == kunci {
+			return i
+		}
+	}
+	return -1
+}
+
+// grafikPermohonan stacks the applications of each week by jenis
+func grafikPermohonan(minggu []time.Time, rows []pg_store.ListLaporanPermohonanMingguanRow) Grafik {
+	jumlah := make([]map[string]int, len(minggu))
+	for i := range jumlah {
+		jumlah[i] = map[string]int{}
+	}
+	for _, row := range rows {
+		if i := indeksMinggu(minggu, row.Minggu); i >= 0 {
+			jumlah[i][row.JenisPermohonan] += int(row.Jumlah)
+		}
+	}
+	maks := 0.0
+	for _, m := range jumlah {
+		total := 0
+		for _, n := range m {
+			total += n
+		}
+		maks = max(maks, float64(total))
+	}
+
+	g := kerangkaGrafik(minggu, maks, "")
+	lebar := g.lebarMinggu() * 0.7
+	for i, m := range jumlah {
+		dasar := 0.0
+		for _, j := range warnaJenis {
+			n := m[j.Jenis]
+			if n == 0 {
+				continue
+			}
+			atas := dasar + float64(n)
+			g.Batang = append(g.Batang, BatangGrafik{
+				X:      g.tengahMinggu(i) - lebar/2,
+				Y:      g.y(atas),
+				Lebar:  lebar,
+				Tinggi: g.y(dasar) - g.y(atas),
+				Warna:  j.Warna,
+				Judul:  fmt.Sprintf("%s, minggu %s: %d", j.Jenis, minggu[i].Format("02 Jan"), n),
+			})
+			dasar = atas
+		}
+	}
+	return g
+}
+
+// grafikDurasi draws the average and p90 hours to completion per week
+func grafikDurasi(minggu []time.Time, rows []pg_store.ListLaporanDurasiMingguanRow) Grafik {
+	maks := 0.0
+	for _, row := range rows {
+		maks = max(maks, row.P90Jam)
+	}
+	g := kerangkaGrafik(minggu, maks, " j")
+	rata := GarisGrafik{Label: "Rata-rata", Warna: "#3b82f6"}
+	p90 := GarisGrafik{Label: "Persentil 90", Warna: "#f59e0b"}
+	for _, row := range rows {
+		i := indeksMinggu(minggu, row.Minggu)
+		if i < 0 {
+			continue
+		}
+		label := minggu[i].Format("02 Jan")
+		rata.addTitik(g.tengahMinggu(i), g.y(row.RataRataJam), fmt.Sprintf("Rata-rata minggu %s: %s (%d selesai)", label, formatDurasi(row.RataRataJam), row.Jumlah))
+		p90.addTitik(g.tengahMinggu(i), g.y(row.P90Jam), fmt.Sprintf("Persentil 90 minggu %s: %s", label, formatDurasi(row.P90Jam)))
+	}
+	g.Garis = []GarisGrafik{rata, p90}
+	return g
+}
+
+// grafikSesi draws the weekly quota use and, for weeks where check-in was
+// used, the share of registered citizens who did not come
+func grafikSesi(minggu []time.Time, rows []pg_store.ListLaporanSesiMingguanRow) Grafik {
+	g := kerangkaGrafik(minggu, 100, "%")
+	terisi := GarisGrafik{Label: "Kuota terisi", Warna: "#10b981"}
+	absen := GarisGrafik{Label: "Tidak hadir", Warna: "#ef4444"}
+	for _, row := range rows {
+		i := indeksMinggu(minggu, row.Minggu)
+		if i < 0 || row.KuotaMaksimal == 0 {
+			continue
+		}
+		label := minggu[i].Format("02 Jan")
+		pct := float64(row.KuotaTerisi) * 100 / float64(row.KuotaMaksimal)
+		terisi.addTitik(g.tengahMinggu(i), g.y(pct), fmt.Sprintf("Kuota terisi minggu %s: %d dari %d (%d sesi)", label, row.KuotaTerisi, row.KuotaMaksimal, row.JumlahSesi))
+		if row.TerdaftarTercatat > 0 {
+			tidak := row.TerdaftarTercatat - row.Hadir
+			pct := float64(tidak) * 100 / float64(row.TerdaftarTercatat)
+			absen.addTitik(g.tengahMinggu(i), g.y(pct), fmt.Sprintf("Tidak hadir minggu %s: %d dari %d", label, tidak, row.TerdaftarTercatat))
+		}
+	}
+	g.Garis = []GarisGrafik{terisi, absen}
+	return g
+}
+
+// kerangkaGrafik lays out the axes for one value per week: a rounded top
+// value with gridlines, and a date under every few weeks
+func kerangkaGrafik(minggu []time.Time, maks float64, satuan string) Grafik {
+	puncak, langkah := skalaSumbu(maks)
+	g := Grafik{Lebar: grafikLebar, Tinggi: grafikTinggi, jumlahMinggu: len(minggu), puncak: puncak}
+	for v := 0.0; v <= puncak+langkah/2; v += langkah {
+		g.SumbuY = append(g.SumbuY, LabelGrafik{
+			X:    grafikKiri - 6,
+			Y:    g.y(v),
+			Teks: strconv.FormatFloat(v, 'f', -1, 64) + satuan,
+		})
+	}
+	setiap := (len(minggu) + 7) / 8
+	for i, m := range minggu {
+		if i%setiap != 0 {
+			continue
+		}
+		g.SumbuX = append(g.SumbuX, LabelGrafik{
+			X:    g.tengahMinggu(i),
+			Y:    grafikTinggi - 6,
+			Teks: m.Format("02 Jan"),
+		})
+	}
+	return g
+}
+
+// skalaSumbu rounds maks up to 1, 2 or 5 times a power of ten in about four
+// steps, so gridlines fall on round numbers
+func skalaSumbu(maks float64) (puncak, langkah float64) {
+	if maks <= 0 {
+		return 4, 1
+	}
+	kasar := maks / 4
+	pangkat := math.Pow(10, math.Floor(math.Log10(kasar)))
+	for _, f := range []float64{1, 2, 5, 10} {
+		langkah = f * pangkat
+		if langkah >= kasar {
+			break
+		}
+	}
+	langkah = max(langkah, 1)
+	return math.Ceil(maks/langkah) * langkah, langkah
+}
+
+func (g Grafik) lebarMinggu() float64 {
+	return (grafikLebar - grafikKiri - grafikKanan) / float64(g.jumlahMinggu)
+}
+
+func (g Grafik) tengahMinggu(i int) float64 {
+	return grafikKiri + g.lebarMinggu()*(float64(i)+0.5)
+}
+
+func (g Grafik) y(v float64) float64 {
+	return grafikAtas + (grafikTinggi-grafikAtas-grafikBawah)*(1-v/g.puncak)
+}
+
+func (l *GarisGrafik) addTitik(x, y float64, judul string) {
+	if l.Titik != "" {
+		l.Titik += " "
+	}
+	l.Titik += fmt.Sprintf("%.1f,%.1f", x, y)
+	l.Penanda = append(l.Penanda, PenandaGrafik{X: x, Y: y, Judul: judul})
+}
+
+// formatPersen shows a share as a whole percentage, or "-" without a base
+func formatPersen(n, total int) string {
+	if total <= 0 {
+		return "-"
+	}
+	return strconv.Itoa(int(math.Round(float64(n)*100/float64(total)))) + "%"
+}
+
+// formatDurasi shows hours below two days, and days from there
+func formatDurasi(jam float64) string {
+	if jam < 48 {
+		return fmt.Sprintf("%.1f jam", jam)
+	}
+	return fmt.Sprintf("%.1f hari", jam/24)
+}
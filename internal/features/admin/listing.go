@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"net/url"
+
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/ui/components"
+)
+
+// Rows per page of the admin tables
+const perPage = 20
+
+// rankBySearch puts the closest matches first while a search is active and
+// no column has been picked
+func rankBySearch(q common.ListQuery) common.ListQuery {
+	if q.Get("search") != "" && q.Get("sort") == "" {
+		q.Sort = "relevansi"
+		q.Desc = true
+	}
+	return q
+}
+
+func sortHeader(q common.ListQuery, label, column, class string) components.SortHeaderProps {
+	return components.SortHeaderProps{
+		Label:  label,
+		URL:    q.SortURL(column),
+		Active: q.Sort == column,
+		Desc:   q.Desc,
+		Class:  class,
+	}
+}
+
+func pagination(q common.ListQuery, total int, noun string) components.PaginationProps {
+	return components.PaginationProps{
+		Page:    q.Page,
+		PerPage: q.PerPage,
+		Total:   total,
+		Noun:    noun,
+		PageURL: q.PageURL,
+	}
+}
+
+// eksporURL returns the download of the table with its current filters and
+// order. The export sits next to the page, at <path>/ekspor.
+func eksporURL(q common.ListQuery, format string) string {
+	v := url.Values{}
+	for k, vals := range q.Values {
+		if k != "page" {
+			v[k] = vals
+		}
+	}
+	v.Set("format", format)
+	return q.Path + "/ekspor?" + v.Encode()
+}
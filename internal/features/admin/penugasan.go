@@ -0,0 +1,266 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/policy"
+	"github.com/nobuww/simpel-ktp/internal/session"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// batasDistribusi caps how many applications one round-robin run distributes
+const batasDistribusi = 200
+
+const pesanVersiBerubah = "Permohonan ini sudah berubah sejak dibuka. Periksa data terbaru di bawah lalu coba lagi."
+
+// errPenugasanBerubah aborts a status change the optimistic lock refused
+var errPenugasanBerubah = errors.New("permohonan tidak dipegang petugas atau versinya berubah")
+
+// statusFormData loads the status form of a permohonan together with who
+// holds it. Only the holder gets the status fields; admins with
+// permohonan.assign also get the list of petugas to assign it to.
+func (h *Handler) statusFormData(ctx context.Context, user *session.UserSession, id uuid.UUID, wilayah policy.Wilayah) (StatusFormData, error) {
+	row, err := h.store.GetPenugasanPermohonan(ctx, id)
+	if err != nil {
+		return StatusFormData{}, err
+	}
+	data := StatusFormData{
+		PermohonanID:  id.String(),
+		CurrentStatus: row.StatusTerkini.String,
+		Versi:         strconv.Itoa(int(row.Versi)),
+		CanVerify:     policy.Can(user, policy.PermohonanVerify, &wilayah),
+		CanReject:     policy.Can(user, policy.PermohonanReject, &wilayah),
+		CanAssign:     policy.Can(user, policy.PermohonanAssign, &wilayah),
+	}
+	if row.DitanganiOleh.Valid {
+		data.Penangan = row.NamaPenangan.String
+		data.PenanganID = uuid.UUID(row.DitanganiOleh.Bytes).String()
+		data.Milik = row.DitanganiOleh == actorID(user)
+		if row.DitugaskanPada.Valid {
+			data.DitugaskanPada = clock.Local(row.DitugaskanPada.Time).Format("2 Jan 2006 15:04")
+		}
+	}
+	if data.CanAssign {
+		petugas, err := h.store.ListPetugasPenangan(ctx, id)
+		if err != nil {
+			return StatusFormData{}, err
+		}
+		for _, p := range petugas {
+			data.Petugas = append(data.Petugas, PetugasPenangan{
+				ID:    p.ID.String(),
+				Nama:  p.NamaPetugas,
+				Role:  common.FormatRole(p.Role),
+				Beban: int(p.Beban),
+			})
+		}
+	}
+	return data, nil
+}
+
+// renderStatusForm writes the status form partial, with pesan shown above
+// it. A non-OK code still carries the form so the dialog shows the current
+// holder after a conflict.
+func (h *Handler) renderStatusForm(w http.ResponseWriter, r *http.Request, user *session.UserSession, id uuid.UUID, wilayah policy.Wilayah, code int, pesan string) {
+	ctx := r.Context()
+	data, err := h.statusFormData(ctx, user, id, wilayah)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat permohonan")
+		return
+	}
+	data.Pesan = pesan
+	if code != http.StatusOK {
+		common.HXReswap(w, "innerHTML")
+		w.WriteHeader(code)
+	}
+	StatusUpdateForm(data).Render(ctx, w)
+}
+
+// formVersi reads the versi the form was loaded with
+func formVersi(r *http.Request) (int32, bool) {
+	v, err := strconv.ParseInt(r.FormValue("versi"), 10, 32)
+	return int32(v), err == nil
+}
+
+// loadPenugasan resolves the permohonan of an assignment request and checks
+// it lies within the petugas' scope
+func (h *Handler) loadPenugasan(w http.ResponseWriter, r *http.Request) (*session.UserSession, uuid.UUID, policy.Wilayah, bool) {
+	if err := r.ParseForm(); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Invalid form data")
+		return nil, uuid.Nil, policy.Wilayah{}, false
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		common.WriteNotFound(w, "ID tidak valid")
+		return nil, uuid.Nil, policy.Wilayah{}, false
+	}
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return nil, uuid.Nil, policy.Wilayah{}, false
+	}
+	wilayah, err := h.policy.Permohonan(r.Context(), user, id)
+	if err != nil {
+		writePolicyError(w, err, "Permohonan tidak ditemukan")
+		return nil, uuid.Nil, policy.Wilayah{}, false
+	}
+	return user, id, wilayah, true
+}
+
+// assign moves the permohonan to petugasID, or releases it when invalid,
+// and answers with the refreshed status form
+func (h *Handler) assign(w http.ResponseWriter, r *http.Request, user *session.UserSession, id uuid.UUID, wilayah policy.Wilayah, petugasID pgtype.UUID) {
+	versi, ok := formVersi(r)
+	if !ok {
+		common.WriteError(w, http.StatusBadRequest, "Versi permohonan tidak valid")
+		return
+	}
+	n, err := h.store.AssignPermohonan(r.Context(), pg_store.AssignPermohonanParams{
+		ID:        id,
+		Versi:     versi,
+		PetugasID: petugasID,
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan penugasan")
+		return
+	}
+	if n == 0 {
+		h.renderStatusForm(w, r, user, id, wilayah, http.StatusConflict, pesanVersiBerubah)
+		return
+	}
+	h.renderStatusForm(w, r, user, id, wilayah, http.StatusOK, "")
+}
+
+// ClaimPermohonanHandler lets a petugas who may change the status claim an
+// application nobody holds yet
+func (h *Handler) ClaimPermohonanHandler(w http.ResponseWriter, r *http.Request) {
+	user, id, wilayah, ok := h.loadPenugasan(w, r)
+	if !ok {
+		return
+	}
+	if !policy.Can(user, policy.PermohonanVerify, &wilayah) && !policy.Can(user, policy.PermohonanReject, &wilayah) {
+		common.WriteError(w, http.StatusForbidden, "Anda tidak memiliki akses untuk menangani permohonan ini")
+		return
+	}
+	row, err := h.store.GetPenugasanPermohonan(r.Context(), id)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat permohonan")
+		return
+	}
+	if row.DitanganiOleh.Valid {
+		h.renderStatusForm(w, r, user, id, wilayah, http.StatusConflict, "Permohonan ini sudah ditangani "+row.NamaPenangan.String+".")
+		return
+	}
+	h.assign(w, r, user, id, wilayah, actorID(user))
+}
+
+// ReleasePermohonanHandler returns an application to the unassigned pool. The
+// holder may release it, and so may admins who assign work.
+func (h *Handler) ReleasePermohonanHandler(w http.ResponseWriter, r *http.Request) {
+	user, id, wilayah, ok := h.loadPenugasan(w, r)
+	if !ok {
+		return
+	}
+	row, err := h.store.GetPenugasanPermohonan(r.Context(), id)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat permohonan")
+		return
+	}
+	if row.DitanganiOleh != actorID(user) && !policy.Can(user, policy.PermohonanAssign, &wilayah) {
+		common.WriteError(w, http.StatusForbidden, "Hanya petugas yang menangani permohonan ini yang dapat melepasnya")
+		return
+	}
+	h.assign(w, r, user, id, wilayah, pgtype.UUID{})
+}
+
+// AssignPermohonanHandler assigns or reassigns an application to one of
+// the petugas whose wilayah covers it
+func (h *Handler) AssignPermohonanHandler(w http.ResponseWriter, r *http.Request) {
+	user, id, wilayah, ok := h.loadPenugasan(w, r)
+	if !ok {
+		return
+	}
+	if !policy.Can(user, policy.PermohonanAssign, &wilayah) {
+		common.WriteError(w, http.StatusForbidden, "Anda tidak memiliki akses untuk menugaskan permohonan ini")
+		return
+	}
+	ctx := r.Context()
+
+	petugasID, err := uuid.Parse(r.FormValue("petugas_id"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Pilih petugas")
+		return
+	}
+	petugas, err := h.store.ListPetugasPenangan(ctx, id)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat petugas")
+		return
+	}
+	valid := false
+	for _, p := range petugas {
+		if p.ID == petugasID {
+			valid = true
+		}
+	}
+	if !valid {
+		common.WriteError(w, http.StatusBadRequest, "Petugas tidak dapat menangani permohonan di wilayah ini")
+		return
+	}
+	h.assign(w, r, user, id, wilayah, pgtype.UUID{Bytes: petugasID, Valid: true})
+}
+
+// DistributePermohonanHandler distributes the unassigned open applications in
+// the admin's scope round-robin over the petugas of each application's
+// wilayah. Applications whose wilayah has no petugas stay unassigned.
+func (h *Handler) DistributePermohonanHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	scope := policy.ScopeOf(user)
+
+	dibagikan := 0
+	err := h.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		rows, err := q.ListPermohonanBelumDitangani(ctx, pg_store.ListPermohonanBelumDitanganiParams{
+			Limit:       batasDistribusi,
+			KecamatanID: scope.KecamatanID,
+			KelurahanID: scope.KelurahanID,
+		})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			petugasID, err := q.PickPetugasBergilir(ctx, row.ID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			// A petugas claiming it meanwhile moved the versi on; skip it
+			n, err := q.AssignPermohonan(ctx, pg_store.AssignPermohonanParams{
+				ID:        row.ID,
+				Versi:     row.Versi,
+				PetugasID: pgtype.UUID{Bytes: petugasID, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+			dibagikan += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal membagikan permohonan")
+		return
+	}
+	http.Redirect(w, r, "/admin/permohonan?dibagikan="+strconv.Itoa(dibagikan), http.StatusSeeOther)
+}
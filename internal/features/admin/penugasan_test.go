@@ -0,0 +1,184 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/middleware"
+	"github.com/nobuww/simpel-ktp/internal/policy"
+	"github.com/nobuww/simpel-ktp/internal/session"
+	"github.com/nobuww/simpel-ktp/internal/store"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// penugasanStore holds one permohonan in kecamatan 1, kelurahan 10.
+// AssignPermohonan behaves like the query plus the versi trigger of
+// 20261019290000: it only updates at the current versi and then moves the
+// versi on when the holder changes.
+type penugasanStore struct {
+	store.Repository
+	versi   int32
+	holder  pgtype.UUID
+	petugas uuid.UUID
+	assigns []pg_store.AssignPermohonanParams
+}
+
+func (s *penugasanStore) GetPermohonanWilayah(ctx context.Context, id uuid.UUID) (pg_store.GetPermohonanWilayahRow, error) {
+	return pg_store.GetPermohonanWilayahRow{KecamatanID: 1, KelurahanID: pgtype.Int2{Int16: 10, Valid: true}}, nil
+}
+
+func (s *penugasanStore) GetPenugasanPermohonan(ctx context.Context, id uuid.UUID) (pg_store.GetPenugasanPermohonanRow, error) {
+	row := pg_store.GetPenugasanPermohonanRow{
+		ID:            id,
+		StatusTerkini: pgtype.Text{String: "VERIFIKASI", Valid: true},
+		Versi:         s.versi,
+		DitanganiOleh: s.holder,
+	}
+	if s.holder.Valid {
+		row.NamaPenangan = pgtype.Text{String: "Petugas Lain", Valid: true}
+	}
+	return row, nil
+}
+
+func (s *penugasanStore) ListPetugasPenangan(ctx context.Context, id uuid.UUID) ([]pg_store.ListPetugasPenanganRow, error) {
+	return []pg_store.ListPetugasPenanganRow{{ID: s.petugas, NamaPetugas: "Petugas Lain", Role: session.RoleAdminKelurahan}}, nil
+}
+
+func (s *penugasanStore) AssignPermohonan(ctx context.Context, arg pg_store.AssignPermohonanParams) (int64, error) {
+	s.assigns = append(s.assigns, arg)
+	if arg.Versi != s.versi {
+		return 0, nil
+	}
+	if s.holder != arg.PetugasID {
+		s.holder = arg.PetugasID
+		s.versi++
+	}
+	return 1, nil
+}
+
+var penugasanNow = clock.Fixed(time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC))
+
+func ptr(v int16) *int16 {
+	return &v
+}
+
+// postPenugasan submits form to one of the penugasan handlers as user
+func postPenugasan(handler http.HandlerFunc, user *session.UserSession, id uuid.UUID, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/admin/permohonan/"+id.String(), strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, middleware.UserContextKey, user)
+	rec := httptest.NewRecorder()
+	handler(rec, req.WithContext(ctx))
+	return rec
+}
+
+func TestPenugasanStaleVersi(t *testing.T) {
+	user := &session.UserSession{
+		UserID:      uuid.NewString(),
+		UserType:    session.UserTypePetugas,
+		KecamatanID: ptr(1),
+		Permissions: []string{
+			string(policy.PermohonanVerify),
+			string(policy.PermohonanReject),
+			string(policy.PermohonanAssign),
+		},
+	}
+	lain := uuid.New()
+
+	tests := []struct {
+		name    string
+		handler func(h *Handler) http.HandlerFunc
+		form    url.Values
+		holder  pgtype.UUID
+	}{
+		{
+			name:    "claim",
+			handler: func(h *Handler) http.HandlerFunc { return h.ClaimPermohonanHandler },
+		},
+		{
+			name:    "assign",
+			handler: func(h *Handler) http.HandlerFunc { return h.AssignPermohonanHandler },
+			form:    url.Values{"petugas_id": {lain.String()}},
+		},
+		{
+			name:    "release",
+			handler: func(h *Handler) http.HandlerFunc { return h.ReleasePermohonanHandler },
+			holder:  pgtype.UUID{Bytes: lain, Valid: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Another petugas changed the permohonan after the form loaded at versi 1
+			st := &penugasanStore{versi: 2, holder: tt.holder, petugas: lain}
+			h := New(st, penugasanNow, policy.New(st), nil)
+
+			form := url.Values{"versi": {"1"}}
+			for k, v := range tt.form {
+				form[k] = v
+			}
+			rec := postPenugasan(tt.handler(h), user, uuid.New(), form)
+
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusConflict)
+			}
+			if len(st.assigns) != 1 || st.assigns[0].Versi != 1 {
+				t.Fatalf("AssignPermohonan calls = %+v, want one at the stale versi 1", st.assigns)
+			}
+			if st.versi != 2 || st.holder != tt.holder {
+				t.Errorf("stale assignment changed the permohonan: versi %d, holder %v", st.versi, st.holder)
+			}
+			if got := rec.Header().Get("HX-Reswap"); got != "innerHTML" {
+				t.Errorf("HX-Reswap = %q, want innerHTML", got)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, pesanVersiBerubah) {
+				t.Error("re-rendered form does not explain the conflict")
+			}
+			if !strings.Contains(body, `name="versi" value="2"`) {
+				t.Error("re-rendered form does not carry the current versi")
+			}
+		})
+	}
+}
+
+func TestPenugasanCurrentVersiBumpsVersi(t *testing.T) {
+	user := &session.UserSession{
+		UserID:      uuid.NewString(),
+		UserType:    session.UserTypePetugas,
+		KecamatanID: ptr(1),
+		Permissions: []string{string(policy.PermohonanVerify)},
+	}
+	st := &penugasanStore{versi: 1, petugas: uuid.New()}
+	h := New(st, penugasanNow, policy.New(st), nil)
+
+	id := uuid.New()
+	form := url.Values{"versi": {"1"}}
+	post := func() *httptest.ResponseRecorder {
+		return postPenugasan(h.ClaimPermohonanHandler, user, id, form)
+	}
+
+	if rec := post(); rec.Code != http.StatusOK {
+		t.Fatalf("first claim status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if st.versi != 2 || st.holder != actorID(user) {
+		t.Fatalf("after the claim versi = %d, holder %v; want 2 and the claiming petugas", st.versi, st.holder)
+	}
+
+	// Released meanwhile, a second submit of the same form is still refused
+	st.holder = pgtype.UUID{}
+	if rec := post(); rec.Code != http.StatusConflict {
+		t.Errorf("resubmitted claim status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,123 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/sla"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// jenisPermohonan lists the application types in the order of the SLA table
+var jenisPermohonan = []string{"BARU", "HILANG", "RUSAK", "UPDATE"}
+
+// SLAHandler shows the SLA target of each status and jenis permohonan
+func (h *Handler) SLAHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	targets, err := h.store.ListTargetSLA(ctx)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat target SLA")
+		return
+	}
+	nilai := map[string]string{}
+	for _, t := range targets {
+		nilai[namaTargetSLA(t.Status, t.JenisPermohonan)] = strconv.Itoa(int(t.TargetHari))
+	}
+
+	SLAPage(slaPageData(user.UserName, user.UserRole, nilai, r.URL.Query().Get("disimpan") == "1", "")).Render(ctx, w)
+}
+
+// SaveSLAHandler saves the whole table of targets at once. An empty cell
+// removes the target, so applications of that jenis never breach it.
+func (h *Handler) SaveSLAHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	nilai := map[string]string{}
+	targets := map[string]int{}
+	for _, s := range sla.StatusBerjalan {
+		for _, jenis := range jenisPermohonan {
+			name := namaTargetSLA(s.Kode, jenis)
+			v := strings.TrimSpace(r.FormValue(name))
+			nilai[name] = v
+			if v == "" {
+				continue
+			}
+			hari, err := strconv.Atoi(v)
+			if err != nil || hari < 1 || hari > 365 {
+				w.WriteHeader(http.StatusBadRequest)
+				SLAPage(slaPageData(user.UserName, user.UserRole, nilai, false, "Target "+s.Label+" untuk "+jenis+" harus antara 1 dan 365 hari")).Render(ctx, w)
+				return
+			}
+			targets[name] = hari
+		}
+	}
+
+	actor := actorID(user)
+	err := h.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		for _, s := range sla.StatusBerjalan {
+			for _, jenis := range jenisPermohonan {
+				hari, ok := targets[namaTargetSLA(s.Kode, jenis)]
+				if !ok {
+					if err := q.DeleteTargetSLA(ctx, pg_store.DeleteTargetSLAParams{Status: s.Kode, JenisPermohonan: jenis}); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := q.UpsertTargetSLA(ctx, pg_store.UpsertTargetSLAParams{
+					Status:          s.Kode,
+					JenisPermohonan: jenis,
+					TargetHari:      int16(hari),
+					UpdatedBy:       actor,
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan target SLA")
+		return
+	}
+	http.Redirect(w, r, "/admin/sla?disimpan=1", http.StatusSeeOther)
+}
+
+// namaTargetSLA names the form field of one cell of the table
+func namaTargetSLA(status, jenis string) string {
+	return "target_" + status + "_" + jenis
+}
+
+func slaPageData(userName, userRole string, nilai map[string]string, disimpan bool, pesanError string) SLAPageData {
+	data := SLAPageData{
+		UserName:   userName,
+		UserRole:   common.FormatRole(userRole),
+		ActivePage: "sla",
+		Jenis:      jenisPermohonan,
+		Disimpan:   disimpan,
+		Error:      pesanError,
+	}
+	for _, s := range sla.StatusBerjalan {
+		baris := BarisSLA{Label: s.Label}
+		for _, jenis := range jenisPermohonan {
+			name := namaTargetSLA(s.Kode, jenis)
+			baris.Sel = append(baris.Sel, SelSLA{Name: name, Nilai: nilai[name]})
+		}
+		data.Baris = append(data.Baris, baris)
+	}
+	return data
+}
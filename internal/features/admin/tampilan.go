@@ -0,0 +1,197 @@
+package admin
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/policy"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+const halamanPermohonan = "permohonan"
+
+// permohonanFilterKeys are the query parameters that make up a view of the
+// permohonan table. A saved view keeps only these.
+var permohonanFilterKeys = []string{
+	"search", "status", "jenis",
+	"daftar_dari", "daftar_sampai", "tanggal_sesi",
+	"kelurahan", "tertahan", "dokumen_kurang", "lewat_sla", "penanganan",
+	"sort", "dir",
+}
+
+// permohonanFilter reads the permohonan table filters from the query string.
+// Values that do not parse are ignored rather than rejected so that a stale
+// shared link still opens. penanganan=saya is the queue of petugasID.
+func permohonanFilter(q common.ListQuery, scope policy.Scope, petugasID pgtype.UUID) pg_store.CountPermohonanAdminParams {
+	f := pg_store.CountPermohonanAdminParams{
+		Search:         q.Text("search"),
+		Status:         q.Text("status"),
+		Jenis:          q.Text("jenis"),
+		DokumenKurang:  q.Get("dokumen_kurang") == "1",
+		LewatSla:       q.Get("lewat_sla") == "1",
+		BelumDitangani: q.Get("penanganan") == "belum",
+		KecamatanID:    scope.KecamatanID,
+		KelurahanID:    scope.KelurahanID,
+	}
+	if t, ok := parseTanggal(q.Get("daftar_dari")); ok {
+		f.DaftarDari = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	// The end date is inclusive on the form and exclusive in the query
+	if t, ok := parseTanggal(q.Get("daftar_sampai")); ok {
+		f.DaftarSampai = pgtype.Timestamptz{Time: t.AddDate(0, 0, 1), Valid: true}
+	}
+	if t, ok := parseTanggal(q.Get("tanggal_sesi")); ok {
+		f.TanggalSesi = pgtype.Date{Time: t, Valid: true}
+	}
+	if id, err := strconv.ParseInt(q.Get("kelurahan"), 10, 16); err == nil {
+		f.FilterKelurahanID = pgtype.Int2{Int16: int16(id), Valid: true}
+	}
+	if hari, err := strconv.Atoi(q.Get("tertahan")); err == nil && hari > 0 {
+		f.TertahanHari = pgtype.Int4{Int32: int32(hari), Valid: true}
+	}
+	if q.Get("penanganan") == "saya" {
+		f.DitanganiOleh = petugasID
+	}
+	return f
+}
+
+// listPermohonanParams applies the filters to one page of the list, in the
+// table's sort order
+func listPermohonanParams(f pg_store.CountPermohonanAdminParams, q common.ListQuery, limit, offset int32) pg_store.ListPermohonanAdminParams {
+	return pg_store.ListPermohonanAdminParams{
+		Limit:             limit,
+		Offset:            offset,
+		Search:            f.Search,
+		Status:            f.Status,
+		Jenis:             f.Jenis,
+		DaftarDari:        f.DaftarDari,
+		DaftarSampai:      f.DaftarSampai,
+		TanggalSesi:       f.TanggalSesi,
+		FilterKelurahanID: f.FilterKelurahanID,
+		TertahanHari:      f.TertahanHari,
+		DokumenKurang:     f.DokumenKurang,
+		LewatSla:          f.LewatSla,
+		DitanganiOleh:     f.DitanganiOleh,
+		BelumDitangani:    f.BelumDitangani,
+		KecamatanID:       f.KecamatanID,
+		KelurahanID:       f.KelurahanID,
+		SortBy:            q.Sort,
+		SortDesc:          q.Desc,
+	}
+}
+
+// parseTanggal parses a date input as the start of that day in local time
+func parseTanggal(v string) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, clock.Location)
+	return t, err == nil
+}
+
+// SaveTampilanHandler saves the current permohonan filters under a name for
+// the logged-in petugas. Saving under an existing name replaces that view.
+func (h *Handler) SaveTampilanHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	petugasID, err := uuid.Parse(user.UserID)
+	if err != nil {
+		common.WriteError(w, http.StatusForbidden, "Akses ditolak")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		common.WriteError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+	nama := strings.TrimSpace(r.FormValue("nama"))
+	if nama == "" || len(nama) > 60 {
+		common.WriteError(w, http.StatusBadRequest, "Nama tampilan wajib diisi (maksimal 60 karakter)")
+		return
+	}
+
+	query := url.Values{}
+	for _, key := range permohonanFilterKeys {
+		if v := strings.TrimSpace(r.FormValue(key)); v != "" {
+			query.Set(key, v)
+		}
+	}
+	err = h.store.SaveTampilanTersimpan(r.Context(), pg_store.SaveTampilanTersimpanParams{
+		PetugasID: petugasID,
+		Halaman:   halamanPermohonan,
+		Nama:      nama,
+		Query:     query.Encode(),
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan tampilan")
+		return
+	}
+	http.Redirect(w, r, tampilanURL(query.Encode()), http.StatusSeeOther)
+}
+
+// DeleteTampilanHandler deletes one of the logged-in petugas' saved views
+func (h *Handler) DeleteTampilanHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	petugasID, err := uuid.Parse(user.UserID)
+	if err != nil {
+		common.WriteError(w, http.StatusForbidden, "Akses ditolak")
+		return
+	}
+	id, err := uuid.Parse(r.FormValue("id"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, "ID tampilan tidak valid")
+		return
+	}
+	err = h.store.DeleteTampilanTersimpan(r.Context(), pg_store.DeleteTampilanTersimpanParams{
+		ID:        id,
+		PetugasID: petugasID,
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal menghapus tampilan")
+		return
+	}
+	http.Redirect(w, r, "/admin/permohonan", http.StatusSeeOther)
+}
+
+// loadTampilan lists the saved views of the logged-in petugas. Accounts that
+// are not petugas rows have none.
+func (h *Handler) loadTampilan(r *http.Request, userID string) []TampilanItem {
+	petugasID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil
+	}
+	rows, err := h.store.ListTampilanTersimpan(r.Context(), pg_store.ListTampilanTersimpanParams{
+		PetugasID: petugasID,
+		Halaman:   halamanPermohonan,
+	})
+	if err != nil {
+		return nil
+	}
+	items := make([]TampilanItem, len(rows))
+	for i, t := range rows {
+		items[i] = TampilanItem{
+			ID:   t.ID.String(),
+			Nama: t.Nama,
+			URL:  tampilanURL(t.Query),
+		}
+	}
+	return items
+}
+
+func tampilanURL(query string) string {
+	if query == "" {
+		return "/admin/permohonan"
+	}
+	return "/admin/permohonan?" + query
+}
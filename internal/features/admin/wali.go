@@ -0,0 +1,219 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/policy"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// hubunganWali labels the relationships that make a citizen a guardian of
+// their household
+var hubunganWali = map[string]string{
+	"KEPALA_KELUARGA": "Kepala Keluarga",
+	"SUAMI":           "Suami",
+	"ISTRI":           "Istri",
+}
+
+// hubunganAnggota labels every relationship on a Kartu Keluarga
+var hubunganAnggota = map[string]string{
+	"KEPALA_KELUARGA": "Kepala Keluarga",
+	"SUAMI":           "Suami",
+	"ISTRI":           "Istri",
+	"ANAK":            "Anak",
+	"MENANTU":         "Menantu",
+	"CUCU":            "Cucu",
+	"ORANG_TUA":       "Orang Tua",
+	"MERTUA":          "Mertua",
+	"FAMILI_LAIN":     "Famili Lain",
+	"LAINNYA":         "Lainnya",
+}
+
+// WaliHandler lists citizens who linked a KK as head of household or spouse
+// and wait for a petugas to check the claim against the KK before they may
+// manage the household and apply for its members
+func (h *Handler) WaliHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	scope := policy.ScopeOf(user)
+
+	rows, err := h.store.ListWaliMenunggu(ctx, pg_store.ListWaliMenungguParams{
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err != nil {
+		rows = []pg_store.ListWaliMenungguRow{}
+	}
+
+	list := make([]WaliItem, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, WaliItem{
+			NIK:       row.Nik,
+			Nama:      row.NamaLengkap,
+			NoKK:      row.NoKk.String,
+			Hubungan:  hubunganWali[row.HubunganKeluarga.String],
+			Kelurahan: row.NamaKelurahan.String,
+			Terdaftar: clock.Local(row.CreatedAt.Time).Format("2 Jan 2006"),
+		})
+	}
+
+	permintaan, err := h.store.ListPermintaanAnggotaMenunggu(ctx, pg_store.ListPermintaanAnggotaMenungguParams{
+		KecamatanID: scope.KecamatanID,
+		KelurahanID: scope.KelurahanID,
+	})
+	if err != nil {
+		permintaan = []pg_store.ListPermintaanAnggotaMenungguRow{}
+	}
+	anggota := make([]PermintaanAnggotaItem, 0, len(permintaan))
+	for _, row := range permintaan {
+		anggota = append(anggota, PermintaanAnggotaItem{
+			NIK:       row.Nik,
+			Nama:      row.NamaLengkap,
+			NoKK:      row.NoKk,
+			Hubungan:  hubunganAnggota[row.HubunganKeluarga],
+			Pengaju:   row.NamaPengaju,
+			Kelurahan: row.NamaKelurahan.String,
+			Diajukan:  clock.Local(row.CreatedAt).Format("2 Jan 2006"),
+		})
+	}
+
+	WaliPage(WaliPageData{
+		UserName:   user.UserName,
+		UserRole:   common.FormatRole(user.UserRole),
+		ActivePage: "wali",
+		List:       list,
+		Anggota:    anggota,
+	}).Render(ctx, w)
+}
+
+// ApproveWaliHandler confirms a citizen's place as head of household or
+// spouse after the petugas has checked it against the KK
+func (h *Handler) ApproveWaliHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	penduduk, err := h.policy.Penduduk(ctx, user, r.FormValue("nik"))
+	if err != nil {
+		writePolicyError(w, err, "Penduduk tidak ditemukan")
+		return
+	}
+	n, err := h.store.ApproveWaliPenduduk(ctx, pg_store.ApproveWaliPendudukParams{
+		DisetujuiOleh: actorID(user),
+		Nik:           penduduk.Nik,
+	})
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan verifikasi")
+		return
+	}
+	if n == 0 {
+		common.WriteError(w, http.StatusConflict, "Penduduk ini tidak lagi menunggu verifikasi")
+		return
+	}
+
+	common.HXRedirect(w, "/admin/wali")
+}
+
+// RejectWaliHandler unlinks a citizen whose claim does not match the KK. They
+// may link the KK again with the relationship printed on it.
+func (h *Handler) RejectWaliHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	penduduk, err := h.policy.Penduduk(ctx, user, r.FormValue("nik"))
+	if err != nil {
+		writePolicyError(w, err, "Penduduk tidak ditemukan")
+		return
+	}
+	n, err := h.store.RejectWaliPenduduk(ctx, penduduk.Nik)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal menolak verifikasi")
+		return
+	}
+	if n == 0 {
+		common.WriteError(w, http.StatusConflict, "Penduduk ini tidak lagi menunggu verifikasi")
+		return
+	}
+
+	common.HXRedirect(w, "/admin/wali")
+}
+
+// ApproveAnggotaHandler adds a citizen on record to the KK a guardian asked
+// for, after the petugas has found them on that KK
+func (h *Handler) ApproveAnggotaHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	penduduk, err := h.policy.Penduduk(ctx, user, r.FormValue("nik"))
+	if err != nil {
+		writePolicyError(w, err, "Penduduk tidak ditemukan")
+		return
+	}
+	var n int64
+	err = h.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		var err error
+		n, err = q.ApprovePermintaanAnggota(ctx, penduduk.Nik)
+		if err != nil || n == 0 {
+			return err
+		}
+		_, err = q.DeletePermintaanAnggota(ctx, penduduk.Nik)
+		return err
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		common.WriteError(w, http.StatusConflict, "Kartu Keluarga ini sudah memiliki anggota dengan hubungan tersebut")
+		return
+	}
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan verifikasi")
+		return
+	}
+	if n == 0 {
+		common.WriteError(w, http.StatusConflict, "Penduduk ini tidak lagi menunggu verifikasi atau sudah terdaftar di Kartu Keluarga lain")
+		return
+	}
+
+	common.HXRedirect(w, "/admin/wali")
+}
+
+// RejectAnggotaHandler drops a guardian's request to add a citizen on record
+// who is not on their KK
+func (h *Handler) RejectAnggotaHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	penduduk, err := h.policy.Penduduk(ctx, user, r.FormValue("nik"))
+	if err != nil {
+		writePolicyError(w, err, "Penduduk tidak ditemukan")
+		return
+	}
+	n, err := h.store.DeletePermintaanAnggota(ctx, penduduk.Nik)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal menolak permintaan")
+		return
+	}
+	if n == 0 {
+		common.WriteError(w, http.StatusConflict, "Penduduk ini tidak lagi menunggu verifikasi")
+		return
+	}
+
+	common.HXRedirect(w, "/admin/wali")
+}
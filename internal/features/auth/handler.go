@@ -2,25 +2,34 @@ package auth
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/nobuww/simpel-ktp/internal/clock"
 	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/middleware"
+	"github.com/nobuww/simpel-ktp/internal/nik"
+	"github.com/nobuww/simpel-ktp/internal/notify"
+	"github.com/nobuww/simpel-ktp/internal/qrcode"
 	"github.com/nobuww/simpel-ktp/internal/session"
 	"github.com/nobuww/simpel-ktp/internal/store"
 )
 
 type Handler struct {
-	store   *store.Store
+	store   store.Repository
 	service *Service
 	session *session.Manager
 }
 
-func New(store *store.Store, sessionMgr *session.Manager) *Handler {
+func New(store store.Repository, clk clock.Clock, sessionMgr *session.Manager, notifier notify.Notifier) *Handler {
 	return &Handler{
 		store:   store,
-		service: NewService(store),
+		service: NewService(store, clk, notifier),
 		session: sessionMgr,
 	}
 }
@@ -33,6 +42,24 @@ func (h *Handler) LoginPetugasPageHandler(w http.ResponseWriter, r *http.Request
 	LoginPetugasPage().Render(r.Context(), w)
 }
 
+// LoginPetugas2FAPageHandler shows the second login step to a petugas who
+// has passed the password step
+func (h *Handler) LoginPetugas2FAPageHandler(w http.ResponseWriter, r *http.Request) {
+	if h.pending2FA(r) == nil {
+		http.Redirect(w, r, "/petugas/login", http.StatusSeeOther)
+		return
+	}
+	LoginPetugas2FAPage().Render(r.Context(), w)
+}
+
+func (h *Handler) LupaPasswordPageHandler(w http.ResponseWriter, r *http.Request) {
+	LupaPasswordPage().Render(r.Context(), w)
+}
+
+func (h *Handler) ResetPasswordPageHandler(w http.ResponseWriter, r *http.Request) {
+	ResetPasswordPage(r.URL.Query().Get("nik")).Render(r.Context(), w)
+}
+
 func (h *Handler) RegisterPageHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -79,15 +106,20 @@ func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 
 	// Call service
 	result, err := h.service.LoginWarga(ctx, WargaLoginInput{
-		NIK:      nik,
-		Password: password,
+		NIK:       nik,
+		Password:  password,
+		IPAddress: session.ClientIP(r),
+		UserAgent: r.UserAgent(),
 	})
 	if err != nil {
+		var throttled *ThrottleError
 		switch {
+		case errors.As(err, &throttled):
+			AuthError(throttleMessage(throttled)).Render(ctx, w)
 		case errors.Is(err, ErrInvalidCredentials):
 			AuthError("NIK atau password salah").Render(ctx, w)
 		case errors.Is(err, ErrAccountNoPassword):
-			AuthError("Akun belum memiliki password. Silakan hubungi petugas.").Render(ctx, w)
+			AuthError("Akun belum memiliki password. Klaim akun Anda melalui Lupa Password.").Render(ctx, w)
 		default:
 			AuthError("Terjadi kesalahan, silakan coba lagi").Render(ctx, w)
 		}
@@ -130,28 +162,259 @@ func (h *Handler) HandleLoginPetugas(w http.ResponseWriter, r *http.Request) {
 
 	// Call service
 	result, err := h.service.LoginPetugas(ctx, PetugasLoginInput{
-		NIP:      nip,
-		Password: password,
+		NIP:       nip,
+		Password:  password,
+		IPAddress: session.ClientIP(r),
+		UserAgent: r.UserAgent(),
 	})
 	if err != nil {
+		var throttled *ThrottleError
 		switch {
+		case errors.As(err, &throttled):
+			AuthError(throttleMessage(throttled)).Render(ctx, w)
 		case errors.Is(err, ErrInvalidCredentials):
 			AuthError("NIP atau password salah").Render(ctx, w)
+		case errors.Is(err, ErrAccountInactive):
+			AuthError("Akun Anda telah dinonaktifkan, hubungi admin").Render(ctx, w)
 		default:
 			AuthError("Terjadi kesalahan, silakan coba lagi").Render(ctx, w)
 		}
 		return
 	}
 
+	// Petugas with 2FA get a session that is only good for the second step;
+	// those whose role requires 2FA but have not enrolled are sent to enrol
+	tahap, redirectPath, message := "", "/admin", "Login berhasil! Mengalihkan ke dashboard..."
+	switch {
+	case result.TOTPAktif:
+		tahap, redirectPath, message = session.Tahap2FAVerifikasi, "/petugas/login/2fa", "Password benar. Lanjutkan dengan kode verifikasi..."
+	case result.Wajib2FA:
+		tahap, redirectPath, message = session.Tahap2FAPendaftaran, "/admin/2fa", "Login berhasil! Aktifkan verifikasi dua langkah untuk melanjutkan..."
+	}
+
 	// Create session
-	if err := h.session.SetPetugasSession(w, r, result.ID, result.NamaPetugas, result.Role, result.KelurahanID, remember); err != nil {
+	if err := h.session.SetPetugasSession(w, r, session.UserSession{
+		UserID:      result.ID,
+		UserName:    result.NamaPetugas,
+		UserRole:    result.Role,
+		KecamatanID: result.KecamatanID,
+		KelurahanID: result.KelurahanID,
+		Permissions: result.Permissions,
+		Tahap2FA:    tahap,
+	}, remember); err != nil {
+		AuthError("Gagal membuat sesi login").Render(ctx, w)
+		return
+	}
+
+	common.HXRedirect(w, redirectPath)
+
+	AuthSuccess(message).Render(ctx, w)
+}
+
+// HandleLoginPetugas2FA handles POST /auth/login/petugas/2fa, completing the
+// login with a TOTP or recovery code
+func (h *Handler) HandleLoginPetugas2FA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	pending := h.pending2FA(r)
+	if pending == nil {
+		common.HXRedirect(w, "/petugas/login")
+		AuthError("Sesi verifikasi berakhir, silakan login ulang").Render(ctx, w)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		AuthError("Gagal memproses form").Render(ctx, w)
+		return
+	}
+
+	kode := strings.TrimSpace(r.FormValue("kode"))
+	if kode == "" {
+		AuthError("Kode verifikasi harus diisi").Render(ctx, w)
+		return
+	}
+
+	err := h.service.VerifyPetugas2FA(ctx, TwoFactorInput{
+		PetugasID: pending.UserID,
+		Kode:      kode,
+		IPAddress: session.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		var throttled *ThrottleError
+		switch {
+		case errors.As(err, &throttled):
+			AuthError(throttleMessage(throttled)).Render(ctx, w)
+		case errors.Is(err, ErrInvalidCredentials):
+			AuthError("Kode verifikasi salah").Render(ctx, w)
+		case errors.Is(err, ErrAccountInactive), errors.Is(err, ErrTOTPNotEnrolled):
+			// The account changed since the password step; start over
+			_ = h.session.ClearSession(w, r)
+			common.HXRedirect(w, "/petugas/login")
+			AuthError("Silakan login ulang").Render(ctx, w)
+		default:
+			AuthError("Terjadi kesalahan, silakan coba lagi").Render(ctx, w)
+		}
+		return
+	}
+
+	if err := h.session.Complete2FA(w, r, pending); err != nil {
 		AuthError("Gagal membuat sesi login").Render(ctx, w)
 		return
 	}
 
 	common.HXRedirect(w, "/admin")
 
-	AuthSuccess("Login berhasil! Mengalihkan ke dashboard...").Render(ctx, w)
+	AuthSuccess("Verifikasi berhasil! Mengalihkan ke dashboard...").Render(ctx, w)
+}
+
+// KeamananAkunHandler shows the petugas' 2FA settings, with a QR code to scan
+// while enrolment is pending
+func (h *Handler) KeamananAkunHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	status, err := h.service.TwoFactorStatus(ctx, user.UserID)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memuat pengaturan keamanan")
+		return
+	}
+
+	data := KeamananAkunPageData{
+		UserName:   user.UserName,
+		UserRole:   common.FormatRole(user.UserRole),
+		ActivePage: "keamanan",
+		Status:     *status,
+	}
+	if !status.Aktif {
+		code, err := qrcode.Encode(status.URI)
+		if err != nil {
+			common.WriteError(w, http.StatusInternalServerError, "Gagal membuat kode QR")
+			return
+		}
+		data.QRCode = code.SVG()
+	}
+
+	KeamananAkunPage(data).Render(ctx, w)
+}
+
+// HandleActivate2FA handles POST /admin/2fa/aktifkan, confirming enrolment
+// with a code from the authenticator app
+func (h *Handler) HandleActivate2FA(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		AuthError("Gagal memproses form").Render(ctx, w)
+		return
+	}
+
+	codes, err := h.service.ActivateTOTP(ctx, user.UserID, r.FormValue("kode"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalid2FACode):
+			AuthError("Kode salah. Pastikan jam perangkat Anda sesuai lalu coba lagi.").Render(ctx, w)
+		case errors.Is(err, ErrTOTPActive), errors.Is(err, ErrTOTPNotEnrolled):
+			common.HXRedirect(w, "/admin/2fa")
+		default:
+			AuthError("Terjadi kesalahan, silakan coba lagi").Render(ctx, w)
+		}
+		return
+	}
+
+	// Enrolling lifts the restriction put on petugas whose role requires 2FA
+	if user.Tahap2FA == session.Tahap2FAPendaftaran {
+		if err := h.session.Complete2FA(w, r, user); err != nil {
+			AuthError("Gagal memperbarui sesi login").Render(ctx, w)
+			return
+		}
+	}
+
+	RecoveryCodes(codes).Render(ctx, w)
+}
+
+// HandleKodePemulihan2FA handles POST /admin/2fa/kode-pemulihan, replacing all
+// recovery codes
+func (h *Handler) HandleKodePemulihan2FA(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		AuthError("Gagal memproses form").Render(ctx, w)
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(ctx, h.twoFactorInput(r, user))
+	if err != nil {
+		AuthError(twoFactorMessage(err)).Render(ctx, w)
+		return
+	}
+
+	RecoveryCodes(codes).Render(ctx, w)
+}
+
+// HandleDisable2FA handles POST /admin/2fa/nonaktifkan
+func (h *Handler) HandleDisable2FA(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/petugas/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		AuthError("Gagal memproses form").Render(ctx, w)
+		return
+	}
+
+	if err := h.service.DisableTOTP(ctx, h.twoFactorInput(r, user)); err != nil {
+		AuthError(twoFactorMessage(err)).Render(ctx, w)
+		return
+	}
+
+	common.HXRedirect(w, "/admin/2fa")
+}
+
+// pending2FA returns the session waiting for its second factor, if any
+func (h *Handler) pending2FA(r *http.Request) *session.UserSession {
+	user := h.session.GetSession(r)
+	if user == nil || user.UserType != session.UserTypePetugas || user.Tahap2FA != session.Tahap2FAVerifikasi {
+		return nil
+	}
+	return user
+}
+
+func (h *Handler) twoFactorInput(r *http.Request, user *session.UserSession) TwoFactorInput {
+	return TwoFactorInput{
+		PetugasID: user.UserID,
+		Kode:      r.FormValue("kode"),
+		IPAddress: session.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// twoFactorMessage maps errors from the 2FA settings actions to user messages
+func twoFactorMessage(err error) string {
+	var throttled *ThrottleError
+	switch {
+	case errors.As(err, &throttled):
+		return throttleMessage(throttled)
+	case errors.Is(err, ErrInvalidCredentials):
+		return "Kode verifikasi salah"
+	case errors.Is(err, ErrTOTPRequired):
+		return "Peran Anda mewajibkan verifikasi dua langkah sehingga tidak dapat dinonaktifkan"
+	case errors.Is(err, ErrTOTPNotEnrolled):
+		return "Verifikasi dua langkah belum aktif"
+	}
+	return "Terjadi kesalahan, silakan coba lagi"
 }
 
 // HandleRegister handles POST /auth/register for warga (citizen) registration
@@ -171,6 +434,7 @@ func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 	alamat := strings.TrimSpace(r.FormValue("alamat"))
 	noHP := strings.TrimSpace(r.FormValue("no_hp"))
 	jenisKelamin := r.FormValue("jenis_kelamin")
+	statusPerkawinan := r.FormValue("status_perkawinan")
 	kelurahanIDStr := r.FormValue("kelurahan_id")
 
 	// Validate required fields
@@ -198,6 +462,12 @@ func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 		AuthError("Jenis kelamin tidak valid").Render(ctx, w)
 		return
 	}
+	switch statusPerkawinan {
+	case "BELUM_KAWIN", "KAWIN", "CERAI_HIDUP", "CERAI_MATI":
+	default:
+		AuthError("Status perkawinan tidak valid").Render(ctx, w)
+		return
+	}
 	if kelurahanIDStr == "" {
 		AuthError("Kelurahan harus dipilih").Render(ctx, w)
 		return
@@ -211,19 +481,26 @@ func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 
 	// Call service
 	result, err := h.service.RegisterWarga(ctx, RegisterInput{
-		NIK:          nik,
-		NamaLengkap:  namaLengkap,
-		Email:        email,
-		Password:     password,
-		Alamat:       alamat,
-		NoHP:         noHP,
-		JenisKelamin: jenisKelamin,
-		KelurahanID:  int16(kelurahanID),
+		NIK:              nik,
+		NamaLengkap:      namaLengkap,
+		Email:            email,
+		Password:         password,
+		Alamat:           alamat,
+		NoHP:             noHP,
+		JenisKelamin:     jenisKelamin,
+		KelurahanID:      int16(kelurahanID),
+		StatusPerkawinan: statusPerkawinan,
 	})
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrNIKExists):
 			AuthError("NIK sudah terdaftar").Render(ctx, w)
+		case errors.Is(err, ErrNIKInvalid):
+			AuthError(nikMessage(err)).Render(ctx, w)
+		case errors.Is(err, ErrKelurahanNotFound):
+			AuthError("Kelurahan tidak ditemukan").Render(ctx, w)
+		case errors.Is(err, ErrNIKUnclaimed):
+			AuthError("NIK sudah terdata oleh petugas. Klaim akun Anda melalui Lupa Password.").Render(ctx, w)
 		case errors.Is(err, ErrEmailExists):
 			AuthError("Email sudah terdaftar").Render(ctx, w)
 		default:
@@ -244,6 +521,121 @@ func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 	AuthSuccess("Pendaftaran berhasil! Mengalihkan...").Render(ctx, w)
 }
 
+// nikMessage explains a rejected NIK; handlers shadow the nik package with
+// their form value
+func nikMessage(err error) string {
+	return nik.Message(err)
+}
+
+// throttleMessage tells the user how long to wait before trying again
+func throttleMessage(e *ThrottleError) string {
+	wait := "beberapa detik"
+	if e.RetryAfter >= time.Minute {
+		wait = fmt.Sprintf("%d menit", int(math.Ceil(e.RetryAfter.Minutes())))
+	} else if e.RetryAfter > 0 {
+		wait = fmt.Sprintf("%d detik", int(math.Ceil(e.RetryAfter.Seconds())))
+	}
+	if errors.Is(e, ErrTooManyAttempts) {
+		return "Terlalu banyak percobaan login gagal dari jaringan Anda. Coba lagi dalam " + wait + "."
+	}
+	return "Akun dikunci sementara karena terlalu banyak percobaan login gagal. Coba lagi dalam " + wait + " atau hubungi petugas."
+}
+
+// HandleLupaPassword handles POST /auth/lupa-password, sending a one-time code
+// to the warga's email or phone
+func (h *Handler) HandleLupaPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		AuthError("Gagal memproses form").Render(ctx, w)
+		return
+	}
+
+	nik := strings.TrimSpace(r.FormValue("nik"))
+	kanal := notify.Channel(r.FormValue("kanal"))
+
+	if len(nik) != 16 {
+		AuthError("NIK harus 16 digit").Render(ctx, w)
+		return
+	}
+	if kanal != notify.ChannelEmail && kanal != notify.ChannelSMS {
+		AuthError("Pilih pengiriman kode melalui email atau SMS").Render(ctx, w)
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(ctx, nik, kanal); err != nil {
+		AuthError("Gagal mengirim kode, silakan coba lagi").Render(ctx, w)
+		return
+	}
+
+	common.HXRedirect(w, "/reset-password?nik="+url.QueryEscape(nik))
+
+	AuthSuccess("Jika NIK terdaftar dengan kontak tersebut, kode verifikasi telah dikirim.").Render(ctx, w)
+}
+
+// HandleResetPassword handles POST /auth/reset-password, setting a new
+// password with a one-time code and logging the warga in
+func (h *Handler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		AuthError("Gagal memproses form").Render(ctx, w)
+		return
+	}
+
+	nik := strings.TrimSpace(r.FormValue("nik"))
+	kode := strings.TrimSpace(r.FormValue("kode"))
+	password := r.FormValue("password")
+	konfirmasi := r.FormValue("password_konfirmasi")
+
+	if len(nik) != 16 {
+		AuthError("NIK harus 16 digit").Render(ctx, w)
+		return
+	}
+	if kode == "" {
+		AuthError("Kode verifikasi harus diisi").Render(ctx, w)
+		return
+	}
+	if len(password) < 8 {
+		AuthError("Password minimal 8 karakter").Render(ctx, w)
+		return
+	}
+	if password != konfirmasi {
+		AuthError("Konfirmasi password tidak cocok").Render(ctx, w)
+		return
+	}
+
+	result, err := h.service.ResetPassword(ctx, ResetPasswordInput{
+		NIK:       nik,
+		Kode:      kode,
+		Password:  password,
+		IPAddress: session.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		var throttled *ThrottleError
+		switch {
+		case errors.As(err, &throttled):
+			AuthError(throttleMessage(throttled)).Render(ctx, w)
+		case errors.Is(err, ErrResetCodeInvalid):
+			AuthError("Kode verifikasi salah atau kedaluwarsa").Render(ctx, w)
+		default:
+			AuthError("Terjadi kesalahan, silakan coba lagi").Render(ctx, w)
+		}
+		return
+	}
+
+	if err := h.session.SetWargaSession(w, r, result.NIK, result.NamaLengkap, false); err != nil {
+		common.HXRedirect(w, "/login")
+		AuthSuccess("Password berhasil diubah. Silakan login.").Render(ctx, w)
+		return
+	}
+
+	common.HXRedirect(w, "/dashboard")
+
+	AuthSuccess("Password berhasil diubah! Mengalihkan...").Render(ctx, w)
+}
+
 // HandleLogout handles POST /auth/logout
 func (h *Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	if err := h.session.ClearSession(w, r); err != nil {
@@ -259,3 +651,31 @@ func (h *Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
+
+// HandleLogoutAll handles POST /auth/logout-all, ending the user's sessions on every device
+func (h *Handler) HandleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	// Sessions that have not passed 2FA are not in the context and cannot end other sessions
+	user := middleware.GetUserFromContext(r.Context())
+	redirectPath := "/"
+	if user != nil {
+		if user.UserType == session.UserTypePetugas {
+			redirectPath = "/petugas/login"
+		}
+		if err := h.session.RevokeUserSessions(r.Context(), user.UserType, user.UserID); err != nil {
+			http.Error(w, "Gagal logout dari semua perangkat", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := h.session.ClearSession(w, r); err != nil {
+		http.Error(w, "Gagal logout", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		common.HXRedirect(w, redirectPath)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	http.Redirect(w, r, redirectPath, http.StatusSeeOther)
+}
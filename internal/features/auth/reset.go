@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/nobuww/simpel-ktp/internal/notify"
+	"github.com/nobuww/simpel-ktp/internal/session"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+const (
+	resetCodeTTLMinutes = 15
+	resetCodeMaxTries   = 5
+	// At most resetCodeMaxPerWindow codes are sent per NIK within the window
+	resetCodeMaxPerWindow = 3
+	resetCodeWindowMinute = 60
+	// Wrong codes allowed from one IP address within ipWindowMinutes
+	resetIPMaxFailures = 10
+)
+
+// ErrResetCodeInvalid covers wrong, expired, spent and over-tried codes alike
+var ErrResetCodeInvalid = errors.New("reset code invalid or expired")
+
+// RequestPasswordReset sends a one-time code to the email or phone number on
+// file for the NIK. It also serves penduduk created by petugas or the seeder
+// that never had a password, letting them claim their account. Unknown NIKs,
+// missing contacts, rate-limited requests and failed sends succeed silently
+// so that the response does not reveal which NIKs are registered.
+func (s *Service) RequestPasswordReset(ctx context.Context, nik string, kanal notify.Channel) error {
+	penduduk, err := s.store.GetPendudukByNIK(ctx, nik)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return ErrInternalError
+	}
+
+	var tujuan string
+	switch kanal {
+	case notify.ChannelEmail:
+		tujuan = penduduk.Email.String
+	case notify.ChannelSMS:
+		tujuan = penduduk.NoHp.String
+	}
+	if tujuan == "" {
+		return nil
+	}
+
+	sent, err := s.store.CountKodeResetPasswordSince(ctx, pg_store.CountKodeResetPasswordSinceParams{
+		Nik:     nik,
+		Minutes: resetCodeWindowMinute,
+	})
+	if err != nil {
+		return ErrInternalError
+	}
+	if sent >= resetCodeMaxPerWindow {
+		log.Printf("password reset for %s rate limited", nik)
+		return nil
+	}
+
+	kode, err := generateResetCode()
+	if err != nil {
+		return ErrInternalError
+	}
+	kodeHash, err := bcrypt.GenerateFromPassword([]byte(kode), bcrypt.DefaultCost)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	if err := s.store.CreateKodeResetPassword(ctx, pg_store.CreateKodeResetPasswordParams{
+		Nik:        nik,
+		KodeHash:   string(kodeHash),
+		Kanal:      string(kanal),
+		TtlMinutes: resetCodeTTLMinutes,
+	}); err != nil {
+		return ErrInternalError
+	}
+
+	body := fmt.Sprintf("Kode verifikasi Simpel KTP Anda: %s. Berlaku %d menit. Jangan berikan kode ini kepada siapa pun, termasuk petugas.", kode, resetCodeTTLMinutes)
+	if err := s.notifier.Send(ctx, notify.Message{
+		Channel: kanal,
+		To:      tujuan,
+		Subject: "Kode Reset Password Simpel KTP",
+		Body:    body,
+	}); err != nil {
+		log.Printf("failed to send password reset code to %s: %v", nik, err)
+	}
+	return nil
+}
+
+// ResetPasswordInput contains the code, the new password and where the
+// request came from
+type ResetPasswordInput struct {
+	NIK       string
+	Kode      string
+	Password  string
+	IPAddress string
+	UserAgent string
+}
+
+// ResetPassword sets a new password when the code matches the latest active
+// code for the NIK, then signs the warga out of every other device. The try
+// is counted before the code is compared, and an address with too many wrong
+// codes is throttled like a login.
+func (s *Service) ResetPassword(ctx context.Context, input ResetPasswordInput) (*WargaLoginResult, error) {
+	attempt := LoginAttempt{
+		UserType:   session.UserTypeWarga,
+		Identifier: input.NIK,
+		IPAddress:  input.IPAddress,
+		UserAgent:  input.UserAgent,
+	}
+	failures, err := s.store.CountGagalResetByIP(ctx, pg_store.CountGagalResetByIPParams{
+		IpAddress: pgtype.Text{String: input.IPAddress, Valid: true},
+		Minutes:   ipWindowMinutes,
+	})
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	if failures >= resetIPMaxFailures {
+		s.recordAttempt(ctx, attempt, alasanIPDibatasi)
+		return nil, &ThrottleError{Err: ErrTooManyAttempts, RetryAfter: ipWindowMinutes * time.Minute}
+	}
+
+	penduduk, err := s.store.GetPendudukByNIK(ctx, input.NIK)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.recordAttempt(ctx, attempt, alasanKodeResetSalah)
+			return nil, ErrResetCodeInvalid
+		}
+		return nil, ErrInternalError
+	}
+
+	aktif, err := s.store.GetKodeResetPasswordAktif(ctx, input.NIK)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.recordAttempt(ctx, attempt, alasanKodeResetSalah)
+			return nil, ErrResetCodeInvalid
+		}
+		return nil, ErrInternalError
+	}
+	if _, err := s.store.IncrementPercobaanKodeReset(ctx, pg_store.IncrementPercobaanKodeResetParams{
+		ID:       aktif.ID,
+		MaxTries: resetCodeMaxTries,
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.recordAttempt(ctx, attempt, alasanKodeResetSalah)
+			return nil, ErrResetCodeInvalid
+		}
+		return nil, ErrInternalError
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(aktif.KodeHash), []byte(input.Kode)); err != nil {
+		s.recordAttempt(ctx, attempt, alasanKodeResetSalah)
+		return nil, ErrResetCodeInvalid
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+
+	err = s.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		n, err := q.UseKodeResetPassword(ctx, aktif.ID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrResetCodeInvalid
+		}
+		if err := q.SetPendudukPassword(ctx, pg_store.SetPendudukPasswordParams{
+			Nik:          input.NIK,
+			PasswordHash: pgtype.Text{String: string(hashedPassword), Valid: true},
+		}); err != nil {
+			return err
+		}
+		// Proving control of the contact on file also lifts a lockout
+		if err := q.ResetKunciLogin(ctx, pg_store.ResetKunciLoginParams{
+			UserType:   session.UserTypeWarga,
+			Identifier: input.NIK,
+		}); err != nil {
+			return err
+		}
+		return q.DeleteSesiLoginByUser(ctx, pg_store.DeleteSesiLoginByUserParams{
+			UserType: session.UserTypeWarga,
+			UserID:   input.NIK,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrResetCodeInvalid) {
+			return nil, ErrResetCodeInvalid
+		}
+		return nil, ErrInternalError
+	}
+	s.recordAttempt(ctx, attempt, alasanBerhasil)
+
+	return &WargaLoginResult{
+		NIK:         penduduk.Nik,
+		NamaLengkap: penduduk.NamaLengkap,
+	}, nil
+}
+
+// generateResetCode returns a random 6-digit code
+func generateResetCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -3,11 +3,16 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/nik"
+	"github.com/nobuww/simpel-ktp/internal/notify"
+	"github.com/nobuww/simpel-ktp/internal/session"
 	"github.com/nobuww/simpel-ktp/internal/store"
 	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
 )
@@ -16,25 +21,33 @@ import (
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrAccountNoPassword  = errors.New("account has no password set")
+	ErrAccountInactive    = errors.New("account is deactivated")
 	ErrNIKExists          = errors.New("NIK already registered")
+	ErrNIKUnclaimed       = errors.New("NIK recorded without an account")
+	ErrNIKInvalid         = errors.New("NIK invalid")
 	ErrEmailExists        = errors.New("email already registered")
+	ErrKelurahanNotFound  = errors.New("kelurahan not found")
 	ErrInternalError      = errors.New("internal error")
 )
 
 // Service handles authentication business logic
 type Service struct {
-	store *store.Store
+	store    store.Repository
+	clock    clock.Clock
+	notifier notify.Notifier
 }
 
 // NewService creates a new auth service
-func NewService(store *store.Store) *Service {
-	return &Service{store: store}
+func NewService(store store.Repository, clk clock.Clock, notifier notify.Notifier) *Service {
+	return &Service{store: store, clock: clk, notifier: notifier}
 }
 
 // WargaLoginInput contains input for warga login
 type WargaLoginInput struct {
-	NIK      string
-	Password string
+	NIK       string
+	Password  string
+	IPAddress string
+	UserAgent string
 }
 
 // WargaLoginResult contains the result of a successful warga login
@@ -45,22 +58,37 @@ type WargaLoginResult struct {
 
 // LoginWarga authenticates a warga (citizen) by NIK and password
 func (s *Service) LoginWarga(ctx context.Context, input WargaLoginInput) (*WargaLoginResult, error) {
+	attempt := LoginAttempt{
+		UserType:   session.UserTypeWarga,
+		Identifier: input.NIK,
+		IPAddress:  input.IPAddress,
+		UserAgent:  input.UserAgent,
+	}
+	if err := s.checkThrottle(ctx, attempt); err != nil {
+		return nil, err
+	}
+
 	penduduk, err := s.store.GetPendudukByNIK(ctx, input.NIK)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrInvalidCredentials
+			return nil, s.recordFailure(ctx, attempt, alasanTidakDitemukan)
 		}
 		return nil, ErrInternalError
 	}
 
 	// Check if password is set
 	if !penduduk.PasswordHash.Valid || penduduk.PasswordHash.String == "" {
+		s.recordAttempt(ctx, attempt, alasanTanpaPassword)
 		return nil, ErrAccountNoPassword
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(penduduk.PasswordHash.String), []byte(input.Password)); err != nil {
-		return nil, ErrInvalidCredentials
+		return nil, s.recordFailure(ctx, attempt, alasanPasswordSalah)
+	}
+
+	if err := s.recordSuccess(ctx, attempt); err != nil {
+		return nil, err
 	}
 
 	return &WargaLoginResult{
@@ -71,8 +99,10 @@ func (s *Service) LoginWarga(ctx context.Context, input WargaLoginInput) (*Warga
 
 // PetugasLoginInput contains input for petugas login
 type PetugasLoginInput struct {
-	NIP      string
-	Password string
+	NIP       string
+	Password  string
+	IPAddress string
+	UserAgent string
 }
 
 // PetugasLoginResult contains the result of a successful petugas login
@@ -80,22 +110,73 @@ type PetugasLoginResult struct {
 	ID          string
 	NamaPetugas string
 	Role        string
+	KecamatanID *int16
 	KelurahanID *int16
+	Permissions []string
+	// TOTPAktif means a second factor must be verified before the session is usable
+	TOTPAktif bool
+	// Wajib2FA means the role requires 2FA; petugas without it must enrol first
+	Wajib2FA bool
 }
 
 // LoginPetugas authenticates a petugas (officer) by NIP and password
 func (s *Service) LoginPetugas(ctx context.Context, input PetugasLoginInput) (*PetugasLoginResult, error) {
 	petugas, err := s.store.GetPetugasByNIP(ctx, pgtype.Text{String: input.NIP, Valid: true})
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrInvalidCredentials
-		}
+	found := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrInternalError
 	}
 
+	// Known accounts are locked by ID, which survives a change of NIP;
+	// guesses at unknown NIPs are counted under the NIP typed
+	attempt := LoginAttempt{
+		UserType:   session.UserTypePetugas,
+		Identifier: input.NIP,
+		IPAddress:  input.IPAddress,
+		UserAgent:  input.UserAgent,
+	}
+	if found {
+		attempt.Identifier = petugasLockID(petugas)
+	}
+	if err := s.checkThrottle(ctx, attempt); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, s.recordFailure(ctx, attempt, alasanTidakDitemukan)
+	}
+
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(petugas.PasswordHash), []byte(input.Password)); err != nil {
-		return nil, ErrInvalidCredentials
+		return nil, s.recordFailure(ctx, attempt, alasanPasswordSalah)
+	}
+
+	// Only reveal the account state once the password has been proven
+	if !petugas.IsActive {
+		s.recordAttempt(ctx, attempt, alasanNonaktif)
+		return nil, ErrAccountInactive
+	}
+
+	totpAktif, err := s.totpAktif(ctx, petugas.ID)
+	if err != nil {
+		return nil, err
+	}
+	wajib2FA, err := s.store.GetWajib2FA(ctx, petugas.Role)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+
+	// With 2FA the failure streak is only cleared after the second factor, so
+	// that re-entering the password does not reset the budget for code guesses
+	if !totpAktif {
+		if err := s.recordSuccess(ctx, attempt); err != nil {
+			return nil, err
+		}
+	}
+
+	var kecamatanID *int16
+	if petugas.KecamatanID.Valid {
+		val := petugas.KecamatanID.Int16
+		kecamatanID = &val
 	}
 
 	var kelurahanID *int16
@@ -104,11 +185,23 @@ func (s *Service) LoginPetugas(ctx context.Context, input PetugasLoginInput) (*P
 		kelurahanID = &val
 	}
 
+	permissions, err := s.store.ListPermissionByRole(ctx, petugas.Role)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+
 	return &PetugasLoginResult{
 		ID:          petugas.ID.String(),
 		NamaPetugas: petugas.NamaPetugas,
 		Role:        petugas.Role,
+		KecamatanID: kecamatanID,
 		KelurahanID: kelurahanID,
+		Permissions: permissions,
+		TOTPAktif:   totpAktif,
+		Wajib2FA:    wajib2FA,
 	}, nil
 }
 
@@ -122,6 +215,9 @@ type RegisterInput struct {
 	NoHP         string
 	JenisKelamin string
 	KelurahanID  int16
+	// StatusPerkawinan is one of the penduduk.status_perkawinan values; the
+	// date of birth is taken from the NIK
+	StatusPerkawinan string
 }
 
 // RegisterResult contains the result of a successful registration
@@ -130,16 +226,37 @@ type RegisterResult struct {
 	NamaLengkap string
 }
 
-// RegisterWarga creates a new warga (citizen) account
+// RegisterWarga creates a new warga (citizen) account. The NIK must be well
+// formed and agree with the gender; such errors wrap both ErrNIKInvalid and
+// the nik package error. A NIK issued in another kecamatan is accepted, since
+// citizens who moved in keep theirs; petugas see it flagged instead.
 func (s *Service) RegisterWarga(ctx context.Context, input RegisterInput) (*RegisterResult, error) {
-	// Check if NIK already exists
-	exists, err := s.store.CheckPendudukExists(ctx, input.NIK)
+	parsed, err := nik.Parse(input.NIK, s.clock.Now())
 	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNIKInvalid, err)
+	}
+	if _, err := s.store.GetKelurahanById(ctx, input.KelurahanID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrKelurahanNotFound
+		}
 		return nil, ErrInternalError
 	}
-	if exists {
+	if err := parsed.Check(input.JenisKelamin); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNIKInvalid, err)
+	}
+
+	// Check if NIK already exists; records without a password are claimed
+	// through the password reset flow instead
+	existing, err := s.store.GetPendudukByNIK(ctx, input.NIK)
+	if err == nil {
+		if !existing.PasswordHash.Valid || existing.PasswordHash.String == "" {
+			return nil, ErrNIKUnclaimed
+		}
 		return nil, ErrNIKExists
 	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInternalError
+	}
 
 	// Check if email already exists (if provided)
 	if input.Email != "" {
@@ -160,11 +277,13 @@ func (s *Service) RegisterWarga(ctx context.Context, input RegisterInput) (*Regi
 
 	// Build params
 	params := pg_store.CreatePendudukParams{
-		Nik:          input.NIK,
-		NamaLengkap:  input.NamaLengkap,
-		JenisKelamin: input.JenisKelamin,
-		KelurahanID:  pgtype.Int2{Int16: input.KelurahanID, Valid: true},
-		PasswordHash: pgtype.Text{String: string(hashedPassword), Valid: true},
+		Nik:              input.NIK,
+		NamaLengkap:      input.NamaLengkap,
+		JenisKelamin:     input.JenisKelamin,
+		KelurahanID:      pgtype.Int2{Int16: input.KelurahanID, Valid: true},
+		PasswordHash:     pgtype.Text{String: string(hashedPassword), Valid: true},
+		TanggalLahir:     pgtype.Date{Time: parsed.TanggalLahir, Valid: true},
+		StatusPerkawinan: input.StatusPerkawinan,
 	}
 
 	if input.Email != "" {
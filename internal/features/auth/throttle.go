@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// Outcomes recorded in percobaan_login
+const (
+	alasanBerhasil       = "BERHASIL"
+	alasanPasswordSalah  = "PASSWORD_SALAH"
+	alasanTidakDitemukan = "TIDAK_DITEMUKAN"
+	alasanAkunTerkunci   = "AKUN_TERKUNCI"
+	alasanIPDibatasi     = "IP_DIBATASI"
+	alasanNonaktif       = "NONAKTIF"
+	alasanTanpaPassword  = "TANPA_PASSWORD"
+	alasanKode2FASalah   = "KODE_2FA_SALAH"
+	alasanKodeResetSalah = "KODE_RESET_SALAH"
+)
+
+const (
+	// Failed attempts allowed from one IP address within ipWindowMinutes
+	ipMaxFailures   = 20
+	ipWindowMinutes = 15
+
+	// Consecutive failures before an account is locked; each further failure
+	// doubles the lockout, starting at backoffBase
+	backoffAfter = 3
+	backoffBase  = 30 * time.Second
+	// From lockoutAfter failures on, the account stays locked for lockoutLong
+	// unless a petugas unlocks it
+	lockoutAfter = 10
+	lockoutLong  = time.Hour
+)
+
+var (
+	ErrAccountLocked   = errors.New("account temporarily locked")
+	ErrTooManyAttempts = errors.New("too many failed attempts from this address")
+)
+
+// ThrottleError wraps ErrAccountLocked or ErrTooManyAttempts with the time
+// the caller has to wait
+type ThrottleError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	return fmt.Sprintf("%v, retry after %s", e.Err, e.RetryAfter)
+}
+
+func (e *ThrottleError) Unwrap() error {
+	return e.Err
+}
+
+// LoginAttempt identifies who is trying to log in and from where
+type LoginAttempt struct {
+	UserType   string
+	Identifier string
+	IPAddress  string
+	UserAgent  string
+}
+
+// petugasLockID is the kunci_login identifier of a petugas account. It is
+// the ID rather than the NIP, which may be empty or change.
+func petugasLockID(p pg_store.Petugas) string {
+	return p.ID.String()
+}
+
+// lockDuration returns how long an account is locked after n consecutive failures
+func lockDuration(n int32) time.Duration {
+	switch {
+	case n < backoffAfter:
+		return 0
+	case n >= lockoutAfter:
+		return lockoutLong
+	}
+	return backoffBase << (n - backoffAfter)
+}
+
+// checkThrottle rejects the attempt when the IP address has too many recent
+// failures or the account is locked, before any password is checked
+func (s *Service) checkThrottle(ctx context.Context, a LoginAttempt) error {
+	failures, err := s.store.CountGagalLoginByIP(ctx, pg_store.CountGagalLoginByIPParams{
+		IpAddress: pgtype.Text{String: a.IPAddress, Valid: true},
+		Minutes:   ipWindowMinutes,
+	})
+	if err != nil {
+		return ErrInternalError
+	}
+	if failures >= ipMaxFailures {
+		s.recordAttempt(ctx, a, alasanIPDibatasi)
+		return &ThrottleError{Err: ErrTooManyAttempts, RetryAfter: ipWindowMinutes * time.Minute}
+	}
+
+	kunci, err := s.store.GetKunciLogin(ctx, pg_store.GetKunciLoginParams{
+		UserType:   a.UserType,
+		Identifier: a.Identifier,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return ErrInternalError
+	}
+	if kunci.TerkunciSampai.Valid {
+		if wait := kunci.TerkunciSampai.Time.Sub(s.clock.Now()); wait > 0 {
+			s.recordAttempt(ctx, a, alasanAkunTerkunci)
+			return &ThrottleError{Err: ErrAccountLocked, RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// recordFailure logs a wrong password or unknown account and locks the
+// account once the streak reaches backoffAfter. The returned error carries the
+// lockout so that the caller learns about it on the failing attempt.
+func (s *Service) recordFailure(ctx context.Context, a LoginAttempt, alasan string) error {
+	s.recordAttempt(ctx, a, alasan)
+
+	streak, err := s.store.RecordGagalLogin(ctx, pg_store.RecordGagalLoginParams{
+		UserType:   a.UserType,
+		Identifier: a.Identifier,
+		IpTerakhir: pgtype.Text{String: a.IPAddress, Valid: a.IPAddress != ""},
+	})
+	if err != nil {
+		return ErrInternalError
+	}
+
+	wait := lockDuration(streak)
+	if wait == 0 {
+		return ErrInvalidCredentials
+	}
+	if _, err := s.store.LockKunciLogin(ctx, pg_store.LockKunciLoginParams{
+		UserType:   a.UserType,
+		Identifier: a.Identifier,
+		Seconds:    int32(wait / time.Second),
+	}); err != nil {
+		return ErrInternalError
+	}
+	return &ThrottleError{Err: ErrAccountLocked, RetryAfter: wait}
+}
+
+// recordSuccess logs the login and clears the failure streak
+func (s *Service) recordSuccess(ctx context.Context, a LoginAttempt) error {
+	s.recordAttempt(ctx, a, alasanBerhasil)
+	if err := s.store.ResetKunciLogin(ctx, pg_store.ResetKunciLoginParams{
+		UserType:   a.UserType,
+		Identifier: a.Identifier,
+	}); err != nil {
+		return ErrInternalError
+	}
+	return nil
+}
+
+// recordAttempt writes the audit row. It is best effort: losing an audit row
+// must not block or grant a login.
+func (s *Service) recordAttempt(ctx context.Context, a LoginAttempt, alasan string) {
+	_ = s.store.InsertPercobaanLogin(ctx, pg_store.InsertPercobaanLoginParams{
+		UserType:   a.UserType,
+		Identifier: a.Identifier,
+		IpAddress:  pgtype.Text{String: a.IPAddress, Valid: a.IPAddress != ""},
+		UserAgent:  pgtype.Text{String: a.UserAgent, Valid: a.UserAgent != ""},
+		Berhasil:   alasan == alasanBerhasil,
+		Alasan:     alasan,
+	})
+}
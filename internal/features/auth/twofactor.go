@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/nobuww/simpel-ktp/internal/session"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+	"github.com/nobuww/simpel-ktp/internal/totp"
+)
+
+const (
+	totpIssuer = "Simpel KTP"
+
+	recoveryCodeCount = 10
+	// Recovery codes avoid characters that are easy to misread (0/O, 1/I/L)
+	recoveryCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+	recoveryCodeLength   = 10
+)
+
+var (
+	ErrInvalid2FACode  = errors.New("invalid 2FA code")
+	ErrTOTPNotEnrolled = errors.New("2FA not enrolled")
+	ErrTOTPActive      = errors.New("2FA already active")
+	ErrTOTPRequired    = errors.New("2FA required for this role")
+)
+
+// TwoFactorStatus describes a petugas' 2FA enrolment for the security page
+type TwoFactorStatus struct {
+	Aktif       bool
+	Wajib       bool
+	KodeTersisa int64
+	// Secret and URI are set while an enrolment is pending
+	Secret string
+	URI    string
+}
+
+// TwoFactorInput is a code entered by a logged-in or half-logged-in petugas
+type TwoFactorInput struct {
+	PetugasID string
+	Kode      string
+	IPAddress string
+	UserAgent string
+}
+
+// totpAktif reports whether the petugas has confirmed a TOTP enrolment
+func (s *Service) totpAktif(ctx context.Context, petugasID uuid.UUID) (bool, error) {
+	t, err := s.store.GetPetugasTotp(ctx, petugasID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, ErrInternalError
+	}
+	return t.Aktif, nil
+}
+
+// VerifyPetugas2FA checks the second login step. It accepts a TOTP code or an
+// unused recovery code and counts wrong codes towards the same lockout as
+// wrong passwords.
+func (s *Service) VerifyPetugas2FA(ctx context.Context, input TwoFactorInput) error {
+	petugas, err := s.getPetugas(ctx, input.PetugasID)
+	if err != nil {
+		return err
+	}
+	if !petugas.IsActive {
+		return ErrAccountInactive
+	}
+
+	attempt := LoginAttempt{
+		UserType:   session.UserTypePetugas,
+		Identifier: petugasLockID(petugas),
+		IPAddress:  input.IPAddress,
+		UserAgent:  input.UserAgent,
+	}
+	if err := s.checkThrottle(ctx, attempt); err != nil {
+		return err
+	}
+
+	if err := s.checkSecondFactor(ctx, petugas.ID, input.Kode); err != nil {
+		if errors.Is(err, ErrInvalid2FACode) {
+			return s.recordFailure(ctx, attempt, alasanKode2FASalah)
+		}
+		return err
+	}
+	return s.recordSuccess(ctx, attempt)
+}
+
+// TwoFactorStatus returns the enrolment state, starting a pending enrolment
+// with a fresh secret when the petugas has none
+func (s *Service) TwoFactorStatus(ctx context.Context, petugasID string) (*TwoFactorStatus, error) {
+	petugas, err := s.getPetugas(ctx, petugasID)
+	if err != nil {
+		return nil, err
+	}
+
+	wajib, err := s.store.GetWajib2FA(ctx, petugas.Role)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	status := &TwoFactorStatus{Wajib: wajib}
+
+	t, err := s.store.GetPetugasTotp(ctx, petugas.ID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		if err := s.store.UpsertPetugasTotpPending(ctx, pg_store.UpsertPetugasTotpPendingParams{
+			PetugasID: petugas.ID,
+			Secret:    secret,
+		}); err != nil {
+			return nil, ErrInternalError
+		}
+		t = pg_store.PetugasTotp{Secret: secret}
+	case err != nil:
+		return nil, ErrInternalError
+	}
+
+	if t.Aktif {
+		status.Aktif = true
+		status.KodeTersisa, err = s.store.CountKodePemulihanTersisa(ctx, petugas.ID)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		return status, nil
+	}
+
+	status.Secret = t.Secret
+	status.URI = totp.URI(totpIssuer, totpAkun(petugas), t.Secret)
+	return status, nil
+}
+
+// ActivateTOTP confirms a pending enrolment with a code from the app and
+// returns a new set of recovery codes, which are shown only once
+func (s *Service) ActivateTOTP(ctx context.Context, petugasID, kode string) ([]string, error) {
+	petugas, err := s.getPetugas(ctx, petugasID)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := s.store.GetPetugasTotp(ctx, petugas.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTOTPNotEnrolled
+		}
+		return nil, ErrInternalError
+	}
+	if t.Aktif {
+		return nil, ErrTOTPActive
+	}
+
+	step, ok := totp.Validate(t.Secret, normalizeKode(kode), s.clock.Now())
+	if !ok {
+		return nil, ErrInvalid2FACode
+	}
+
+	var codes []string
+	err = s.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		if err := q.ActivatePetugasTotp(ctx, pg_store.ActivatePetugasTotpParams{
+			PetugasID:    petugas.ID,
+			TerakhirStep: step,
+		}); err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(ctx, q, petugas.ID)
+		return err
+	})
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current code
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, input TwoFactorInput) ([]string, error) {
+	petugas, err := s.verifyLoggedIn(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		codes, err = replaceRecoveryCodes(ctx, q, petugas.ID)
+		return err
+	})
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	return codes, nil
+}
+
+// DisableTOTP removes the petugas' 2FA after checking a current code. It is
+// refused when the role requires 2FA; an admin reset is needed then.
+func (s *Service) DisableTOTP(ctx context.Context, input TwoFactorInput) error {
+	petugas, err := s.getPetugas(ctx, input.PetugasID)
+	if err != nil {
+		return err
+	}
+	wajib, err := s.store.GetWajib2FA(ctx, petugas.Role)
+	if err != nil {
+		return ErrInternalError
+	}
+	if wajib {
+		return ErrTOTPRequired
+	}
+
+	if _, err := s.verifyLoggedIn(ctx, input); err != nil {
+		return err
+	}
+
+	err = s.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		if err := q.DeleteKodePemulihan(ctx, petugas.ID); err != nil {
+			return err
+		}
+		return q.DeletePetugasTotp(ctx, petugas.ID)
+	})
+	if err != nil {
+		return ErrInternalError
+	}
+	return nil
+}
+
+// verifyLoggedIn checks a code entered on the security page, throttled like
+// the login step so that a hijacked session cannot guess codes freely
+func (s *Service) verifyLoggedIn(ctx context.Context, input TwoFactorInput) (pg_store.Petugas, error) {
+	petugas, err := s.getPetugas(ctx, input.PetugasID)
+	if err != nil {
+		return pg_store.Petugas{}, err
+	}
+
+	attempt := LoginAttempt{
+		UserType:   session.UserTypePetugas,
+		Identifier: petugasLockID(petugas),
+		IPAddress:  input.IPAddress,
+		UserAgent:  input.UserAgent,
+	}
+	if err := s.checkThrottle(ctx, attempt); err != nil {
+		return pg_store.Petugas{}, err
+	}
+	if err := s.checkSecondFactor(ctx, petugas.ID, input.Kode); err != nil {
+		if errors.Is(err, ErrInvalid2FACode) {
+			return pg_store.Petugas{}, s.recordFailure(ctx, attempt, alasanKode2FASalah)
+		}
+		return pg_store.Petugas{}, err
+	}
+	return petugas, nil
+}
+
+// checkSecondFactor accepts a TOTP code newer than the last one used, or an
+// unused recovery code, which is spent
+func (s *Service) checkSecondFactor(ctx context.Context, petugasID uuid.UUID, kode string) error {
+	t, err := s.store.GetPetugasTotp(ctx, petugasID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTOTPNotEnrolled
+		}
+		return ErrInternalError
+	}
+	if !t.Aktif {
+		return ErrTOTPNotEnrolled
+	}
+
+	kode = normalizeKode(kode)
+	if len(kode) == totp.Digits {
+		step, ok := totp.ValidateAfter(t.Secret, kode, s.clock.Now(), t.TerakhirStep)
+		if !ok {
+			return ErrInvalid2FACode
+		}
+		// The update only succeeds for a newer step, which also stops two
+		// requests racing with the same code
+		n, err := s.store.UpdatePetugasTotpStep(ctx, pg_store.UpdatePetugasTotpStepParams{
+			PetugasID:    petugasID,
+			TerakhirStep: step,
+		})
+		if err != nil {
+			return ErrInternalError
+		}
+		if n == 0 {
+			return ErrInvalid2FACode
+		}
+		return nil
+	}
+
+	n, err := s.store.UseKodePemulihan(ctx, pg_store.UseKodePemulihanParams{
+		PetugasID: petugasID,
+		KodeHash:  hashRecoveryCode(kode),
+	})
+	if err != nil {
+		return ErrInternalError
+	}
+	if n == 0 {
+		return ErrInvalid2FACode
+	}
+	return nil
+}
+
+func (s *Service) getPetugas(ctx context.Context, petugasID string) (pg_store.Petugas, error) {
+	id, err := uuid.Parse(petugasID)
+	if err != nil {
+		return pg_store.Petugas{}, ErrInvalidCredentials
+	}
+	petugas, err := s.store.GetPetugasById(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pg_store.Petugas{}, ErrInvalidCredentials
+		}
+		return pg_store.Petugas{}, ErrInternalError
+	}
+	return petugas, nil
+}
+
+// totpAkun labels the account in the authenticator app: the NIP the petugas
+// logs in with, or the username for accounts without one
+func totpAkun(p pg_store.Petugas) string {
+	if p.Nip.String != "" {
+		return p.Nip.String
+	}
+	return p.Username
+}
+
+// replaceRecoveryCodes deletes the existing recovery codes and stores a new set
+func replaceRecoveryCodes(ctx context.Context, q *pg_store.Queries, petugasID uuid.UUID) ([]string, error) {
+	if err := q.DeleteKodePemulihan(ctx, petugasID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		kode, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		if err := q.InsertKodePemulihan(ctx, pg_store.InsertKodePemulihanParams{
+			PetugasID: petugasID,
+			KodeHash:  hashRecoveryCode(kode),
+		}); err != nil {
+			return nil, err
+		}
+		codes = append(codes, kode[:recoveryCodeLength/2]+"-"+kode[recoveryCodeLength/2:])
+	}
+	return codes, nil
+}
+
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		// 256 is not a multiple of the alphabet size; the bias is negligible here
+		b[i] = recoveryCodeAlphabet[int(b[i])%len(recoveryCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// hashRecoveryCode hashes a normalized recovery code. The codes carry enough
+// entropy that a fast hash is sufficient.
+func hashRecoveryCode(kode string) string {
+	sum := sha256.Sum256([]byte(kode))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeKode drops spaces and dashes and upper-cases the code, so that
+// recovery codes can be typed as shown or without separators
+func normalizeKode(kode string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(kode)))
+}
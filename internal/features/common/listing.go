@@ -0,0 +1,116 @@
+package common
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ListQuery is the page, sort order and filters of a table, read from the
+// query string so that every view of a table has its own URL
+type ListQuery struct {
+	Path    string
+	Values  url.Values
+	Page    int
+	PerPage int
+	// Sort is one of the columns passed to ParseListQuery
+	Sort string
+	Desc bool
+}
+
+// ParseListQuery reads page, sort and dir from the request. sorts lists the
+// columns the table can be sorted by; the first one is the default, sorted
+// newest or highest first.
+func ParseListQuery(r *http.Request, perPage int, sorts ...string) ListQuery {
+	v := r.URL.Query()
+	page, _ := strconv.Atoi(v.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	q := ListQuery{
+		Path:    r.URL.Path,
+		Values:  v,
+		Page:    page,
+		PerPage: perPage,
+		Sort:    sorts[0],
+		Desc:    true,
+	}
+	if s := v.Get("sort"); slices.Contains(sorts, s) {
+		q.Sort = s
+		q.Desc = v.Get("dir") == "desc"
+	}
+	return q
+}
+
+// Get returns a filter value from the query string
+func (q ListQuery) Get(key string) string {
+	return strings.TrimSpace(q.Values.Get(key))
+}
+
+// Text returns a filter value as a nullable query parameter, null when empty
+func (q ListQuery) Text(key string) pgtype.Text {
+	v := q.Get(key)
+	return pgtype.Text{String: v, Valid: v != ""}
+}
+
+// ClampPage moves a page past the end of the table to its last page, given
+// the number of rows the filters match. It keeps the offset of a page typed
+// into the URL from overflowing.
+func (q ListQuery) ClampPage(total int64) ListQuery {
+	last := max((total+int64(q.PerPage)-1)/int64(q.PerPage), 1)
+	if int64(q.Page) > last {
+		q.Page = int(last)
+	}
+	return q
+}
+
+func (q ListQuery) Limit() int32 {
+	return int32(q.PerPage)
+}
+
+// Offset returns the rows before the page. Pass the query through ClampPage
+// first; a page beyond the int32 range gives the largest offset.
+func (q ListQuery) Offset() int32 {
+	if q.Page-1 > math.MaxInt32/q.PerPage {
+		return math.MaxInt32
+	}
+	return int32((q.Page - 1) * q.PerPage)
+}
+
+// PageURL returns the URL of another page with the same sort and filters
+func (q ListQuery) PageURL(page int) string {
+	return q.with(func(v url.Values) {
+		v.Set("page", strconv.Itoa(page))
+	})
+}
+
+// SortURL returns the URL that sorts by column, reversing the direction when
+// the table is already sorted by it. Sorting starts again at the first page.
+func (q ListQuery) SortURL(column string) string {
+	dir := "asc"
+	if column == q.Sort && !q.Desc {
+		dir = "desc"
+	}
+	return q.with(func(v url.Values) {
+		v.Set("sort", column)
+		v.Set("dir", dir)
+		v.Del("page")
+	})
+}
+
+func (q ListQuery) with(change func(url.Values)) string {
+	v := url.Values{}
+	for k, vals := range q.Values {
+		v[k] = slices.Clone(vals)
+	}
+	change(v)
+	if len(v) == 0 {
+		return q.Path
+	}
+	return q.Path + "?" + v.Encode()
+}
@@ -0,0 +1,44 @@
+package common
+
+import (
+	"math"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestClampPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		total    int64
+		wantPage int
+		wantOff  int32
+	}{
+		{"first page", "1", 45, 1, 0},
+		{"last page", "3", 45, 3, 40},
+		{"past the end", "9", 45, 3, 40},
+		{"empty table", "4", 0, 1, 0},
+		{"page that overflows the offset", strconv.Itoa(math.MaxInt), 45, 3, 40},
+		{"page that overflows int32", "2147483647", 45, 3, 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/admin/penduduk?page="+tt.page, nil)
+			q := ParseListQuery(r, 20, "tanggal").ClampPage(tt.total)
+			if q.Page != tt.wantPage {
+				t.Errorf("Page = %d, want %d", q.Page, tt.wantPage)
+			}
+			if got := q.Offset(); got != tt.wantOff {
+				t.Errorf("Offset() = %d, want %d", got, tt.wantOff)
+			}
+		})
+	}
+}
+
+func TestOffsetWithoutClampDoesNotWrap(t *testing.T) {
+	r := httptest.NewRequest("GET", "/admin/penduduk?page="+strconv.Itoa(math.MaxInt), nil)
+	if got := ParseListQuery(r, 20, "tanggal").Offset(); got < 0 {
+		t.Errorf("Offset() = %d, want a non-negative offset", got)
+	}
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/nobuww/simpel-ktp/internal/middleware"
 	"github.com/nobuww/simpel-ktp/internal/session"
@@ -22,6 +23,8 @@ func GetUserOrRedirect(w http.ResponseWriter, r *http.Request, loginURL string)
 // FormatRole converts the internal role string to a human-readable format.
 func FormatRole(role string) string {
 	switch role {
+	case session.RoleAdminKota:
+		return "Admin Disdukcapil"
 	case session.RoleAdminKecamatan:
 		return "Admin Kecamatan"
 	case session.RoleAdminKelurahan:
@@ -30,3 +33,19 @@ func FormatRole(role string) string {
 		return role
 	}
 }
+
+// MaskPII hides all but the first keep characters of a personal data field,
+// for petugas without the penduduk.view_pii permission and on printouts
+func MaskPII(s string, keep int) string {
+	runes := []rune(s)
+	if len(runes) <= keep {
+		return s
+	}
+	return string(runes[:keep]) + strings.Repeat("*", len(runes)-keep)
+}
+
+// MaskNIK keeps the wilayah digits of a NIK and hides the birth date and
+// serial number
+func MaskNIK(nik string) string {
+	return MaskPII(nik, 6)
+}
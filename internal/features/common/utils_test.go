@@ -0,0 +1,27 @@
+package common
+
+import "testing"
+
+func TestMaskPII(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		keep int
+		want string
+	}{
+		{"nik", "3172050101800001", 6, "317205**********"},
+		{"phone", "081234567890", 4, "0812********"},
+		{"shorter than keep", "0812", 4, "0812"},
+		{"multibyte name", "Siti Ña", 2, "Si*****"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskPII(tt.s, tt.keep); got != tt.want {
+				t.Errorf("MaskPII(%q, %d) = %q, want %q", tt.s, tt.keep, got, tt.want)
+			}
+		})
+	}
+	if got := MaskNIK("3172050101800001"); got != MaskPII("3172050101800001", 6) {
+		t.Errorf("MaskNIK = %q, want the same as the roster", got)
+	}
+}
@@ -10,11 +10,11 @@ import (
 
 // Handler manages home page HTTP handlers
 type Handler struct {
-	store *store.Store
+	store store.Repository
 }
 
 // New creates a new home handler with the required dependencies
-func New(s *store.Store) *Handler {
+func New(s store.Repository) *Handler {
 	return &Handler{
 		store: s,
 	}
@@ -0,0 +1,177 @@
+package permohonan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/pdf"
+	"github.com/nobuww/simpel-ktp/internal/qrcode"
+)
+
+// BuktiBooking is what the printed booking receipt shows
+type BuktiBooking struct {
+	KodeBooking     string
+	Nama            string
+	NIK             string
+	JenisPermohonan string
+	JadwalTanggal   string
+	JadwalJam       string
+	NamaLokasi      string
+	NomorAntrian    int
+	TanggalDaftar   string
+	// Dokumen are the originals to bring to the session
+	Dokumen []string
+	Dicetak time.Time
+}
+
+// dokumenDibawa describes the original of each required document
+var dokumenDibawa = map[string]string{
+	"KK":           "Kartu Keluarga (asli)",
+	"KTP":          "KTP lama",
+	"KTP_RUSAK":    "KTP yang rusak",
+	"SURAT_POLISI": "Surat keterangan kehilangan dari kepolisian (asli)",
+}
+
+func (s *PermohonanService) GetBuktiBooking(ctx context.Context, permohonanID, nikUser string) (BuktiBooking, error) {
+	detail, err := s.permohonanMilik(ctx, permohonanID, nikUser)
+	if err != nil {
+		return BuktiBooking{}, err
+	}
+	syarat, err := s.repo.ListSyaratDokumen(ctx, detail.JenisPermohonan)
+	if err != nil {
+		return BuktiBooking{}, err
+	}
+
+	bukti := BuktiBooking{
+		KodeBooking: detail.KodeBooking.String,
+		Nama:        detail.NamaLengkap,
+		// A printed receipt is easily left lying around
+		NIK:             common.MaskNIK(detail.Nik),
+		JenisPermohonan: formatApplicationType(strings.ToLower(detail.JenisPermohonan)),
+		JadwalTanggal:   formatDate(detail.JadwalTanggal),
+		JadwalJam:       formatTime(detail.JadwalJamMulai) + " - " + formatTime(detail.JadwalJamSelesai),
+		NamaLokasi:      detail.NamaLokasi.String,
+		NomorAntrian:    int(detail.NomorAntrian.Int16),
+		Dicetak:         clock.Local(s.clock.Now()),
+	}
+	if detail.CreatedAt.Valid {
+		bukti.TanggalDaftar = clock.Local(detail.CreatedAt.Time).Format("02 Jan 2006 15:04")
+	}
+	for _, jenis := range syarat {
+		if label, ok := dokumenDibawa[jenis]; ok {
+			bukti.Dokumen = append(bukti.Dokumen, label)
+		} else {
+			bukti.Dokumen = append(bukti.Dokumen, jenis)
+		}
+	}
+	return bukti, nil
+}
+
+// HandleBuktiBooking downloads the booking receipt of one of the user's
+// applications as a PDF
+func (h *Handler) HandleBuktiBooking(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/login")
+	if !ok {
+		return
+	}
+	bukti, err := h.service.GetBuktiBooking(r.Context(), r.URL.Query().Get("id"), user.UserID)
+	if errors.Is(err, ErrPermohonanTidakDitemukan) {
+		common.WriteNotFound(w, "Permohonan tidak ditemukan")
+		return
+	}
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal membuat bukti booking")
+		return
+	}
+
+	doc, err := buktiBookingPDF(bukti, h.tiket.Payload(bukti.KodeBooking))
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal membuat bukti booking")
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", "bukti-booking-"+bukti.KodeBooking+".pdf"))
+	w.Header().Set("Cache-Control", "no-store")
+	doc.WriteTo(w)
+}
+
+// buktiBookingPDF lays out the receipt on one A4 page: the booking details
+// with the signed QR payload beside them, then the documents to bring
+func buktiBookingPDF(b BuktiBooking, payload string) (*pdf.Document, error) {
+	qr, err := qrcode.Encode(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	const left, right = 56.0, pdf.PageWidth - 56
+	doc := pdf.New("Bukti Booking " + b.KodeBooking)
+	p := doc.AddPage()
+
+	p.Text(left, 72, pdf.Bold, 16, "BUKTI BOOKING PEREKAMAN KTP-EL")
+	p.Text(left, 90, pdf.Regular, 10, "Simpel KTP - Dinas Kependudukan dan Pencatatan Sipil")
+	p.Line(left, 102, right, 102, 1)
+
+	p.Text(left, 132, pdf.Regular, 10, "Kode Booking")
+	p.Text(left, 158, pdf.Bold, 24, b.KodeBooking)
+
+	antrian := "-"
+	if b.NomorAntrian > 0 {
+		antrian = strconv.Itoa(b.NomorAntrian)
+	}
+	rows := [][2]string{
+		{"Nama", b.Nama},
+		{"NIK", b.NIK},
+		{"Jenis Permohonan", b.JenisPermohonan},
+		{"Tanggal Sesi", b.JadwalTanggal},
+		{"Jam Sesi", b.JadwalJam},
+		{"Lokasi", b.NamaLokasi},
+		{"Nomor Antrian", antrian},
+		{"Tanggal Daftar", b.TanggalDaftar},
+	}
+	y := 190.0
+	for _, row := range rows {
+		p.Text(left, y, pdf.Regular, 10, row[0])
+		p.Text(left+110, y, pdf.Bold, 10, pdf.Fit(row[1], pdf.Bold, 10, 200))
+		y += 18
+	}
+
+	// The QR code inside qrSize, keeping a quiet zone of four modules clear
+	// on every side
+	const qrSize = 150.0
+	qrX, qrY := right-qrSize, 120.0
+	quiet := 4 * qrSize / float64(qr.Size+8)
+	p.QR(qr, qrX+quiet, qrY+quiet, qrSize-2*quiet)
+	p.Text(qrX, qrY+qrSize+16, pdf.Regular, 8, "Tunjukkan kode ini kepada petugas")
+
+	y += 14
+	p.Line(left, y, right, y, 0.5)
+	y += 24
+	p.Text(left, y, pdf.Bold, 12, "Dokumen yang perlu dibawa")
+	y += 20
+	dokumen := append([]string{"Bukti booking ini, dicetak atau di layar ponsel"}, b.Dokumen...)
+	for _, d := range dokumen {
+		p.Text(left+8, y, pdf.Regular, 10, "•  "+d)
+		y += 16
+	}
+
+	y += 16
+	p.Text(left, y, pdf.Bold, 12, "Catatan")
+	y += 20
+	catatan := "Datanglah paling lambat 15 menit sebelum sesi dimulai. Perekaman dilayani sesuai nomor antrian; " +
+		"bila terlambat, Anda dapat dilayani setelah antrian sesi tersebut selesai."
+	for _, line := range pdf.Wrap(catatan, pdf.Regular, 10, right-left) {
+		p.Text(left, y, pdf.Regular, 10, line)
+		y += 14
+	}
+
+	p.Line(left, pdf.PageHeight-60, right, pdf.PageHeight-60, 0.5)
+	p.Text(left, pdf.PageHeight-46, pdf.Regular, 8, "Dicetak "+b.Dicetak.Format("02 Jan 2006 15:04")+" WIB")
+	return doc, nil
+}
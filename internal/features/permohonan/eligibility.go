@@ -0,0 +1,107 @@
+package permohonan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/nik"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// UmurWajibKTP is the age at which a citizen becomes eligible for a KTP.
+// Citizens who are or have been married are eligible regardless of age.
+const UmurWajibKTP = 17
+
+// Eligibility errors, shown to the citizen as-is
+var (
+	ErrBelumWajibKTP = errors.New("KTP dapat diajukan setelah berusia 17 tahun pada tanggal sesi, atau jika sudah/pernah kawin")
+	// ErrSudahPunyaKTP is a warning: the citizen may still apply for a new
+	// KTP once they confirm, e.g. when the earlier record is wrong
+	ErrSudahPunyaKTP = errors.New("anda sudah pernah menerima KTP dari permohonan KTP baru; biasanya yang diajukan adalah KTP hilang, rusak, atau perubahan data. Centang konfirmasi bila tetap ingin mengajukan KTP baru")
+
+	ErrBukanTanggungan = errors.New("anda tidak dapat mengajukan permohonan untuk NIK ini; hanya kepala keluarga atau pasangannya yang dapat mengajukan untuk anggota keluarga")
+)
+
+// checkEligibility applies the rules for jenisPermohonan to the citizen, with
+// age measured on the day of the chosen session:
+//
+//   - BARU needs an eligible citizen; one with a completed BARU application
+//     must confirm the warning, passed as konfirmasi
+//   - HILANG, RUSAK and UPDATE replace an existing KTP, so only the age or
+//     marriage rule applies; KTPs issued before this system have no record
+//
+// A date of birth that is neither stored nor encoded in the NIK skips the age
+// rule; petugas still check the documents during verification.
+func (s *PermohonanService) checkEligibility(ctx context.Context, nikWarga, jenisPermohonan string, tanggalSesi time.Time, konfirmasi bool) error {
+	penduduk, err := s.repo.GetPendudukByNIK(ctx, nikWarga)
+	if err != nil {
+		return fmt.Errorf("failed to load penduduk: %w", err)
+	}
+
+	if lahir, ok := tanggalLahir(nikWarga, penduduk.TanggalLahir, s.clock.Now()); ok {
+		if penduduk.StatusPerkawinan == "BELUM_KAWIN" && !wajibKTP(lahir, tanggalSesi) {
+			return ErrBelumWajibKTP
+		}
+	}
+
+	if jenisPermohonan != "BARU" || konfirmasi {
+		return nil
+	}
+	punya, err := s.sudahPunyaKTP(ctx, nikWarga)
+	if err != nil {
+		return err
+	}
+	if punya {
+		return ErrSudahPunyaKTP
+	}
+	return nil
+}
+
+// sudahPunyaKTP reports whether the citizen has a completed BARU application
+func (s *PermohonanService) sudahPunyaKTP(ctx context.Context, nikWarga string) (bool, error) {
+	selesai, err := s.repo.HasPermohonanSelesai(ctx, pg_store.HasPermohonanSelesaiParams{
+		Nik:             pgtype.Text{String: nikWarga, Valid: true},
+		JenisPermohonan: "BARU",
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check completed applications: %w", err)
+	}
+	return selesai, nil
+}
+
+// checkTanggungan verifies that pemohon is a guardian in untuk's household
+func (s *PermohonanService) checkTanggungan(ctx context.Context, pemohon, untuk string) error {
+	tanggungan, err := s.repo.ListTanggunganKeluarga(ctx, pemohon)
+	if err != nil {
+		return fmt.Errorf("failed to load household members: %w", err)
+	}
+	for _, t := range tanggungan {
+		if t.Nik == untuk {
+			return nil
+		}
+	}
+	return ErrBukanTanggungan
+}
+
+// tanggalLahir returns the stored date of birth, falling back to the one
+// encoded in the NIK for records created before it was stored
+func tanggalLahir(nikWarga string, stored pgtype.Date, today time.Time) (time.Time, bool) {
+	if stored.Valid {
+		return stored.Time, true
+	}
+	parsed, err := nik.Parse(nikWarga, today)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed.TanggalLahir, true
+}
+
+// wajibKTP reports whether someone born on lahir has turned UmurWajibKTP by
+// the day of on. Those born on 29 February turn 17 on 1 March in common years.
+func wajibKTP(lahir, on time.Time) bool {
+	ulangTahun := time.Date(lahir.Year()+UmurWajibKTP, lahir.Month(), lahir.Day(), 0, 0, 0, 0, on.Location())
+	return !on.Before(ulangTahun)
+}
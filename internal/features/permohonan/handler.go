@@ -1,20 +1,25 @@
 package permohonan
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/middleware"
+	"github.com/nobuww/simpel-ktp/internal/tiket"
 )
 
 type Handler struct {
 	service Service
+	tiket   *tiket.Signer
 }
 
-func New(s Service) *Handler {
+func New(s Service, signer *tiket.Signer) *Handler {
 	return &Handler{
 		service: s,
+		tiket:   signer,
 	}
 }
 
@@ -25,8 +30,12 @@ func (h *Handler) HandleKTPBaruForm(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx := r.Context()
 
-	formData, err := h.service.GetFormData(ctx, user.UserID)
+	formData, err := h.service.GetFormData(ctx, user.UserID, r.FormValue("untuk"))
 	if err != nil {
+		if errors.Is(err, ErrBukanTanggungan) {
+			common.WriteError(w, http.StatusForbidden, "Anda tidak dapat mengajukan permohonan untuk NIK ini")
+			return
+		}
 		http.Error(w, "Gagal memuat data user", http.StatusInternalServerError)
 		return
 	}
@@ -35,8 +44,7 @@ func (h *Handler) HandleKTPBaruForm(w http.ResponseWriter, r *http.Request) {
 	var jadwalList []JadwalOption
 	if lokasiIDStr := r.FormValue("lokasi_id"); lokasiIDStr != "" {
 		if lid, err := strconv.Atoi(lokasiIDStr); err == nil {
-			val := int32(lid)
-			jadwalList, _ = h.service.GetAvailableJadwal(ctx, &val)
+			jadwalList, _ = h.service.GetAvailableJadwal(ctx, int16(lid))
 		}
 	}
 
@@ -45,8 +53,8 @@ func (h *Handler) HandleKTPBaruForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form data (10MB max)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Parse multipart form data (body capped by the CSRF middleware)
+	if err := r.ParseMultipartForm(middleware.MaxFormMemory); err != nil {
 		formData.Errors["general"] = "Gagal memproses form: " + err.Error()
 		KTPBaruFormPage(formData, locations, jadwalList).Render(ctx, w)
 		return
@@ -70,14 +78,22 @@ func (h *Handler) HandleKTPBaruForm(w http.ResponseWriter, r *http.Request) {
 
 	req := CreatePermohonanRequest{
 		UserID:   user.UserID,
+		Untuk:    formData.UntukNIK(),
 		JadwalID: jadwalID,
 		Type:     "baru",
 		Documents: []DocumentFile{
 			{Type: "KK", Path: filePath},
 		},
+		KonfirmasiKTPBaru: r.FormValue("konfirmasi_ktp_baru") == "1",
 	}
 
 	permohonanID, err := h.service.CreatePermohonan(ctx, req)
+	if errors.Is(err, ErrSudahPunyaKTP) {
+		formData.SudahPunyaKTP = true
+		formData.Errors["konfirmasi_ktp_baru"] = "Centang konfirmasi untuk tetap mengajukan KTP baru"
+		KTPBaruFormPage(formData, locations, jadwalList).Render(ctx, w)
+		return
+	}
 	if err != nil {
 		formData.Errors["general"] = "Gagal membuat permohonan: " + err.Error()
 		KTPBaruFormPage(formData, locations, jadwalList).Render(ctx, w)
@@ -94,8 +110,12 @@ func (h *Handler) HandleKTPHilangForm(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx := r.Context()
 
-	formData, err := h.service.GetFormData(ctx, user.UserID)
+	formData, err := h.service.GetFormData(ctx, user.UserID, r.FormValue("untuk"))
 	if err != nil {
+		if errors.Is(err, ErrBukanTanggungan) {
+			common.WriteError(w, http.StatusForbidden, "Anda tidak dapat mengajukan permohonan untuk NIK ini")
+			return
+		}
 		http.Error(w, "Gagal memuat data user", http.StatusInternalServerError)
 		return
 	}
@@ -103,8 +123,7 @@ func (h *Handler) HandleKTPHilangForm(w http.ResponseWriter, r *http.Request) {
 	var jadwalList []JadwalOption
 	if lokasiIDStr := r.FormValue("lokasi_id"); lokasiIDStr != "" {
 		if lid, err := strconv.Atoi(lokasiIDStr); err == nil {
-			val := int32(lid)
-			jadwalList, _ = h.service.GetAvailableJadwal(ctx, &val)
+			jadwalList, _ = h.service.GetAvailableJadwal(ctx, int16(lid))
 		}
 	}
 
@@ -113,8 +132,8 @@ func (h *Handler) HandleKTPHilangForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form data (10MB max)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Parse multipart form data (body capped by the CSRF middleware)
+	if err := r.ParseMultipartForm(middleware.MaxFormMemory); err != nil {
 		formData.Errors["general"] = "Gagal memproses form: " + err.Error()
 		KTPHilangFormPage(formData, locations, jadwalList).Render(ctx, w)
 		return
@@ -146,6 +165,7 @@ func (h *Handler) HandleKTPHilangForm(w http.ResponseWriter, r *http.Request) {
 
 	req := CreatePermohonanRequest{
 		UserID:   user.UserID,
+		Untuk:    formData.UntukNIK(),
 		JadwalID: jadwalID,
 		Type:     "hilang",
 		Documents: []DocumentFile{
@@ -170,8 +190,12 @@ func (h *Handler) HandleKTPRusakForm(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx := r.Context()
 
-	formData, err := h.service.GetFormData(ctx, user.UserID)
+	formData, err := h.service.GetFormData(ctx, user.UserID, r.FormValue("untuk"))
 	if err != nil {
+		if errors.Is(err, ErrBukanTanggungan) {
+			common.WriteError(w, http.StatusForbidden, "Anda tidak dapat mengajukan permohonan untuk NIK ini")
+			return
+		}
 		http.Error(w, "Gagal memuat data user", http.StatusInternalServerError)
 		return
 	}
@@ -179,8 +203,7 @@ func (h *Handler) HandleKTPRusakForm(w http.ResponseWriter, r *http.Request) {
 	var jadwalList []JadwalOption
 	if lokasiIDStr := r.FormValue("lokasi_id"); lokasiIDStr != "" {
 		if lid, err := strconv.Atoi(lokasiIDStr); err == nil {
-			val := int32(lid)
-			jadwalList, _ = h.service.GetAvailableJadwal(ctx, &val)
+			jadwalList, _ = h.service.GetAvailableJadwal(ctx, int16(lid))
 		}
 	}
 
@@ -189,8 +212,8 @@ func (h *Handler) HandleKTPRusakForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form data (10MB max)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Parse multipart form data (body capped by the CSRF middleware)
+	if err := r.ParseMultipartForm(middleware.MaxFormMemory); err != nil {
 		formData.Errors["general"] = "Gagal memproses form: " + err.Error()
 		KTPRusakFormPage(formData, locations, jadwalList).Render(ctx, w)
 		return
@@ -223,6 +246,7 @@ func (h *Handler) HandleKTPRusakForm(w http.ResponseWriter, r *http.Request) {
 
 	req := CreatePermohonanRequest{
 		UserID:   user.UserID,
+		Untuk:    formData.UntukNIK(),
 		JadwalID: jadwalID,
 		Type:     "rusak",
 		Documents: []DocumentFile{
@@ -248,8 +272,12 @@ func (h *Handler) HandleKTPUbahForm(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx := r.Context()
 
-	formData, err := h.service.GetFormData(ctx, user.UserID)
+	formData, err := h.service.GetFormData(ctx, user.UserID, r.FormValue("untuk"))
 	if err != nil {
+		if errors.Is(err, ErrBukanTanggungan) {
+			common.WriteError(w, http.StatusForbidden, "Anda tidak dapat mengajukan permohonan untuk NIK ini")
+			return
+		}
 		http.Error(w, "Gagal memuat data user", http.StatusInternalServerError)
 		return
 	}
@@ -257,8 +285,7 @@ func (h *Handler) HandleKTPUbahForm(w http.ResponseWriter, r *http.Request) {
 	var jadwalList []JadwalOption
 	if lokasiIDStr := r.FormValue("lokasi_id"); lokasiIDStr != "" {
 		if lid, err := strconv.Atoi(lokasiIDStr); err == nil {
-			val := int32(lid)
-			jadwalList, _ = h.service.GetAvailableJadwal(ctx, &val)
+			jadwalList, _ = h.service.GetAvailableJadwal(ctx, int16(lid))
 		}
 	}
 
@@ -267,8 +294,8 @@ func (h *Handler) HandleKTPUbahForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form data (10MB max)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Parse multipart form data (body capped by the CSRF middleware)
+	if err := r.ParseMultipartForm(middleware.MaxFormMemory); err != nil {
 		formData.Errors["general"] = "Gagal memproses form: " + err.Error()
 		KTPUbahFormPage(formData, locations, jadwalList).Render(ctx, w)
 		return
@@ -301,6 +328,7 @@ func (h *Handler) HandleKTPUbahForm(w http.ResponseWriter, r *http.Request) {
 
 	req := CreatePermohonanRequest{
 		UserID:   user.UserID,
+		Untuk:    formData.UntukNIK(),
 		JadwalID: jadwalID,
 		Type:     "ubah",
 		Documents: []DocumentFile{
@@ -320,7 +348,7 @@ func (h *Handler) HandleKTPUbahForm(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) HandleSuccessPage(w http.ResponseWriter, r *http.Request) {
-	_, ok := common.GetUserOrRedirect(w, r, "/login")
+	user, ok := common.GetUserOrRedirect(w, r, "/login")
 	if !ok {
 		return
 	}
@@ -334,7 +362,7 @@ func (h *Handler) HandleSuccessPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	successData, err := h.service.GetSuccessData(ctx, permohonanID, applicationType)
+	successData, err := h.service.GetSuccessData(ctx, permohonanID, applicationType, user.UserID)
 	if err != nil {
 		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 		return
@@ -356,8 +384,7 @@ func (h *Handler) HandleGetJadwalOptions(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	lid := int32(lokasiID)
-	jadwalList, err := h.service.GetAvailableJadwal(r.Context(), &lid)
+	jadwalList, err := h.service.GetAvailableJadwal(r.Context(), int16(lokasiID))
 	if err != nil {
 		JadwalSelectPartial([]JadwalOption{}, "Error fetching schedules").Render(r.Context(), w)
 		return
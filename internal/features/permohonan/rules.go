@@ -0,0 +1,127 @@
+package permohonan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// Booking rule errors, shown to the citizen as-is
+var (
+	ErrSesiTidakTersedia = errors.New("sesi yang dipilih tidak tersedia")
+	ErrSesiSudahLewat    = errors.New("sesi yang dipilih sudah lewat")
+	ErrHariSamaDitutup   = errors.New("pendaftaran untuk sesi hari ini sudah ditutup")
+	ErrTerlaluMendadak   = errors.New("sesi terlalu dekat, pilih sesi yang lebih lambat")
+	ErrTerlaluJauh       = errors.New("sesi terlalu jauh ke depan")
+	ErrBatasBulanan      = errors.New("batas jumlah booking bulan ini sudah tercapai")
+)
+
+// BookingRules are the per-location limits applied when a citizen picks a session
+type BookingRules struct {
+	MinNoticeHours int
+	MaxDaysAhead   int
+	// SameDayCutoff is the time of day after which today's sessions can no
+	// longer be booked. nil means same-day booking is not allowed at all.
+	SameDayCutoff *time.Duration
+	// MaxPerMonth limits bookings per NIK per calendar month; 0 means unlimited
+	MaxPerMonth int
+}
+
+// DefaultBookingRules apply to locations without a configured aturan_booking row
+var DefaultBookingRules = BookingRules{
+	MinNoticeHours: 0,
+	MaxDaysAhead:   30,
+}
+
+func rulesFromRow(row pg_store.AturanBooking) BookingRules {
+	rules := BookingRules{
+		MinNoticeHours: int(row.MinJamPemberitahuan),
+		MaxDaysAhead:   int(row.MaksHariKeDepan),
+	}
+	if row.BatasJamHariSama.Valid {
+		cutoff := time.Duration(row.BatasJamHariSama.Microseconds) * time.Microsecond
+		rules.SameDayCutoff = &cutoff
+	}
+	if row.MaksBookingPerBulan.Valid {
+		rules.MaxPerMonth = int(row.MaksBookingPerBulan.Int16)
+	}
+	return rules
+}
+
+// CheckSession reports whether a session starting at sessionStart may be booked at now
+func (r BookingRules) CheckSession(now, sessionStart time.Time) error {
+	if !sessionStart.After(now) {
+		return ErrSesiSudahLewat
+	}
+
+	today := clock.StartOfDay(now)
+	sessionDay := clock.StartOfDay(sessionStart)
+
+	if sessionDay.Equal(today) {
+		if r.SameDayCutoff == nil || now.Sub(today) > *r.SameDayCutoff {
+			return ErrHariSamaDitutup
+		}
+	}
+
+	if sessionStart.Sub(now) < time.Duration(r.MinNoticeHours)*time.Hour {
+		return ErrTerlaluMendadak
+	}
+
+	if sessionDay.After(today.AddDate(0, 0, r.MaxDaysAhead)) {
+		return ErrTerlaluJauh
+	}
+
+	return nil
+}
+
+// sessionStart combines a session's DATE and TIME columns into an instant in Asia/Jakarta
+func sessionStart(tanggal pgtype.Date, jamMulai pgtype.Time) time.Time {
+	d := tanggal.Time
+	start := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, clock.Location)
+	return start.Add(time.Duration(jamMulai.Microseconds) * time.Microsecond)
+}
+
+// startOfMonth returns midnight of the first day of t's month in Asia/Jakarta
+func startOfMonth(t time.Time) time.Time {
+	t = t.In(clock.Location)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, clock.Location)
+}
+
+func (s *PermohonanService) bookingRules(ctx context.Context, lokasiID int16) (BookingRules, error) {
+	row, err := s.repo.GetAturanBooking(ctx, lokasiID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DefaultBookingRules, nil
+		}
+		return BookingRules{}, fmt.Errorf("failed to load booking rules: %w", err)
+	}
+	return rulesFromRow(row), nil
+}
+
+// checkMonthlyLimit enforces MaxPerMonth for the given NIK in the current
+// month. q must hold the NIK's LockPermohonanNIK lock.
+func (s *PermohonanService) checkMonthlyLimit(ctx context.Context, q pg_store.Querier, rules BookingRules, nik pgtype.Text) error {
+	if rules.MaxPerMonth <= 0 {
+		return nil
+	}
+
+	monthStart := startOfMonth(s.clock.Now())
+	count, err := q.CountPermohonanByNIKInPeriod(ctx, pg_store.CountPermohonanByNIKInPeriodParams{
+		Nik:         nik,
+		PeriodStart: pgtype.Timestamptz{Time: monthStart, Valid: true},
+		PeriodEnd:   pgtype.Timestamptz{Time: monthStart.AddDate(0, 1, 0), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check monthly bookings: %w", err)
+	}
+	if int(count) >= rules.MaxPerMonth {
+		return ErrBatasBulanan
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package permohonan
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nobuww/simpel-ktp/internal/clock"
+)
+
+// wib builds an instant from a wall-clock time in Asia/Jakarta
+func wib(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, clock.Location)
+}
+
+func TestCheckSessionAroundMidnightWIB(t *testing.T) {
+	cutoff := 10 * time.Hour
+	sameDay := BookingRules{MaxDaysAhead: 7, SameDayCutoff: &cutoff}
+	nextDayOnly := BookingRules{MaxDaysAhead: 7}
+
+	tests := []struct {
+		name    string
+		rules   BookingRules
+		now     time.Time
+		session time.Time
+		want    error
+	}{
+		{
+			// 23:59 WIB is 16:59 UTC; the 08:00 session is tomorrow, not today
+			name:    "just before midnight the morning session is tomorrow",
+			rules:   nextDayOnly,
+			now:     wib(2026, 3, 9, 23, 59),
+			session: wib(2026, 3, 10, 8, 0),
+		},
+		{
+			// 00:01 WIB is still the previous day in UTC; the session is today
+			name:    "just after midnight the morning session is today",
+			rules:   nextDayOnly,
+			now:     wib(2026, 3, 10, 0, 1),
+			session: wib(2026, 3, 10, 8, 0),
+			want:    ErrHariSamaDitutup,
+		},
+		{
+			name:    "same-day booking before the cut-off",
+			rules:   sameDay,
+			now:     wib(2026, 3, 10, 0, 1),
+			session: wib(2026, 3, 10, 13, 0),
+		},
+		{
+			name:    "same-day booking after the cut-off",
+			rules:   sameDay,
+			now:     wib(2026, 3, 10, 10, 1),
+			session: wib(2026, 3, 10, 13, 0),
+			want:    ErrHariSamaDitutup,
+		},
+		{
+			name:    "session already started",
+			rules:   sameDay,
+			now:     wib(2026, 3, 10, 8, 0),
+			session: wib(2026, 3, 10, 8, 0),
+			want:    ErrSesiSudahLewat,
+		},
+		{
+			name:    "minimum notice counts across midnight",
+			rules:   BookingRules{MaxDaysAhead: 7, MinNoticeHours: 12},
+			now:     wib(2026, 3, 9, 22, 0),
+			session: wib(2026, 3, 10, 8, 0),
+			want:    ErrTerlaluMendadak,
+		},
+		{
+			name:    "last day of the booking window",
+			rules:   nextDayOnly,
+			now:     wib(2026, 3, 9, 23, 59),
+			session: wib(2026, 3, 16, 15, 0),
+		},
+		{
+			name:    "past the booking window",
+			rules:   nextDayOnly,
+			now:     wib(2026, 3, 9, 23, 59),
+			session: wib(2026, 3, 17, 8, 0),
+			want:    ErrTerlaluJauh,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Compare in UTC too, so a stray server zone cannot hide a bug
+			err := tt.rules.CheckSession(tt.now.UTC(), tt.session)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("CheckSession() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
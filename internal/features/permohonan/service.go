@@ -2,28 +2,42 @@ package permohonan
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
 	"github.com/nobuww/simpel-ktp/internal/store"
 	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+	"github.com/nobuww/simpel-ktp/internal/tiket"
 )
 
 type Service interface {
-	GetFormData(ctx context.Context, nik string) (FormData, error)
-	GetAvailableJadwal(ctx context.Context, kelurahanID *int32) ([]JadwalOption, error)
+	GetFormData(ctx context.Context, nik, untuk string) (FormData, error)
+	GetAvailableJadwal(ctx context.Context, lokasiID int16) ([]JadwalOption, error)
 	GetLocations(ctx context.Context) ([]LocationOption, error)
 	CreatePermohonan(ctx context.Context, req CreatePermohonanRequest) (uuid.UUID, error)
-	GetSuccessData(ctx context.Context, permohonanID string, applicationType string) (SuccessData, error)
+	GetSuccessData(ctx context.Context, permohonanID, applicationType, nikUser string) (SuccessData, error)
+	GetBuktiBooking(ctx context.Context, permohonanID, nikUser string) (BuktiBooking, error)
 }
 
+// ErrPermohonanTidakDitemukan is returned for applications that do not exist
+// or were neither made by nor for the user asking
+var ErrPermohonanTidakDitemukan = errors.New("permohonan tidak ditemukan")
+
 type CreatePermohonanRequest struct {
-	UserID    string
+	UserID string
+	// Untuk is the NIK of the household member applied for; empty when the
+	// user applies for themselves
+	Untuk     string
 	JadwalID  string
 	Type      string
 	Documents []DocumentFile
+	// KonfirmasiKTPBaru is set when the citizen confirmed the ErrSudahPunyaKTP
+	// warning on the form
+	KonfirmasiKTPBaru bool
 }
 
 type DocumentFile struct {
@@ -32,22 +46,49 @@ type DocumentFile struct {
 }
 
 type PermohonanService struct {
-	repo store.Repository
+	repo   store.Repository
+	clock  clock.Clock
+	signer *tiket.Signer
 }
 
-func NewService(repo store.Repository) *PermohonanService {
+func NewService(repo store.Repository, clk clock.Clock, signer *tiket.Signer) *PermohonanService {
 	return &PermohonanService{
-		repo: repo,
+		repo:   repo,
+		clock:  clk,
+		signer: signer,
 	}
 }
 
-func (s *PermohonanService) GetFormData(ctx context.Context, nik string) (FormData, error) {
+// GetFormData prefills the form for the citizen nik or, when untuk is set,
+// for the household member with that ref they apply for
+func (s *PermohonanService) GetFormData(ctx context.Context, nik, untuk string) (FormData, error) {
 	formData := FormData{
 		NIK:    nik,
 		Errors: make(map[string]string),
 	}
 
-	profile, err := s.repo.GetPendudukProfile(ctx, nik)
+	tanggungan, err := s.repo.ListTanggunganKeluarga(ctx, nik)
+	if err != nil {
+		return formData, err
+	}
+	for _, t := range tanggungan {
+		formData.Tanggungan = append(formData.Tanggungan, TanggunganOption{
+			NIK:      t.Nik,
+			Ref:      s.signer.Ref(t.Nik),
+			Nama:     t.NamaLengkap,
+			Hubungan: t.HubunganKeluarga.String,
+		})
+	}
+	if untuk != "" {
+		t, ok := formData.tanggungan(untuk)
+		if !ok {
+			return formData, ErrBukanTanggungan
+		}
+		formData.NIK = t.NIK
+		formData.Untuk = t.Ref
+	}
+
+	profile, err := s.repo.GetPendudukProfile(ctx, formData.NIK)
 	if err == nil {
 		formData.NamaLengkap = profile.NamaLengkap
 		formData.JenisKelamin = profile.JenisKelamin
@@ -55,37 +96,37 @@ func (s *PermohonanService) GetFormData(ctx context.Context, nik string) (FormDa
 		formData.NoHP = profile.NoHp.String
 		formData.Email = profile.Email.String
 		formData.NamaKelurahan = profile.NamaKelurahan.String
+		formData.StatusPerkawinan = profile.StatusPerkawinan
+		formData.TanggalLahir = "-"
+		if lahir, ok := tanggalLahir(formData.NIK, profile.TanggalLahir, s.clock.Now()); ok {
+			formData.TanggalLahir = lahir.Format("02 Jan 2006")
+		}
 	} else {
 		return formData, err
 	}
 
+	formData.SudahPunyaKTP, err = s.sudahPunyaKTP(ctx, formData.NIK)
+	if err != nil {
+		return formData, err
+	}
+
 	return formData, nil
 }
 
-func (s *PermohonanService) GetAvailableJadwal(ctx context.Context, kelurahanID *int32) ([]JadwalOption, error) {
-	today := time.Now()
-	tomorrow := today.AddDate(0, 0, 1)
-	next30Days := tomorrow.AddDate(0, 0, 30)
-
-	tomorrowPg := pgtype.Date{Time: tomorrow, Valid: true}
-	next30DaysPg := pgtype.Date{Time: next30Days, Valid: true}
-
-	var targetKecamatan bool
-	var kelurahanIDPg pgtype.Int4
-	if kelurahanID != nil && *kelurahanID == 0 {
-		targetKecamatan = true
-		// Pass Valid: false to fetch all, will filter in loop
-		kelurahanIDPg = pgtype.Int4{Valid: false}
-	} else if kelurahanID != nil {
-		kelurahanIDPg = pgtype.Int4{Int32: *kelurahanID, Valid: true}
-	} else {
-		kelurahanIDPg = pgtype.Int4{Valid: false}
+func (s *PermohonanService) GetAvailableJadwal(ctx context.Context, lokasiID int16) ([]JadwalOption, error) {
+	rules, err := s.bookingRules(ctx, lokasiID)
+	if err != nil {
+		return nil, err
 	}
 
+	now := s.clock.Now()
+	today := clock.StartOfDay(now)
+	lastDay := today.AddDate(0, 0, rules.MaxDaysAhead)
+
 	jadwalList, err := s.repo.ListJadwalSesi(ctx, pg_store.ListJadwalSesiParams{
-		Tanggal:     tomorrowPg,
-		Tanggal_2:   next30DaysPg,
-		KelurahanID: kelurahanIDPg,
+		Tanggal:   pgtype.Date{Time: today, Valid: true},
+		Tanggal_2: pgtype.Date{Time: lastDay, Valid: true},
+		LokasiID:  lokasiID,
 	})
 	if err != nil {
 		return nil, err
@@ -93,10 +134,10 @@ func (s *PermohonanService) GetAvailableJadwal(ctx context.Context, kelurahanID
 
 	options := make([]JadwalOption, 0, len(jadwalList))
 	for _, j := range jadwalList {
-		if targetKecamatan && j.NamaKelurahan != "Kecamatan Pademangan" {
+		if j.StatusSesi.String != "BUKA" {
 			continue
 		}
-		if j.StatusSesi.String != "BUKA" {
+		if rules.CheckSession(now, sessionStart(j.Tanggal, j.JamMulai)) != nil {
 			continue
 		}
 		kuotaSisa := int(j.KuotaMaksimal - j.KuotaTerisi)
@@ -107,7 +148,7 @@ func (s *PermohonanService) GetAvailableJadwal(ctx context.Context, kelurahanID
 		label := fmt.Sprintf("%s - %s (%s, sisa %d kuota)",
 			formatDate(j.Tanggal),
 			formatTime(j.JamMulai),
-			j.NamaKelurahan,
+			j.NamaLokasi,
 			kuotaSisa,
 		)
 
@@ -123,21 +164,21 @@ func (s *PermohonanService) GetAvailableJadwal(ctx context.Context, kelurahanID
 }
 
 func (s *PermohonanService) GetLocations(ctx context.Context) ([]LocationOption, error) {
-	locations, err := s.repo.ListAllKelurahan(ctx)
+	locations, err := s.repo.ListLokasiLayanan(ctx, pg_store.ListLokasiLayananParams{})
 	if err != nil {
 		return nil, err
 	}
 
-	options := make([]LocationOption, 0, len(locations)+1)
-	options = append(options, LocationOption{
-		ID:    0,
-		Label: "Kecamatan Pademangan",
-	})
-
+	options := make([]LocationOption, 0, len(locations))
 	for _, l := range locations {
 		options = append(options, LocationOption{
-			ID:    l.ID,
-			Label: l.NamaKelurahan,
+			ID:     l.ID,
+			Label:  l.NamaLokasi,
+			Alamat: l.Alamat.String,
+			JamOperasional: fmt.Sprintf("%s - %s",
+				formatTime(l.JamBuka),
+				formatTime(l.JamTutup),
+			),
 		})
 	}
 
@@ -150,21 +191,35 @@ func (s *PermohonanService) CreatePermohonan(ctx context.Context, req CreatePerm
 		return uuid.Nil, fmt.Errorf("invalid jadwal ID: %w", err)
 	}
 
-	nikText := pgtype.Text{String: req.UserID, Valid: true}
+	// The checks below apply to the citizen the KTP is for
+	subjek := req.UserID
+	if req.Untuk != "" && req.Untuk != req.UserID {
+		if err := s.checkTanggungan(ctx, req.UserID, req.Untuk); err != nil {
+			return uuid.Nil, err
+		}
+		subjek = req.Untuk
+	}
+	nikText := pgtype.Text{String: subjek, Valid: true}
 
-	// Check for active permohonan
-	counts, err := s.repo.CountPermohonanByNIK(ctx, nikText)
+	// Enforce the location's booking rules on the server, not just in the dropdown
+	jadwal, err := s.repo.GetJadwalSesiById(ctx, jadwalUUID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to check existing applications: %w", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrSesiTidakTersedia
+		}
+		return uuid.Nil, fmt.Errorf("failed to load jadwal: %w", err)
 	}
-
-	activeCount := counts.Verifikasi + counts.Proses + counts.SiapAmbil
-	if activeCount > 0 {
-		return uuid.Nil, fmt.Errorf("anda masih memiliki permohonan yang sedang berjalan (Status: Verifikasi/Proses/Siap Ambil)")
+	if jadwal.StatusSesi.String != "BUKA" || jadwal.KuotaTerisi >= jadwal.KuotaMaksimal {
+		return uuid.Nil, ErrSesiTidakTersedia
 	}
 
-	jadwalUUIDPg := pgtype.UUID{Bytes: jadwalUUID, Valid: true}
-
+	rules, err := s.bookingRules(ctx, jadwal.LokasiID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := rules.CheckSession(s.clock.Now(), sessionStart(jadwal.Tanggal, jadwal.JamMulai)); err != nil {
+		return uuid.Nil, err
+	}
 	jenisPermohonan := ""
 	switch req.Type {
 	case "baru":
@@ -179,37 +234,67 @@ func (s *PermohonanService) CreatePermohonan(ctx context.Context, req CreatePerm
 		jenisPermohonan = req.Type
 	}
 
-	permohonanID, err := s.repo.CreatePermohonan(ctx, pg_store.CreatePermohonanParams{
-		Nik:             nikText,
-		JadwalSesiID:    jadwalUUIDPg,
-		JenisPermohonan: jenisPermohonan,
-	})
-	if err != nil {
+	if err := s.checkEligibility(ctx, subjek, jenisPermohonan, sessionStart(jadwal.Tanggal, jadwal.JamMulai), req.KonfirmasiKTPBaru); err != nil {
 		return uuid.Nil, err
 	}
 
-	permohonanUUID := pgtype.UUID{Bytes: permohonanID, Valid: true}
-	for _, doc := range req.Documents {
-		err = s.repo.CreateDokumenSyarat(ctx, pg_store.CreateDokumenSyaratParams{
-			PermohonanID: permohonanUUID,
-			FilePath:     doc.Path,
-			JenisDokumen: doc.Type,
+	jadwalUUIDPg := pgtype.UUID{Bytes: jadwalUUID, Valid: true}
+
+	// The active and monthly checks and the insert run under a per-NIK lock,
+	// so parallel submits cannot both pass the counts
+	var permohonanID uuid.UUID
+	err = s.repo.ExecTx(ctx, func(q *pg_store.Queries) error {
+		if err := q.LockPermohonanNIK(ctx, subjek); err != nil {
+			return err
+		}
+
+		// Check for active permohonan
+		counts, err := q.CountPermohonanByNIK(ctx, nikText)
+		if err != nil {
+			return fmt.Errorf("failed to check existing applications: %w", err)
+		}
+
+		activeCount := counts.Verifikasi + counts.Proses + counts.SiapAmbil
+		if activeCount > 0 {
+			return fmt.Errorf("anda masih memiliki permohonan yang sedang berjalan (Status: Verifikasi/Proses/Siap Ambil)")
+		}
+
+		if err := s.checkMonthlyLimit(ctx, q, rules, nikText); err != nil {
+			return err
+		}
+
+		permohonanID, err = q.CreatePermohonan(ctx, pg_store.CreatePermohonanParams{
+			Nik:             nikText,
+			JadwalSesiID:    jadwalUUIDPg,
+			JenisPermohonan: jenisPermohonan,
+			PemohonNik:      pgtype.Text{String: req.UserID, Valid: true},
 		})
 		if err != nil {
-			return permohonanID, fmt.Errorf("failed to save document %s: %w", doc.Type, err)
+			return err
+		}
+
+		permohonanUUID := pgtype.UUID{Bytes: permohonanID, Valid: true}
+		for _, doc := range req.Documents {
+			err = q.CreateDokumenSyarat(ctx, pg_store.CreateDokumenSyaratParams{
+				PermohonanID: permohonanUUID,
+				FilePath:     doc.Path,
+				JenisDokumen: doc.Type,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save document %s: %w", doc.Type, err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, err
 	}
 
 	return permohonanID, nil
 }
 
-func (s *PermohonanService) GetSuccessData(ctx context.Context, permohonanID string, applicationType string) (SuccessData, error) {
-	permohonanUUID, err := uuid.Parse(permohonanID)
-	if err != nil {
-		return SuccessData{}, err
-	}
-
-	detail, err := s.repo.GetPermohonanDetail(ctx, permohonanUUID)
+func (s *PermohonanService) GetSuccessData(ctx context.Context, permohonanID, applicationType, nikUser string) (SuccessData, error) {
+	detail, err := s.permohonanMilik(ctx, permohonanID, nikUser)
 	if err != nil {
 		return SuccessData{}, err
 	}
@@ -220,12 +305,32 @@ func (s *PermohonanService) GetSuccessData(ctx context.Context, permohonanID str
 		ApplicationType: formatApplicationType(applicationType),
 		JadwalTanggal:   formatDate(detail.JadwalTanggal),
 		JadwalJam:       formatTime(detail.JadwalJamMulai) + " - " + formatTime(detail.JadwalJamSelesai),
-		NamaKelurahan:   detail.NamaKelurahan,
+		NamaLokasi:      detail.NamaLokasi.String,
 	}
 
 	return successData, nil
 }
 
+// permohonanMilik loads an application the user made for themselves or for a
+// household member
+func (s *PermohonanService) permohonanMilik(ctx context.Context, permohonanID, nikUser string) (pg_store.GetPermohonanDetailRow, error) {
+	id, err := uuid.Parse(permohonanID)
+	if err != nil {
+		return pg_store.GetPermohonanDetailRow{}, ErrPermohonanTidakDitemukan
+	}
+	detail, err := s.repo.GetPermohonanDetail(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return detail, ErrPermohonanTidakDitemukan
+	}
+	if err != nil {
+		return detail, err
+	}
+	if detail.Nik != nikUser && detail.PemohonNik.String != nikUser {
+		return detail, ErrPermohonanTidakDitemukan
+	}
+	return detail, nil
+}
+
 // Helpers
 
 func formatApplicationType(typeCode string) string {
@@ -236,7 +341,7 @@ func formatApplicationType(typeCode string) string {
 		return "KTP Hilang"
 	case "rusak":
 		return "KTP Rusak"
-	case "ubah":
+	case "ubah", "update":
 		return "Perubahan Data KTP"
 	default:
 		return typeCode
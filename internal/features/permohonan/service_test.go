@@ -0,0 +1,100 @@
+package permohonan
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/store"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+	"github.com/nobuww/simpel-ktp/internal/tiket"
+)
+
+// jadwalRepo serves a fixed list of sessions; calls to anything else panic
+type jadwalRepo struct {
+	store.Repository
+	sesi   []pg_store.ListJadwalSesiRow
+	params pg_store.ListJadwalSesiParams
+}
+
+func (r *jadwalRepo) GetAturanBooking(ctx context.Context, lokasiID int16) (pg_store.AturanBooking, error) {
+	return pg_store.AturanBooking{}, pgx.ErrNoRows
+}
+
+func (r *jadwalRepo) ListJadwalSesi(ctx context.Context, arg pg_store.ListJadwalSesiParams) ([]pg_store.ListJadwalSesiRow, error) {
+	r.params = arg
+	return r.sesi, nil
+}
+
+func sesiRow(tanggal time.Time, jam time.Duration) pg_store.ListJadwalSesiRow {
+	return pg_store.ListJadwalSesiRow{
+		ID:            uuid.New(),
+		Tanggal:       pgtype.Date{Time: time.Date(tanggal.Year(), tanggal.Month(), tanggal.Day(), 0, 0, 0, 0, time.UTC), Valid: true},
+		JamMulai:      pgtype.Time{Microseconds: jam.Microseconds(), Valid: true},
+		KuotaMaksimal: 10,
+		StatusSesi:    pgtype.Text{String: "BUKA", Valid: true},
+		NamaLokasi:    "Kantor Kecamatan",
+	}
+}
+
+func TestGetAvailableJadwalAroundMidnightWIB(t *testing.T) {
+	today := sesiRow(wib(2026, 3, 10, 0, 0), 8*time.Hour)
+	tomorrow := sesiRow(wib(2026, 3, 11, 0, 0), 8*time.Hour)
+	yesterday := sesiRow(wib(2026, 3, 9, 0, 0), 13*time.Hour)
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantFrom  string
+		wantSesi  []uuid.UUID
+		available []pg_store.ListJadwalSesiRow
+	}{
+		{
+			// 23:59 WIB on the 9th; the 10th is tomorrow and bookable
+			name:      "before midnight",
+			now:       wib(2026, 3, 9, 23, 59),
+			wantFrom:  "2026-03-09",
+			available: []pg_store.ListJadwalSesiRow{yesterday, today, tomorrow},
+			wantSesi:  []uuid.UUID{today.ID, tomorrow.ID},
+		},
+		{
+			// 00:01 WIB on the 10th is 17:01 UTC on the 9th; today's session
+			// is same-day and closed by the default rules
+			name:      "after midnight",
+			now:       wib(2026, 3, 10, 0, 1),
+			wantFrom:  "2026-03-10",
+			available: []pg_store.ListJadwalSesiRow{today, tomorrow},
+			wantSesi:  []uuid.UUID{tomorrow.ID},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &jadwalRepo{sesi: tt.available}
+			svc := NewService(repo, clock.Fixed(tt.now.UTC()), tiket.NewSigner([]byte("test")))
+
+			got, err := svc.GetAvailableJadwal(context.Background(), 1)
+			if err != nil {
+				t.Fatalf("GetAvailableJadwal() error = %v", err)
+			}
+			if from := repo.params.Tanggal.Time.Format("2006-01-02"); from != tt.wantFrom {
+				t.Errorf("queried from %s, want %s", from, tt.wantFrom)
+			}
+			var ids []uuid.UUID
+			for _, o := range got {
+				ids = append(ids, uuid.MustParse(o.ID))
+			}
+			if len(ids) != len(tt.wantSesi) {
+				t.Fatalf("GetAvailableJadwal() returned %d sessions, want %d", len(ids), len(tt.wantSesi))
+			}
+			for i := range ids {
+				if ids[i] != tt.wantSesi[i] {
+					t.Errorf("session %d = %s, want %s", i, ids[i], tt.wantSesi[i])
+				}
+			}
+		})
+	}
+}
@@ -2,16 +2,56 @@ package permohonan
 
 // FormData contains user data to pre-fill forms
 type FormData struct {
-	NIK           string
+	// NIK is the citizen the KTP is for
+	NIK string
+	// Untuk is the Ref of the household member applied for, empty when the
+	// user applies for themselves
+	Untuk string
+	// Tanggungan are the household members the user may apply for
+	Tanggungan    []TanggunganOption
 	NamaLengkap   string
 	JenisKelamin  string
 	Alamat        string
 	NoHP          string
 	Email         string
 	NamaKelurahan string
+	// TanggalLahir is formatted for display, "-" when unknown
+	TanggalLahir     string
+	StatusPerkawinan string
+	// SudahPunyaKTP is set when the citizen already received a KTP from a
+	// BARU application; the KTP baru form then asks them to confirm
+	SudahPunyaKTP bool
 	Errors        map[string]string
 }
 
+// TanggunganOption is a household member offered in the "apply for" picker
+type TanggunganOption struct {
+	NIK string
+	// Ref stands in for the NIK in links and form fields
+	Ref      string
+	Nama     string
+	Hubungan string
+}
+
+// tanggungan returns the household member with the given ref
+func (d FormData) tanggungan(ref string) (TanggunganOption, bool) {
+	for _, t := range d.Tanggungan {
+		if t.Ref == ref {
+			return t, true
+		}
+	}
+	return TanggunganOption{}, false
+}
+
+// UntukNIK returns the NIK of the household member applied for, empty when
+// the user applies for themselves
+func (d FormData) UntukNIK() string {
+	if d.Untuk == "" {
+		return ""
+	}
+	return d.NIK
+}
+
 // JadwalOption represents a jadwal option for the select box
 type JadwalOption struct {
 	ID         string
@@ -20,7 +60,10 @@ type JadwalOption struct {
 	StatusSesi string
 }
 
+// LocationOption represents a lokasi layanan for the select box
 type LocationOption struct {
-	ID    int16
-	Label string
+	ID             int16
+	Label          string
+	Alamat         string
+	JamOperasional string // e.g. "08:00 - 15:00"
 }
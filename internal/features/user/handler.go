@@ -5,21 +5,28 @@ import (
 	"net/http"
 
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
 	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/qrcode"
 	"github.com/nobuww/simpel-ktp/internal/store"
 	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+	"github.com/nobuww/simpel-ktp/internal/tiket"
 	"github.com/nobuww/simpel-ktp/ui/components"
 )
 
 // Handler manages user-related HTTP handlers
 type Handler struct {
-	store *store.Store
+	store store.Repository
+	clock clock.Clock
+	tiket *tiket.Signer
 }
 
 // New creates a new user handler with the required dependencies
-func New(s *store.Store) *Handler {
+func New(s store.Repository, clk clock.Clock, signer *tiket.Signer) *Handler {
 	return &Handler{
 		store: s,
+		clock: clk,
+		tiket: signer,
 	}
 }
 
@@ -33,10 +40,10 @@ func (h *Handler) DashboardHandler(w http.ResponseWriter, r *http.Request) {
 	// Convert NIK to pgtype.Text
 	nikText := pgtype.Text{String: user.UserID, Valid: true}
 
-	// Get user's permohonan stats
-	stats, err := h.store.CountPermohonanByNIK(ctx, nikText)
+	// Get user's permohonan stats, including those for household members
+	stats, err := h.store.CountPermohonanByPemohon(ctx, nikText)
 	if err != nil {
-		stats = pg_store.CountPermohonanByNIKRow{}
+		stats = pg_store.CountPermohonanByPemohonRow{}
 	}
 
 	// Get recent permohonan (limit 5)
@@ -60,13 +67,16 @@ func (h *Handler) DashboardHandler(w http.ResponseWriter, r *http.Request) {
 			Selesai:    stats.Selesai,
 			Ditolak:    stats.Ditolak,
 		},
-		RecentPermohonan: convertPermohonanList(permohonanList),
+		RecentPermohonan: h.convertPermohonanList(permohonanList, user.UserID),
 	}
 
 	DashboardPage(data).Render(ctx, w)
 }
 
-func convertPermohonanList(list []pg_store.GetPermohonanByNIKRow) []PermohonanItem {
+// convertPermohonanList maps the rows for the dashboard. Applications for
+// someone other than nikUser carry that household member's name; those still
+// awaiting the session carry the signed QR code for check-in.
+func (h *Handler) convertPermohonanList(list []pg_store.GetPermohonanByNIKRow, nikUser string) []PermohonanItem {
 	items := make([]PermohonanItem, 0, len(list))
 	for _, p := range list {
 		item := PermohonanItem{
@@ -75,6 +85,9 @@ func convertPermohonanList(list []pg_store.GetPermohonanByNIKRow) []PermohonanIt
 			JenisPermohonan: p.JenisPermohonan,
 			StatusTerkini:   p.StatusTerkini.String,
 		}
+		if p.Nik.String != nikUser {
+			item.UntukNama = p.NamaLengkap.String
+		}
 		if p.NomorAntrian.Valid {
 			item.NomorAntrian = int(p.NomorAntrian.Int16)
 		}
@@ -87,11 +100,16 @@ func convertPermohonanList(list []pg_store.GetPermohonanByNIKRow) []PermohonanIt
 			minute := (p.JadwalJamMulai.Microseconds % 3600000000) / 60000000
 			item.JadwalJam = fmt.Sprintf("%02d:%02d", hour, minute)
 		}
-		if p.LokasiKelurahan.Valid {
-			item.LokasiKelurahan = p.LokasiKelurahan.String
+		if p.NamaLokasi.Valid {
+			item.Lokasi = p.NamaLokasi.String
 		}
 		if p.CreatedAt.Valid {
-			item.TanggalDaftar = p.CreatedAt.Time.Format("02 Jan 2006")
+			item.TanggalDaftar = clock.Local(p.CreatedAt.Time).Format("02 Jan 2006")
+		}
+		if menungguSesi(p.StatusTerkini.String, p.WaktuHadir) && p.KodeBooking.Valid {
+			if code, err := qrcode.Encode(h.tiket.Payload(p.KodeBooking.String)); err == nil {
+				item.QRCode = code.SVG()
+			}
 		}
 
 		items = append(items, item)
@@ -99,6 +117,12 @@ func convertPermohonanList(list []pg_store.GetPermohonanByNIKRow) []PermohonanIt
 	return items
 }
 
+// menungguSesi reports whether an application still expects the citizen at
+// its session, which is when the check-in QR code is worth showing
+func menungguSesi(status string, hadir pgtype.Timestamptz) bool {
+	return !hadir.Valid && (status == "VERIFIKASI" || status == "PROSES")
+}
+
 // StatusDetailHandler handles the status tracking detail page
 func (h *Handler) StatusDetailHandler(w http.ResponseWriter, r *http.Request) {
 	user, ok := common.GetUserOrRedirect(w, r, "/login")
@@ -114,7 +138,9 @@ func (h *Handler) StatusDetailHandler(w http.ResponseWriter, r *http.Request) {
 
 	if kode != "" {
 		detail, err = h.store.GetPermohonanByKodeBooking(ctx, pgtype.Text{String: kode, Valid: true})
-		if err != nil {
+		// Someone else's application is reported as missing so that booking
+		// codes cannot be probed
+		if err != nil || !milikWarga(detail, user.UserID) {
 			common.WriteNotFound(w, "Permohonan tidak ditemukan")
 			return
 		}
@@ -153,12 +179,18 @@ func (h *Handler) StatusDetailHandler(w http.ResponseWriter, r *http.Request) {
 		NextSteps:       determineNextSteps(detail),
 	}
 	if detail.TanggalDaftar.Valid {
-		data.TanggalDaftar = detail.TanggalDaftar.Time.Format("02 Jan 2006")
+		data.TanggalDaftar = clock.Local(detail.TanggalDaftar.Time).Format("02 Jan 2006")
 	}
 
 	StatusDetailPage(data).Render(ctx, w)
 }
 
+// milikWarga reports whether the citizen may view the application: it is
+// their own, or they submitted it for a household member
+func milikWarga(p pg_store.GetPermohonanByKodeBookingRow, nikUser string) bool {
+	return p.Nik.String == nikUser || p.PemohonNik.String == nikUser
+}
+
 func calculateStages(p pg_store.GetPermohonanByKodeBookingRow) ([]components.TrackerStage, int) {
 	stages := []components.TrackerStage{
 		{ID: "1", Name: "Pengajuan", Description: "Permohonan diterima", Status: components.StatusPending},
@@ -169,7 +201,7 @@ func calculateStages(p pg_store.GetPermohonanByKodeBookingRow) ([]components.Tra
 	}
 
 	if p.TanggalDaftar.Valid {
-		stages[0].StartDate = p.TanggalDaftar.Time.Format("02 Jan 2006, 15:04")
+		stages[0].StartDate = clock.Local(p.TanggalDaftar.Time).Format("02 Jan 2006, 15:04")
 	}
 
 	status := ""
@@ -248,5 +280,17 @@ func determineNextSteps(p pg_store.GetPermohonanByKodeBookingRow) []NextStep {
 		})
 	}
 
+	// Until the photo is taken the citizen still has to come to the session
+	// with the receipt
+	if status == "VERIFIKASI" || status == "PROSES" {
+		steps = append(steps, NextStep{
+			Title:       "Bawa Bukti Booking",
+			Description: "Tunjukkan bukti booking beserta dokumen asli kepada petugas saat datang ke sesi.",
+			IsPrimary:   true,
+			ActionURL:   "/permohonan/bukti?id=" + p.ID.String(),
+			ActionLabel: "Unduh Bukti Booking (PDF)",
+		})
+	}
+
 	return steps
 }
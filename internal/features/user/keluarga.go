@@ -0,0 +1,424 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/nik"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// hubunganKeluarga lists the relationships on a Kartu Keluarga (values of
+// penduduk.hubungan_keluarga) in the order the forms show them
+var hubunganKeluarga = []Option{
+	{Value: "KEPALA_KELUARGA", Label: "Kepala Keluarga"},
+	{Value: "SUAMI", Label: "Suami"},
+	{Value: "ISTRI", Label: "Istri"},
+	{Value: "ANAK", Label: "Anak"},
+	{Value: "MENANTU", Label: "Menantu"},
+	{Value: "CUCU", Label: "Cucu"},
+	{Value: "ORANG_TUA", Label: "Orang Tua"},
+	{Value: "MERTUA", Label: "Mertua"},
+	{Value: "FAMILI_LAIN", Label: "Famili Lain"},
+	{Value: "LAINNYA", Label: "Lainnya"},
+}
+
+var statusPerkawinan = []Option{
+	{Value: "BELUM_KAWIN", Label: "Belum Kawin"},
+	{Value: "KAWIN", Label: "Kawin"},
+	{Value: "CERAI_HIDUP", Label: "Cerai Hidup"},
+	{Value: "CERAI_MATI", Label: "Cerai Mati"},
+}
+
+// keluargaPesan are the messages shown after a successful change, keyed by
+// the status query parameter of the redirect
+var keluargaPesan = map[string]string{
+	"terhubung":   "Akun Anda berhasil dihubungkan ke Kartu Keluarga.",
+	"menunggu":    "Akun Anda berhasil dihubungkan ke Kartu Keluarga. Petugas kelurahan akan memverifikasi hubungan Anda dengan KK sebelum Anda dapat mengelola anggota keluarga.",
+	"ditambahkan": "Anggota keluarga berhasil ditambahkan.",
+	"diajukan":    "Anggota keluarga sudah terdaftar sebagai penduduk. Petugas kelurahan akan memverifikasinya sebelum ia masuk ke Kartu Keluarga Anda.",
+}
+
+// isWaliHubungan reports whether a member with this relationship becomes a
+// guardian of the household once a petugas verifies it. A KK has at most one
+// member in each of these relationships.
+func isWaliHubungan(hubungan string) bool {
+	return hubungan == "KEPALA_KELUARGA" || hubungan == "SUAMI" || hubungan == "ISTRI"
+}
+
+// isWali reports whether the citizen is a verified guardian of their
+// household, who may add members and apply on their behalf. It must agree
+// with the ListTanggunganKeluarga query.
+func isWali(p pg_store.Penduduk) bool {
+	return p.NoKk.Valid && isWaliHubungan(p.HubunganKeluarga.String) && p.WaliDisetujuiAt.Valid
+}
+
+// samaNama compares two names ignoring case and spacing
+func samaNama(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
+
+func optionLabel(options []Option, value string) (string, bool) {
+	for _, o := range options {
+		if o.Value == value {
+			return o.Label, true
+		}
+	}
+	return "", false
+}
+
+func formatHubungan(hubungan string) string {
+	if label, ok := optionLabel(hubunganKeluarga, hubungan); ok {
+		return label
+	}
+	return hubungan
+}
+
+// KeluargaHandler shows the citizen's Kartu Keluarga, or a form to link one
+func (h *Handler) KeluargaHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/login")
+	if !ok {
+		return
+	}
+	h.renderKeluarga(w, r, user.UserID, user.UserName, keluargaPesan[r.URL.Query().Get("status")], "")
+}
+
+// LinkKKHandler links the logged-in citizen to a Kartu Keluarga,
+// registering the KK when no other member has done so yet. Joining a KK that
+// already has members takes the NIK of one of them, and a guardian's place in
+// the KK is verified by a petugas before it grants guardianship.
+func (h *Handler) LinkKKHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	noKK := strings.TrimSpace(r.FormValue("no_kk"))
+	hubungan := r.FormValue("hubungan_keluarga")
+	nikAnggota := strings.TrimSpace(r.FormValue("nik_anggota"))
+
+	fail := func(msg string) {
+		h.renderKeluarga(w, r, user.UserID, user.UserName, "", msg)
+	}
+
+	penduduk, err := h.store.GetPendudukByNIK(ctx, user.UserID)
+	if err != nil {
+		fail("Gagal memuat data akun")
+		return
+	}
+	if penduduk.NoKk.Valid {
+		fail("Akun Anda sudah terhubung ke Kartu Keluarga")
+		return
+	}
+	if !validNoKK(noKK) {
+		fail("Nomor KK harus 16 digit angka")
+		return
+	}
+	if _, ok := optionLabel(hubunganKeluarga, hubungan); !ok {
+		fail("Hubungan keluarga tidak valid")
+		return
+	}
+
+	anggota, err := h.store.ListAnggotaKeluarga(ctx, pgtype.Text{String: noKK, Valid: true})
+	if err != nil {
+		fail("Gagal memuat data Kartu Keluarga")
+		return
+	}
+	if len(anggota) > 0 && !hasAnggota(anggota, nikAnggota) {
+		fail("NIK anggota keluarga tidak cocok dengan Kartu Keluarga ini")
+		return
+	}
+	if hubunganTerisi(anggota, hubungan) {
+		fail("Kartu Keluarga ini sudah memiliki " + strings.ToLower(formatHubungan(hubungan)))
+		return
+	}
+
+	err = h.store.ExecTx(ctx, func(q *pg_store.Queries) error {
+		if err := q.CreateKartuKeluarga(ctx, pg_store.CreateKartuKeluargaParams{
+			NoKk:        noKK,
+			KelurahanID: penduduk.KelurahanID,
+			Alamat:      penduduk.Alamat,
+		}); err != nil {
+			return err
+		}
+		return q.SetKeluargaPenduduk(ctx, pg_store.SetKeluargaPendudukParams{
+			Nik:              user.UserID,
+			NoKk:             pgtype.Text{String: noKK, Valid: true},
+			HubunganKeluarga: pgtype.Text{String: hubungan, Valid: true},
+		})
+	})
+	if isUniqueViolation(err) {
+		fail("Kartu Keluarga ini sudah memiliki " + strings.ToLower(formatHubungan(hubungan)))
+		return
+	}
+	if err != nil {
+		fail("Gagal menghubungkan Kartu Keluarga. Silakan coba lagi.")
+		return
+	}
+
+	status := "terhubung"
+	if isWaliHubungan(hubungan) {
+		status = "menunggu"
+	}
+	http.Redirect(w, r, "/keluarga?status="+status, http.StatusSeeOther)
+}
+
+// CreateAnggotaHandler lets a guardian add a household member. Members without
+// a record get one without a password, which they can claim later through
+// the password reset flow; members who already have an account link the KK
+// themselves. A member already on record without an account, such as a
+// walk-in entered by a petugas, is only linked after a petugas approves the
+// request, and the typed name has to match the record. A head of household
+// or spouse added here still needs a petugas's verification to become a
+// guardian.
+func (h *Handler) CreateAnggotaHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := common.GetUserOrRedirect(w, r, "/login")
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	nikAnggota := strings.TrimSpace(r.FormValue("nik"))
+	namaLengkap := strings.TrimSpace(r.FormValue("nama_lengkap"))
+	jenisKelamin := r.FormValue("jenis_kelamin")
+	status := r.FormValue("status_perkawinan")
+	hubungan := r.FormValue("hubungan_keluarga")
+
+	fail := func(msg string) {
+		h.renderKeluarga(w, r, user.UserID, user.UserName, "", msg)
+	}
+
+	wali, err := h.store.GetPendudukByNIK(ctx, user.UserID)
+	if err != nil {
+		fail("Gagal memuat data akun")
+		return
+	}
+	if !isWali(wali) {
+		common.WriteError(w, http.StatusForbidden, "Hanya kepala keluarga atau pasangannya yang dapat menambah anggota keluarga")
+		return
+	}
+	noKK := wali.NoKk.String
+
+	if namaLengkap == "" {
+		fail("Nama lengkap harus diisi")
+		return
+	}
+	if jenisKelamin != nik.LakiLaki && jenisKelamin != nik.Perempuan {
+		fail("Jenis kelamin tidak valid")
+		return
+	}
+	if _, ok := optionLabel(statusPerkawinan, status); !ok {
+		fail("Status perkawinan tidak valid")
+		return
+	}
+	if _, ok := optionLabel(hubunganKeluarga, hubungan); !ok {
+		fail("Hubungan keluarga tidak valid")
+		return
+	}
+	// Members may have been registered in another kecamatan, so only the
+	// structure and gender are checked
+	parsed, err := nik.Parse(nikAnggota, h.clock.Now())
+	if err == nil {
+		err = parsed.Check(jenisKelamin)
+	}
+	if err != nil {
+		fail(nik.Message(err))
+		return
+	}
+	anggota, err := h.store.ListAnggotaKeluarga(ctx, wali.NoKk)
+	if err != nil {
+		fail("Gagal memuat data Kartu Keluarga")
+		return
+	}
+	if hubunganTerisi(anggota, hubungan) {
+		fail("Kartu Keluarga ini sudah memiliki " + strings.ToLower(formatHubungan(hubungan)))
+		return
+	}
+
+	kkText := pgtype.Text{String: noKK, Valid: true}
+	hubunganText := pgtype.Text{String: hubungan, Valid: true}
+
+	existing, err := h.store.GetPendudukByNIK(ctx, nikAnggota)
+	switch {
+	case err == nil:
+		if existing.PasswordHash.Valid && existing.PasswordHash.String != "" {
+			fail("NIK ini sudah memiliki akun. Minta anggota tersebut menghubungkan Kartu Keluarga dari akunnya sendiri.")
+			return
+		}
+		if existing.NoKk.Valid {
+			if existing.NoKk.String == noKK {
+				fail("NIK ini sudah menjadi anggota keluarga Anda")
+			} else {
+				fail("NIK ini terdaftar di Kartu Keluarga lain")
+			}
+			return
+		}
+		if !samaNama(existing.NamaLengkap, namaLengkap) {
+			fail("Nama lengkap tidak sesuai dengan data penduduk untuk NIK ini")
+			return
+		}
+		// The record is only linked once a petugas has checked it against
+		// the KK
+		var n int64
+		n, err = h.store.CreatePermintaanAnggota(ctx, pg_store.CreatePermintaanAnggotaParams{
+			Nik:              nikAnggota,
+			NoKk:             noKK,
+			HubunganKeluarga: hubungan,
+			DiajukanOleh:     wali.Nik,
+		})
+		if err == nil && n == 0 {
+			fail("NIK ini sudah diajukan sebagai anggota keluarga dan sedang menunggu verifikasi petugas")
+			return
+		}
+		if err == nil {
+			http.Redirect(w, r, "/keluarga?status=diajukan", http.StatusSeeOther)
+			return
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		var kk pg_store.KartuKeluarga
+		kk, err = h.store.GetKartuKeluarga(ctx, noKK)
+		if err != nil {
+			break
+		}
+		err = h.store.CreateAnggotaKeluarga(ctx, pg_store.CreateAnggotaKeluargaParams{
+			Nik:              nikAnggota,
+			KelurahanID:      kk.KelurahanID,
+			NamaLengkap:      namaLengkap,
+			Alamat:           kk.Alamat,
+			JenisKelamin:     jenisKelamin,
+			TanggalLahir:     pgtype.Date{Time: parsed.TanggalLahir, Valid: true},
+			StatusPerkawinan: status,
+			NoKk:             kkText,
+			HubunganKeluarga: hubunganText,
+		})
+	}
+	if isUniqueViolation(err) {
+		fail("Kartu Keluarga ini sudah memiliki " + strings.ToLower(formatHubungan(hubungan)))
+		return
+	}
+	if err != nil {
+		fail("Gagal menambah anggota keluarga. Silakan coba lagi.")
+		return
+	}
+
+	http.Redirect(w, r, "/keluarga?status=ditambahkan", http.StatusSeeOther)
+}
+
+func (h *Handler) renderKeluarga(w http.ResponseWriter, r *http.Request, nikUser, userName, pesan, errMsg string) {
+	ctx := r.Context()
+
+	penduduk, err := h.store.GetPendudukByNIK(ctx, nikUser)
+	if err != nil {
+		http.Error(w, "Gagal memuat data keluarga", http.StatusInternalServerError)
+		return
+	}
+
+	data := KeluargaPageData{
+		UserName:         userName,
+		Pesan:            pesan,
+		Error:            errMsg,
+		HubunganOptions:  hubunganKeluarga,
+		StatusPerkawinan: statusPerkawinan,
+	}
+	if penduduk.NoKk.Valid {
+		data.NoKK = penduduk.NoKk.String
+		data.Hubungan = formatHubungan(penduduk.HubunganKeluarga.String)
+		data.Wali = isWali(penduduk)
+		data.MenungguVerifikasi = isWaliHubungan(penduduk.HubunganKeluarga.String) && !data.Wali
+
+		rows, err := h.store.ListAnggotaKeluarga(ctx, penduduk.NoKk)
+		if err != nil {
+			http.Error(w, "Gagal memuat data keluarga", http.StatusInternalServerError)
+			return
+		}
+		for _, a := range rows {
+			item := AnggotaItem{
+				NIK:       maskNIK(a.Nik),
+				Nama:      a.NamaLengkap,
+				Hubungan:  formatHubungan(a.HubunganKeluarga.String),
+				PunyaAkun: a.PunyaAkun,
+				Diri:      a.Nik == nikUser,
+			}
+			item.MenungguVerifikasi = isWaliHubungan(a.HubunganKeluarga.String) && !a.WaliDisetujui
+			if a.TanggalLahir.Valid {
+				item.TanggalLahir = a.TanggalLahir.Time.Format("02 Jan 2006")
+			}
+			if data.Wali && !item.Diri {
+				item.AjukanURL = "/permohonan/baru?untuk=" + h.tiket.Ref(a.Nik)
+			}
+			data.Anggota = append(data.Anggota, item)
+		}
+
+		// Citizens on record whose link a petugas has yet to approve
+		permintaan, err := h.store.ListPermintaanAnggotaByKK(ctx, penduduk.NoKk.String)
+		if err != nil {
+			http.Error(w, "Gagal memuat data keluarga", http.StatusInternalServerError)
+			return
+		}
+		for _, p := range permintaan {
+			item := AnggotaItem{
+				NIK:                maskNIK(p.Nik),
+				Nama:               p.NamaLengkap,
+				Hubungan:           formatHubungan(p.HubunganKeluarga),
+				MenungguVerifikasi: true,
+			}
+			if p.TanggalLahir.Valid {
+				item.TanggalLahir = p.TanggalLahir.Time.Format("02 Jan 2006")
+			}
+			data.Anggota = append(data.Anggota, item)
+		}
+	}
+
+	KeluargaPage(data).Render(ctx, w)
+}
+
+// hasAnggota reports whether nik is a member of the KK
+func hasAnggota(anggota []pg_store.ListAnggotaKeluargaRow, nik string) bool {
+	for _, a := range anggota {
+		if a.Nik == nik {
+			return true
+		}
+	}
+	return false
+}
+
+// hubunganTerisi reports whether a relationship held by one member only, the
+// head of household or a spouse, is already taken in the KK
+func hubunganTerisi(anggota []pg_store.ListAnggotaKeluargaRow, hubungan string) bool {
+	if !isWaliHubungan(hubungan) {
+		return false
+	}
+	for _, a := range anggota {
+		if a.HubunganKeluarga.String == hubungan {
+			return true
+		}
+	}
+	return false
+}
+
+// isUniqueViolation reports whether err comes from a unique index, here one
+// of the indexes allowing a single head of household, husband and wife per KK
+// when two members claim the place at once
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// validNoKK checks that a KK number has 16 digits
+func validNoKK(s string) bool {
+	if len(s) != 16 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
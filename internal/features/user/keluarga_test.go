@@ -0,0 +1,121 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/middleware"
+	"github.com/nobuww/simpel-ktp/internal/nik"
+	"github.com/nobuww/simpel-ktp/internal/session"
+	"github.com/nobuww/simpel-ktp/internal/store"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+	"github.com/nobuww/simpel-ktp/internal/tiket"
+)
+
+const (
+	nikWali   = "3172050101800001"
+	nikWalkIn = "3172051503100004"
+	noKKWali  = "3172050000000001"
+)
+
+// keluargaStore holds a verified guardian and a walk-in record without a
+// password or KK. Linking the walk-in directly panics through the embedded
+// nil Repository.
+type keluargaStore struct {
+	store.Repository
+	permintaan []pg_store.CreatePermintaanAnggotaParams
+}
+
+func (s *keluargaStore) GetPendudukByNIK(ctx context.Context, nik string) (pg_store.Penduduk, error) {
+	switch nik {
+	case nikWali:
+		return pg_store.Penduduk{
+			Nik:              nikWali,
+			NamaLengkap:      "Budi Santoso",
+			NoKk:             pgtype.Text{String: noKKWali, Valid: true},
+			HubunganKeluarga: pgtype.Text{String: "KEPALA_KELUARGA", Valid: true},
+			WaliDisetujuiAt:  pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		}, nil
+	case nikWalkIn:
+		return pg_store.Penduduk{Nik: nikWalkIn, NamaLengkap: "Rizki Pratama"}, nil
+	}
+	return pg_store.Penduduk{}, pgx.ErrNoRows
+}
+
+func (s *keluargaStore) ListAnggotaKeluarga(ctx context.Context, noKk pgtype.Text) ([]pg_store.ListAnggotaKeluargaRow, error) {
+	return []pg_store.ListAnggotaKeluargaRow{{
+		Nik:              nikWali,
+		NamaLengkap:      "Budi Santoso",
+		HubunganKeluarga: pgtype.Text{String: "KEPALA_KELUARGA", Valid: true},
+		PunyaAkun:        true,
+		WaliDisetujui:    true,
+	}}, nil
+}
+
+func (s *keluargaStore) ListPermintaanAnggotaByKK(ctx context.Context, noKk string) ([]pg_store.ListPermintaanAnggotaByKKRow, error) {
+	return nil, nil
+}
+
+func (s *keluargaStore) CreatePermintaanAnggota(ctx context.Context, arg pg_store.CreatePermintaanAnggotaParams) (int64, error) {
+	for _, p := range s.permintaan {
+		if p.Nik == arg.Nik {
+			return 0, nil
+		}
+	}
+	s.permintaan = append(s.permintaan, arg)
+	return 1, nil
+}
+
+func postAnggota(h *Handler, nama string) *httptest.ResponseRecorder {
+	form := url.Values{
+		"nik":               {nikWalkIn},
+		"nama_lengkap":      {nama},
+		"jenis_kelamin":     {nik.LakiLaki},
+		"status_perkawinan": {"BELUM_KAWIN"},
+		"hubungan_keluarga": {"ANAK"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/keluarga/anggota", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	user := &session.UserSession{UserID: nikWali, UserType: session.UserTypeWarga, UserName: "Budi Santoso"}
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+	rec := httptest.NewRecorder()
+	h.CreateAnggotaHandler(rec, req)
+	return rec
+}
+
+func TestCreateAnggotaExistingRecordNeedsApproval(t *testing.T) {
+	st := &keluargaStore{}
+	h := New(st, clock.Fixed(time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC)), tiket.NewSigner([]byte("test")))
+
+	// The guardian typed a NIK they found somewhere, with another name
+	rec := postAnggota(h, "Orang Lain")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Nama lengkap tidak sesuai") {
+		t.Fatalf("mismatched name: status = %d, want the form with an error", rec.Code)
+	}
+	if len(st.permintaan) != 0 {
+		t.Fatalf("mismatched name created a link request: %+v", st.permintaan)
+	}
+
+	// Case and spacing do not matter, but the link still waits for a petugas
+	rec = postAnggota(h, "  rizki   PRATAMA ")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/keluarga?status=diajukan" {
+		t.Fatalf("matching name: status = %d, location %q; want a redirect to status=diajukan", rec.Code, rec.Header().Get("Location"))
+	}
+	want := pg_store.CreatePermintaanAnggotaParams{Nik: nikWalkIn, NoKk: noKKWali, HubunganKeluarga: "ANAK", DiajukanOleh: nikWali}
+	if len(st.permintaan) != 1 || st.permintaan[0] != want {
+		t.Fatalf("link requests = %+v, want %+v", st.permintaan, want)
+	}
+
+	rec = postAnggota(h, "Rizki Pratama")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "menunggu verifikasi petugas") {
+		t.Errorf("second request: status = %d, want the form with an error", rec.Code)
+	}
+}
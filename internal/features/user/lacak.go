@@ -0,0 +1,206 @@
+package user
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/features/common"
+	"github.com/nobuww/simpel-ktp/internal/nik"
+	"github.com/nobuww/simpel-ktp/internal/session"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+const (
+	// Failed lookups allowed from one IP address within lacakWindowMinutes
+	lacakMaxGagalIP    = 10
+	lacakWindowMinutes = 60
+
+	// Guesses at one booking code from one address slow down after
+	// lacakBebasGagal failures within lacakJedaWindowMinutes: each further
+	// guess waits lacakJedaAwal, doubled per failure up to lacakJedaMaks.
+	// The code itself is never locked, so guessing from elsewhere cannot
+	// keep its owner out.
+	lacakBebasGagal        = 3
+	lacakJedaAwal          = time.Minute
+	lacakJedaMaks          = time.Hour
+	lacakJedaWindowMinutes = 24 * 60
+)
+
+const (
+	pesanLacakGagal    = "Kode booking atau data verifikasi tidak cocok"
+	pesanLacakDibatasi = "Terlalu banyak percobaan. Silakan coba lagi dalam %d menit."
+)
+
+// PublicStatusHandler lets anyone track an application without logging in,
+// given the booking code and either the last 4 digits of the applicant's NIK
+// or their date of birth. The result leaves out the NIK and contact details
+// and shows only the initials of the name.
+func (h *Handler) PublicStatusHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPost {
+		LacakPublikPage(LacakPublikData{}).Render(ctx, w)
+		return
+	}
+
+	kode := strings.ToUpper(strings.TrimSpace(r.FormValue("kode")))
+	nik4 := strings.TrimSpace(r.FormValue("nik4"))
+	lahir := strings.TrimSpace(r.FormValue("tanggal_lahir"))
+	ip := session.ClientIP(r)
+
+	data := LacakPublikData{Kode: kode}
+	if kode == "" || (nik4 == "" && lahir == "") {
+		data.Error = "Isi kode booking dan 4 digit terakhir NIK atau tanggal lahir"
+		w.WriteHeader(http.StatusBadRequest)
+		LacakPublikPage(data).Render(ctx, w)
+		return
+	}
+
+	tunggu, err := h.checkLacakLimit(ctx, kode, ip)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, "Gagal memproses permintaan")
+		return
+	}
+	if tunggu > 0 {
+		data.Error = fmt.Sprintf(pesanLacakDibatasi, int(math.Ceil(tunggu.Minutes())))
+		w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(tunggu.Seconds()))))
+		w.WriteHeader(http.StatusTooManyRequests)
+		LacakPublikPage(data).Render(ctx, w)
+		return
+	}
+
+	// Unknown codes and wrong second factors get the same answer so that
+	// booking codes cannot be probed
+	detail, err := h.store.GetPermohonanByKodeBooking(ctx, pgtype.Text{String: kode, Valid: true})
+	ok := err == nil && verifyPemohon(detail, nik4, lahir, h.clock.Now())
+	h.recordLacak(ctx, kode, ip, ok)
+	if !ok {
+		data.Error = pesanLacakGagal
+		LacakPublikPage(data).Render(ctx, w)
+		return
+	}
+
+	stages, currentIdx := calculateStages(detail)
+	data.Hasil = &LacakPublikHasil{
+		KodeBooking:     detail.KodeBooking.String,
+		JenisPermohonan: detail.JenisPermohonan,
+		Nama:            inisialNama(detail.NamaLengkap.String),
+		Stages:          stages,
+		CurrentStageIdx: currentIdx,
+	}
+	if detail.TanggalDaftar.Valid {
+		data.Hasil.TanggalDaftar = clock.Local(detail.TanggalDaftar.Time).Format("02 Jan 2006")
+	}
+	if detail.JadwalTanggal.Valid {
+		data.Hasil.Jadwal = detail.JadwalTanggal.Time.Format("02 Jan 2006")
+	}
+	if detail.NamaLokasi.Valid {
+		data.Hasil.Lokasi = detail.NamaLokasi.String
+	}
+
+	LacakPublikPage(data).Render(ctx, w)
+}
+
+// checkLacakLimit returns how long the address has to wait before its next
+// lookup, or zero when it may look up the code now
+func (h *Handler) checkLacakLimit(ctx context.Context, kode, ip string) (time.Duration, error) {
+	ipText := pgtype.Text{String: ip, Valid: true}
+	gagalIP, err := h.store.CountGagalLacakByIP(ctx, pg_store.CountGagalLacakByIPParams{
+		IpAddress: ipText,
+		Minutes:   lacakWindowMinutes,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if gagalIP >= lacakMaxGagalIP {
+		return lacakWindowMinutes * time.Minute, nil
+	}
+
+	gagal, err := h.store.GetGagalLacakByIPKode(ctx, pg_store.GetGagalLacakByIPKodeParams{
+		KodeBooking: kode,
+		IpAddress:   ipText,
+		Minutes:     lacakJedaWindowMinutes,
+	})
+	if err != nil || gagal.Terakhir.IsZero() {
+		return 0, err
+	}
+	sisa := gagal.Terakhir.Add(lacakJeda(gagal.Gagal)).Sub(h.clock.Now())
+	return max(sisa, 0), nil
+}
+
+// lacakJeda returns the wait after the given number of failed guesses at a
+// code from one address
+func lacakJeda(gagal int64) time.Duration {
+	if gagal < lacakBebasGagal {
+		return 0
+	}
+	jeda := lacakJedaAwal
+	for i := int64(lacakBebasGagal); i < gagal && jeda < lacakJedaMaks; i++ {
+		jeda *= 2
+	}
+	return min(jeda, lacakJedaMaks)
+}
+
+// recordLacak writes the lookup for rate limiting. It is best effort, like
+// the login audit.
+func (h *Handler) recordLacak(ctx context.Context, kode, ip string, berhasil bool) {
+	_ = h.store.InsertPercobaanLacak(ctx, pg_store.InsertPercobaanLacakParams{
+		KodeBooking: kode,
+		IpAddress:   pgtype.Text{String: ip, Valid: ip != ""},
+		Berhasil:    berhasil,
+	})
+}
+
+// verifyPemohon checks the second factor against the applicant. Every
+// factor that was filled in has to match.
+func verifyPemohon(p pg_store.GetPermohonanByKodeBookingRow, nik4, lahir string, today time.Time) bool {
+	nikWarga := p.Nik.String
+	if len(nikWarga) != 16 {
+		return false
+	}
+	if nik4 != "" && subtle.ConstantTimeCompare([]byte(nik4), []byte(nikWarga[12:])) != 1 {
+		return false
+	}
+	if lahir != "" {
+		tgl, err := time.Parse("2006-01-02", lahir)
+		if err != nil {
+			return false
+		}
+		stored, ok := tanggalLahirPenduduk(nikWarga, p.TanggalLahir, today)
+		if !ok || !stored.Equal(tgl) {
+			return false
+		}
+	}
+	return true
+}
+
+// tanggalLahirPenduduk returns the stored date of birth, falling back to the
+// one encoded in the NIK
+func tanggalLahirPenduduk(nikWarga string, stored pgtype.Date, today time.Time) (time.Time, bool) {
+	if stored.Valid {
+		return time.Date(stored.Time.Year(), stored.Time.Month(), stored.Time.Day(), 0, 0, 0, 0, time.UTC), true
+	}
+	parsed, err := nik.Parse(nikWarga, today)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t := parsed.TanggalLahir
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), true
+}
+
+// inisialNama shortens every word of a name to its first letter, e.g.
+// "Ahmad Supriyadi" becomes "A*** S***"
+func inisialNama(nama string) string {
+	words := strings.Fields(nama)
+	for i, w := range words {
+		r := []rune(w)
+		words[i] = string(r[0]) + "***"
+	}
+	return strings.Join(words, " ")
+}
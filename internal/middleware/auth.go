@@ -3,7 +3,9 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
 
+	"github.com/nobuww/simpel-ktp/internal/policy"
 	"github.com/nobuww/simpel-ktp/internal/session"
 )
 
@@ -22,11 +24,13 @@ func NewAuth(sm *session.Manager) *Auth {
 }
 
 // InjectUser adds the current user session to the request context
-// This middleware should be applied to all routes for template access
+// This middleware should be applied to all routes for template access.
+// Sessions live in the database, so revoked sessions are rejected here immediately.
+// Sessions still waiting for their second factor are left out.
 func (a *Auth) InjectUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userSession := a.Session.GetSession(r)
-		if userSession != nil {
+		if userSession != nil && userSession.Tahap2FA != session.Tahap2FAVerifikasi {
 			ctx := context.WithValue(r.Context(), UserContextKey, userSession)
 			r = r.WithContext(ctx)
 		}
@@ -38,7 +42,8 @@ func (a *Auth) InjectUser(next http.Handler) http.Handler {
 // Redirects to /login if not authenticated or not a warga
 func (a *Auth) RequireWarga(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !a.Session.IsWarga(r) {
+		user := GetUserFromContext(r.Context())
+		if user == nil || user.UserType != session.UserTypeWarga {
 			// Check if HTMX request
 			if r.Header.Get("HX-Request") == "true" {
 				w.Header().Set("HX-Redirect", "/login")
@@ -53,10 +58,12 @@ func (a *Auth) RequireWarga(next http.Handler) http.Handler {
 }
 
 // RequirePetugas ensures the user is authenticated as a petugas (officer)
-// Redirects to /petugas/login if not authenticated or not a petugas
+// Redirects to /petugas/login if not authenticated or not a petugas.
+// A petugas who must enrol in 2FA is kept on /admin/2fa until done.
 func (a *Auth) RequirePetugas(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !a.Session.IsPetugas(r) {
+		user := GetUserFromContext(r.Context())
+		if user == nil || user.UserType != session.UserTypePetugas {
 			// Check if HTMX request
 			if r.Header.Get("HX-Request") == "true" {
 				w.Header().Set("HX-Redirect", "/petugas/login")
@@ -66,15 +73,39 @@ func (a *Auth) RequirePetugas(next http.Handler) http.Handler {
 			http.Redirect(w, r, "/petugas/login", http.StatusSeeOther)
 			return
 		}
+		if user.Tahap2FA == session.Tahap2FAPendaftaran && !strings.HasPrefix(r.URL.Path, "/admin/2fa") {
+			if r.Header.Get("HX-Request") == "true" {
+				w.Header().Set("HX-Redirect", "/admin/2fa")
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			http.Redirect(w, r, "/admin/2fa", http.StatusSeeOther)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// RequirePermission ensures the petugas holds the given permission.
+// Must be applied after RequirePetugas; responds 403 when the permission is missing
+func (a *Auth) RequirePermission(perm policy.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !policy.Can(GetUserFromContext(r.Context()), perm, nil) {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`<div class="text-center py-8 text-red-500"><p>Anda tidak memiliki akses untuk tindakan ini</p></div>`))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RequireAuth ensures the user is authenticated (either warga or petugas)
 // Redirects to /login if not authenticated
 func (a *Auth) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !a.Session.IsAuthenticated(r) {
+		if GetUserFromContext(r.Context()) == nil {
 			// Check if HTMX request
 			if r.Header.Get("HX-Request") == "true" {
 				w.Header().Set("HX-Redirect", "/login")
@@ -91,7 +122,7 @@ func (a *Auth) RequireAuth(next http.Handler) http.Handler {
 // RedirectIfAuthenticated redirects authenticated users away from login/register pages
 func (a *Auth) RedirectIfAuthenticated(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userSession := a.Session.GetSession(r)
+		userSession := GetUserFromContext(r.Context())
 		if userSession != nil {
 			redirectPath := "/"
 			if userSession.UserType == session.UserTypePetugas {
@@ -112,3 +143,9 @@ func GetUserFromContext(ctx context.Context) *session.UserSession {
 	}
 	return user
 }
+
+// Can reports whether the user in ctx holds perm, for templates that hide
+// actions the user may not perform
+func Can(ctx context.Context, perm policy.Permission) bool {
+	return policy.Can(GetUserFromContext(ctx), perm, nil)
+}
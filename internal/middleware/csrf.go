@@ -3,9 +3,13 @@ package middleware
 import (
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"net/http"
 	"net/url"
+	"os"
+	"strings"
 	"time"
 )
 
@@ -19,16 +23,52 @@ const (
 	CSRFFormName   = "csrf.Token"
 )
 
-func CSRF(next http.Handler) http.Handler {
+const (
+	// MaxFormBytes caps the body of every state-changing request. The
+	// largest forms carry two uploads of up to 10MB each.
+	MaxFormBytes = 21 << 20
+	// MaxFormMemory is how much of a multipart form is kept in memory; the
+	// rest of the uploads goes to temporary files
+	MaxFormMemory = 10 << 20
+)
+
+// CSRF rejects state-changing requests that do not carry the expected token
+// or come from an untrusted origin. Logged-in users use the token stored with
+// their session, so it changes on every login and dies with the session;
+// anonymous visitors (login and registration forms) get a token in a cookie.
+type CSRF struct {
+	secure  bool
+	origins map[string]bool
+}
+
+// NewCSRF creates the middleware. Requests from the server's own host are
+// always accepted; CSRF_TRUSTED_ORIGINS adds a comma-separated list of other
+// hosts or origins, e.g. "localhost:8080,https://simpel-ktp.example.go.id".
+func NewCSRF(secure bool) *CSRF {
+	origins := make(map[string]bool)
+	for _, o := range strings.Split(os.Getenv("CSRF_TRUSTED_ORIGINS"), ",") {
+		if host := originHost(strings.TrimSpace(o)); host != "" {
+			origins[host] = true
+		}
+	}
+	return &CSRF{secure: secure, origins: origins}
+}
+
+// Protect must run after Auth.InjectUser so that the session token is known
+func (c *CSRF) Protect(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := ""
-		if cookie, err := r.Cookie(CSRFCookieName); err == nil {
-			token = cookie.Value
+		if user := GetUserFromContext(r.Context()); user != nil {
+			token = user.CSRFToken
 		}
-
 		if token == "" {
-			token = generateRandomToken()
-			setCSRFCookie(w, token)
+			if cookie, err := r.Cookie(CSRFCookieName); err == nil {
+				token = cookie.Value
+			}
+			if token == "" {
+				token = generateRandomToken()
+				c.setCookie(w, token)
+			}
 		}
 
 		ctx := context.WithValue(r.Context(), CSRFTokenKey, token)
@@ -38,18 +78,30 @@ func CSRF(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
-		if !isValidOrigin(r) {
-			http.Error(w, "Origin/Referer invalid", http.StatusForbidden)
+		if !c.isValidOrigin(r) {
+			http.Error(w, "Asal permintaan tidak valid", http.StatusForbidden)
 			return
 		}
 
+		r.Body = http.MaxBytesReader(w, r.Body, MaxFormBytes)
 		requestToken := r.Header.Get(CSRFHeaderName)
 		if requestToken == "" {
-			requestToken = r.FormValue(CSRFFormName)
+			var err error
+			requestToken, err = formToken(r)
+			// r is a copy of the server's request, which therefore does not
+			// clean up the files of a form parsed here
+			if r.MultipartForm != nil {
+				defer r.MultipartForm.RemoveAll()
+			}
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Ukuran formulir melebihi batas", http.StatusRequestEntityTooLarge)
+				return
+			}
 		}
 
-		if requestToken == "" || requestToken != token {
-			http.Error(w, "CSRF token mismatch", http.StatusForbidden)
+		if requestToken == "" || subtle.ConstantTimeCompare([]byte(requestToken), []byte(token)) != 1 {
+			http.Error(w, "Formulir kedaluwarsa, muat ulang halaman lalu coba lagi", http.StatusForbidden)
 			return
 		}
 
@@ -57,6 +109,22 @@ func CSRF(next http.Handler) http.Handler {
 	})
 }
 
+// formToken reads the token form field from the capped body. Multipart forms
+// are parsed with the same memory limit as the upload handlers, which then
+// find the form already parsed.
+func formToken(r *http.Request) (string, error) {
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err = r.ParseMultipartForm(MaxFormMemory)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		return "", err
+	}
+	return r.PostFormValue(CSRFFormName), nil
+}
+
 func GetCSRFToken(ctx context.Context) string {
 	if val, ok := ctx.Value(CSRFTokenKey).(string); ok {
 		return val
@@ -70,42 +138,48 @@ func generateRandomToken() string {
 	return base64.RawURLEncoding.EncodeToString(b)
 }
 
-func setCSRFCookie(w http.ResponseWriter, token string) {
+func (c *CSRF) setCookie(w http.ResponseWriter, token string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     CSRFCookieName,
 		Value:    token,
 		Path:     "/",
-		HttpOnly: true,  // Not accessible by JS
-		Secure:   false, // Set to true in production if HTTPS
+		HttpOnly: true, // Templates read the token from the request context
+		Secure:   c.secure,
 		SameSite: http.SameSiteLaxMode,
 		Expires:  time.Now().Add(24 * time.Hour),
 	})
 }
 
-func isValidOrigin(r *http.Request) bool {
-	origin := r.Header.Get("Origin")
-	if origin != "" {
-		u, err := url.Parse(origin)
-		if err != nil {
-			return false
-		}
-		if u.Host == "localhost:8080" || u.Host == "127.0.0.1:8080" {
-			return true
-		}
-		return u.Host == r.Host
+// isValidOrigin checks Origin, falling back to Referer, against the request
+// host and the trusted origins. Requests with neither header are rejected.
+func (c *CSRF) isValidOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return false
 	}
 
-	referer := r.Header.Get("Referer")
-	if referer != "" {
-		u, err := url.Parse(referer)
-		if err != nil {
-			return false
-		}
-		if u.Host == "localhost:8080" || u.Host == "127.0.0.1:8080" {
-			return true
-		}
-		return u.Host == r.Host
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return false
 	}
+	return u.Host == r.Host || c.origins[u.Host]
+}
 
-	return false
+// originHost returns the host[:port] of an origin, which may be given with or
+// without a scheme
+func originHost(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	if !strings.Contains(origin, "://") {
+		origin = "//" + origin
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return ""
+	}
+	return u.Host
 }
@@ -0,0 +1,255 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/nobuww/simpel-ktp/internal/session"
+)
+
+const csrfTestToken = "test-csrf-token"
+
+// csrfRequest builds a request to the test host carrying csrfTestToken in the
+// csrf cookie. With form set the body is sent form-encoded.
+func csrfRequest(method string, form url.Values) *http.Request {
+	var req *http.Request
+	if form != nil {
+		req = httptest.NewRequest(method, "http://example.com/profil", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, "http://example.com/profil", nil)
+	}
+	req.Header.Set("Origin", "http://example.com")
+	req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: csrfTestToken})
+	return req
+}
+
+// serveCSRF runs req through Protect and reports the status and whether the
+// wrapped handler was reached
+func serveCSRF(c *CSRF, req *http.Request) (int, bool) {
+	reached := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	c.Protect(next).ServeHTTP(rec, req)
+	return rec.Code, reached
+}
+
+func TestCSRFRejectsMissingOrWrongToken(t *testing.T) {
+	c := NewCSRF(false)
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		tests := []struct {
+			name string
+			req  func() *http.Request
+		}{
+			{"no token", func() *http.Request { return csrfRequest(method, nil) }},
+			{"wrong header", func() *http.Request {
+				req := csrfRequest(method, nil)
+				req.Header.Set(CSRFHeaderName, "wrong")
+				return req
+			}},
+			{"wrong form field", func() *http.Request {
+				return csrfRequest(method, url.Values{CSRFFormName: {"wrong"}})
+			}},
+			{"empty header and form field", func() *http.Request {
+				req := csrfRequest(method, url.Values{CSRFFormName: {""}})
+				req.Header.Set(CSRFHeaderName, "")
+				return req
+			}},
+		}
+		for _, tt := range tests {
+			t.Run(method+" "+tt.name, func(t *testing.T) {
+				code, reached := serveCSRF(c, tt.req())
+				if code != http.StatusForbidden || reached {
+					t.Errorf("status = %d, reached = %v; want %d and not reached", code, reached, http.StatusForbidden)
+				}
+			})
+		}
+	}
+}
+
+func TestCSRFAcceptsHeaderAndFormField(t *testing.T) {
+	c := NewCSRF(false)
+	tests := []struct {
+		name   string
+		method string
+		req    func(method string) *http.Request
+	}{
+		{"HX header", http.MethodPost, hxRequest},
+		{"HX header", http.MethodPut, hxRequest},
+		{"HX header", http.MethodPatch, hxRequest},
+		{"HX header", http.MethodDelete, hxRequest},
+		{"form field", http.MethodPost, formRequest},
+		{"form field", http.MethodPut, formRequest},
+		{"form field", http.MethodPatch, formRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.name, func(t *testing.T) {
+			code, reached := serveCSRF(c, tt.req(tt.method))
+			if code != http.StatusOK || !reached {
+				t.Errorf("status = %d, reached = %v; want %d and reached", code, reached, http.StatusOK)
+			}
+		})
+	}
+}
+
+func hxRequest(method string) *http.Request {
+	req := csrfRequest(method, nil)
+	req.Header.Set("HX-Request", "true")
+	req.Header.Set(CSRFHeaderName, csrfTestToken)
+	return req
+}
+
+func formRequest(method string) *http.Request {
+	return csrfRequest(method, url.Values{CSRFFormName: {csrfTestToken}})
+}
+
+func TestCSRFUsesSessionToken(t *testing.T) {
+	c := NewCSRF(false)
+	user := &session.UserSession{UserType: session.UserTypeWarga, CSRFToken: "session-token"}
+	withUser := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), UserContextKey, user))
+	}
+
+	// The cookie token no longer counts once the session has its own
+	req := withUser(csrfRequest(http.MethodPost, url.Values{CSRFFormName: {csrfTestToken}}))
+	if code, _ := serveCSRF(c, req); code != http.StatusForbidden {
+		t.Errorf("cookie token with a session: status = %d, want %d", code, http.StatusForbidden)
+	}
+
+	req = withUser(csrfRequest(http.MethodPost, url.Values{CSRFFormName: {"session-token"}}))
+	if code, _ := serveCSRF(c, req); code != http.StatusOK {
+		t.Errorf("session token: status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestCSRFSafeMethodsPassWithoutToken(t *testing.T) {
+	c := NewCSRF(false)
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+		req := httptest.NewRequest(method, "http://example.com/profil", nil)
+		reached := false
+		rec := httptest.NewRecorder()
+		c.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reached = true
+			if GetCSRFToken(r.Context()) == "" {
+				t.Errorf("%s: no token in the request context", method)
+			}
+		})).ServeHTTP(rec, req)
+		if !reached {
+			t.Errorf("%s without a token was blocked", method)
+		}
+		if !strings.Contains(rec.Header().Get("Set-Cookie"), CSRFCookieName+"=") {
+			t.Errorf("%s: anonymous visitor got no csrf cookie", method)
+		}
+	}
+}
+
+func TestCSRFChecksOrigin(t *testing.T) {
+	t.Setenv("CSRF_TRUSTED_ORIGINS", "localhost:8080, https://simpel-ktp.example.go.id")
+	c := NewCSRF(false)
+	tests := []struct {
+		name    string
+		origin  string
+		referer string
+		want    int
+	}{
+		{"same host", "http://example.com", "", http.StatusOK},
+		{"referer when Origin is missing", "", "http://example.com/profil", http.StatusOK},
+		{"trusted host without scheme", "http://localhost:8080", "", http.StatusOK},
+		{"trusted origin", "https://simpel-ktp.example.go.id", "", http.StatusOK},
+		{"foreign origin", "https://evil.example", "", http.StatusForbidden},
+		{"foreign referer", "", "https://evil.example/form", http.StatusForbidden},
+		{"foreign origin beats a good referer", "https://evil.example", "http://example.com/profil", http.StatusForbidden},
+		{"neither header", "", "", http.StatusForbidden},
+		{"opaque origin", "null", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := hxRequest(http.MethodPost)
+			req.Header.Del("Origin")
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			if code, _ := serveCSRF(c, req); code != tt.want {
+				t.Errorf("status = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+// multipartRequest builds a multipart POST carrying token in the form field
+// and an upload of size bytes
+func multipartRequest(token string, size int) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField(CSRFFormName, token)
+	fw, _ := mw.CreateFormFile("kartu_keluarga", "kk.pdf")
+	fw.Write(bytes.Repeat([]byte("x"), size))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/permohonan/baru", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Origin", "http://example.com")
+	req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: csrfTestToken})
+	return req
+}
+
+func TestCSRFMultipartFormField(t *testing.T) {
+	c := NewCSRF(false)
+
+	var upload int64
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(MaxFormMemory); err != nil {
+			t.Errorf("ParseMultipartForm after the middleware: %v", err)
+			return
+		}
+		if _, header, err := r.FormFile("kartu_keluarga"); err == nil {
+			upload = header.Size
+		}
+	})
+	rec := httptest.NewRecorder()
+	c.Protect(next).ServeHTTP(rec, multipartRequest(csrfTestToken, 1<<10))
+	if rec.Code != http.StatusOK || upload != 1<<10 {
+		t.Errorf("valid multipart form: status = %d, upload = %d bytes; want %d and %d", rec.Code, upload, http.StatusOK, 1<<10)
+	}
+
+	if code, reached := serveCSRF(c, multipartRequest("wrong", 1<<10)); code != http.StatusForbidden || reached {
+		t.Errorf("wrong multipart token: status = %d, reached = %v; want %d", code, reached, http.StatusForbidden)
+	}
+}
+
+func TestCSRFCapsBody(t *testing.T) {
+	c := NewCSRF(false)
+
+	// Without the header the token is read from the body, which stops at the cap
+	code, reached := serveCSRF(c, multipartRequest(csrfTestToken, MaxFormBytes))
+	if code != http.StatusRequestEntityTooLarge || reached {
+		t.Errorf("oversized multipart form: status = %d, reached = %v; want %d", code, reached, http.StatusRequestEntityTooLarge)
+	}
+
+	// With the header the handler reads the body, still capped
+	req := hxRequest(http.MethodPost)
+	req.Body = io.NopCloser(bytes.NewReader(make([]byte, MaxFormBytes+1)))
+	var readErr error
+	rec := httptest.NewRecorder()
+	c.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	})).ServeHTTP(rec, req)
+	var tooLarge *http.MaxBytesError
+	if !errors.As(readErr, &tooLarge) {
+		t.Errorf("reading an oversized body = %v, want a MaxBytesError", readErr)
+	}
+}
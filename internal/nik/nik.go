@@ -0,0 +1,148 @@
+// Package nik parses Nomor Induk Kependudukan, the 16-digit national
+// identity number. Its digits are, in order:
+//
+//	PP KK CC DDMMYY SSSS
+//
+// PP is the province, PPKK the kabupaten/kota and PPKKCC the kecamatan where
+// the number was issued (the kode wilayah stored on ref_kecamatan). DDMMYY is
+// the date of birth, with 40 added to the day for women, and SSSS a serial
+// number that is never 0000.
+package nik
+
+import (
+	"errors"
+	"time"
+)
+
+const Length = 16
+
+// Values of penduduk.jenis_kelamin
+const (
+	LakiLaki  = "LAKI_LAKI"
+	Perempuan = "PEREMPUAN"
+)
+
+var (
+	ErrFormat       = errors.New("nik: must be 16 digits")
+	ErrWilayah      = errors.New("nik: invalid area code")
+	ErrTanggalLahir = errors.New("nik: invalid date of birth")
+	ErrNomorUrut    = errors.New("nik: invalid serial number")
+
+	ErrJenisKelamin  = errors.New("nik: gender does not match")
+	ErrDiLuarWilayah = errors.New("nik: issued outside the kecamatan")
+)
+
+// provinsi lists the province codes in use, including the Papua provinces
+// created in 2022
+var provinsi = map[string]bool{
+	"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true,
+	"21": true,
+	"31": true, "32": true, "33": true, "34": true, "35": true, "36": true,
+	"51": true, "52": true, "53": true,
+	"61": true, "62": true, "63": true, "64": true, "65": true,
+	"71": true, "72": true, "73": true, "74": true, "75": true, "76": true,
+	"81": true, "82": true,
+	"91": true, "92": true, "93": true, "94": true, "95": true, "96": true, "97": true,
+}
+
+// NIK is a parsed identity number
+type NIK struct {
+	Nomor         string
+	KodeProvinsi  string
+	KodeKota      string
+	KodeKecamatan string
+	TanggalLahir  time.Time
+	JenisKelamin  string
+	NomorUrut     string
+}
+
+// Parse validates the structure of s and decodes it. The two-digit birth
+// year is placed in the latest century that does not put it after today.
+func Parse(s string, today time.Time) (*NIK, error) {
+	if len(s) != Length {
+		return nil, ErrFormat
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return nil, ErrFormat
+		}
+	}
+
+	if !provinsi[s[0:2]] || s[2:4] == "00" || s[4:6] == "00" {
+		return nil, ErrWilayah
+	}
+
+	day, month, yy := number(s[6:8]), number(s[8:10]), number(s[10:12])
+	jenisKelamin := LakiLaki
+	if day > 40 {
+		day -= 40
+		jenisKelamin = Perempuan
+	}
+
+	year := today.Year()/100*100 + yy
+	lahir := time.Date(year, time.Month(month), day, 0, 0, 0, 0, today.Location())
+	if lahir.After(today) {
+		lahir = time.Date(year-100, time.Month(month), day, 0, 0, 0, 0, today.Location())
+	}
+	// time.Date normalizes overflow, so a round trip catches 31 February and the like
+	if day < 1 || month < 1 || month > 12 || lahir.Day() != day || lahir.Month() != time.Month(month) || lahir.After(today) {
+		return nil, ErrTanggalLahir
+	}
+
+	if s[12:16] == "0000" {
+		return nil, ErrNomorUrut
+	}
+
+	return &NIK{
+		Nomor:         s,
+		KodeProvinsi:  s[0:2],
+		KodeKota:      s[0:4],
+		KodeKecamatan: s[0:6],
+		TanggalLahir:  lahir,
+		JenisKelamin:  jenisKelamin,
+		NomorUrut:     s[12:16],
+	}, nil
+}
+
+// Check compares the NIK with the gender given alongside it
+func (n *NIK) Check(jenisKelamin string) error {
+	if n.JenisKelamin != jenisKelamin {
+		return ErrJenisKelamin
+	}
+	return nil
+}
+
+// CheckWilayah returns ErrDiLuarWilayah when the NIK was issued outside the
+// kecamatan with the given kode wilayah. Citizens who moved keep their NIK,
+// so this is a warning for the reviewing petugas rather than a reason to
+// reject. An empty kodeKecamatan skips the check.
+func (n *NIK) CheckWilayah(kodeKecamatan string) error {
+	if kodeKecamatan != "" && n.KodeKecamatan != kodeKecamatan {
+		return ErrDiLuarWilayah
+	}
+	return nil
+}
+
+// Message describes a Parse or Check error for users, in Indonesian
+func Message(err error) string {
+	switch {
+	case errors.Is(err, ErrFormat):
+		return "NIK harus 16 digit angka"
+	case errors.Is(err, ErrWilayah):
+		return "Kode wilayah pada NIK tidak valid"
+	case errors.Is(err, ErrTanggalLahir):
+		return "Tanggal lahir pada NIK tidak valid"
+	case errors.Is(err, ErrNomorUrut):
+		return "Nomor urut pada NIK tidak valid"
+	case errors.Is(err, ErrJenisKelamin):
+		return "Jenis kelamin tidak sesuai dengan NIK"
+	case errors.Is(err, ErrDiLuarWilayah):
+		return "NIK diterbitkan di luar kecamatan domisili; periksa dokumen pindah datang"
+	}
+	return "NIK tidak valid"
+}
+
+// number converts two ASCII digits, already checked by Parse
+func number(s string) int {
+	return int(s[0]-'0')*10 + int(s[1]-'0')
+}
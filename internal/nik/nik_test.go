@@ -0,0 +1,104 @@
+package nik
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var today = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name         string
+		nik          string
+		wantErr      error
+		lahir        string
+		jenisKelamin string
+	}{
+		{"male", "3172050101800001", nil, "1980-01-01", LakiLaki},
+		{"female has 40 added to the day", "3172054101850002", nil, "1985-01-01", Perempuan},
+		{"female on the 31st", "3172057112990003", nil, "1999-12-31", Perempuan},
+		{"two-digit year this century", "3172051503100004", nil, "2010-03-15", LakiLaki},
+		{"born today", "3172051910260005", nil, "2026-10-19", LakiLaki},
+		{"two-digit year after this year is last century", "3172051910270007", nil, "1927-10-19", LakiLaki},
+		{"leap day", "3172052902000006", nil, "2000-02-29", LakiLaki},
+		{"too short", "317205010180000", ErrFormat, "", ""},
+		{"too long", "31720501018000011", ErrFormat, "", ""},
+		{"empty", "", ErrFormat, "", ""},
+		{"letter", "31720501018A0001", ErrFormat, "", ""},
+		{"space", "3172050101 80001", ErrFormat, "", ""},
+		{"unknown province", "9972050101800001", ErrWilayah, "", ""},
+		{"kota 00", "3100050101800001", ErrWilayah, "", ""},
+		{"kecamatan 00", "3172000101800001", ErrWilayah, "", ""},
+		{"day 00", "3172050001800001", ErrTanggalLahir, "", ""},
+		{"day 32", "3172053201800001", ErrTanggalLahir, "", ""},
+		{"female day 40", "3172054001800001", ErrTanggalLahir, "", ""},
+		{"female day 72", "3172057201800001", ErrTanggalLahir, "", ""},
+		{"month 00", "3172050100800001", ErrTanggalLahir, "", ""},
+		{"month 13", "3172050113800001", ErrTanggalLahir, "", ""},
+		{"31 April", "3172053104800001", ErrTanggalLahir, "", ""},
+		{"29 February outside a leap year", "3172052902010001", ErrTanggalLahir, "", ""},
+		{"female 30 February", "3172057002800001", ErrTanggalLahir, "", ""},
+		{"born after today is last century", "3172052010260001", nil, "1926-10-20", LakiLaki},
+		{"later this year is last century", "3172054112260009", nil, "1926-12-01", Perempuan},
+		{"serial 0000", "3172050101800000", ErrNomorUrut, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.nik, today)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.nik, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if lahir := got.TanggalLahir.Format("2006-01-02"); lahir != tt.lahir {
+				t.Errorf("TanggalLahir = %s, want %s", lahir, tt.lahir)
+			}
+			if got.JenisKelamin != tt.jenisKelamin {
+				t.Errorf("JenisKelamin = %s, want %s", got.JenisKelamin, tt.jenisKelamin)
+			}
+			if got.KodeKecamatan != tt.nik[:6] {
+				t.Errorf("KodeKecamatan = %s, want %s", got.KodeKecamatan, tt.nik[:6])
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	n, err := Parse("3172054101850002", today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Check(Perempuan); err != nil {
+		t.Errorf("Check(Perempuan) = %v, want nil", err)
+	}
+	if err := n.Check(LakiLaki); !errors.Is(err, ErrJenisKelamin) {
+		t.Errorf("Check(LakiLaki) = %v, want %v", err, ErrJenisKelamin)
+	}
+}
+
+func TestCheckWilayah(t *testing.T) {
+	// A citizen who moved to Pademangan (317205) from Bekasi (327501)
+	n, err := Parse("3275010101800001", today)
+	if err != nil {
+		t.Fatalf("a NIK from another kecamatan must parse: %v", err)
+	}
+	tests := []struct {
+		name          string
+		kodeKecamatan string
+		want          error
+	}{
+		{"issued here", "327501", nil},
+		{"issued in another kecamatan", "317205", ErrDiLuarWilayah},
+		{"no kecamatan to compare", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := n.CheckWilayah(tt.kodeKecamatan); !errors.Is(err, tt.want) {
+				t.Errorf("CheckWilayah(%q) = %v, want %v", tt.kodeKecamatan, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Channel is the medium a message is delivered through
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+// Message is a notification to a single recipient. Subject is ignored for SMS.
+type Message struct {
+	Channel Channel
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to warga
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New builds a notifier from the environment. Email goes through SMTP when
+// SMTP_HOST is set and SMS through an HTTP gateway when SMS_GATEWAY_URL is set;
+// channels without configuration are written to the log, for development.
+// With GO_ENV=production both channels must be configured, since a reset
+// code written to the log never reaches the warga.
+func New() Notifier {
+	d := Dispatcher{Email: Log{}, SMS: Log{}}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		d.Email = SMTP{
+			Addr:     net.JoinHostPort(host, port),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+	}
+
+	if url := os.Getenv("SMS_GATEWAY_URL"); url != "" {
+		d.SMS = SMSGateway{
+			URL:    url,
+			Token:  os.Getenv("SMS_GATEWAY_TOKEN"),
+			Client: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	if os.Getenv("GO_ENV") == "production" {
+		if _, ok := d.Email.(Log); ok {
+			log.Fatal("SMTP_HOST is not set; email notifications cannot be delivered in production")
+		}
+		if _, ok := d.SMS.(Log); ok {
+			log.Fatal("SMS_GATEWAY_URL is not set; SMS notifications cannot be delivered in production")
+		}
+	}
+
+	return d
+}
+
+// Dispatcher routes each message to the notifier of its channel
+type Dispatcher struct {
+	Email Notifier
+	SMS   Notifier
+}
+
+func (d Dispatcher) Send(ctx context.Context, msg Message) error {
+	switch msg.Channel {
+	case ChannelEmail:
+		return d.Email.Send(ctx, msg)
+	case ChannelSMS:
+		return d.SMS.Send(ctx, msg)
+	}
+	return fmt.Errorf("unknown channel %q", msg.Channel)
+}
+
+// Log writes messages to the server log instead of delivering them
+type Log struct{}
+
+func (Log) Send(_ context.Context, msg Message) error {
+	log.Printf("[notify:%s] to=%s subject=%q body=%q", msg.Channel, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// SMTP sends plain-text email through an SMTP server with PLAIN auth
+type SMTP struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s SMTP) Send(_ context.Context, msg Message) error {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", s.From)
+	fmt.Fprintf(&body, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&body, "Subject: %s\r\n", msg.Subject)
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(msg.Body)
+
+	return smtp.SendMail(s.Addr, auth, s.From, []string{msg.To}, []byte(body.String()))
+}
+
+// SMSGateway posts messages as JSON {"to", "message"} to an HTTP SMS gateway,
+// authenticating with a bearer token
+type SMSGateway struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+func (g SMSGateway) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(map[string]string{
+		"to":      msg.To,
+		"message": msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sms gateway responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/nobuww/simpel-ktp/internal/qrcode"
+)
+
+func render(t *testing.T, pages int) []byte {
+	t.Helper()
+	code, err := qrcode.Encode("SKTP-ABC123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := New("Daftar Antrian (Kel. Sukamaju) \\ 19/10")
+	for i := 0; i < pages; i++ {
+		p := d.AddPage()
+		p.Text(40, 60, Bold, 14, fmt.Sprintf("Halaman %d", i+1))
+		p.Text(40, 80, Regular, 10, "Siti (Ayu) – Jl. Merdeka \\ No. 5 €")
+		p.TextRight(555, 80, Regular, 10, "Muhammad Rizki")
+		p.Line(40, 90, 555, 90, 0.5)
+		p.Box(40, 100, 100, 20, 0.9)
+		p.QR(code, 40, 140, 80)
+	}
+	var buf bytes.Buffer
+	n, err := d.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo reported %d bytes, wrote %d", n, buf.Len())
+	}
+	return buf.Bytes()
+}
+
+var (
+	startxrefRe = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	trailerRe   = regexp.MustCompile(`trailer\n<< /Size (\d+) /Root (\d+) 0 R /Info (\d+) 0 R >>`)
+	lengthRe    = regexp.MustCompile(`^<< /Length (\d+) >>\nstream\n`)
+	kidsRe      = regexp.MustCompile(`/Kids \[([^\]]*)\] /Count (\d+)`)
+	refRe       = regexp.MustCompile(`(\d+) 0 R`)
+)
+
+// parse reads the document back through its cross-reference table the way a
+// reader does, returning the body of every object by number
+func parse(t *testing.T, b []byte) (objs map[int][]byte, root int) {
+	t.Helper()
+	if !bytes.HasPrefix(b, []byte("%PDF-1.4\n")) {
+		t.Fatalf("missing PDF header")
+	}
+	m := startxrefRe.FindSubmatch(b)
+	if m == nil {
+		t.Fatalf("missing startxref at the end of the file")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if xref >= len(b) || !bytes.HasPrefix(b[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	var first, count int
+	rest := b[xref+len("xref\n"):]
+	if _, err := fmt.Sscanf(string(rest), "%d %d\n", &first, &count); err != nil || first != 0 {
+		t.Fatalf("bad xref subsection header: %v", err)
+	}
+	rest = rest[bytes.IndexByte(rest, '\n')+1:]
+	// Every entry is exactly 20 bytes, the first one the free list head
+	if string(rest[:20]) != "0000000000 65535 f \n" {
+		t.Errorf("xref entry 0 = %q", rest[:20])
+	}
+	objs = map[int][]byte{}
+	for i := 1; i < count; i++ {
+		entry := string(rest[20*i : 20*(i+1)])
+		var off, gen int
+		var kind string
+		if _, err := fmt.Sscanf(entry, "%010d %05d %s", &off, &gen, &kind); err != nil || kind != "n" || entry[18:] != " \n" {
+			t.Fatalf("bad xref entry %d: %q", i, entry)
+		}
+		head := fmt.Sprintf("%d 0 obj\n", i)
+		if !bytes.HasPrefix(b[off:], []byte(head)) {
+			t.Fatalf("xref entry %d points at %q", i, b[off:min(off+20, len(b))])
+		}
+		body := b[off+len(head):]
+		end := bytes.Index(body, []byte("\nendobj\n"))
+		if end < 0 {
+			t.Fatalf("object %d has no endobj", i)
+		}
+		objs[i] = body[:end]
+	}
+	if !bytes.HasPrefix(rest[20*count:], []byte("trailer\n")) {
+		t.Fatalf("xref table is not followed by the trailer")
+	}
+
+	tm := trailerRe.FindSubmatch(rest[20*count:])
+	if tm == nil {
+		t.Fatalf("malformed trailer")
+	}
+	if size, _ := strconv.Atoi(string(tm[1])); size != count {
+		t.Errorf("trailer /Size %d, xref has %d entries", size, count)
+	}
+	root, _ = strconv.Atoi(string(tm[2]))
+	if info, _ := strconv.Atoi(string(tm[3])); objs[info] == nil {
+		t.Errorf("trailer /Info %d is not an object", info)
+	}
+	return objs, root
+}
+
+func TestWriteToStructure(t *testing.T) {
+	for _, pages := range []int{0, 1, 3} {
+		t.Run(fmt.Sprintf("%d pages", pages), func(t *testing.T) {
+			objs, root := parse(t, render(t, pages))
+
+			catalog := objs[root]
+			if !bytes.HasPrefix(catalog, []byte("<< /Type /Catalog ")) {
+				t.Fatalf("root object is not a catalog: %q", catalog)
+			}
+			pagesRef := refRe.FindSubmatch(catalog)
+			n, _ := strconv.Atoi(string(pagesRef[1]))
+			km := kidsRe.FindSubmatch(objs[n])
+			if km == nil {
+				t.Fatalf("pages object has no kids: %q", objs[n])
+			}
+			kids := refRe.FindAllSubmatch(km[1], -1)
+			if c, _ := strconv.Atoi(string(km[2])); c != pages || len(kids) != pages {
+				t.Fatalf("pages object lists %d kids with /Count %d, want %d", len(kids), c, pages)
+			}
+
+			for _, kid := range kids {
+				k, _ := strconv.Atoi(string(kid[1]))
+				page := objs[k]
+				if !bytes.HasPrefix(page, []byte("<< /Type /Page ")) {
+					t.Fatalf("kid %d is not a page: %q", k, page)
+				}
+				for _, ref := range refRe.FindAllSubmatch(page, -1) {
+					if r, _ := strconv.Atoi(string(ref[1])); objs[r] == nil {
+						t.Errorf("page %d refers to missing object %d", k, r)
+					}
+				}
+
+				cm := regexp.MustCompile(`/Contents (\d+) 0 R`).FindSubmatch(page)
+				c, _ := strconv.Atoi(string(cm[1]))
+				stream := objs[c]
+				lm := lengthRe.FindSubmatch(stream)
+				if lm == nil {
+					t.Fatalf("content %d is not a stream: %q", c, stream)
+				}
+				length, _ := strconv.Atoi(string(lm[1]))
+				data := stream[len(lm[0]):]
+				if !bytes.HasSuffix(data, []byte("endstream")) || len(data)-len("endstream") != length {
+					t.Errorf("stream %d has %d bytes, /Length says %d", c, len(data)-len("endstream"), length)
+				}
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Antrian 12", "Antrian 12"},
+		{"(a) \\ b", `\(a\) \\ b`},
+		{"Café – €", `Caf\351 \226 \200`},
+		{"日本", "??"},
+		{"a\nb", `a\012b`},
+	}
+	for _, tt := range tests {
+		if got := encode(tt.in); got != tt.want {
+			t.Errorf("encode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package policy
+
+import (
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/session"
+)
+
+// Permission names an action a petugas may perform. Roles are granted
+// permissions through the role_permission table; the granted set is loaded
+// into the session at login.
+type Permission string
+
+const (
+	PermohonanVerify Permission = "permohonan.verify"
+	PermohonanReject Permission = "permohonan.reject"
+	PermohonanAssign Permission = "permohonan.assign"
+	JadwalManage     Permission = "jadwal.manage"
+	PetugasManage    Permission = "petugas.manage"
+	PendudukViewPII  Permission = "penduduk.view_pii"
+	LaporanView      Permission = "laporan.view"
+	SLAManage        Permission = "sla.manage"
+)
+
+// Wilayah locates a resource in the kota > kecamatan > kelurahan hierarchy
+type Wilayah struct {
+	KecamatanID pgtype.Int2
+	KelurahanID pgtype.Int2
+}
+
+// Can reports whether the user holds perm and, when resource is non-nil,
+// whether the resource lies within the user's scope. A nil resource checks
+// the permission alone, e.g. to decide whether to show a menu item.
+func Can(user *session.UserSession, perm Permission, resource *Wilayah) bool {
+	if user == nil || user.UserType != session.UserTypePetugas {
+		return false
+	}
+	if !slices.Contains(user.Permissions, string(perm)) {
+		return false
+	}
+	if resource != nil && !ScopeOf(user).Covers(resource.KecamatanID, resource.KelurahanID) {
+		return false
+	}
+	return true
+}
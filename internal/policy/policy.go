@@ -0,0 +1,151 @@
+package policy
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/session"
+	"github.com/nobuww/simpel-ktp/internal/store"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// ErrNotFound is returned for resources that do not exist or lie outside the
+// petugas's wilayah. Handlers answer both with 404 so that out-of-scope
+// records cannot be told apart from missing ones.
+var ErrNotFound = errors.New("resource not found in scope")
+
+// Scope narrows admin access along the kota > kecamatan > kelurahan hierarchy.
+// Admin Kota has neither ID set and sees the whole city.
+type Scope struct {
+	KecamatanID pgtype.Int2
+	KelurahanID pgtype.Int2
+}
+
+// ScopeOf returns the wilayah a petugas session is allowed to access
+func ScopeOf(user *session.UserSession) Scope {
+	var scope Scope
+	if user.KecamatanID != nil {
+		scope.KecamatanID = pgtype.Int2{Int16: *user.KecamatanID, Valid: true}
+	}
+	if user.KelurahanID != nil {
+		scope.KelurahanID = pgtype.Int2{Int16: *user.KelurahanID, Valid: true}
+	}
+	return scope
+}
+
+// IsKota reports whether the scope covers the whole city
+func (s Scope) IsKota() bool {
+	return !s.KecamatanID.Valid && !s.KelurahanID.Valid
+}
+
+// Covers reports whether a resource located in the given wilayah falls within the scope.
+// A NULL kelurahan is the kecamatan itself; a NULL kecamatan is the city.
+func (s Scope) Covers(kecamatanID, kelurahanID pgtype.Int2) bool {
+	if s.KelurahanID.Valid {
+		return kelurahanID.Valid && kelurahanID.Int16 == s.KelurahanID.Int16
+	}
+	if s.KecamatanID.Valid {
+		return kecamatanID.Valid && kecamatanID.Int16 == s.KecamatanID.Int16
+	}
+	return true
+}
+
+// Service checks admin resources against the petugas's scope. Every admin
+// handler that loads a record by ID goes through it before touching the record.
+type Service struct {
+	repo store.Repository
+}
+
+// New creates a policy service backed by the given repository
+func New(repo store.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Permohonan returns the wilayah of the lokasi layanan a permohonan is booked at,
+// or ErrNotFound when it is outside the user's scope
+func (s *Service) Permohonan(ctx context.Context, user *session.UserSession, id uuid.UUID) (Wilayah, error) {
+	row, err := s.repo.GetPermohonanWilayah(ctx, id)
+	if err != nil {
+		return Wilayah{}, notFound(err)
+	}
+	wilayah := Wilayah{
+		KecamatanID: pgtype.Int2{Int16: row.KecamatanID, Valid: true},
+		KelurahanID: row.KelurahanID,
+	}
+	if !ScopeOf(user).Covers(wilayah.KecamatanID, wilayah.KelurahanID) {
+		return Wilayah{}, ErrNotFound
+	}
+	return wilayah, nil
+}
+
+// Jadwal loads a jadwal sesi, returning ErrNotFound when its lokasi layanan
+// is outside the user's scope
+func (s *Service) Jadwal(ctx context.Context, user *session.UserSession, id uuid.UUID) (pg_store.GetJadwalSesiByIdRow, error) {
+	row, err := s.repo.GetJadwalSesiById(ctx, id)
+	if err != nil {
+		return pg_store.GetJadwalSesiByIdRow{}, notFound(err)
+	}
+	if !ScopeOf(user).Covers(pgtype.Int2{Int16: row.LokasiKecamatanID, Valid: true}, row.LokasiKelurahanID) {
+		return pg_store.GetJadwalSesiByIdRow{}, ErrNotFound
+	}
+	return row, nil
+}
+
+// Lokasi loads a lokasi layanan, returning ErrNotFound when it is outside the user's scope
+func (s *Service) Lokasi(ctx context.Context, user *session.UserSession, id int16) (pg_store.LokasiLayanan, error) {
+	row, err := s.repo.GetLokasiLayananById(ctx, id)
+	if err != nil {
+		return pg_store.LokasiLayanan{}, notFound(err)
+	}
+	if !ScopeOf(user).Covers(pgtype.Int2{Int16: row.KecamatanID, Valid: true}, row.KelurahanID) {
+		return pg_store.LokasiLayanan{}, ErrNotFound
+	}
+	return row, nil
+}
+
+// Petugas loads a petugas account, returning ErrNotFound when it is outside the
+// user's scope. Admin Kecamatan thereby never sees Admin Kota accounts.
+func (s *Service) Petugas(ctx context.Context, user *session.UserSession, id uuid.UUID) (pg_store.Petugas, error) {
+	row, err := s.repo.GetPetugasById(ctx, id)
+	if err != nil {
+		return pg_store.Petugas{}, notFound(err)
+	}
+	if !ScopeOf(user).Covers(row.KecamatanID, row.KelurahanID) {
+		return pg_store.Petugas{}, ErrNotFound
+	}
+	return row, nil
+}
+
+// Penduduk loads a penduduk by NIK, returning ErrNotFound when their kelurahan
+// is outside the user's scope
+func (s *Service) Penduduk(ctx context.Context, user *session.UserSession, nik string) (pg_store.Penduduk, error) {
+	row, err := s.repo.GetPendudukByNIK(ctx, nik)
+	if err != nil {
+		return pg_store.Penduduk{}, notFound(err)
+	}
+	scope := ScopeOf(user)
+	if scope.IsKota() {
+		return row, nil
+	}
+	if !row.KelurahanID.Valid {
+		return pg_store.Penduduk{}, ErrNotFound
+	}
+	kelurahan, err := s.repo.GetKelurahanById(ctx, row.KelurahanID.Int16)
+	if err != nil {
+		return pg_store.Penduduk{}, notFound(err)
+	}
+	if !scope.Covers(pgtype.Int2{Int16: kelurahan.KecamatanID, Valid: true}, row.KelurahanID) {
+		return pg_store.Penduduk{}, ErrNotFound
+	}
+	return row, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
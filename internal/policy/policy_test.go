@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/session"
+)
+
+func int2(v int16) pgtype.Int2 {
+	return pgtype.Int2{Int16: v, Valid: true}
+}
+
+func ptr(v int16) *int16 {
+	return &v
+}
+
+// Kecamatan 1 holds kelurahan 10 and 11; kecamatan 2 holds kelurahan 20
+var (
+	scopeKota      = Scope{}
+	scopeKecamatan = Scope{KecamatanID: int2(1)}
+	scopeKelurahan = Scope{KecamatanID: int2(1), KelurahanID: int2(10)}
+)
+
+func TestScopeCovers(t *testing.T) {
+	tests := []struct {
+		name      string
+		scope     Scope
+		kecamatan pgtype.Int2
+		kelurahan pgtype.Int2
+		want      bool
+	}{
+		{"kota covers a kelurahan", scopeKota, int2(2), int2(20), true},
+		{"kota covers a kecamatan", scopeKota, int2(2), pgtype.Int2{}, true},
+		{"kota covers the city", scopeKota, pgtype.Int2{}, pgtype.Int2{}, true},
+
+		{"kecamatan covers its kelurahan", scopeKecamatan, int2(1), int2(11), true},
+		{"kecamatan covers itself", scopeKecamatan, int2(1), pgtype.Int2{}, true},
+		{"kecamatan does not cover another kecamatan", scopeKecamatan, int2(2), pgtype.Int2{}, false},
+		{"kecamatan does not cover a kelurahan of another kecamatan", scopeKecamatan, int2(2), int2(20), false},
+		{"kecamatan does not cover the city", scopeKecamatan, pgtype.Int2{}, pgtype.Int2{}, false},
+
+		{"kelurahan covers itself", scopeKelurahan, int2(1), int2(10), true},
+		{"kelurahan does not cover a sibling", scopeKelurahan, int2(1), int2(11), false},
+		{"kelurahan does not cover its kecamatan", scopeKelurahan, int2(1), pgtype.Int2{}, false},
+		{"kelurahan does not cover another kecamatan", scopeKelurahan, int2(2), int2(20), false},
+		{"kelurahan does not cover the city", scopeKelurahan, pgtype.Int2{}, pgtype.Int2{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.Covers(tt.kecamatan, tt.kelurahan); got != tt.want {
+				t.Errorf("Covers(%v, %v) = %v, want %v", tt.kecamatan, tt.kelurahan, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		user   *session.UserSession
+		want   Scope
+		isKota bool
+	}{
+		{"admin kota", &session.UserSession{}, scopeKota, true},
+		{"admin kecamatan", &session.UserSession{KecamatanID: ptr(1)}, scopeKecamatan, false},
+		{"admin kelurahan", &session.UserSession{KecamatanID: ptr(1), KelurahanID: ptr(10)}, scopeKelurahan, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScopeOf(tt.user)
+			if got != tt.want {
+				t.Errorf("ScopeOf = %+v, want %+v", got, tt.want)
+			}
+			if got.IsKota() != tt.isKota {
+				t.Errorf("IsKota = %v, want %v", got.IsKota(), tt.isKota)
+			}
+		})
+	}
+}
+
+func TestCan(t *testing.T) {
+	petugas := func(kecamatan, kelurahan *int16, perms ...Permission) *session.UserSession {
+		user := &session.UserSession{
+			UserType:    session.UserTypePetugas,
+			KecamatanID: kecamatan,
+			KelurahanID: kelurahan,
+		}
+		for _, p := range perms {
+			user.Permissions = append(user.Permissions, string(p))
+		}
+		return user
+	}
+	kota := petugas(nil, nil, PermohonanVerify, PetugasManage)
+	kecamatan := petugas(ptr(1), nil, PermohonanVerify)
+	kelurahan := petugas(ptr(1), ptr(10), PermohonanVerify)
+
+	inKelurahan := &Wilayah{KecamatanID: int2(1), KelurahanID: int2(10)}
+	inSibling := &Wilayah{KecamatanID: int2(1), KelurahanID: int2(11)}
+	inKecamatan := &Wilayah{KecamatanID: int2(1)}
+	elsewhere := &Wilayah{KecamatanID: int2(2), KelurahanID: int2(20)}
+	city := &Wilayah{}
+
+	tests := []struct {
+		name     string
+		user     *session.UserSession
+		perm     Permission
+		resource *Wilayah
+		want     bool
+	}{
+		{"no user", nil, PermohonanVerify, nil, false},
+		{"warga", &session.UserSession{UserType: session.UserTypeWarga, Permissions: []string{string(PermohonanVerify)}}, PermohonanVerify, nil, false},
+
+		{"permission without a wilayah", kecamatan, PermohonanVerify, nil, true},
+		{"missing permission without a wilayah", kecamatan, PetugasManage, nil, false},
+		{"missing permission inside the scope", kecamatan, PetugasManage, inKecamatan, false},
+		{"no permissions at all", petugas(nil, nil), PermohonanVerify, nil, false},
+
+		{"kota anywhere", kota, PermohonanVerify, elsewhere, true},
+		{"kota on the city", kota, PermohonanVerify, city, true},
+
+		{"kecamatan in its kelurahan", kecamatan, PermohonanVerify, inSibling, true},
+		{"kecamatan on itself", kecamatan, PermohonanVerify, inKecamatan, true},
+		{"kecamatan elsewhere", kecamatan, PermohonanVerify, elsewhere, false},
+		{"kecamatan on the city", kecamatan, PermohonanVerify, city, false},
+
+		{"kelurahan on itself", kelurahan, PermohonanVerify, inKelurahan, true},
+		{"kelurahan on a sibling", kelurahan, PermohonanVerify, inSibling, false},
+		{"kelurahan on its kecamatan", kelurahan, PermohonanVerify, inKecamatan, false},
+		{"kelurahan on the city", kelurahan, PermohonanVerify, city, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Can(tt.user, tt.perm, tt.resource); got != tt.want {
+				t.Errorf("Can(%s) = %v, want %v", tt.perm, got, tt.want)
+			}
+		})
+	}
+}
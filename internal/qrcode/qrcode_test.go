@@ -0,0 +1,307 @@
+package qrcode
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The data and error correction codewords of the HELLO WORLD 1-M example in
+// ISO/IEC 18004 Annex I
+func TestRSRemainderHelloWorld(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+// formatAt reads the 15 format bits next to the top-left finder, most
+// significant first
+func formatAt(modules [][]bool) string {
+	var pos [15][2]int
+	for i := 0; i <= 5; i++ {
+		pos[i] = [2]int{8, i}
+	}
+	pos[6], pos[7], pos[8] = [2]int{8, 7}, [2]int{8, 8}, [2]int{7, 8}
+	for i := 9; i < 15; i++ {
+		pos[i] = [2]int{14 - i, 8}
+	}
+	var b strings.Builder
+	for i := 14; i >= 0; i-- {
+		if modules[pos[i][1]][pos[i][0]] {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+// Level M rows of the format information table in ISO/IEC 18004 Annex C
+func TestFormatBits(t *testing.T) {
+	want := []string{
+		"101010000010010",
+		"101000100100101",
+		"101111001111100",
+		"101101101001011",
+		"100010111111001",
+		"100000011001110",
+		"100111110010111",
+		"100101010100000",
+	}
+	for mask, w := range want {
+		q := newMatrix(1)
+		q.drawFormatBits(mask)
+		if got := formatAt(q.modules); got != w {
+			t.Errorf("mask %d: format bits %s, want %s", mask, got, w)
+		}
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []string{
+		"SKTP1.ABC123.q1w2e3r4t5y6u7i8o9p0aa",
+		"otpauth://totp/Simpel%20KTP:199001012020121001?secret=JBSWY3DPEHPK3PXP&issuer=Simpel%20KTP",
+		"",
+		strings.Repeat("x", 150), // version 7, with version information
+		strings.Repeat("y", 213), // the most a version 10 symbol holds
+	}
+	for _, text := range tests {
+		code, err := Encode(text)
+		if err != nil {
+			t.Fatalf("Encode(%d bytes): %v", len(text), err)
+		}
+		if got := decode(t, code); got != text {
+			t.Errorf("decoded %q, want %q", got, text)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("z", 214)); err != ErrTooLong {
+		t.Errorf("Encode(214 bytes) error = %v, want ErrTooLong", err)
+	}
+}
+
+// decode reads a symbol back the way a scanner does, sharing nothing with the
+// encoder but the block layout tables: it reads the format information, finds
+// the data modules, removes the mask, checks every block against its
+// Reed-Solomon codewords and parses the byte mode segment.
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+	size := c.Size
+	version := (size - 17) / 4
+	if version < 1 || version > 10 || 17+4*version != size {
+		t.Fatalf("size %d is not a version 1-10 symbol", size)
+	}
+	m := make([][]bool, size)
+	for y := range m {
+		m[y] = make([]bool, size)
+		for x := range m[y] {
+			m[y][x] = c.Dark(x, y)
+		}
+	}
+
+	if !m[size-8][8] {
+		t.Errorf("dark module is light")
+	}
+
+	format1 := formatAt(m)
+	var format2 strings.Builder
+	for i := 14; i >= 0; i-- {
+		x, y := size-1-i, 8
+		if i >= 8 {
+			x, y = 8, size-15+i
+		}
+		if m[y][x] {
+			format2.WriteByte('1')
+		} else {
+			format2.WriteByte('0')
+		}
+	}
+	if format1 != format2.String() {
+		t.Fatalf("format copies differ: %s and %s", format1, format2.String())
+	}
+	format, _ := strconv.ParseInt(format1, 2, 0)
+	format ^= 0x5412
+	if level := format >> 13; level != 0 {
+		t.Fatalf("error correction level bits %02b, want 00 (M)", level)
+	}
+	mask := int(format>>10) & 7
+
+	function := make([][]bool, size)
+	for y := range function {
+		function[y] = make([]bool, size)
+		for x := range function[y] {
+			switch {
+			case x < 9 && y < 9, x >= size-8 && y < 9, x < 9 && y >= size-8:
+				// finders, separators and format information
+				function[y][x] = true
+			case x == 6 || y == 6:
+				function[y][x] = true
+			case version >= 7 && ((x >= size-11 && x < size-8 && y < 6) || (y >= size-11 && y < size-8 && x < 6)):
+				function[y][x] = true
+			}
+		}
+	}
+	if version >= 2 {
+		pos := alignment[version]
+		for _, cy := range pos {
+			for _, cx := range pos {
+				if (cx < 9 && cy < 9) || (cx >= size-8 && cy < 9) || (cx < 9 && cy >= size-8) {
+					continue // would overlap a finder
+				}
+				for y := cy - 2; y <= cy+2; y++ {
+					for x := cx - 2; x <= cx+2; x++ {
+						function[y][x] = true
+					}
+				}
+			}
+		}
+	}
+
+	var bits []bool
+	up := true
+	for right := size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for i := 0; i < size; i++ {
+			y := i
+			if up {
+				y = size - 1 - i
+			}
+			for x := right; x > right-2; x-- {
+				if function[y][x] {
+					continue
+				}
+				invert := false
+				switch mask {
+				case 0:
+					invert = (y+x)%2 == 0
+				case 1:
+					invert = y%2 == 0
+				case 2:
+					invert = x%3 == 0
+				case 3:
+					invert = (y+x)%3 == 0
+				case 4:
+					invert = (y/2+x/3)%2 == 0
+				case 5:
+					invert = (y*x)%2+(y*x)%3 == 0
+				case 6:
+					invert = ((y*x)%2+(y*x)%3)%2 == 0
+				case 7:
+					invert = ((y+x)%2+(y*x)%3)%2 == 0
+				}
+				bits = append(bits, m[y][x] != invert)
+			}
+		}
+		up = !up
+	}
+
+	spec := specs[version]
+	var blocks [][]byte
+	for _, g := range spec.groups {
+		for i := 0; i < g[0]; i++ {
+			blocks = append(blocks, make([]byte, 0, g[1]+spec.ecPerBlock))
+		}
+	}
+	total := 0
+	for _, g := range spec.groups {
+		total += g[0] * (g[1] + spec.ecPerBlock)
+	}
+	if len(bits) < total*8 {
+		t.Fatalf("%d data modules for %d codewords", len(bits), total)
+	}
+	codewords := make([]byte, total)
+	for i := range codewords {
+		for j := 0; j < 8; j++ {
+			if bits[8*i+j] {
+				codewords[i] |= 1 << (7 - j)
+			}
+		}
+	}
+	// Data codewords are interleaved across blocks, shorter blocks first
+	// running out, then the error correction codewords the same way
+	next := 0
+	longest := spec.groups[len(spec.groups)-1][1]
+	for i := 0; i < longest; i++ {
+		b := 0
+		for _, g := range spec.groups {
+			for k := 0; k < g[0]; k, b = k+1, b+1 {
+				if i < g[1] {
+					blocks[b] = append(blocks[b], codewords[next])
+					next++
+				}
+			}
+		}
+	}
+	for i := 0; i < spec.ecPerBlock; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[next])
+			next++
+		}
+	}
+
+	exp := make([]byte, 255)
+	for i, v := 0, 1; i < 255; i++ {
+		exp[i] = byte(v)
+		v <<= 1
+		if v&0x100 != 0 {
+			v ^= 0x11D
+		}
+	}
+	mul := func(a, b byte) byte {
+		var p byte
+		for ; b != 0; b >>= 1 {
+			if b&1 != 0 {
+				p ^= a
+			}
+			hi := a & 0x80
+			a <<= 1
+			if hi != 0 {
+				a ^= 0x1D
+			}
+		}
+		return p
+	}
+	var data []byte
+	for b, block := range blocks {
+		// A valid block is divisible by the generator, so it vanishes at
+		// each of the generator's roots
+		for j := 0; j < spec.ecPerBlock; j++ {
+			var s byte
+			for _, cw := range block {
+				s = mul(s, exp[j]) ^ cw
+			}
+			if s != 0 {
+				t.Fatalf("block %d fails Reed-Solomon check at root %d", b, j)
+			}
+		}
+		data = append(data, block[:len(block)-spec.ecPerBlock]...)
+	}
+
+	pos := 0
+	read := func(n int) int {
+		v := 0
+		for ; n > 0; n-- {
+			v = v<<1 | int(data[pos/8]>>(7-pos%8)&1)
+			pos++
+		}
+		return v
+	}
+	if mode := read(4); mode != 0b0100 {
+		t.Fatalf("mode %04b, want byte mode", mode)
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	n := read(countBits)
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(read(8))
+	}
+	return string(out)
+}
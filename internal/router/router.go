@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/nobuww/simpel-ktp/internal/clock"
 	"github.com/nobuww/simpel-ktp/internal/features/admin"
 	"github.com/nobuww/simpel-ktp/internal/features/auth"
 	"github.com/nobuww/simpel-ktp/internal/features/errors"
@@ -11,11 +12,14 @@ import (
 	"github.com/nobuww/simpel-ktp/internal/features/permohonan"
 	"github.com/nobuww/simpel-ktp/internal/features/user"
 	"github.com/nobuww/simpel-ktp/internal/middleware"
+	"github.com/nobuww/simpel-ktp/internal/notify"
+	"github.com/nobuww/simpel-ktp/internal/policy"
 	"github.com/nobuww/simpel-ktp/internal/session"
 	"github.com/nobuww/simpel-ktp/internal/store"
+	"github.com/nobuww/simpel-ktp/internal/tiket"
 )
 
-func New(s *store.Store, sessionMgr *session.Manager) *chi.Mux {
+func New(s store.Repository, sessionMgr *session.Manager, clk clock.Clock, notifier notify.Notifier, signer *tiket.Signer) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Security middlewares
@@ -24,6 +28,10 @@ func New(s *store.Store, sessionMgr *session.Manager) *chi.Mux {
 	authMiddleware := middleware.NewAuth(sessionMgr)
 	r.Use(authMiddleware.InjectUser)
 
+	// Every POST and DELETE needs the CSRF token of the user's session
+	csrf := middleware.NewCSRF(sessionMgr.Secure())
+	r.Use(csrf.Protect)
+
 	// Custom 404 handler
 	errorHandler := errors.New()
 	r.NotFound(errorHandler.NotFoundHandler)
@@ -46,51 +54,130 @@ func New(s *store.Store, sessionMgr *session.Manager) *chi.Mux {
 	})
 
 	// Auth
-	authHandler := auth.New(s, sessionMgr)
+	authHandler := auth.New(s, clk, sessionMgr, notifier)
 
 	// Public auth pages (redirect if already logged in)
 	r.Group(func(r chi.Router) {
 		r.Use(authMiddleware.RedirectIfAuthenticated)
 		r.Get("/login", authHandler.LoginPageHandler)
 		r.Get("/petugas/login", authHandler.LoginPetugasPageHandler)
+		r.Get("/petugas/login/2fa", authHandler.LoginPetugas2FAPageHandler)
 		r.Get("/register", authHandler.RegisterPageHandler)
+		r.Get("/lupa-password", authHandler.LupaPasswordPageHandler)
+		r.Get("/reset-password", authHandler.ResetPasswordPageHandler)
 	})
 
 	// Auth API endpoints
 	r.Post("/auth/login", authHandler.HandleLogin)
 	r.Post("/auth/login/petugas", authHandler.HandleLoginPetugas)
+	r.Post("/auth/login/petugas/2fa", authHandler.HandleLoginPetugas2FA)
 	r.Post("/auth/register", authHandler.HandleRegister)
+	r.Post("/auth/lupa-password", authHandler.HandleLupaPassword)
+	r.Post("/auth/reset-password", authHandler.HandleResetPassword)
 	r.Post("/auth/logout", authHandler.HandleLogout)
+	r.Post("/auth/logout-all", authHandler.HandleLogoutAll)
 
 	// Admin routes (protected)
-	adminHandler := admin.New(s)
+	adminHandler := admin.New(s, clk, policy.New(s), signer)
 	r.Group(func(r chi.Router) {
 		r.Use(authMiddleware.RequirePetugas)
 		r.Get("/admin", adminHandler.DashboardHandler)
+		r.Get("/admin/cari", adminHandler.SearchHandler)
 		r.Get("/admin/penduduk", adminHandler.PendudukHandler)
+		r.Get("/admin/penduduk/ekspor", adminHandler.ExportPendudukHandler)
 		r.Get("/admin/permohonan", adminHandler.PermohonanHandler)
+		r.Get("/admin/permohonan/ekspor", adminHandler.ExportPermohonanHandler)
 		r.Get("/admin/permohonan/{id}", adminHandler.PermohonanDetailHandler)
-		r.Get("/admin/permohonan/{id}/status", adminHandler.PermohonanStatusFormHandler)
-		r.Post("/admin/permohonan/update-status", adminHandler.UpdateStatusHandler)
+		r.Post("/admin/permohonan/tampilan", adminHandler.SaveTampilanHandler)
+		r.Post("/admin/permohonan/tampilan/hapus", adminHandler.DeleteTampilanHandler)
 		r.Get("/admin/jadwal", adminHandler.JadwalHandler)
-		r.Post("/admin/jadwal", adminHandler.CreateJadwalHandler)
-		r.Post("/admin/jadwal/generate", adminHandler.GenerateJadwalHandler)
-
 		r.Get("/admin/jadwal/{id}/antrian", adminHandler.JadwalAntrianHandler)
-		r.Get("/admin/jadwal/{id}/delete-confirm", adminHandler.DeleteJadwalConfirmHandler)
-		r.Delete("/admin/jadwal/{id}", adminHandler.DeleteJadwalHandler)
-		r.Get("/admin/petugas", adminHandler.PetugasHandler)
-		r.Post("/admin/petugas", adminHandler.CreatePetugasHandler)
+		r.Get("/admin/jadwal/{id}/antrian/ekspor", adminHandler.ExportAntrianHandler)
+		r.Get("/admin/jadwal/{id}/antrian/pdf", adminHandler.PrintAntrianHandler)
+		r.Get("/admin/kehadiran", adminHandler.KehadiranHandler)
+		r.Post("/admin/kehadiran", adminHandler.CreateKehadiranHandler)
+		r.Get("/admin/akun-terkunci", adminHandler.AkunTerkunciHandler)
+		r.Post("/admin/akun-terkunci/buka", adminHandler.UnlockAkunHandler)
+
+		// Own 2FA settings; the only pages open to petugas who still must enrol
+		r.Get("/admin/2fa", authHandler.KeamananAkunHandler)
+		r.Post("/admin/2fa/aktifkan", authHandler.HandleActivate2FA)
+		r.Post("/admin/2fa/kode-pemulihan", authHandler.HandleKodePemulihan2FA)
+		r.Post("/admin/2fa/nonaktifkan", authHandler.HandleDisable2FA)
+
+		// Status changes check verify/reject per target status in the handler
+		// and require the petugas to hold the permohonan
+		r.Get("/admin/permohonan/{id}/status", adminHandler.PermohonanStatusFormHandler)
+		r.Post("/admin/permohonan/update-status", adminHandler.UpdateStatusHandler)
+		r.Post("/admin/permohonan/{id}/ambil", adminHandler.ClaimPermohonanHandler)
+		r.Post("/admin/permohonan/{id}/lepas", adminHandler.ReleasePermohonanHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.RequirePermission(policy.PermohonanAssign))
+			r.Post("/admin/permohonan/{id}/tugaskan", adminHandler.AssignPermohonanHandler)
+			r.Post("/admin/permohonan/bagikan", adminHandler.DistributePermohonanHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.RequirePermission(policy.JadwalManage))
+			r.Post("/admin/jadwal", adminHandler.CreateJadwalHandler)
+			r.Post("/admin/jadwal/generate", adminHandler.GenerateJadwalHandler)
+			r.Post("/admin/jadwal/aturan", adminHandler.UpdateAturanBookingHandler)
+			r.Post("/admin/jadwal/lokasi", adminHandler.UpdateLokasiLayananHandler)
+			r.Get("/admin/jadwal/{id}/delete-confirm", adminHandler.DeleteJadwalConfirmHandler)
+			r.Delete("/admin/jadwal/{id}", adminHandler.DeleteJadwalHandler)
+		})
+
+		// Verifying a guardian means comparing NIKs and KK numbers in full
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.RequirePermission(policy.PendudukViewPII))
+			r.Get("/admin/wali", adminHandler.WaliHandler)
+			r.Post("/admin/wali/setujui", adminHandler.ApproveWaliHandler)
+			r.Post("/admin/wali/tolak", adminHandler.RejectWaliHandler)
+			r.Post("/admin/wali/anggota/setujui", adminHandler.ApproveAnggotaHandler)
+			r.Post("/admin/wali/anggota/tolak", adminHandler.RejectAnggotaHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.RequirePermission(policy.LaporanView))
+			r.Get("/admin/laporan", adminHandler.LaporanHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.RequirePermission(policy.SLAManage))
+			r.Get("/admin/sla", adminHandler.SLAHandler)
+			r.Post("/admin/sla", adminHandler.SaveSLAHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.RequirePermission(policy.PetugasManage))
+			r.Get("/admin/petugas", adminHandler.PetugasHandler)
+			r.Get("/admin/petugas/ekspor", adminHandler.ExportPetugasHandler)
+			r.Post("/admin/petugas", adminHandler.CreatePetugasHandler)
+			r.Get("/admin/petugas/{id}/edit", adminHandler.EditPetugasFormHandler)
+			r.Post("/admin/petugas/update", adminHandler.UpdatePetugasHandler)
+			r.Post("/admin/petugas/status", adminHandler.SetPetugasStatusHandler)
+			r.Post("/admin/petugas/reset-password", adminHandler.ResetPetugasPasswordHandler)
+			r.Post("/admin/petugas/revoke-sessions", adminHandler.RevokePetugasSessionsHandler)
+			r.Post("/admin/petugas/reset-2fa", adminHandler.ResetPetugas2FAHandler)
+		})
 	})
 
+	// Public tracking with a booking code and a second factor
+	userHandler := user.New(s, clk, signer)
+	r.Get("/lacak", userHandler.PublicStatusHandler)
+	r.Post("/lacak", userHandler.PublicStatusHandler)
+
 	// User routes (protected - warga only)
-	userHandler := user.New(s)
-	permohonanService := permohonan.NewService(s)
-	permohonanHandler := permohonan.New(permohonanService)
+	permohonanService := permohonan.NewService(s, clk, signer)
+	permohonanHandler := permohonan.New(permohonanService, signer)
 	r.Group(func(r chi.Router) {
 		r.Use(authMiddleware.RequireWarga)
 		r.Get("/dashboard", userHandler.DashboardHandler)
 		r.Get("/lacak-status", userHandler.StatusDetailHandler)
+		r.Get("/keluarga", userHandler.KeluargaHandler)
+		r.Post("/keluarga/hubungkan", userHandler.LinkKKHandler)
+		r.Post("/keluarga/anggota", userHandler.CreateAnggotaHandler)
 
 		// Permohonan routes
 		r.Get("/permohonan/baru", permohonanHandler.HandleKTPBaruForm)
@@ -102,6 +189,7 @@ func New(s *store.Store, sessionMgr *session.Manager) *chi.Mux {
 		r.Get("/permohonan/ubah", permohonanHandler.HandleKTPUbahForm)
 		r.Post("/permohonan/ubah", permohonanHandler.HandleKTPUbahForm)
 		r.Get("/permohonan/sukses", permohonanHandler.HandleSuccessPage)
+		r.Get("/permohonan/bukti", permohonanHandler.HandleBuktiBooking)
 		r.Get("/permohonan/jadwal-options", permohonanHandler.HandleGetJadwalOptions)
 	})
 
@@ -0,0 +1,328 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/notify"
+	"github.com/nobuww/simpel-ktp/internal/policy"
+	"github.com/nobuww/simpel-ktp/internal/session"
+	"github.com/nobuww/simpel-ktp/internal/store"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+	"github.com/nobuww/simpel-ktp/internal/tiket"
+)
+
+const csrfToken = "test-csrf-token"
+
+// Every record in scopeStore lies in kecamatan 2, kelurahan 20
+var (
+	kecamatanLain = pgtype.Int2{Int16: 2, Valid: true}
+	kelurahanLain = pgtype.Int2{Int16: 20, Valid: true}
+)
+
+// scopeStore serves the logged-in petugas and records outside their scope.
+// With missing set, lookups by ID find nothing. Any query a handler makes
+// beyond the scope check panics through the embedded nil Repository.
+type scopeStore struct {
+	store.Repository
+	sesi    pg_store.SesiLogin
+	missing bool
+}
+
+func (s *scopeStore) GetSesiLogin(ctx context.Context, tokenHash string) (pg_store.SesiLogin, error) {
+	return s.sesi, nil
+}
+
+func (s *scopeStore) TouchSesiLogin(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (s *scopeStore) GetPermohonanWilayah(ctx context.Context, id uuid.UUID) (pg_store.GetPermohonanWilayahRow, error) {
+	if s.missing {
+		return pg_store.GetPermohonanWilayahRow{}, pgx.ErrNoRows
+	}
+	return pg_store.GetPermohonanWilayahRow{KecamatanID: kecamatanLain.Int16, KelurahanID: kelurahanLain}, nil
+}
+
+func (s *scopeStore) GetJadwalSesiById(ctx context.Context, id uuid.UUID) (pg_store.GetJadwalSesiByIdRow, error) {
+	if s.missing {
+		return pg_store.GetJadwalSesiByIdRow{}, pgx.ErrNoRows
+	}
+	return pg_store.GetJadwalSesiByIdRow{
+		ID:                id,
+		LokasiKecamatanID: kecamatanLain.Int16,
+		LokasiKelurahanID: kelurahanLain,
+	}, nil
+}
+
+func (s *scopeStore) GetPetugasById(ctx context.Context, id uuid.UUID) (pg_store.Petugas, error) {
+	if s.missing {
+		return pg_store.Petugas{}, pgx.ErrNoRows
+	}
+	return pg_store.Petugas{
+		ID:          id,
+		Role:        session.RoleAdminKelurahan,
+		KecamatanID: kecamatanLain,
+		KelurahanID: kelurahanLain,
+	}, nil
+}
+
+// Lockouts known to scopeStore, of a warga and of a petugas outside the scope
+var (
+	kunciWargaID   = uuid.New()
+	kunciPetugasID = uuid.New()
+)
+
+func (s *scopeStore) GetPendudukByNIK(ctx context.Context, nik string) (pg_store.Penduduk, error) {
+	if s.missing {
+		return pg_store.Penduduk{}, pgx.ErrNoRows
+	}
+	return pg_store.Penduduk{Nik: nik, KelurahanID: kelurahanLain}, nil
+}
+
+func (s *scopeStore) GetKelurahanById(ctx context.Context, id int16) (pg_store.GetKelurahanByIdRow, error) {
+	return pg_store.GetKelurahanByIdRow{ID: id, KecamatanID: kecamatanLain.Int16}, nil
+}
+
+func (s *scopeStore) GetPermohonanCheckIn(ctx context.Context, kodeBooking pgtype.Text) (pg_store.GetPermohonanCheckInRow, error) {
+	if s.missing {
+		return pg_store.GetPermohonanCheckInRow{}, pgx.ErrNoRows
+	}
+	return pg_store.GetPermohonanCheckInRow{ID: uuid.New(), KodeBooking: kodeBooking}, nil
+}
+
+func (s *scopeStore) GetKunciLoginById(ctx context.Context, id uuid.UUID) (pg_store.KunciLogin, error) {
+	switch {
+	case s.missing:
+		return pg_store.KunciLogin{}, pgx.ErrNoRows
+	case id == kunciPetugasID:
+		return pg_store.KunciLogin{ID: id, UserType: session.UserTypePetugas, Identifier: uuid.NewString()}, nil
+	}
+	return pg_store.KunciLogin{ID: id, UserType: session.UserTypeWarga, Identifier: "3172050101800001"}, nil
+}
+
+var allPermissions = []string{
+	string(policy.PermohonanVerify),
+	string(policy.PermohonanReject),
+	string(policy.PermohonanAssign),
+	string(policy.JadwalManage),
+	string(policy.PetugasManage),
+	string(policy.PendudukViewPII),
+	string(policy.LaporanView),
+	string(policy.SLAManage),
+}
+
+// scopedRoute is an admin route addressing one record. The ID goes into the
+// {id} path segment, or into the form field named by field.
+type scopedRoute struct {
+	method string
+	path   string
+	field  string
+	id     string
+	extra  url.Values
+}
+
+var scopedRoutes = []scopedRoute{
+	{method: http.MethodGet, path: "/admin/permohonan/{id}"},
+	{method: http.MethodGet, path: "/admin/permohonan/{id}/status"},
+	{method: http.MethodPost, path: "/admin/permohonan/{id}/ambil"},
+	{method: http.MethodPost, path: "/admin/permohonan/{id}/lepas"},
+	{method: http.MethodPost, path: "/admin/permohonan/{id}/tugaskan"},
+	{method: http.MethodGet, path: "/admin/jadwal/{id}/antrian"},
+	{method: http.MethodGet, path: "/admin/jadwal/{id}/antrian/ekspor"},
+	{method: http.MethodGet, path: "/admin/jadwal/{id}/antrian/pdf"},
+	{method: http.MethodGet, path: "/admin/jadwal/{id}/delete-confirm"},
+	{method: http.MethodDelete, path: "/admin/jadwal/{id}"},
+	{method: http.MethodGet, path: "/admin/petugas/{id}/edit"},
+	{method: http.MethodPost, path: "/admin/permohonan/update-status", field: "id", extra: url.Values{"status": {"VERIFIKASI"}}},
+	{method: http.MethodPost, path: "/admin/permohonan/update-status", field: "id", extra: url.Values{"status": {"DITOLAK"}, "alasan": {"DOKUMEN_TIDAK_LENGKAP"}}},
+	{method: http.MethodPost, path: "/admin/petugas/update", field: "id", extra: url.Values{"nama": {"Petugas"}, "email": {"petugas@example.com"}}},
+	{method: http.MethodPost, path: "/admin/petugas/status", field: "id", extra: url.Values{"aktif": {"false"}}},
+	{method: http.MethodPost, path: "/admin/petugas/reset-password", field: "id"},
+	{method: http.MethodPost, path: "/admin/petugas/revoke-sessions", field: "id"},
+	{method: http.MethodPost, path: "/admin/petugas/reset-2fa", field: "id"},
+	{method: http.MethodPost, path: "/admin/wali/setujui", field: "nik", id: "3172050101800001"},
+	{method: http.MethodPost, path: "/admin/wali/tolak", field: "nik", id: "3172050101800001"},
+	{method: http.MethodPost, path: "/admin/wali/anggota/setujui", field: "nik", id: "3172050101800001"},
+	{method: http.MethodPost, path: "/admin/wali/anggota/tolak", field: "nik", id: "3172050101800001"},
+	{method: http.MethodPost, path: "/admin/akun-terkunci/buka", field: "id", id: kunciWargaID.String()},
+	{method: http.MethodPost, path: "/admin/akun-terkunci/buka", field: "id", id: kunciPetugasID.String()},
+}
+
+// request builds the route's request for the record id; the caller adds the
+// session and CSRF token
+func (rt scopedRoute) request(id string) *http.Request {
+	if rt.id != "" {
+		id = rt.id
+	}
+	path := strings.Replace(rt.path, "{id}", id, 1)
+	form := url.Values{}
+	for k, v := range rt.extra {
+		form[k] = v
+	}
+	if rt.field != "" {
+		form.Set(rt.field, id)
+	}
+	req := httptest.NewRequest(rt.method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "http://"+req.Host)
+	return req
+}
+
+func (rt scopedRoute) String() string {
+	if rt.field == "" {
+		return rt.method + " " + rt.path
+	}
+	return fmt.Sprintf("%s %s %s=%s", rt.method, rt.path, rt.field, rt.id)
+}
+
+// newScopeRouter serves scopeStore to a logged-in petugas of the given role
+func newScopeRouter(role string, kecamatanID, kelurahanID pgtype.Int2, missing bool, signer *tiket.Signer) *chi.Mux {
+	repo := &scopeStore{
+		sesi: pg_store.SesiLogin{
+			ID:          uuid.New(),
+			UserType:    session.UserTypePetugas,
+			UserID:      uuid.NewString(),
+			UserName:    "Petugas " + role,
+			UserRole:    role,
+			KecamatanID: kecamatanID,
+			KelurahanID: kelurahanID,
+			Permissions: allPermissions,
+			CsrfToken:   csrfToken,
+		},
+		missing: missing,
+	}
+	return New(repo, session.New(repo), clock.Fixed(time.Date(2026, 3, 10, 3, 0, 0, 0, time.UTC)), notify.Log{}, signer)
+}
+
+var scopeRoles = []struct {
+	name        string
+	role        string
+	kecamatanID pgtype.Int2
+	kelurahanID pgtype.Int2
+	missing     bool
+}{
+	{"kecamatan", session.RoleAdminKecamatan, pgtype.Int2{Int16: 1, Valid: true}, pgtype.Int2{}, false},
+	{"kelurahan", session.RoleAdminKelurahan, pgtype.Int2{Int16: 1, Valid: true}, pgtype.Int2{Int16: 10, Valid: true}, false},
+	{"kota", session.RoleAdminKota, pgtype.Int2{}, pgtype.Int2{}, true},
+}
+
+// TestAdminIDRoutesOutsideScope checks that every admin route addressing a
+// record by ID, in the path or in the form, answers 404 when the record lies
+// outside the petugas's wilayah, and for admin kota, when it does not exist
+func TestAdminIDRoutesOutsideScope(t *testing.T) {
+	for _, role := range scopeRoles {
+		r := newScopeRouter(role.role, role.kecamatanID, role.kelurahanID, role.missing, tiket.New())
+
+		for _, route := range scopedRoutes {
+			t.Run(role.name+" "+route.String(), func(t *testing.T) {
+				req := route.request(uuid.NewString())
+				// A route that is not registered would pass with a 404 of its own
+				if !r.Match(chi.NewRouteContext(), req.Method, req.URL.Path) {
+					t.Fatalf("%s is not routed", route)
+				}
+				req.AddCookie(&http.Cookie{Name: session.SessionName, Value: "token"})
+				req.Header.Set("X-CSRF-Token", csrfToken)
+
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+
+				if rec.Code != http.StatusNotFound {
+					t.Errorf("%s = %d, want %d", route, rec.Code, http.StatusNotFound)
+				}
+			})
+		}
+	}
+}
+
+// TestCheckInOutsideScope checks that a scanned booking outside the wilayah
+// is reported in the scanner panel and not checked in
+func TestCheckInOutsideScope(t *testing.T) {
+	signer := tiket.New()
+	for _, role := range scopeRoles {
+		t.Run(role.name, func(t *testing.T) {
+			r := newScopeRouter(role.role, role.kecamatanID, role.kelurahanID, role.missing, signer)
+			form := url.Values{"payload": {signer.Payload("ABC12345")}}
+			req := httptest.NewRequest(http.MethodPost, "/admin/kehadiran", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Origin", "http://"+req.Host)
+			req.Header.Set("X-CSRF-Token", csrfToken)
+			req.AddCookie(&http.Cookie{Name: session.SessionName, Value: "token"})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			want := "tidak terdaftar di wilayah Anda"
+			if role.missing {
+				want = "tidak ditemukan"
+			}
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("POST /admin/kehadiran = %d %q, want %d containing %q", rec.Code, rec.Body.String(), http.StatusOK, want)
+			}
+		})
+	}
+}
+
+// TestAdminWritesNeedSessionAndCSRF checks that the state-changing scoped
+// routes turn away requests without a petugas session or without the
+// session's CSRF token before any record is loaded
+func TestAdminWritesNeedSessionAndCSRF(t *testing.T) {
+	// A kecamatan admin, so that a request passing the checks would get 404
+	// rather than panic on an unexpected query
+	r := newScopeRouter(session.RoleAdminKecamatan, pgtype.Int2{Int16: 1, Valid: true}, pgtype.Int2{}, false, tiket.New())
+
+	routes := append([]scopedRoute{{method: http.MethodPost, path: "/admin/kehadiran", field: "payload"}}, scopedRoutes...)
+	for _, route := range routes {
+		if route.method == http.MethodGet {
+			continue
+		}
+		t.Run(route.String(), func(t *testing.T) {
+			cases := []struct {
+				name    string
+				prepare func(req *http.Request)
+				want    int
+			}{
+				{"no session", func(req *http.Request) {
+					// The anonymous CSRF cookie matches, so only the session is missing
+					req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrfToken})
+					req.Header.Set("X-CSRF-Token", csrfToken)
+				}, http.StatusSeeOther},
+				{"no CSRF token", func(req *http.Request) {
+					req.AddCookie(&http.Cookie{Name: session.SessionName, Value: "token"})
+				}, http.StatusForbidden},
+				{"wrong CSRF token", func(req *http.Request) {
+					req.AddCookie(&http.Cookie{Name: session.SessionName, Value: "token"})
+					req.Header.Set("X-CSRF-Token", "token-of-another-session")
+				}, http.StatusForbidden},
+				{"foreign origin", func(req *http.Request) {
+					req.AddCookie(&http.Cookie{Name: session.SessionName, Value: "token"})
+					req.Header.Set("X-CSRF-Token", csrfToken)
+					req.Header.Set("Origin", "https://evil.example.com")
+				}, http.StatusForbidden},
+			}
+			for _, c := range cases {
+				req := route.request(uuid.NewString())
+				c.prepare(req)
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+				if rec.Code != c.want {
+					t.Errorf("%s: %s = %d, want %d", c.name, route, rec.Code, c.want)
+				}
+				if c.want == http.StatusSeeOther && rec.Header().Get("Location") != "/petugas/login" {
+					t.Errorf("%s: redirected to %q, want /petugas/login", c.name, rec.Header().Get("Location"))
+				}
+			}
+		})
+	}
+}
@@ -1,158 +1,243 @@
 package session
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
+	"strings"
+	"sync"
 
-	"github.com/gorilla/sessions"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/store"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
 )
 
 const (
 	SessionName = "simpel-ktp-session"
 
-	KeyUserID   = "user_id"
-	KeyUserType = "user_type"
-	KeyUserName = "user_name"
-	KeyUserRole = "user_role"
-
 	UserTypeWarga   = "warga"
 	UserTypePetugas = "petugas"
 
+	RoleAdminKota      = "ADMIN_KOTA"
 	RoleAdminKecamatan = "ADMIN_KECAMATAN"
 	RoleAdminKelurahan = "ADMIN_KELURAHAN"
 
-	KeyKelurahanID = "kelurahan_id"
+	// Tahap2FAVerifikasi marks a petugas session that passed the password
+	// step but has not entered a TOTP or recovery code yet. Such sessions are
+	// not treated as logged in.
+	Tahap2FAVerifikasi = "VERIFIKASI"
+	// Tahap2FAPendaftaran marks a petugas whose role requires 2FA but who has
+	// not enrolled; only the enrolment pages are reachable.
+	Tahap2FAPendaftaran = "PENDAFTARAN"
+
+	maxAgeDefault    = 86400      // 24 hours
+	maxAgeRemember   = 86400 * 30 // 30 days
+	maxAgeVerifikasi = 300        // 5 minutes to enter the second factor
 )
 
+// Manager keeps sessions in the sesi_login table. The cookie only carries a
+// random token; the database stores its SHA-256 hash, so deleting rows revokes
+// sessions immediately.
 type Manager struct {
-	store *sessions.CookieStore
+	repo   store.Repository
+	secure bool
 }
 
 type UserSession struct {
+	SessionID   string
 	UserID      string
 	UserType    string
 	UserName    string
-	UserRole    string 
-	KelurahanID *int16 
+	UserRole    string
+	KecamatanID *int16
+	KelurahanID *int16
+	Permissions []string
+	Tahap2FA    string
+	Remember    bool
+	// CSRFToken must accompany state-changing requests made with this session
+	CSRFToken string
 }
 
-func New(secret string) *Manager {
-	if secret == "" {
-		secret = os.Getenv("SESSION_SECRET")
-	}
-	if secret == "" {
-		secret = "development-secret-change-in-production"
-	}
-
-	store := sessions.NewCookieStore([]byte(secret))
+func New(repo store.Repository) *Manager {
 	isSecure := os.Getenv("GO_ENV") == "production"
 	if secureEnv := os.Getenv("HTTP_SECURE"); secureEnv != "" {
 		isSecure = secureEnv == "true"
 	}
 
-	store.Options = &sessions.Options{
-		Path:     "/",
-		MaxAge:   86400, // 24 hours default
-		HttpOnly: true,
-		Secure:   isSecure,
-		SameSite: http.SameSiteLaxMode,
-	}
-
-	return &Manager{store: store}
+	return &Manager{repo: repo, secure: isSecure}
 }
 
 // SetWargaSession creates a session for warga (citizen) users
 func (m *Manager) SetWargaSession(w http.ResponseWriter, r *http.Request, nik, namaLengkap string, remember bool) error {
-	session, err := m.store.Get(r, SessionName)
-	if err != nil {
-		return err
-	}
+	return m.create(w, r, UserSession{
+		UserID:   nik,
+		UserType: UserTypeWarga,
+		UserName: namaLengkap,
+	}, remember)
+}
 
-	session.Values[KeyUserID] = nik
-	session.Values[KeyUserType] = UserTypeWarga
-	session.Values[KeyUserName] = namaLengkap
-	session.Values[KeyUserRole] = ""
+// SetPetugasSession creates a session for petugas (officer) users
+func (m *Manager) SetPetugasSession(w http.ResponseWriter, r *http.Request, petugas UserSession, remember bool) error {
+	petugas.UserType = UserTypePetugas
+	return m.create(w, r, petugas, remember)
+}
 
-	if remember {
-		session.Options.MaxAge = 86400 * 30 // 30 days
-	} else {
-		session.Options.MaxAge = 86400 // 24 hours
-	}
+func (m *Manager) create(w http.ResponseWriter, r *http.Request, user UserSession, remember bool) error {
+	ctx := r.Context()
 
-	return session.Save(r, w)
-}
+	// Logging in again from the same browser replaces its previous session
+	if cookie, err := r.Cookie(SessionName); err == nil {
+		if err := m.repo.DeleteSesiLogin(ctx, hashToken(cookie.Value)); err != nil {
+			return err
+		}
+	}
 
-// SetPetugasSession creates a session for petugas (officer) users
-func (m *Manager) SetPetugasSession(w http.ResponseWriter, r *http.Request, petugasID, namaPetugas, role string, kelurahanID *int16, remember bool) error {
-	session, err := m.store.Get(r, SessionName)
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	csrfToken, err := newToken()
 	if err != nil {
 		return err
 	}
 
-	session.Values[KeyUserID] = petugasID
-	session.Values[KeyUserType] = UserTypePetugas
-	session.Values[KeyUserName] = namaPetugas
-	session.Values[KeyUserType] = UserTypePetugas
-	session.Values[KeyUserName] = namaPetugas
-	session.Values[KeyUserRole] = role
-	if kelurahanID != nil {
-		session.Values[KeyKelurahanID] = *kelurahanID
-	} else {
-		delete(session.Values, KeyKelurahanID)
+	maxAge := maxAgeFor(remember)
+	if user.Tahap2FA == Tahap2FAVerifikasi {
+		maxAge = maxAgeVerifikasi
 	}
 
-	if remember {
-		session.Options.MaxAge = 86400 * 30 // 30 days
-	} else {
-		session.Options.MaxAge = 86400 // 24 hours
+	permissions := user.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	if err := m.repo.CreateSesiLogin(ctx, pg_store.CreateSesiLoginParams{
+		TokenHash:   hashToken(token),
+		UserType:    user.UserType,
+		UserID:      user.UserID,
+		UserName:    user.UserName,
+		UserRole:    user.UserRole,
+		KecamatanID: int2(user.KecamatanID),
+		KelurahanID: int2(user.KelurahanID),
+		Permissions: permissions,
+		UserAgent:   text(r.UserAgent()),
+		IpAddress:   text(ClientIP(r)),
+		Tahap2fa:    user.Tahap2FA,
+		IngatSaya:   remember,
+		CsrfToken:   csrfToken,
+		MaxAge:      int32(maxAge),
+	}); err != nil {
+		return err
 	}
 
-	return session.Save(r, w)
+	m.setCookie(w, token, maxAge)
+	return nil
 }
 
+// GetSession returns the session for the request cookie, or nil when the
+// cookie is missing, expired or revoked
 func (m *Manager) GetSession(r *http.Request) *UserSession {
-	session, err := m.store.Get(r, SessionName)
-	if err != nil {
+	cookie, err := r.Cookie(SessionName)
+	if err != nil || cookie.Value == "" {
 		return nil
 	}
 
-	userID, ok := session.Values[KeyUserID].(string)
-	if !ok || userID == "" {
+	sesi, err := m.repo.GetSesiLogin(r.Context(), hashToken(cookie.Value))
+	if err != nil {
 		return nil
 	}
 
-	userType, _ := session.Values[KeyUserType].(string)
-	userName, _ := session.Values[KeyUserName].(string)
-	userRole, _ := session.Values[KeyUserRole].(string)
+	// Last-seen tracking is informational; a failed write must not log the user out
+	_ = m.repo.TouchSesiLogin(r.Context(), sesi.ID)
+
+	user := &UserSession{
+		SessionID:   sesi.ID.String(),
+		UserID:      sesi.UserID,
+		UserType:    sesi.UserType,
+		UserName:    sesi.UserName,
+		UserRole:    sesi.UserRole,
+		Permissions: sesi.Permissions,
+		Tahap2FA:    sesi.Tahap2fa,
+		Remember:    sesi.IngatSaya,
+		CSRFToken:   sesi.CsrfToken,
+	}
+	if sesi.KecamatanID.Valid {
+		user.KecamatanID = &sesi.KecamatanID.Int16
+	}
+	if sesi.KelurahanID.Valid {
+		user.KelurahanID = &sesi.KelurahanID.Int16
+	}
+	return user
+}
+
+// Complete2FA clears the 2FA stage of the session and issues a new token, so
+// that a cookie captured before the second factor cannot be reused
+func (m *Manager) Complete2FA(w http.ResponseWriter, r *http.Request, user *UserSession) error {
+	id, err := uuid.Parse(user.SessionID)
+	if err != nil {
+		return err
+	}
 
-	var kelurahanID *int16
-	if val, ok := session.Values[KeyKelurahanID].(int16); ok {
-		kelurahanID = &val
+	token, err := newToken()
+	if err != nil {
+		return err
 	}
 
-	return &UserSession{
-		UserID:      userID,
-		UserType:    userType,
-		UserName:    userName,
-		UserRole:    userRole,
-		KelurahanID: kelurahanID,
+	maxAge := maxAgeFor(user.Remember)
+	if err := m.repo.CompleteSesi2FA(r.Context(), pg_store.CompleteSesi2FAParams{
+		ID:        id,
+		TokenHash: hashToken(token),
+		MaxAge:    int32(maxAge),
+	}); err != nil {
+		return err
 	}
+
+	user.Tahap2FA = ""
+	m.setCookie(w, token, maxAge)
+	return nil
 }
 
+// ClearSession deletes the current browser's session and expires its cookie
 func (m *Manager) ClearSession(w http.ResponseWriter, r *http.Request) error {
-	session, err := m.store.Get(r, SessionName)
-	if err != nil {
-		return err
+	if cookie, err := r.Cookie(SessionName); err == nil {
+		if err := m.repo.DeleteSesiLogin(r.Context(), hashToken(cookie.Value)); err != nil {
+			return err
+		}
 	}
 
-	session.Options.MaxAge = -1
-	session.Values = make(map[interface{}]interface{})
+	m.setCookie(w, "", -1)
+	return nil
+}
+
+// RevokeUserSessions ends every session of a user on all devices
+func (m *Manager) RevokeUserSessions(ctx context.Context, userType, userID string) error {
+	return m.repo.DeleteSesiLoginByUser(ctx, pg_store.DeleteSesiLoginByUserParams{
+		UserType: userType,
+		UserID:   userID,
+	})
+}
+
+// PurgeExpired removes sessions past their expiry
+func (m *Manager) PurgeExpired(ctx context.Context) error {
+	return m.repo.DeleteExpiredSesiLogin(ctx)
+}
 
-	return session.Save(r, w)
+// Secure reports whether cookies are limited to HTTPS
+func (m *Manager) Secure() bool {
+	return m.secure
 }
 
 func (m *Manager) IsAuthenticated(r *http.Request) bool {
-	return m.GetSession(r) != nil
+	s := m.GetSession(r)
+	return s != nil && s.Tahap2FA != Tahap2FAVerifikasi
 }
 
 func (m *Manager) IsWarga(r *http.Request) bool {
@@ -162,5 +247,130 @@ func (m *Manager) IsWarga(r *http.Request) bool {
 
 func (m *Manager) IsPetugas(r *http.Request) bool {
 	s := m.GetSession(r)
-	return s != nil && s.UserType == UserTypePetugas
+	return s != nil && s.UserType == UserTypePetugas && s.Tahap2FA != Tahap2FAVerifikasi
+}
+
+func (m *Manager) setCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func maxAgeFor(remember bool) int {
+	if remember {
+		return maxAgeRemember
+	}
+	return maxAgeDefault
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// trustedProxies parses TRUSTED_PROXIES, a comma-separated list of IPs or
+// CIDR ranges of the reverse proxies in front of the app, e.g. the docker
+// network Caddy runs on. It is read on first use, after .env is loaded.
+var trustedProxies = sync.OnceValue(func() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			addr, err := netip.ParseAddr(p)
+			if err != nil {
+				log.Printf("TRUSTED_PROXIES: ignoring %q: %v", p, err)
+				continue
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			log.Printf("TRUSTED_PROXIES: ignoring %q: %v", p, err)
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+})
+
+// ClientIP returns the address of the client that sent the request. Behind a
+// proxy listed in TRUSTED_PROXIES it is taken from X-Forwarded-For, or
+// X-Real-IP, otherwise from the connection; a client cannot spoof it by
+// sending the headers itself.
+func ClientIP(r *http.Request) string {
+	return clientIP(r, trustedProxies())
+}
+
+func clientIP(r *http.Request, proxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remote, err := netip.ParseAddr(host)
+	if err != nil || !trusted(remote, proxies) {
+		return host
+	}
+
+	// Each proxy appends the address it received the request from, so the
+	// client is the rightmost entry that is not one of our proxies
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return host
+		}
+		if i == 0 || !trusted(addr, proxies) {
+			return addr.Unmap().String()
+		}
+	}
+
+	// Proxies that do not append X-Forwarded-For set X-Real-IP instead
+	if len(hops) == 0 {
+		if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+			return addr.Unmap().String()
+		}
+	}
+	return host
+}
+
+func trusted(addr netip.Addr, proxies []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, p := range proxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func int2(v *int16) pgtype.Int2 {
+	if v == nil {
+		return pgtype.Int2{}
+	}
+	return pgtype.Int2{Int16: *v, Valid: true}
+}
+
+func text(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
 }
@@ -0,0 +1,99 @@
+package session
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies := []netip.Prefix{
+		netip.MustParsePrefix("172.18.0.0/16"),
+		netip.MustParsePrefix("10.0.0.7/32"),
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.5:40000",
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "headers from an untrusted peer are ignored",
+			remoteAddr: "203.0.113.5:40000",
+			forwarded:  []string{"198.51.100.1"},
+			realIP:     "198.51.100.2",
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "behind caddy",
+			remoteAddr: "172.18.0.2:51234",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "spoofed entries left of the client are skipped",
+			remoteAddr: "172.18.0.2:51234",
+			forwarded:  []string{"1.2.3.4, 198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "chain of trusted proxies",
+			remoteAddr: "172.18.0.2:51234",
+			forwarded:  []string{"198.51.100.1, 10.0.0.7"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "repeated headers are one list",
+			remoteAddr: "172.18.0.2:51234",
+			forwarded:  []string{"1.2.3.4", "198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "X-Real-IP without X-Forwarded-For",
+			remoteAddr: "172.18.0.2:51234",
+			realIP:     "198.51.100.3",
+			want:       "198.51.100.3",
+		},
+		{
+			name:       "X-Real-IP does not override X-Forwarded-For",
+			remoteAddr: "172.18.0.2:51234",
+			forwarded:  []string{"198.51.100.1"},
+			realIP:     "1.2.3.4",
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "malformed entry falls back to the proxy",
+			remoteAddr: "172.18.0.2:51234",
+			forwarded:  []string{"198.51.100.1, bogus"},
+			want:       "172.18.0.2",
+		},
+		{
+			name:       "ipv6 client",
+			remoteAddr: "172.18.0.2:51234",
+			forwarded:  []string{"2001:db8::1"},
+			want:       "2001:db8::1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, f := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", f)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := clientIP(r, proxies); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package sla
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/notify"
+	"github.com/nobuww/simpel-ktp/internal/store"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// jamDigest is the local hour from which the daily digest goes out
+const jamDigest = 7
+
+// Digest emails each admin kecamatan the applications in their kecamatan
+// that are past their SLA target, grouped by kelurahan
+type Digest struct {
+	store    store.Repository
+	notifier notify.Notifier
+	clock    clock.Clock
+}
+
+func NewDigest(s store.Repository, n notify.Notifier, clk clock.Clock) *Digest {
+	return &Digest{store: s, notifier: n, clock: clk}
+}
+
+// Run checks every quarter of an hour whether today's digest is due. A run
+// claims the day in the database for a while, so a second server instance
+// does not send it at the same time. The day is done once every admin with
+// breached applications received it; until then later checks retry the
+// admins not reached yet.
+func (d *Digest) Run() {
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		if err := d.sendIfDue(context.Background()); err != nil {
+			log.Printf("Failed to send SLA digest: %v", err)
+		}
+	}
+}
+
+func (d *Digest) sendIfDue(ctx context.Context) error {
+	if clock.Local(d.clock.Now()).Hour() < jamDigest {
+		return nil
+	}
+	tanggal := pgtype.Date{Time: clock.Today(d.clock), Valid: true}
+	n, err := d.store.ClaimDigestSLA(ctx, tanggal)
+	if err != nil || n == 0 {
+		return err
+	}
+	// On failure the claim runs out and the next check tries again
+	if err := d.Send(ctx, tanggal); err != nil {
+		return err
+	}
+	return d.store.CompleteDigestSLA(ctx, tanggal)
+}
+
+// Send emails the digest of a day to the admins that have not received it
+// yet. Admins of a kecamatan without breached applications get nothing.
+func (d *Digest) Send(ctx context.Context, tanggal pgtype.Date) error {
+	rows, err := d.store.ListPermohonanLewatSLA(ctx)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	perKecamatan := map[int16][]pg_store.ListPermohonanLewatSLARow{}
+	for _, row := range rows {
+		perKecamatan[row.KecamatanID] = append(perKecamatan[row.KecamatanID], row)
+	}
+
+	admins, err := d.store.ListAdminKecamatanAktif(ctx)
+	if err != nil {
+		return err
+	}
+	penerima, err := d.store.ListPenerimaDigestSLA(ctx, tanggal)
+	if err != nil {
+		return err
+	}
+	sudah := make(map[uuid.UUID]bool, len(penerima))
+	for _, id := range penerima {
+		sudah[id] = true
+	}
+
+	now := d.clock.Now()
+	var errs []error
+	for _, admin := range admins {
+		daftar := perKecamatan[admin.KecamatanID.Int16]
+		if len(daftar) == 0 || sudah[admin.ID] {
+			continue
+		}
+		if admin.Email.String == "" {
+			log.Printf("SLA digest: admin kecamatan %s of Kecamatan %s has no email address, skipping %d breached applications",
+				admin.Username, admin.NamaKecamatan, len(daftar))
+			continue
+		}
+		err := d.notifier.Send(ctx, notify.Message{
+			Channel: notify.ChannelEmail,
+			To:      admin.Email.String,
+			Subject: fmt.Sprintf("%d permohonan melewati target SLA di Kecamatan %s", len(daftar), admin.NamaKecamatan),
+			Body:    isiDigest(admin, daftar, now),
+		})
+		if err == nil {
+			err = d.store.CreatePenerimaDigestSLA(ctx, pg_store.CreatePenerimaDigestSLAParams{
+				Tanggal:   tanggal,
+				PetugasID: admin.ID,
+			})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", admin.Username, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isiDigest writes the plain-text digest; rows arrive ordered by kelurahan
+func isiDigest(admin pg_store.ListAdminKecamatanAktifRow, rows []pg_store.ListPermohonanLewatSLARow, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Yth. %s,\n\n", admin.NamaPetugas)
+	fmt.Fprintf(&b, "Per %s terdapat %d permohonan di Kecamatan %s yang melewati target SLA statusnya:\n",
+		clock.Local(now).Format("02 Jan 2006 15:04"), len(rows), admin.NamaKecamatan)
+
+	kelurahan := ""
+	for _, row := range rows {
+		if row.NamaKelurahan != kelurahan {
+			kelurahan = row.NamaKelurahan
+			fmt.Fprintf(&b, "\n%s\n", kelurahan)
+		}
+		status := Label(row.StatusTerkini.String)
+		lewat := Hari(Terlambat(row.StatusSejak, int(row.TargetHari), now))
+		fmt.Fprintf(&b, "- %s  %s  %s, %s sejak %s (target %d hari, lewat %d hari)\n",
+			row.KodeBooking.String, row.NamaLengkap, row.JenisPermohonan, status,
+			clock.Local(row.StatusSejak).Format("02 Jan 2006"), row.TargetHari, lewat)
+	}
+
+	b.WriteString("\nDaftar lengkap tersedia di menu Permohonan panel admin dengan filter \"Lewat target SLA\".\n")
+	return b.String()
+}
@@ -0,0 +1,158 @@
+package sla
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nobuww/simpel-ktp/internal/clock"
+	"github.com/nobuww/simpel-ktp/internal/notify"
+	"github.com/nobuww/simpel-ktp/internal/store"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// digestRepo keeps the digest tables in memory; a claim is released by
+// calling expire, as the ten minutes running out would
+type digestRepo struct {
+	store.Repository
+	admins   []pg_store.ListAdminKecamatanAktifRow
+	rows     []pg_store.ListPermohonanLewatSLARow
+	claimed  bool
+	selesai  bool
+	penerima map[uuid.UUID]bool
+}
+
+func (r *digestRepo) ClaimDigestSLA(ctx context.Context, tanggal pgtype.Date) (int64, error) {
+	if r.claimed || r.selesai {
+		return 0, nil
+	}
+	r.claimed = true
+	return 1, nil
+}
+
+func (r *digestRepo) expire() { r.claimed = false }
+
+func (r *digestRepo) CompleteDigestSLA(ctx context.Context, tanggal pgtype.Date) error {
+	r.selesai = true
+	return nil
+}
+
+func (r *digestRepo) ListPermohonanLewatSLA(ctx context.Context) ([]pg_store.ListPermohonanLewatSLARow, error) {
+	return r.rows, nil
+}
+
+func (r *digestRepo) ListAdminKecamatanAktif(ctx context.Context) ([]pg_store.ListAdminKecamatanAktifRow, error) {
+	return r.admins, nil
+}
+
+func (r *digestRepo) ListPenerimaDigestSLA(ctx context.Context, tanggal pgtype.Date) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for id := range r.penerima {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (r *digestRepo) CreatePenerimaDigestSLA(ctx context.Context, arg pg_store.CreatePenerimaDigestSLAParams) error {
+	r.penerima[arg.PetugasID] = true
+	return nil
+}
+
+// flakyNotifier fails every message to the addresses in down
+type flakyNotifier struct {
+	down map[string]bool
+	sent []string
+}
+
+func (n *flakyNotifier) Send(ctx context.Context, msg notify.Message) error {
+	if n.down[msg.To] {
+		return errors.New("connection refused")
+	}
+	n.sent = append(n.sent, msg.To)
+	return nil
+}
+
+// admin returns an admin kecamatan whose username is not an address, so
+// the digest has to go to the email column
+func admin(kecamatan int16, email string) pg_store.ListAdminKecamatanAktifRow {
+	return pg_store.ListAdminKecamatanAktifRow{
+		ID:            uuid.New(),
+		KecamatanID:   pgtype.Int2{Int16: kecamatan, Valid: true},
+		NamaPetugas:   "Admin " + email,
+		Username:      "admin.kecamatan",
+		Email:         pgtype.Text{String: email, Valid: email != ""},
+		NamaKecamatan: "Kecamatan",
+	}
+}
+
+func lewat(kecamatan int16) pg_store.ListPermohonanLewatSLARow {
+	return pg_store.ListPermohonanLewatSLARow{
+		ID:            uuid.New(),
+		KodeBooking:   pgtype.Text{String: "ABC123", Valid: true},
+		StatusTerkini: pgtype.Text{String: "PROSES", Valid: true},
+		StatusSejak:   time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		TargetHari:    14,
+		KecamatanID:   kecamatan,
+	}
+}
+
+func TestDigestRetriesAdminsNotReached(t *testing.T) {
+	repo := &digestRepo{
+		admins: []pg_store.ListAdminKecamatanAktifRow{
+			admin(1, "camat1@example.go.id"),
+			admin(2, "camat2@example.go.id"),
+			admin(2, ""),
+			admin(3, "camat3@example.go.id"),
+		},
+		rows:     []pg_store.ListPermohonanLewatSLARow{lewat(1), lewat(2)},
+		penerima: map[uuid.UUID]bool{},
+	}
+	notifier := &flakyNotifier{down: map[string]bool{"camat2@example.go.id": true}}
+	d := NewDigest(repo, notifier, clock.Fixed(time.Date(2026, 10, 19, 1, 0, 0, 0, time.UTC))) // 08:00 WIB)
+	ctx := context.Background()
+
+	if err := d.sendIfDue(ctx); err == nil {
+		t.Fatal("expected the failed send to be reported")
+	}
+	if repo.selesai {
+		t.Fatal("day marked done although an admin was not reached")
+	}
+
+	// A second instance checking while the claim holds sends nothing
+	if err := d.sendIfDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.expire()
+	delete(notifier.down, "camat2@example.go.id")
+	if err := d.sendIfDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !repo.selesai {
+		t.Fatal("day not marked done after every admin was reached")
+	}
+
+	want := []string{"camat1@example.go.id", "camat2@example.go.id"}
+	if len(notifier.sent) != len(want) || notifier.sent[0] != want[0] || notifier.sent[1] != want[1] {
+		t.Errorf("sent to %v, want %v", notifier.sent, want)
+	}
+
+	repo.expire()
+	if err := d.sendIfDue(ctx); err != nil || len(notifier.sent) != 2 {
+		t.Errorf("digest sent again after the day was done: %v, %v", err, notifier.sent)
+	}
+}
+
+func TestDigestWaitsForItsHour(t *testing.T) {
+	repo := &digestRepo{penerima: map[uuid.UUID]bool{}}
+	d := NewDigest(repo, &flakyNotifier{}, clock.Fixed(time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC))) // 06:59 WIB)
+	if err := d.sendIfDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if repo.claimed {
+		t.Error("day claimed before jamDigest")
+	}
+}
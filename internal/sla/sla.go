@@ -0,0 +1,48 @@
+// Package sla measures how long a permohonan has waited in its current
+// status against the target set for that status and jenis, and sends the
+// daily digest of applications past their target to the admin kecamatan.
+//
+// The clock of an application starts when it entered its current status, as
+// recorded in riwayat_status; see the status_sejak database function.
+package sla
+
+import (
+	"math"
+	"time"
+)
+
+// Status is a status that can carry an SLA target
+type Status struct {
+	Kode  string
+	Label string
+}
+
+// StatusBerjalan are the statuses an application waits in, in workflow
+// order. SELESAI and DITOLAK are final and have no target.
+var StatusBerjalan = []Status{
+	{"VERIFIKASI", "Verifikasi"},
+	{"PROSES", "Proses"},
+	{"SIAP_AMBIL", "Siap Ambil"},
+}
+
+// Label returns the display name of a status
+func Label(kode string) string {
+	for _, s := range StatusBerjalan {
+		if s.Kode == kode {
+			return s.Label
+		}
+	}
+	return kode
+}
+
+// Terlambat returns how far past its target an application is that entered
+// its status at sejak, or zero while it is within target
+func Terlambat(sejak time.Time, targetHari int, now time.Time) time.Duration {
+	return max(now.Sub(sejak.Add(time.Duration(targetHari)*24*time.Hour)), 0)
+}
+
+// Hari rounds a duration up to whole days, so an application an hour past
+// its target counts as one day late
+func Hari(d time.Duration) int {
+	return int(math.Ceil(d.Hours() / 24))
+}
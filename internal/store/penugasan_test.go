@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
+)
+
+// TestAssignPermohonanRefusesStaleVersi runs against a migrated database
+// named by TEST_DATABASE_URL that holds at least one permohonan and two
+// petugas. It is skipped otherwise, and rolls back everything it changes.
+func TestAssignPermohonanRefusesStaleVersi(t *testing.T) {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback(ctx)
+	q := pg_store.New(tx)
+
+	var id uuid.UUID
+	var versi int32
+	var holder pgtype.UUID
+	err = tx.QueryRow(ctx, "SELECT id, versi, ditangani_oleh FROM permohonan LIMIT 1").Scan(&id, &versi, &holder)
+	if errors.Is(err, pgx.ErrNoRows) {
+		t.Skip("no permohonan in the database")
+	}
+	if err != nil {
+		t.Fatalf("load permohonan: %v", err)
+	}
+	var petugas []uuid.UUID
+	rows, err := tx.Query(ctx, "SELECT id FROM petugas WHERE id IS DISTINCT FROM $1 LIMIT 2", holder)
+	if err != nil {
+		t.Fatalf("load petugas: %v", err)
+	}
+	for rows.Next() {
+		var p uuid.UUID
+		if err := rows.Scan(&p); err != nil {
+			t.Fatalf("scan petugas: %v", err)
+		}
+		petugas = append(petugas, p)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("load petugas: %v", err)
+	}
+	if len(petugas) < 2 {
+		t.Skip("fewer than two petugas in the database")
+	}
+	first := pgtype.UUID{Bytes: petugas[0], Valid: true}
+	second := pgtype.UUID{Bytes: petugas[1], Valid: true}
+
+	readVersi := func() int32 {
+		row, err := q.GetPenugasanPermohonan(ctx, id)
+		if err != nil {
+			t.Fatalf("GetPenugasanPermohonan: %v", err)
+		}
+		return row.Versi
+	}
+
+	n, err := q.AssignPermohonan(ctx, pg_store.AssignPermohonanParams{ID: id, Versi: versi, PetugasID: first})
+	if err != nil || n != 1 {
+		t.Fatalf("assign at the current versi = %d, %v; want 1 row", n, err)
+	}
+	if got := readVersi(); got != versi+1 {
+		t.Fatalf("versi after assignment = %d, want %d", got, versi+1)
+	}
+
+	// A second petugas still holding the form at the old versi loses
+	n, err = q.AssignPermohonan(ctx, pg_store.AssignPermohonanParams{ID: id, Versi: versi, PetugasID: second})
+	if err != nil || n != 0 {
+		t.Fatalf("assign at the stale versi = %d, %v; want 0 rows", n, err)
+	}
+	row, err := q.GetPenugasanPermohonan(ctx, id)
+	if err != nil {
+		t.Fatalf("GetPenugasanPermohonan: %v", err)
+	}
+	if row.DitanganiOleh != first || row.Versi != versi+1 {
+		t.Errorf("stale assignment changed the permohonan: holder %v, versi %d", row.DitanganiOleh, row.Versi)
+	}
+
+	// Releasing is a change of holder too, so it moves the versi on again
+	n, err = q.AssignPermohonan(ctx, pg_store.AssignPermohonanParams{ID: id, Versi: versi + 1})
+	if err != nil || n != 1 {
+		t.Fatalf("release at the current versi = %d, %v; want 1 row", n, err)
+	}
+	if got := readVersi(); got != versi+2 {
+		t.Errorf("versi after release = %d, want %d", got, versi+2)
+	}
+}
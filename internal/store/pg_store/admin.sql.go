@@ -7,37 +7,152 @@ package pg_store
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countPendudukAdmin = `-- name: CountPendudukAdmin :one
+SELECT COUNT(*)
+FROM penduduk p
+LEFT JOIN ref_kelurahan k ON p.kelurahan_id = k.id
+WHERE 
+    ($1::text IS NULL OR 
+     p.nik::text ILIKE '%' || $1 || '%' OR 
+     p.nama_lengkap ILIKE '%' || $1 || '%' OR
+     p.alamat ILIKE '%' || $1 || '%' OR
+     p.nama_lengkap %> $1)
+    AND ($2::text IS NULL OR p.jenis_kelamin = $2)
+    AND ($3::smallint IS NULL OR k.kecamatan_id = $3)
+    AND ($4::smallint IS NULL OR p.kelurahan_id = $4)
+`
+
+type CountPendudukAdminParams struct {
+	Search       pgtype.Text `json:"search"`
+	JenisKelamin pgtype.Text `json:"jenisKelamin"`
+	KecamatanID  pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID  pgtype.Int2 `json:"kelurahanId"`
+}
+
+func (q *Queries) CountPendudukAdmin(ctx context.Context, arg CountPendudukAdminParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countPendudukAdmin,
+		arg.Search,
+		arg.JenisKelamin,
+		arg.KecamatanID,
+		arg.KelurahanID,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countPermohonanAdmin = `-- name: CountPermohonanAdmin :one
 SELECT COUNT(*) 
 FROM permohonan p
 JOIN penduduk pd ON p.nik = pd.nik
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
+LEFT JOIN target_sla t ON t.status = p.status_terkini AND t.jenis_permohonan = p.jenis_permohonan
 WHERE 
     ($1::text IS NULL OR 
-     p.nik ILIKE '%' || $1 || '%' OR 
-     p.kode_booking ILIKE '%' || $1 || '%' OR 
-     pd.nama_lengkap ILIKE '%' || $1 || '%')
+     p.nik::text ILIKE '%' || $1 || '%' OR 
+     p.kode_booking::text ILIKE '%' || $1 || '%' OR 
+     pd.nama_lengkap ILIKE '%' || $1 || '%' OR
+     pd.nama_lengkap %> $1)
     AND ($2::text IS NULL OR p.status_terkini = $2)
-    AND (
-        ($3::smallint IS NOT NULL AND js.lokasi_kelurahan_id = $3)
-        OR
-        ($3::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-    )
+    AND ($3::text IS NULL OR p.jenis_permohonan = $3)
+    AND ($4::timestamptz IS NULL OR p.created_at >= $4)
+    AND ($5::timestamptz IS NULL OR p.created_at < $5)
+    AND ($6::date IS NULL OR js.tanggal = $6)
+    AND ($7::smallint IS NULL OR l.kelurahan_id = $7)
+    AND ($8::int IS NULL OR COALESCE(
+            (SELECT MAX(rs.waktu_proses) FROM riwayat_status rs WHERE rs.permohonan_id = p.id),
+            p.created_at
+        ) < CURRENT_TIMESTAMP - make_interval(days => $8::int))
+    AND (NOT $9::bool OR EXISTS (
+            SELECT 1 FROM ref_syarat_dokumen sd
+            WHERE sd.jenis_permohonan = p.jenis_permohonan
+              AND NOT EXISTS (
+                  SELECT 1 FROM dokumen_syarat d
+                  WHERE d.permohonan_id = p.id AND d.jenis_dokumen = sd.jenis_dokumen
+              )))
+    AND (NOT $10::bool OR
+         status_sejak(p.id, p.status_terkini, p.created_at) + make_interval(days => t.target_hari::int) < CURRENT_TIMESTAMP)
+    AND ($11::uuid IS NULL OR
+         (p.ditangani_oleh = $11 AND p.status_terkini IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')))
+    AND (NOT $12::bool OR
+         (p.ditangani_oleh IS NULL AND p.status_terkini IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')))
+    AND ($13::smallint IS NULL OR l.kecamatan_id = $13)
+    AND ($14::smallint IS NULL OR l.kelurahan_id = $14)
 `
 
 type CountPermohonanAdminParams struct {
+	Search            pgtype.Text        `json:"search"`
+	Status            pgtype.Text        `json:"status"`
+	Jenis             pgtype.Text        `json:"jenis"`
+	DaftarDari        pgtype.Timestamptz `json:"daftarDari"`
+	DaftarSampai      pgtype.Timestamptz `json:"daftarSampai"`
+	TanggalSesi       pgtype.Date        `json:"tanggalSesi"`
+	FilterKelurahanID pgtype.Int2        `json:"filterKelurahanId"`
+	TertahanHari      pgtype.Int4        `json:"tertahanHari"`
+	DokumenKurang     bool               `json:"dokumenKurang"`
+	LewatSla          bool               `json:"lewatSla"`
+	DitanganiOleh     pgtype.UUID        `json:"ditanganiOleh"`
+	BelumDitangani    bool               `json:"belumDitangani"`
+	KecamatanID       pgtype.Int2        `json:"kecamatanId"`
+	KelurahanID       pgtype.Int2        `json:"kelurahanId"`
+}
+
+// Takes the same filters as ListPermohonanAdmin
+func (q *Queries) CountPermohonanAdmin(ctx context.Context, arg CountPermohonanAdminParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countPermohonanAdmin,
+		arg.Search,
+		arg.Status,
+		arg.Jenis,
+		arg.DaftarDari,
+		arg.DaftarSampai,
+		arg.TanggalSesi,
+		arg.FilterKelurahanID,
+		arg.TertahanHari,
+		arg.DokumenKurang,
+		arg.LewatSla,
+		arg.DitanganiOleh,
+		arg.BelumDitangani,
+		arg.KecamatanID,
+		arg.KelurahanID,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPetugasAdmin = `-- name: CountPetugasAdmin :one
+SELECT COUNT(*)
+FROM petugas p
+WHERE ($1::text IS NULL OR
+       p.username ILIKE '%' || $1 || '%' OR
+       p.nama_petugas ILIKE '%' || $1 || '%' OR
+       p.nip ILIKE '%' || $1 || '%')
+  AND ($2::text IS NULL OR p.role = $2)
+  AND ($3::smallint IS NULL OR p.kecamatan_id = $3)
+  AND ($4::smallint IS NULL OR p.kelurahan_id = $4)
+`
+
+type CountPetugasAdminParams struct {
 	Search      pgtype.Text `json:"search"`
-	Status      pgtype.Text `json:"status"`
+	Role        pgtype.Text `json:"role"`
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
 	KelurahanID pgtype.Int2 `json:"kelurahanId"`
 }
 
-func (q *Queries) CountPermohonanAdmin(ctx context.Context, arg CountPermohonanAdminParams) (int64, error) {
-	row := q.db.QueryRow(ctx, countPermohonanAdmin, arg.Search, arg.Status, arg.KelurahanID)
+func (q *Queries) CountPetugasAdmin(ctx context.Context, arg CountPetugasAdminParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countPetugasAdmin,
+		arg.Search,
+		arg.Role,
+		arg.KecamatanID,
+		arg.KelurahanID,
+	)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -53,15 +168,16 @@ SELECT
     COUNT(*) FILTER (WHERE status_terkini = 'DITOLAK') AS ditolak
 FROM permohonan p
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-WHERE (
-    -- Admin Kelurahan: filter by their kelurahan
-    ($1::smallint IS NOT NULL AND js.lokasi_kelurahan_id = $1)
-    OR
-    -- Admin Kecamatan: only see kecamatan-level data (NULL lokasi_kelurahan_id)
-    ($1::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-)
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
+WHERE ($1::smallint IS NULL OR l.kecamatan_id = $1)
+  AND ($2::smallint IS NULL OR l.kelurahan_id = $2)
 `
 
+type GetAdminDashboardStatsParams struct {
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+}
+
 type GetAdminDashboardStatsRow struct {
 	TotalPermohonan int64 `json:"totalPermohonan"`
 	Verifikasi      int64 `json:"verifikasi"`
@@ -71,8 +187,9 @@ type GetAdminDashboardStatsRow struct {
 	Ditolak         int64 `json:"ditolak"`
 }
 
-func (q *Queries) GetAdminDashboardStats(ctx context.Context, kelurahanID pgtype.Int2) (GetAdminDashboardStatsRow, error) {
-	row := q.db.QueryRow(ctx, getAdminDashboardStats, kelurahanID)
+// Admin Kota: no filter, Admin Kecamatan: their kecamatan, Admin Kelurahan: their kelurahan
+func (q *Queries) GetAdminDashboardStats(ctx context.Context, arg GetAdminDashboardStatsParams) (GetAdminDashboardStatsRow, error) {
+	row := q.db.QueryRow(ctx, getAdminDashboardStats, arg.KecamatanID, arg.KelurahanID)
 	var i GetAdminDashboardStatsRow
 	err := row.Scan(
 		&i.TotalPermohonan,
@@ -96,10 +213,10 @@ WHERE permohonan_id = $1
 `
 
 type GetDokumenByPermohonanRow struct {
-	ID           uuid.UUID        `json:"id"`
-	FilePath     string           `json:"filePath"`
-	JenisDokumen string           `json:"jenisDokumen"`
-	UploadedAt   pgtype.Timestamp `json:"uploadedAt"`
+	ID           uuid.UUID          `json:"id"`
+	FilePath     string             `json:"filePath"`
+	JenisDokumen string             `json:"jenisDokumen"`
+	UploadedAt   pgtype.Timestamptz `json:"uploadedAt"`
 }
 
 func (q *Queries) GetDokumenByPermohonan(ctx context.Context, permohonanID pgtype.UUID) ([]GetDokumenByPermohonanRow, error) {
@@ -134,9 +251,16 @@ SELECT
     COUNT(*) FILTER (WHERE jenis_kelamin = 'PEREMPUAN') AS perempuan,
     COUNT(*) AS wajib_ktp
 FROM penduduk p
-WHERE ($1::smallint IS NULL OR p.kelurahan_id = $1)
+LEFT JOIN ref_kelurahan k ON p.kelurahan_id = k.id
+WHERE ($1::smallint IS NULL OR k.kecamatan_id = $1)
+  AND ($2::smallint IS NULL OR p.kelurahan_id = $2)
 `
 
+type GetPendudukStatsAdminParams struct {
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+}
+
 type GetPendudukStatsAdminRow struct {
 	Total     int64 `json:"total"`
 	LakiLaki  int64 `json:"lakiLaki"`
@@ -144,8 +268,8 @@ type GetPendudukStatsAdminRow struct {
 	WajibKtp  int64 `json:"wajibKtp"`
 }
 
-func (q *Queries) GetPendudukStatsAdmin(ctx context.Context, kelurahanID pgtype.Int2) (GetPendudukStatsAdminRow, error) {
-	row := q.db.QueryRow(ctx, getPendudukStatsAdmin, kelurahanID)
+func (q *Queries) GetPendudukStatsAdmin(ctx context.Context, arg GetPendudukStatsAdminParams) (GetPendudukStatsAdminRow, error) {
+	row := q.db.QueryRow(ctx, getPendudukStatsAdmin, arg.KecamatanID, arg.KelurahanID)
 	var i GetPendudukStatsAdminRow
 	err := row.Scan(
 		&i.Total,
@@ -173,46 +297,60 @@ SELECT
     js.jam_mulai as jadwal_jam_mulai,
     js.jam_selesai as jadwal_jam_selesai,
     p.nomor_antrian_sesi as nomor_antrian,
-    k_lokasi.nama_kelurahan as lokasi_permohonan
+    l.nama_lokasi as lokasi_permohonan,
+    kec.nama_kecamatan as kecamatan,
+    kec.kode_wilayah,
+    pd.tanggal_lahir,
+    pd.status_perkawinan,
+    pd.hubungan_keluarga,
+    p.pemohon_nik,
+    pm.nama_lengkap as nama_pemohon
 FROM permohonan p
 JOIN penduduk pd ON p.nik = pd.nik
+LEFT JOIN penduduk pm ON p.pemohon_nik = pm.nik
 LEFT JOIN ref_kelurahan k_penduduk ON pd.kelurahan_id = k_penduduk.id
+LEFT JOIN ref_kecamatan kec ON k_penduduk.kecamatan_id = kec.id
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-LEFT JOIN ref_kelurahan k_lokasi ON js.lokasi_kelurahan_id = k_lokasi.id
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
 WHERE p.id = $1
-  AND (
-    ($2::smallint IS NOT NULL AND js.lokasi_kelurahan_id = $2)
-    OR
-    ($2::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-  )
+  AND ($2::smallint IS NULL OR l.kecamatan_id = $2)
+  AND ($3::smallint IS NULL OR l.kelurahan_id = $3)
 `
 
 type GetPermohonanDetailAdminParams struct {
 	ID          uuid.UUID   `json:"id"`
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
 	KelurahanID pgtype.Int2 `json:"kelurahanId"`
 }
 
 type GetPermohonanDetailAdminRow struct {
-	ID               uuid.UUID        `json:"id"`
-	KodeBooking      pgtype.Text      `json:"kodeBooking"`
-	Nik              pgtype.Text      `json:"nik"`
-	NamaLengkap      string           `json:"namaLengkap"`
-	JenisKelamin     string           `json:"jenisKelamin"`
-	Alamat           pgtype.Text      `json:"alamat"`
-	NoTelp           pgtype.Text      `json:"noTelp"`
-	Kelurahan        pgtype.Text      `json:"kelurahan"`
-	JenisPermohonan  string           `json:"jenisPermohonan"`
-	StatusTerkini    pgtype.Text      `json:"statusTerkini"`
-	TanggalDaftar    pgtype.Timestamp `json:"tanggalDaftar"`
-	JadwalTanggal    pgtype.Date      `json:"jadwalTanggal"`
-	JadwalJamMulai   pgtype.Time      `json:"jadwalJamMulai"`
-	JadwalJamSelesai pgtype.Time      `json:"jadwalJamSelesai"`
-	NomorAntrian     pgtype.Int2      `json:"nomorAntrian"`
-	LokasiPermohonan pgtype.Text      `json:"lokasiPermohonan"`
+	ID               uuid.UUID          `json:"id"`
+	KodeBooking      pgtype.Text        `json:"kodeBooking"`
+	Nik              pgtype.Text        `json:"nik"`
+	NamaLengkap      string             `json:"namaLengkap"`
+	JenisKelamin     string             `json:"jenisKelamin"`
+	Alamat           pgtype.Text        `json:"alamat"`
+	NoTelp           pgtype.Text        `json:"noTelp"`
+	Kelurahan        pgtype.Text        `json:"kelurahan"`
+	JenisPermohonan  string             `json:"jenisPermohonan"`
+	StatusTerkini    pgtype.Text        `json:"statusTerkini"`
+	TanggalDaftar    pgtype.Timestamptz `json:"tanggalDaftar"`
+	JadwalTanggal    pgtype.Date        `json:"jadwalTanggal"`
+	JadwalJamMulai   pgtype.Time        `json:"jadwalJamMulai"`
+	JadwalJamSelesai pgtype.Time        `json:"jadwalJamSelesai"`
+	NomorAntrian     pgtype.Int2        `json:"nomorAntrian"`
+	LokasiPermohonan pgtype.Text        `json:"lokasiPermohonan"`
+	Kecamatan        pgtype.Text        `json:"kecamatan"`
+	KodeWilayah      pgtype.Text        `json:"kodeWilayah"`
+	TanggalLahir     pgtype.Date        `json:"tanggalLahir"`
+	StatusPerkawinan string             `json:"statusPerkawinan"`
+	HubunganKeluarga pgtype.Text        `json:"hubunganKeluarga"`
+	PemohonNik       pgtype.Text        `json:"pemohonNik"`
+	NamaPemohon      pgtype.Text        `json:"namaPemohon"`
 }
 
 func (q *Queries) GetPermohonanDetailAdmin(ctx context.Context, arg GetPermohonanDetailAdminParams) (GetPermohonanDetailAdminRow, error) {
-	row := q.db.QueryRow(ctx, getPermohonanDetailAdmin, arg.ID, arg.KelurahanID)
+	row := q.db.QueryRow(ctx, getPermohonanDetailAdmin, arg.ID, arg.KecamatanID, arg.KelurahanID)
 	var i GetPermohonanDetailAdminRow
 	err := row.Scan(
 		&i.ID,
@@ -231,6 +369,59 @@ func (q *Queries) GetPermohonanDetailAdmin(ctx context.Context, arg GetPermohona
 		&i.JadwalJamSelesai,
 		&i.NomorAntrian,
 		&i.LokasiPermohonan,
+		&i.Kecamatan,
+		&i.KodeWilayah,
+		&i.TanggalLahir,
+		&i.StatusPerkawinan,
+		&i.HubunganKeluarga,
+		&i.PemohonNik,
+		&i.NamaPemohon,
+	)
+	return i, err
+}
+
+const getPermohonanWilayah = `-- name: GetPermohonanWilayah :one
+SELECT l.kecamatan_id, l.kelurahan_id
+FROM permohonan p
+JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+WHERE p.id = $1
+`
+
+type GetPermohonanWilayahRow struct {
+	KecamatanID int16       `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+}
+
+func (q *Queries) GetPermohonanWilayah(ctx context.Context, id uuid.UUID) (GetPermohonanWilayahRow, error) {
+	row := q.db.QueryRow(ctx, getPermohonanWilayah, id)
+	var i GetPermohonanWilayahRow
+	err := row.Scan(&i.KecamatanID, &i.KelurahanID)
+	return i, err
+}
+
+const getPetugasById = `-- name: GetPetugasById :one
+SELECT id, kelurahan_id, nip, nama_petugas, created_by, username, password_hash, is_active, created_at, role, kecamatan_id, updated_by, updated_at, email FROM petugas WHERE id = $1
+`
+
+func (q *Queries) GetPetugasById(ctx context.Context, id uuid.UUID) (Petugas, error) {
+	row := q.db.QueryRow(ctx, getPetugasById, id)
+	var i Petugas
+	err := row.Scan(
+		&i.ID,
+		&i.KelurahanID,
+		&i.Nip,
+		&i.NamaPetugas,
+		&i.CreatedBy,
+		&i.Username,
+		&i.PasswordHash,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.Role,
+		&i.KecamatanID,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+		&i.Email,
 	)
 	return i, err
 }
@@ -238,24 +429,34 @@ func (q *Queries) GetPermohonanDetailAdmin(ctx context.Context, arg GetPermohona
 const getPetugasStatsAdmin = `-- name: GetPetugasStatsAdmin :one
 SELECT 
     COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE role = 'ADMIN_KOTA') AS admin_kota,
     COUNT(*) FILTER (WHERE role = 'ADMIN_KECAMATAN') AS admin_kecamatan,
     COUNT(*) FILTER (WHERE role = 'ADMIN_KELURAHAN') AS admin_kelurahan,
     COUNT(*) FILTER (WHERE is_active = TRUE) AS aktif
-FROM petugas
+FROM petugas p
+WHERE ($1::smallint IS NULL OR p.kecamatan_id = $1)
+  AND ($2::smallint IS NULL OR p.kelurahan_id = $2)
 `
 
+type GetPetugasStatsAdminParams struct {
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+}
+
 type GetPetugasStatsAdminRow struct {
 	Total          int64 `json:"total"`
+	AdminKota      int64 `json:"adminKota"`
 	AdminKecamatan int64 `json:"adminKecamatan"`
 	AdminKelurahan int64 `json:"adminKelurahan"`
 	Aktif          int64 `json:"aktif"`
 }
 
-func (q *Queries) GetPetugasStatsAdmin(ctx context.Context) (GetPetugasStatsAdminRow, error) {
-	row := q.db.QueryRow(ctx, getPetugasStatsAdmin)
+func (q *Queries) GetPetugasStatsAdmin(ctx context.Context, arg GetPetugasStatsAdminParams) (GetPetugasStatsAdminRow, error) {
+	row := q.db.QueryRow(ctx, getPetugasStatsAdmin, arg.KecamatanID, arg.KelurahanID)
 	var i GetPetugasStatsAdminRow
 	err := row.Scan(
 		&i.Total,
+		&i.AdminKota,
 		&i.AdminKecamatan,
 		&i.AdminKelurahan,
 		&i.Aktif,
@@ -263,6 +464,28 @@ func (q *Queries) GetPetugasStatsAdmin(ctx context.Context) (GetPetugasStatsAdmi
 	return i, err
 }
 
+const insertRiwayatPetugas = `-- name: InsertRiwayatPetugas :exec
+INSERT INTO riwayat_petugas (petugas_id, aksi, dilakukan_oleh, keterangan)
+VALUES ($1, $2, $3, $4)
+`
+
+type InsertRiwayatPetugasParams struct {
+	PetugasID     uuid.UUID   `json:"petugasId"`
+	Aksi          string      `json:"aksi"`
+	DilakukanOleh pgtype.UUID `json:"dilakukanOleh"`
+	Keterangan    pgtype.Text `json:"keterangan"`
+}
+
+func (q *Queries) InsertRiwayatPetugas(ctx context.Context, arg InsertRiwayatPetugasParams) error {
+	_, err := q.db.Exec(ctx, insertRiwayatPetugas,
+		arg.PetugasID,
+		arg.Aksi,
+		arg.DilakukanOleh,
+		arg.Keterangan,
+	)
+	return err
+}
+
 const listPendudukAdmin = `-- name: ListPendudukAdmin :many
 SELECT 
     p.nik,
@@ -271,21 +494,41 @@ SELECT
     p.alamat,
     p.email,
     p.no_hp,
-    k.nama_kelurahan
+    k.nama_kelurahan,
+    kc.kode_wilayah
 FROM penduduk p
 LEFT JOIN ref_kelurahan k ON p.kelurahan_id = k.id
+LEFT JOIN ref_kecamatan kc ON k.kecamatan_id = kc.id
 WHERE 
-    ($1::text IS NULL OR 
-     p.nik ILIKE '%' || $1 || '%' OR 
-     p.nama_lengkap ILIKE '%' || $1 || '%' OR
-     p.alamat ILIKE '%' || $1 || '%')
-    AND ($2::smallint IS NULL OR p.kelurahan_id = $2)
-ORDER BY p.created_at DESC
+    ($3::text IS NULL OR 
+     p.nik::text ILIKE '%' || $3 || '%' OR 
+     p.nama_lengkap ILIKE '%' || $3 || '%' OR
+     p.alamat ILIKE '%' || $3 || '%' OR
+     p.nama_lengkap %> $3)
+    AND ($4::text IS NULL OR p.jenis_kelamin = $4)
+    AND ($5::smallint IS NULL OR k.kecamatan_id = $5)
+    AND ($6::smallint IS NULL OR p.kelurahan_id = $6)
+ORDER BY
+    CASE WHEN $7::text = 'relevansi' THEN word_similarity($3, concat_ws(' ', p.nama_lengkap, p.nik)) END DESC,
+    CASE WHEN $7::text = 'nama' AND NOT $8::bool THEN p.nama_lengkap END ASC,
+    CASE WHEN $7::text = 'nama' AND $8::bool THEN p.nama_lengkap END DESC,
+    CASE WHEN $7::text = 'kelurahan' AND NOT $8::bool THEN k.nama_kelurahan END ASC,
+    CASE WHEN $7::text = 'kelurahan' AND $8::bool THEN k.nama_kelurahan END DESC,
+    CASE WHEN $7::text = 'tanggal' AND NOT $8::bool THEN p.created_at END ASC,
+    p.created_at DESC,
+    p.nik
+LIMIT $1 OFFSET $2
 `
 
 type ListPendudukAdminParams struct {
-	Search      pgtype.Text `json:"search"`
-	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+	Limit        int32       `json:"limit"`
+	Offset       int32       `json:"offset"`
+	Search       pgtype.Text `json:"search"`
+	JenisKelamin pgtype.Text `json:"jenisKelamin"`
+	KecamatanID  pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID  pgtype.Int2 `json:"kelurahanId"`
+	SortBy       string      `json:"sortBy"`
+	SortDesc     bool        `json:"sortDesc"`
 }
 
 type ListPendudukAdminRow struct {
@@ -296,10 +539,22 @@ type ListPendudukAdminRow struct {
 	Email         pgtype.Text `json:"email"`
 	NoHp          pgtype.Text `json:"noHp"`
 	NamaKelurahan pgtype.Text `json:"namaKelurahan"`
+	KodeWilayah   pgtype.Text `json:"kodeWilayah"`
 }
 
+// sort_by is tanggal, nama, kelurahan or relevansi, which ranks the closest
+// matches to search first
 func (q *Queries) ListPendudukAdmin(ctx context.Context, arg ListPendudukAdminParams) ([]ListPendudukAdminRow, error) {
-	rows, err := q.db.Query(ctx, listPendudukAdmin, arg.Search, arg.KelurahanID)
+	rows, err := q.db.Query(ctx, listPendudukAdmin,
+		arg.Limit,
+		arg.Offset,
+		arg.Search,
+		arg.JenisKelamin,
+		arg.KecamatanID,
+		arg.KelurahanID,
+		arg.SortBy,
+		arg.SortDesc,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -315,6 +570,7 @@ func (q *Queries) ListPendudukAdmin(ctx context.Context, arg ListPendudukAdminPa
 			&i.Email,
 			&i.NoHp,
 			&i.NamaKelurahan,
+			&i.KodeWilayah,
 		); err != nil {
 			return nil, err
 		}
@@ -339,58 +595,130 @@ SELECT
     js.jam_mulai as jadwal_jam_mulai,
     js.jam_selesai as jadwal_jam_selesai,
     p.nomor_antrian_sesi as nomor_antrian,
-    k.id as lokasi_kelurahan_id,
-    k.nama_kelurahan as lokasi_nama_kelurahan
+    l.kelurahan_id as lokasi_kelurahan_id,
+    l.nama_lokasi,
+    status_sejak(p.id, p.status_terkini, p.created_at) as status_sejak,
+    t.target_hari as target_sla_hari,
+    pn.nama_petugas as nama_penangan
 FROM permohonan p
 JOIN penduduk pd ON p.nik = pd.nik
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
+LEFT JOIN target_sla t ON t.status = p.status_terkini AND t.jenis_permohonan = p.jenis_permohonan
+LEFT JOIN petugas pn ON p.ditangani_oleh = pn.id
 WHERE 
     ($3::text IS NULL OR 
-     p.nik ILIKE '%' || $3 || '%' OR 
-     p.kode_booking ILIKE '%' || $3 || '%' OR 
-     pd.nama_lengkap ILIKE '%' || $3 || '%')
+     p.nik::text ILIKE '%' || $3 || '%' OR 
+     p.kode_booking::text ILIKE '%' || $3 || '%' OR 
+     pd.nama_lengkap ILIKE '%' || $3 || '%' OR
+     pd.nama_lengkap %> $3)
     AND ($4::text IS NULL OR p.status_terkini = $4)
-    AND (
-        ($5::smallint IS NOT NULL AND js.lokasi_kelurahan_id = $5)
-        OR
-        ($5::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-    )
-ORDER BY p.created_at DESC
+    AND ($5::text IS NULL OR p.jenis_permohonan = $5)
+    AND ($6::timestamptz IS NULL OR p.created_at >= $6)
+    AND ($7::timestamptz IS NULL OR p.created_at < $7)
+    AND ($8::date IS NULL OR js.tanggal = $8)
+    AND ($9::smallint IS NULL OR l.kelurahan_id = $9)
+    AND ($10::int IS NULL OR COALESCE(
+            (SELECT MAX(rs.waktu_proses) FROM riwayat_status rs WHERE rs.permohonan_id = p.id),
+            p.created_at
+        ) < CURRENT_TIMESTAMP - make_interval(days => $10::int))
+    AND (NOT $11::bool OR EXISTS (
+            SELECT 1 FROM ref_syarat_dokumen sd
+            WHERE sd.jenis_permohonan = p.jenis_permohonan
+              AND NOT EXISTS (
+                  SELECT 1 FROM dokumen_syarat d
+                  WHERE d.permohonan_id = p.id AND d.jenis_dokumen = sd.jenis_dokumen
+              )))
+    AND (NOT $12::bool OR
+         status_sejak(p.id, p.status_terkini, p.created_at) + make_interval(days => t.target_hari::int) < CURRENT_TIMESTAMP)
+    AND ($13::uuid IS NULL OR
+         (p.ditangani_oleh = $13 AND p.status_terkini IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')))
+    AND (NOT $14::bool OR
+         (p.ditangani_oleh IS NULL AND p.status_terkini IN ('VERIFIKASI', 'PROSES', 'SIAP_AMBIL')))
+    AND ($15::smallint IS NULL OR l.kecamatan_id = $15)
+    AND ($16::smallint IS NULL OR l.kelurahan_id = $16)
+ORDER BY
+    CASE WHEN $17::text = 'relevansi' THEN word_similarity($3, concat_ws(' ', pd.nama_lengkap, p.kode_booking, p.nik)) END DESC,
+    CASE WHEN $17::text = 'nama' AND NOT $18::bool THEN pd.nama_lengkap END ASC,
+    CASE WHEN $17::text = 'nama' AND $18::bool THEN pd.nama_lengkap END DESC,
+    CASE WHEN $17::text = 'status' AND NOT $18::bool THEN array_position(ARRAY['VERIFIKASI', 'PROSES', 'SIAP_AMBIL', 'SELESAI', 'DITOLAK'], p.status_terkini::text) END ASC,
+    CASE WHEN $17::text = 'status' AND $18::bool THEN array_position(ARRAY['VERIFIKASI', 'PROSES', 'SIAP_AMBIL', 'SELESAI', 'DITOLAK'], p.status_terkini::text) END DESC,
+    CASE WHEN $17::text = 'sesi' AND NOT $18::bool THEN js.tanggal + js.jam_mulai END ASC,
+    CASE WHEN $17::text = 'sesi' AND $18::bool THEN js.tanggal + js.jam_mulai END DESC NULLS LAST,
+    CASE WHEN $17::text = 'tanggal' AND NOT $18::bool THEN p.created_at END ASC,
+    p.created_at DESC,
+    p.id
 LIMIT $1 OFFSET $2
 `
 
 type ListPermohonanAdminParams struct {
-	Limit       int32       `json:"limit"`
-	Offset      int32       `json:"offset"`
-	Search      pgtype.Text `json:"search"`
-	Status      pgtype.Text `json:"status"`
-	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+	Limit             int32              `json:"limit"`
+	Offset            int32              `json:"offset"`
+	Search            pgtype.Text        `json:"search"`
+	Status            pgtype.Text        `json:"status"`
+	Jenis             pgtype.Text        `json:"jenis"`
+	DaftarDari        pgtype.Timestamptz `json:"daftarDari"`
+	DaftarSampai      pgtype.Timestamptz `json:"daftarSampai"`
+	TanggalSesi       pgtype.Date        `json:"tanggalSesi"`
+	FilterKelurahanID pgtype.Int2        `json:"filterKelurahanId"`
+	TertahanHari      pgtype.Int4        `json:"tertahanHari"`
+	DokumenKurang     bool               `json:"dokumenKurang"`
+	LewatSla          bool               `json:"lewatSla"`
+	DitanganiOleh     pgtype.UUID        `json:"ditanganiOleh"`
+	BelumDitangani    bool               `json:"belumDitangani"`
+	KecamatanID       pgtype.Int2        `json:"kecamatanId"`
+	KelurahanID       pgtype.Int2        `json:"kelurahanId"`
+	SortBy            string             `json:"sortBy"`
+	SortDesc          bool               `json:"sortDesc"`
 }
 
 type ListPermohonanAdminRow struct {
-	ID                  uuid.UUID        `json:"id"`
-	KodeBooking         pgtype.Text      `json:"kodeBooking"`
-	Nik                 pgtype.Text      `json:"nik"`
-	NamaLengkap         string           `json:"namaLengkap"`
-	JenisPermohonan     string           `json:"jenisPermohonan"`
-	StatusTerkini       pgtype.Text      `json:"statusTerkini"`
-	TanggalDaftar       pgtype.Timestamp `json:"tanggalDaftar"`
-	JadwalTanggal       pgtype.Date      `json:"jadwalTanggal"`
-	JadwalJamMulai      pgtype.Time      `json:"jadwalJamMulai"`
-	JadwalJamSelesai    pgtype.Time      `json:"jadwalJamSelesai"`
-	NomorAntrian        pgtype.Int2      `json:"nomorAntrian"`
-	LokasiKelurahanID   pgtype.Int2      `json:"lokasiKelurahanId"`
-	LokasiNamaKelurahan pgtype.Text      `json:"lokasiNamaKelurahan"`
+	ID                uuid.UUID          `json:"id"`
+	KodeBooking       pgtype.Text        `json:"kodeBooking"`
+	Nik               pgtype.Text        `json:"nik"`
+	NamaLengkap       string             `json:"namaLengkap"`
+	JenisPermohonan   string             `json:"jenisPermohonan"`
+	StatusTerkini     pgtype.Text        `json:"statusTerkini"`
+	TanggalDaftar     pgtype.Timestamptz `json:"tanggalDaftar"`
+	JadwalTanggal     pgtype.Date        `json:"jadwalTanggal"`
+	JadwalJamMulai    pgtype.Time        `json:"jadwalJamMulai"`
+	JadwalJamSelesai  pgtype.Time        `json:"jadwalJamSelesai"`
+	NomorAntrian      pgtype.Int2        `json:"nomorAntrian"`
+	LokasiKelurahanID pgtype.Int2        `json:"lokasiKelurahanId"`
+	NamaLokasi        pgtype.Text        `json:"namaLokasi"`
+	StatusSejak       time.Time          `json:"statusSejak"`
+	TargetSlaHari     pgtype.Int2        `json:"targetSlaHari"`
+	NamaPenangan      pgtype.Text        `json:"namaPenangan"`
 }
 
+// sort_by is tanggal, nama, status, sesi or relevansi; status sorts in
+// workflow order and relevansi ranks the closest matches to search first.
+// Names also match search when they are similar rather than equal, so
+// "Muhamad" finds "Muhammad".
+// daftar_sampai is exclusive; tertahan_hari keeps applications whose status
+// has not changed for that many days, and lewat_sla those past the SLA
+// target of their status. ditangani_oleh is the queue of one petugas and
+// belum_ditangani the unassigned applications; both keep open ones only.
 func (q *Queries) ListPermohonanAdmin(ctx context.Context, arg ListPermohonanAdminParams) ([]ListPermohonanAdminRow, error) {
 	rows, err := q.db.Query(ctx, listPermohonanAdmin,
 		arg.Limit,
 		arg.Offset,
 		arg.Search,
 		arg.Status,
+		arg.Jenis,
+		arg.DaftarDari,
+		arg.DaftarSampai,
+		arg.TanggalSesi,
+		arg.FilterKelurahanID,
+		arg.TertahanHari,
+		arg.DokumenKurang,
+		arg.LewatSla,
+		arg.DitanganiOleh,
+		arg.BelumDitangani,
+		arg.KecamatanID,
 		arg.KelurahanID,
+		arg.SortBy,
+		arg.SortDesc,
 	)
 	if err != nil {
 		return nil, err
@@ -412,7 +740,10 @@ func (q *Queries) ListPermohonanAdmin(ctx context.Context, arg ListPermohonanAdm
 			&i.JadwalJamSelesai,
 			&i.NomorAntrian,
 			&i.LokasiKelurahanID,
-			&i.LokasiNamaKelurahan,
+			&i.NamaLokasi,
+			&i.StatusSejak,
+			&i.TargetSlaHari,
+			&i.NamaPenangan,
 		); err != nil {
 			return nil, err
 		}
@@ -433,25 +764,66 @@ SELECT
     p.role,
     p.is_active,
     CASE WHEN p.is_active THEN 'AKTIF' ELSE 'NON_AKTIF' END AS status,
-    k.nama_kelurahan
+    k.nama_kelurahan,
+    kec.nama_kecamatan
 FROM petugas p
 LEFT JOIN ref_kelurahan k ON p.kelurahan_id = k.id
-ORDER BY p.created_at DESC
+LEFT JOIN ref_kecamatan kec ON p.kecamatan_id = kec.id
+WHERE ($3::text IS NULL OR
+       p.username ILIKE '%' || $3 || '%' OR
+       p.nama_petugas ILIKE '%' || $3 || '%' OR
+       p.nip ILIKE '%' || $3 || '%')
+  AND ($4::text IS NULL OR p.role = $4)
+  AND ($5::smallint IS NULL OR p.kecamatan_id = $5)
+  AND ($6::smallint IS NULL OR p.kelurahan_id = $6)
+ORDER BY
+    CASE WHEN $7::text = 'nama' AND NOT $8::bool THEN p.nama_petugas END ASC,
+    CASE WHEN $7::text = 'nama' AND $8::bool THEN p.nama_petugas END DESC,
+    CASE WHEN $7::text = 'role' AND NOT $8::bool THEN p.role END ASC,
+    CASE WHEN $7::text = 'role' AND $8::bool THEN p.role END DESC,
+    CASE WHEN $7::text = 'status' AND NOT $8::bool THEN p.is_active END ASC,
+    CASE WHEN $7::text = 'status' AND $8::bool THEN p.is_active END DESC,
+    CASE WHEN $7::text = 'tanggal' AND NOT $8::bool THEN p.created_at END ASC,
+    p.created_at DESC,
+    p.id
+LIMIT $1 OFFSET $2
 `
 
+type ListPetugasAdminParams struct {
+	Limit       int32       `json:"limit"`
+	Offset      int32       `json:"offset"`
+	Search      pgtype.Text `json:"search"`
+	Role        pgtype.Text `json:"role"`
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+	SortBy      string      `json:"sortBy"`
+	SortDesc    bool        `json:"sortDesc"`
+}
+
 type ListPetugasAdminRow struct {
 	ID            uuid.UUID   `json:"id"`
 	Username      string      `json:"username"`
 	NamaPetugas   string      `json:"namaPetugas"`
 	Nip           pgtype.Text `json:"nip"`
 	Role          string      `json:"role"`
-	IsActive      pgtype.Bool `json:"isActive"`
+	IsActive      bool        `json:"isActive"`
 	Status        string      `json:"status"`
 	NamaKelurahan pgtype.Text `json:"namaKelurahan"`
+	NamaKecamatan pgtype.Text `json:"namaKecamatan"`
 }
 
-func (q *Queries) ListPetugasAdmin(ctx context.Context) ([]ListPetugasAdminRow, error) {
-	rows, err := q.db.Query(ctx, listPetugasAdmin)
+// sort_by is tanggal, nama, role or status
+func (q *Queries) ListPetugasAdmin(ctx context.Context, arg ListPetugasAdminParams) ([]ListPetugasAdminRow, error) {
+	rows, err := q.db.Query(ctx, listPetugasAdmin,
+		arg.Limit,
+		arg.Offset,
+		arg.Search,
+		arg.Role,
+		arg.KecamatanID,
+		arg.KelurahanID,
+		arg.SortBy,
+		arg.SortDesc,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -468,6 +840,52 @@ func (q *Queries) ListPetugasAdmin(ctx context.Context) ([]ListPetugasAdminRow,
 			&i.IsActive,
 			&i.Status,
 			&i.NamaKelurahan,
+			&i.NamaKecamatan,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRiwayatPetugas = `-- name: ListRiwayatPetugas :many
+SELECT
+    rp.aksi,
+    rp.keterangan,
+    rp.waktu,
+    p.nama_petugas AS dilakukan_oleh
+FROM riwayat_petugas rp
+LEFT JOIN petugas p ON rp.dilakukan_oleh = p.id
+WHERE rp.petugas_id = $1
+ORDER BY rp.waktu DESC
+LIMIT 20
+`
+
+type ListRiwayatPetugasRow struct {
+	Aksi          string      `json:"aksi"`
+	Keterangan    pgtype.Text `json:"keterangan"`
+	Waktu         time.Time   `json:"waktu"`
+	DilakukanOleh pgtype.Text `json:"dilakukanOleh"`
+}
+
+func (q *Queries) ListRiwayatPetugas(ctx context.Context, petugasID uuid.UUID) ([]ListRiwayatPetugasRow, error) {
+	rows, err := q.db.Query(ctx, listRiwayatPetugas, petugasID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRiwayatPetugasRow
+	for rows.Next() {
+		var i ListRiwayatPetugasRow
+		if err := rows.Scan(
+			&i.Aksi,
+			&i.Keterangan,
+			&i.Waktu,
+			&i.DilakukanOleh,
 		); err != nil {
 			return nil, err
 		}
@@ -488,18 +906,21 @@ SELECT
     js.kuota_terisi,
     js.kuota_maksimal,
     js.status_sesi,
-    COALESCE(k.nama_kelurahan, 'Kecamatan Pademangan')::text as nama_kelurahan
+    l.nama_lokasi
 FROM jadwal_sesi js
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
-WHERE js.tanggal = CURRENT_DATE
-  AND (
-    ($1::smallint IS NOT NULL AND js.lokasi_kelurahan_id = $1)
-    OR
-    ($1::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-  )
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
+WHERE js.tanggal = $1
+  AND ($2::smallint IS NULL OR l.kecamatan_id = $2)
+  AND ($3::smallint IS NULL OR l.kelurahan_id = $3)
 ORDER BY js.jam_mulai
 `
 
+type ListTodayJadwalParams struct {
+	Tanggal     pgtype.Date `json:"tanggal"`
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+}
+
 type ListTodayJadwalRow struct {
 	ID            uuid.UUID   `json:"id"`
 	Tanggal       pgtype.Date `json:"tanggal"`
@@ -508,11 +929,11 @@ type ListTodayJadwalRow struct {
 	KuotaTerisi   int16       `json:"kuotaTerisi"`
 	KuotaMaksimal int16       `json:"kuotaMaksimal"`
 	StatusSesi    pgtype.Text `json:"statusSesi"`
-	NamaKelurahan string      `json:"namaKelurahan"`
+	NamaLokasi    string      `json:"namaLokasi"`
 }
 
-func (q *Queries) ListTodayJadwal(ctx context.Context, kelurahanID pgtype.Int2) ([]ListTodayJadwalRow, error) {
-	rows, err := q.db.Query(ctx, listTodayJadwal, kelurahanID)
+func (q *Queries) ListTodayJadwal(ctx context.Context, arg ListTodayJadwalParams) ([]ListTodayJadwalRow, error) {
+	rows, err := q.db.Query(ctx, listTodayJadwal, arg.Tanggal, arg.KecamatanID, arg.KelurahanID)
 	if err != nil {
 		return nil, err
 	}
@@ -528,7 +949,7 @@ func (q *Queries) ListTodayJadwal(ctx context.Context, kelurahanID pgtype.Int2)
 			&i.KuotaTerisi,
 			&i.KuotaMaksimal,
 			&i.StatusSesi,
-			&i.NamaKelurahan,
+			&i.NamaLokasi,
 		); err != nil {
 			return nil, err
 		}
@@ -540,26 +961,136 @@ func (q *Queries) ListTodayJadwal(ctx context.Context, kelurahanID pgtype.Int2)
 	return items, nil
 }
 
-const updatePermohonanStatusAdmin = `-- name: UpdatePermohonanStatusAdmin :exec
+const setPetugasActive = `-- name: SetPetugasActive :exec
+UPDATE petugas
+SET is_active = $2,
+    updated_by = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type SetPetugasActiveParams struct {
+	ID        uuid.UUID   `json:"id"`
+	IsActive  bool        `json:"isActive"`
+	UpdatedBy pgtype.UUID `json:"updatedBy"`
+}
+
+func (q *Queries) SetPetugasActive(ctx context.Context, arg SetPetugasActiveParams) error {
+	_, err := q.db.Exec(ctx, setPetugasActive, arg.ID, arg.IsActive, arg.UpdatedBy)
+	return err
+}
+
+const updatePermohonanStatusAdmin = `-- name: UpdatePermohonanStatusAdmin :execrows
 UPDATE permohonan p
 SET status_terkini = $2
-FROM jadwal_sesi js
+FROM jadwal_sesi js, lokasi_layanan l
 WHERE p.id = $1
+  AND p.ditangani_oleh = $3
+  AND p.versi = $4
   AND p.jadwal_sesi_id = js.id
-  AND (
-    ($3::smallint IS NOT NULL AND js.lokasi_kelurahan_id = $3)
-    OR
-    ($3::smallint IS NULL AND js.lokasi_kelurahan_id IS NULL)
-  )
+  AND js.lokasi_id = l.id
+  AND ($5::smallint IS NULL OR l.kecamatan_id = $5)
+  AND ($6::smallint IS NULL OR l.kelurahan_id = $6)
 `
 
 type UpdatePermohonanStatusAdminParams struct {
 	ID            uuid.UUID   `json:"id"`
 	StatusTerkini pgtype.Text `json:"statusTerkini"`
+	PetugasID     pgtype.UUID `json:"petugasId"`
+	Versi         int32       `json:"versi"`
+	KecamatanID   pgtype.Int2 `json:"kecamatanId"`
 	KelurahanID   pgtype.Int2 `json:"kelurahanId"`
 }
 
-func (q *Queries) UpdatePermohonanStatusAdmin(ctx context.Context, arg UpdatePermohonanStatusAdminParams) error {
-	_, err := q.db.Exec(ctx, updatePermohonanStatusAdmin, arg.ID, arg.StatusTerkini, arg.KelurahanID)
+// Changes the status only for the petugas holding the application and only
+// at the versi their form was loaded with; no rows means someone else holds
+// it or it changed in the meantime.
+func (q *Queries) UpdatePermohonanStatusAdmin(ctx context.Context, arg UpdatePermohonanStatusAdminParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePermohonanStatusAdmin,
+		arg.ID,
+		arg.StatusTerkini,
+		arg.PetugasID,
+		arg.Versi,
+		arg.KecamatanID,
+		arg.KelurahanID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updatePetugasPassword = `-- name: UpdatePetugasPassword :exec
+UPDATE petugas
+SET password_hash = $2,
+    updated_by = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UpdatePetugasPasswordParams struct {
+	ID           uuid.UUID   `json:"id"`
+	PasswordHash string      `json:"passwordHash"`
+	UpdatedBy    pgtype.UUID `json:"updatedBy"`
+}
+
+func (q *Queries) UpdatePetugasPassword(ctx context.Context, arg UpdatePetugasPasswordParams) error {
+	_, err := q.db.Exec(ctx, updatePetugasPassword, arg.ID, arg.PasswordHash, arg.UpdatedBy)
+	return err
+}
+
+const updatePetugasProfil = `-- name: UpdatePetugasProfil :exec
+UPDATE petugas
+SET nama_petugas = $2,
+    nip = $3,
+    email = $4,
+    updated_by = $5,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UpdatePetugasProfilParams struct {
+	ID          uuid.UUID   `json:"id"`
+	NamaPetugas string      `json:"namaPetugas"`
+	Nip         pgtype.Text `json:"nip"`
+	Email       pgtype.Text `json:"email"`
+	UpdatedBy   pgtype.UUID `json:"updatedBy"`
+}
+
+func (q *Queries) UpdatePetugasProfil(ctx context.Context, arg UpdatePetugasProfilParams) error {
+	_, err := q.db.Exec(ctx, updatePetugasProfil,
+		arg.ID,
+		arg.NamaPetugas,
+		arg.Nip,
+		arg.Email,
+		arg.UpdatedBy,
+	)
+	return err
+}
+
+const updatePetugasWilayah = `-- name: UpdatePetugasWilayah :exec
+UPDATE petugas
+SET kecamatan_id = $2,
+    kelurahan_id = $3,
+    updated_by = $4,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UpdatePetugasWilayahParams struct {
+	ID          uuid.UUID   `json:"id"`
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+	UpdatedBy   pgtype.UUID `json:"updatedBy"`
+}
+
+// The role trigger derives the new role from the wilayah columns
+func (q *Queries) UpdatePetugasWilayah(ctx context.Context, arg UpdatePetugasWilayahParams) error {
+	_, err := q.db.Exec(ctx, updatePetugasWilayah,
+		arg.ID,
+		arg.KecamatanID,
+		arg.KelurahanID,
+		arg.UpdatedBy,
+	)
 	return err
 }
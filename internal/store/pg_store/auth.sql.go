@@ -8,6 +8,7 @@ package pg_store
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -33,6 +34,51 @@ func (q *Queries) CheckPendudukExists(ctx context.Context, nik string) (bool, er
 	return exists, err
 }
 
+const countKodeResetPasswordSince = `-- name: CountKodeResetPasswordSince :one
+SELECT COUNT(*) FROM kode_reset_password
+WHERE nik = $1 AND created_at > CURRENT_TIMESTAMP - make_interval(mins => $2::int)
+`
+
+type CountKodeResetPasswordSinceParams struct {
+	Nik     string `json:"nik"`
+	Minutes int32  `json:"minutes"`
+}
+
+func (q *Queries) CountKodeResetPasswordSince(ctx context.Context, arg CountKodeResetPasswordSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countKodeResetPasswordSince, arg.Nik, arg.Minutes)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createKodeResetPassword = `-- name: CreateKodeResetPassword :exec
+WITH spent AS (
+    UPDATE kode_reset_password
+    SET digunakan_at = CURRENT_TIMESTAMP
+    WHERE nik = $1 AND digunakan_at IS NULL
+)
+INSERT INTO kode_reset_password (nik, kode_hash, kanal, expires_at)
+VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(mins => $4::int))
+`
+
+type CreateKodeResetPasswordParams struct {
+	Nik        string `json:"nik"`
+	KodeHash   string `json:"kodeHash"`
+	Kanal      string `json:"kanal"`
+	TtlMinutes int32  `json:"ttlMinutes"`
+}
+
+// Issuing a new code spends any earlier one
+func (q *Queries) CreateKodeResetPassword(ctx context.Context, arg CreateKodeResetPasswordParams) error {
+	_, err := q.db.Exec(ctx, createKodeResetPassword,
+		arg.Nik,
+		arg.KodeHash,
+		arg.Kanal,
+		arg.TtlMinutes,
+	)
+	return err
+}
+
 const createPenduduk = `-- name: CreatePenduduk :one
 INSERT INTO penduduk (
     nik,
@@ -42,20 +88,24 @@ INSERT INTO penduduk (
     nama_lengkap,
     alamat,
     no_hp,
-    jenis_kelamin
-) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING nik, kelurahan_id, email, password_hash, nama_lengkap, alamat, no_hp, created_at, jenis_kelamin
+    jenis_kelamin,
+    tanggal_lahir,
+    status_perkawinan
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING nik, kelurahan_id, email, password_hash, nama_lengkap, alamat, no_hp, created_at, jenis_kelamin, tanggal_lahir, status_perkawinan, no_kk, hubungan_keluarga, wali_disetujui_oleh, wali_disetujui_at
 `
 
 type CreatePendudukParams struct {
-	Nik          string      `json:"nik"`
-	KelurahanID  pgtype.Int2 `json:"kelurahanId"`
-	Email        pgtype.Text `json:"email"`
-	PasswordHash pgtype.Text `json:"passwordHash"`
-	NamaLengkap  string      `json:"namaLengkap"`
-	Alamat       pgtype.Text `json:"alamat"`
-	NoHp         pgtype.Text `json:"noHp"`
-	JenisKelamin string      `json:"jenisKelamin"`
+	Nik              string      `json:"nik"`
+	KelurahanID      pgtype.Int2 `json:"kelurahanId"`
+	Email            pgtype.Text `json:"email"`
+	PasswordHash     pgtype.Text `json:"passwordHash"`
+	NamaLengkap      string      `json:"namaLengkap"`
+	Alamat           pgtype.Text `json:"alamat"`
+	NoHp             pgtype.Text `json:"noHp"`
+	JenisKelamin     string      `json:"jenisKelamin"`
+	TanggalLahir     pgtype.Date `json:"tanggalLahir"`
+	StatusPerkawinan string      `json:"statusPerkawinan"`
 }
 
 func (q *Queries) CreatePenduduk(ctx context.Context, arg CreatePendudukParams) (Penduduk, error) {
@@ -68,6 +118,8 @@ func (q *Queries) CreatePenduduk(ctx context.Context, arg CreatePendudukParams)
 		arg.Alamat,
 		arg.NoHp,
 		arg.JenisKelamin,
+		arg.TanggalLahir,
+		arg.StatusPerkawinan,
 	)
 	var i Penduduk
 	err := row.Scan(
@@ -80,12 +132,41 @@ func (q *Queries) CreatePenduduk(ctx context.Context, arg CreatePendudukParams)
 		&i.NoHp,
 		&i.CreatedAt,
 		&i.JenisKelamin,
+		&i.TanggalLahir,
+		&i.StatusPerkawinan,
+		&i.NoKk,
+		&i.HubunganKeluarga,
+		&i.WaliDisetujuiOleh,
+		&i.WaliDisetujuiAt,
+	)
+	return i, err
+}
+
+const getKodeResetPasswordAktif = `-- name: GetKodeResetPasswordAktif :one
+SELECT id, nik, kode_hash, kanal, percobaan, created_at, expires_at, digunakan_at FROM kode_reset_password
+WHERE nik = $1 AND digunakan_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetKodeResetPasswordAktif(ctx context.Context, nik string) (KodeResetPassword, error) {
+	row := q.db.QueryRow(ctx, getKodeResetPasswordAktif, nik)
+	var i KodeResetPassword
+	err := row.Scan(
+		&i.ID,
+		&i.Nik,
+		&i.KodeHash,
+		&i.Kanal,
+		&i.Percobaan,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.DigunakanAt,
 	)
 	return i, err
 }
 
 const getPendudukByNIK = `-- name: GetPendudukByNIK :one
-SELECT nik, kelurahan_id, email, password_hash, nama_lengkap, alamat, no_hp, created_at, jenis_kelamin FROM penduduk WHERE nik = $1
+SELECT nik, kelurahan_id, email, password_hash, nama_lengkap, alamat, no_hp, created_at, jenis_kelamin, tanggal_lahir, status_perkawinan, no_kk, hubungan_keluarga, wali_disetujui_oleh, wali_disetujui_at FROM penduduk WHERE nik = $1
 `
 
 func (q *Queries) GetPendudukByNIK(ctx context.Context, nik string) (Penduduk, error) {
@@ -101,12 +182,18 @@ func (q *Queries) GetPendudukByNIK(ctx context.Context, nik string) (Penduduk, e
 		&i.NoHp,
 		&i.CreatedAt,
 		&i.JenisKelamin,
+		&i.TanggalLahir,
+		&i.StatusPerkawinan,
+		&i.NoKk,
+		&i.HubunganKeluarga,
+		&i.WaliDisetujuiOleh,
+		&i.WaliDisetujuiAt,
 	)
 	return i, err
 }
 
 const getPetugasByNIP = `-- name: GetPetugasByNIP :one
-SELECT id, kelurahan_id, nip, nama_petugas, created_by, username, password_hash, is_active, created_at, role FROM petugas WHERE nip = $1 AND is_active = TRUE
+SELECT id, kelurahan_id, nip, nama_petugas, created_by, username, password_hash, is_active, created_at, role, kecamatan_id, updated_by, updated_at, email FROM petugas WHERE nip = $1
 `
 
 func (q *Queries) GetPetugasByNIP(ctx context.Context, nip pgtype.Text) (Petugas, error) {
@@ -123,6 +210,86 @@ func (q *Queries) GetPetugasByNIP(ctx context.Context, nip pgtype.Text) (Petugas
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.Role,
+		&i.KecamatanID,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+		&i.Email,
 	)
 	return i, err
 }
+
+const incrementPercobaanKodeReset = `-- name: IncrementPercobaanKodeReset :one
+UPDATE kode_reset_password
+SET percobaan = percobaan + 1
+WHERE id = $1
+  AND percobaan < $2::int
+  AND digunakan_at IS NULL
+RETURNING percobaan
+`
+
+type IncrementPercobaanKodeResetParams struct {
+	ID       uuid.UUID `json:"id"`
+	MaxTries int32     `json:"maxTries"`
+}
+
+// Counts a try before the code is compared; no row means the code is spent
+// or out of tries, so parallel guesses cannot exceed max_tries
+func (q *Queries) IncrementPercobaanKodeReset(ctx context.Context, arg IncrementPercobaanKodeResetParams) (int16, error) {
+	row := q.db.QueryRow(ctx, incrementPercobaanKodeReset, arg.ID, arg.MaxTries)
+	var percobaan int16
+	err := row.Scan(&percobaan)
+	return percobaan, err
+}
+
+const listPermissionByRole = `-- name: ListPermissionByRole :many
+SELECT permission FROM role_permission WHERE role = $1 ORDER BY permission
+`
+
+func (q *Queries) ListPermissionByRole(ctx context.Context, role string) ([]string, error) {
+	rows, err := q.db.Query(ctx, listPermissionByRole, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		items = append(items, permission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPendudukPassword = `-- name: SetPendudukPassword :exec
+UPDATE penduduk SET password_hash = $2 WHERE nik = $1
+`
+
+type SetPendudukPasswordParams struct {
+	Nik          string      `json:"nik"`
+	PasswordHash pgtype.Text `json:"passwordHash"`
+}
+
+func (q *Queries) SetPendudukPassword(ctx context.Context, arg SetPendudukPasswordParams) error {
+	_, err := q.db.Exec(ctx, setPendudukPassword, arg.Nik, arg.PasswordHash)
+	return err
+}
+
+const useKodeResetPassword = `-- name: UseKodeResetPassword :execrows
+UPDATE kode_reset_password
+SET digunakan_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND digunakan_at IS NULL
+`
+
+// No row means another request used the code first
+func (q *Queries) UseKodeResetPassword(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, useKodeResetPassword, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
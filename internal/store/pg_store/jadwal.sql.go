@@ -27,7 +27,7 @@ func (q *Queries) CountPermohonanByJadwal(ctx context.Context, jadwalSesiID pgty
 
 const createJadwalSesi = `-- name: CreateJadwalSesi :one
 INSERT INTO jadwal_sesi (
-    lokasi_kelurahan_id,
+    lokasi_id,
     tanggal,
     jam_mulai,
     jam_selesai,
@@ -35,21 +35,21 @@ INSERT INTO jadwal_sesi (
     kuota_terisi,
     status_sesi
 ) VALUES ($1, $2, $3, $4, $5, 0, 'BUKA')
-ON CONFLICT (tanggal, jam_mulai, lokasi_kelurahan_id) DO NOTHING
+ON CONFLICT (tanggal, jam_mulai, lokasi_id) DO NOTHING
 RETURNING id
 `
 
 type CreateJadwalSesiParams struct {
-	LokasiKelurahanID pgtype.Int2 `json:"lokasiKelurahanId"`
-	Tanggal           pgtype.Date `json:"tanggal"`
-	JamMulai          pgtype.Time `json:"jamMulai"`
-	JamSelesai        pgtype.Time `json:"jamSelesai"`
-	KuotaMaksimal     int16       `json:"kuotaMaksimal"`
+	LokasiID      int16       `json:"lokasiId"`
+	Tanggal       pgtype.Date `json:"tanggal"`
+	JamMulai      pgtype.Time `json:"jamMulai"`
+	JamSelesai    pgtype.Time `json:"jamSelesai"`
+	KuotaMaksimal int16       `json:"kuotaMaksimal"`
 }
 
 func (q *Queries) CreateJadwalSesi(ctx context.Context, arg CreateJadwalSesiParams) (uuid.UUID, error) {
 	row := q.db.QueryRow(ctx, createJadwalSesi,
-		arg.LokasiKelurahanID,
+		arg.LokasiID,
 		arg.Tanggal,
 		arg.JamMulai,
 		arg.JamSelesai,
@@ -61,25 +61,46 @@ func (q *Queries) CreateJadwalSesi(ctx context.Context, arg CreateJadwalSesiPara
 }
 
 const deleteJadwalSesi = `-- name: DeleteJadwalSesi :exec
-DELETE FROM jadwal_sesi
-WHERE id = $1
-  AND (
-    ($2::integer IS NOT NULL AND lokasi_kelurahan_id = $2)
-    OR
-    ($2::integer IS NULL AND lokasi_kelurahan_id IS NULL)
-  )
+DELETE FROM jadwal_sesi js
+USING lokasi_layanan l
+WHERE js.id = $1
+  AND js.lokasi_id = l.id
+  AND ($2::smallint IS NULL OR l.kecamatan_id = $2)
+  AND ($3::smallint IS NULL OR l.kelurahan_id = $3)
 `
 
 type DeleteJadwalSesiParams struct {
 	ID          uuid.UUID   `json:"id"`
-	KelurahanID pgtype.Int4 `json:"kelurahanId"`
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
 }
 
 func (q *Queries) DeleteJadwalSesi(ctx context.Context, arg DeleteJadwalSesiParams) error {
-	_, err := q.db.Exec(ctx, deleteJadwalSesi, arg.ID, arg.KelurahanID)
+	_, err := q.db.Exec(ctx, deleteJadwalSesi, arg.ID, arg.KecamatanID, arg.KelurahanID)
 	return err
 }
 
+const getAturanBooking = `-- name: GetAturanBooking :one
+SELECT id, min_jam_pemberitahuan, maks_hari_ke_depan, batas_jam_hari_sama, maks_booking_per_bulan, updated_by, updated_at, lokasi_id FROM aturan_booking
+WHERE lokasi_id = $1
+`
+
+func (q *Queries) GetAturanBooking(ctx context.Context, lokasiID int16) (AturanBooking, error) {
+	row := q.db.QueryRow(ctx, getAturanBooking, lokasiID)
+	var i AturanBooking
+	err := row.Scan(
+		&i.ID,
+		&i.MinJamPemberitahuan,
+		&i.MaksHariKeDepan,
+		&i.BatasJamHariSama,
+		&i.MaksBookingPerBulan,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+		&i.LokasiID,
+	)
+	return i, err
+}
+
 const getJadwalSesiById = `-- name: GetJadwalSesiById :one
 SELECT 
     js.id,
@@ -89,10 +110,12 @@ SELECT
     js.kuota_terisi,
     js.kuota_maksimal,
     js.status_sesi,
-    js.lokasi_kelurahan_id,
-    COALESCE(k.nama_kelurahan, 'Kecamatan Pademangan')::text as nama_kelurahan
+    js.lokasi_id,
+    l.kelurahan_id as lokasi_kelurahan_id,
+    l.nama_lokasi,
+    l.kecamatan_id as lokasi_kecamatan_id
 FROM jadwal_sesi js
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
 WHERE js.id = $1
 `
 
@@ -104,8 +127,10 @@ type GetJadwalSesiByIdRow struct {
 	KuotaTerisi       int16       `json:"kuotaTerisi"`
 	KuotaMaksimal     int16       `json:"kuotaMaksimal"`
 	StatusSesi        pgtype.Text `json:"statusSesi"`
+	LokasiID          int16       `json:"lokasiId"`
 	LokasiKelurahanID pgtype.Int2 `json:"lokasiKelurahanId"`
-	NamaKelurahan     string      `json:"namaKelurahan"`
+	NamaLokasi        string      `json:"namaLokasi"`
+	LokasiKecamatanID int16       `json:"lokasiKecamatanId"`
 }
 
 func (q *Queries) GetJadwalSesiById(ctx context.Context, id uuid.UUID) (GetJadwalSesiByIdRow, error) {
@@ -119,14 +144,34 @@ func (q *Queries) GetJadwalSesiById(ctx context.Context, id uuid.UUID) (GetJadwa
 		&i.KuotaTerisi,
 		&i.KuotaMaksimal,
 		&i.StatusSesi,
+		&i.LokasiID,
 		&i.LokasiKelurahanID,
-		&i.NamaKelurahan,
+		&i.NamaLokasi,
+		&i.LokasiKecamatanID,
 	)
 	return i, err
 }
 
+const getKecamatanById = `-- name: GetKecamatanById :one
+SELECT id, nama_kecamatan
+FROM ref_kecamatan
+WHERE id = $1
+`
+
+type GetKecamatanByIdRow struct {
+	ID            int16  `json:"id"`
+	NamaKecamatan string `json:"namaKecamatan"`
+}
+
+func (q *Queries) GetKecamatanById(ctx context.Context, id int16) (GetKecamatanByIdRow, error) {
+	row := q.db.QueryRow(ctx, getKecamatanById, id)
+	var i GetKecamatanByIdRow
+	err := row.Scan(&i.ID, &i.NamaKecamatan)
+	return i, err
+}
+
 const getKelurahanById = `-- name: GetKelurahanById :one
-SELECT id, nama_kelurahan
+SELECT id, nama_kelurahan, kecamatan_id
 FROM ref_kelurahan
 WHERE id = $1
 `
@@ -134,12 +179,13 @@ WHERE id = $1
 type GetKelurahanByIdRow struct {
 	ID            int16  `json:"id"`
 	NamaKelurahan string `json:"namaKelurahan"`
+	KecamatanID   int16  `json:"kecamatanId"`
 }
 
 func (q *Queries) GetKelurahanById(ctx context.Context, id int16) (GetKelurahanByIdRow, error) {
 	row := q.db.QueryRow(ctx, getKelurahanById, id)
 	var i GetKelurahanByIdRow
-	err := row.Scan(&i.ID, &i.NamaKelurahan)
+	err := row.Scan(&i.ID, &i.NamaKelurahan, &i.KecamatanID)
 	return i, err
 }
 
@@ -185,6 +231,85 @@ func (q *Queries) ListAllKelurahan(ctx context.Context) ([]ListAllKelurahanRow,
 	return items, nil
 }
 
+const listAntrianJadwal = `-- name: ListAntrianJadwal :many
+SELECT
+    p.id,
+    p.kode_booking,
+    p.nik,
+    pd.nama_lengkap,
+    p.status_terkini,
+    p.nomor_antrian_sesi AS nomor_antrian,
+    p.jenis_permohonan,
+    p.waktu_hadir
+FROM permohonan p
+JOIN penduduk pd ON p.nik = pd.nik
+WHERE p.jadwal_sesi_id = $3
+ORDER BY
+    CASE WHEN $4::text = 'nama' AND NOT $5::bool THEN pd.nama_lengkap END ASC,
+    CASE WHEN $4::text = 'nama' AND $5::bool THEN pd.nama_lengkap END DESC,
+    CASE WHEN $4::text = 'status' AND NOT $5::bool THEN p.status_terkini END ASC,
+    CASE WHEN $4::text = 'status' AND $5::bool THEN p.status_terkini END DESC,
+    CASE WHEN $4::text = 'antrian' AND $5::bool THEN p.nomor_antrian_sesi END DESC,
+    p.nomor_antrian_sesi ASC,
+    p.id
+LIMIT $1 OFFSET $2
+`
+
+type ListAntrianJadwalParams struct {
+	Limit        int32       `json:"limit"`
+	Offset       int32       `json:"offset"`
+	JadwalSesiID pgtype.UUID `json:"jadwalSesiId"`
+	SortBy       string      `json:"sortBy"`
+	SortDesc     bool        `json:"sortDesc"`
+}
+
+type ListAntrianJadwalRow struct {
+	ID              uuid.UUID          `json:"id"`
+	KodeBooking     pgtype.Text        `json:"kodeBooking"`
+	Nik             pgtype.Text        `json:"nik"`
+	NamaLengkap     string             `json:"namaLengkap"`
+	StatusTerkini   pgtype.Text        `json:"statusTerkini"`
+	NomorAntrian    pgtype.Int2        `json:"nomorAntrian"`
+	JenisPermohonan string             `json:"jenisPermohonan"`
+	WaktuHadir      pgtype.Timestamptz `json:"waktuHadir"`
+}
+
+// One page of a session's queue. sort_by is antrian, nama or status.
+func (q *Queries) ListAntrianJadwal(ctx context.Context, arg ListAntrianJadwalParams) ([]ListAntrianJadwalRow, error) {
+	rows, err := q.db.Query(ctx, listAntrianJadwal,
+		arg.Limit,
+		arg.Offset,
+		arg.JadwalSesiID,
+		arg.SortBy,
+		arg.SortDesc,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAntrianJadwalRow
+	for rows.Next() {
+		var i ListAntrianJadwalRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.KodeBooking,
+			&i.Nik,
+			&i.NamaLengkap,
+			&i.StatusTerkini,
+			&i.NomorAntrian,
+			&i.JenisPermohonan,
+			&i.WaktuHadir,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listJadwalSesi = `-- name: ListJadwalSesi :many
 SELECT 
     js.id,
@@ -194,22 +319,18 @@ SELECT
     js.kuota_terisi,
     js.kuota_maksimal,
     js.status_sesi,
-    COALESCE(k.nama_kelurahan, 'Kecamatan Pademangan')::text as nama_kelurahan
+    l.nama_lokasi
 FROM jadwal_sesi js
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
+JOIN lokasi_layanan l ON js.lokasi_id = l.id
 WHERE js.tanggal >= $1 AND js.tanggal <= $2
-  AND (
-    ($3::integer IS NOT NULL AND js.lokasi_kelurahan_id = $3)
-    OR
-    ($3::integer IS NULL AND js.lokasi_kelurahan_id IS NULL)
-  )
+  AND js.lokasi_id = $3
 ORDER BY js.tanggal, js.jam_mulai
 `
 
 type ListJadwalSesiParams struct {
-	Tanggal     pgtype.Date `json:"tanggal"`
-	Tanggal_2   pgtype.Date `json:"tanggal2"`
-	KelurahanID pgtype.Int4 `json:"kelurahanId"`
+	Tanggal   pgtype.Date `json:"tanggal"`
+	Tanggal_2 pgtype.Date `json:"tanggal2"`
+	LokasiID  int16       `json:"lokasiId"`
 }
 
 type ListJadwalSesiRow struct {
@@ -220,11 +341,11 @@ type ListJadwalSesiRow struct {
 	KuotaTerisi   int16       `json:"kuotaTerisi"`
 	KuotaMaksimal int16       `json:"kuotaMaksimal"`
 	StatusSesi    pgtype.Text `json:"statusSesi"`
-	NamaKelurahan string      `json:"namaKelurahan"`
+	NamaLokasi    string      `json:"namaLokasi"`
 }
 
 func (q *Queries) ListJadwalSesi(ctx context.Context, arg ListJadwalSesiParams) ([]ListJadwalSesiRow, error) {
-	rows, err := q.db.Query(ctx, listJadwalSesi, arg.Tanggal, arg.Tanggal_2, arg.KelurahanID)
+	rows, err := q.db.Query(ctx, listJadwalSesi, arg.Tanggal, arg.Tanggal_2, arg.LokasiID)
 	if err != nil {
 		return nil, err
 	}
@@ -240,7 +361,7 @@ func (q *Queries) ListJadwalSesi(ctx context.Context, arg ListJadwalSesiParams)
 			&i.KuotaTerisi,
 			&i.KuotaMaksimal,
 			&i.StatusSesi,
-			&i.NamaKelurahan,
+			&i.NamaLokasi,
 		); err != nil {
 			return nil, err
 		}
@@ -259,7 +380,9 @@ SELECT
     p.nik,
     pd.nama_lengkap,
     p.status_terkini,
-    p.nomor_antrian_sesi as nomor_antrian
+    p.nomor_antrian_sesi as nomor_antrian,
+    p.jenis_permohonan,
+    p.waktu_hadir
 FROM permohonan p
 JOIN penduduk pd ON p.nik = pd.nik
 WHERE p.jadwal_sesi_id = $1
@@ -267,12 +390,14 @@ ORDER BY p.nomor_antrian_sesi ASC
 `
 
 type ListPermohonanByJadwalRow struct {
-	ID            uuid.UUID   `json:"id"`
-	KodeBooking   pgtype.Text `json:"kodeBooking"`
-	Nik           pgtype.Text `json:"nik"`
-	NamaLengkap   string      `json:"namaLengkap"`
-	StatusTerkini pgtype.Text `json:"statusTerkini"`
-	NomorAntrian  pgtype.Int2 `json:"nomorAntrian"`
+	ID              uuid.UUID          `json:"id"`
+	KodeBooking     pgtype.Text        `json:"kodeBooking"`
+	Nik             pgtype.Text        `json:"nik"`
+	NamaLengkap     string             `json:"namaLengkap"`
+	StatusTerkini   pgtype.Text        `json:"statusTerkini"`
+	NomorAntrian    pgtype.Int2        `json:"nomorAntrian"`
+	JenisPermohonan string             `json:"jenisPermohonan"`
+	WaktuHadir      pgtype.Timestamptz `json:"waktuHadir"`
 }
 
 func (q *Queries) ListPermohonanByJadwal(ctx context.Context, jadwalSesiID pgtype.UUID) ([]ListPermohonanByJadwalRow, error) {
@@ -291,6 +416,8 @@ func (q *Queries) ListPermohonanByJadwal(ctx context.Context, jadwalSesiID pgtyp
 			&i.NamaLengkap,
 			&i.StatusTerkini,
 			&i.NomorAntrian,
+			&i.JenisPermohonan,
+			&i.WaktuHadir,
 		); err != nil {
 			return nil, err
 		}
@@ -333,3 +460,56 @@ func (q *Queries) UpdateJadwalSesi(ctx context.Context, arg UpdateJadwalSesiPara
 	)
 	return err
 }
+
+const upsertAturanBooking = `-- name: UpsertAturanBooking :one
+INSERT INTO aturan_booking (
+    lokasi_id,
+    min_jam_pemberitahuan,
+    maks_hari_ke_depan,
+    batas_jam_hari_sama,
+    maks_booking_per_bulan,
+    updated_by,
+    updated_at
+) VALUES ($1, $2, $3, $4, $5, $6, NOW())
+ON CONFLICT (lokasi_id) DO UPDATE
+SET 
+    min_jam_pemberitahuan = EXCLUDED.min_jam_pemberitahuan,
+    maks_hari_ke_depan = EXCLUDED.maks_hari_ke_depan,
+    batas_jam_hari_sama = EXCLUDED.batas_jam_hari_sama,
+    maks_booking_per_bulan = EXCLUDED.maks_booking_per_bulan,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = EXCLUDED.updated_at
+RETURNING id, min_jam_pemberitahuan, maks_hari_ke_depan, batas_jam_hari_sama, maks_booking_per_bulan, updated_by, updated_at, lokasi_id
+`
+
+type UpsertAturanBookingParams struct {
+	LokasiID            int16       `json:"lokasiId"`
+	MinJamPemberitahuan int16       `json:"minJamPemberitahuan"`
+	MaksHariKeDepan     int16       `json:"maksHariKeDepan"`
+	BatasJamHariSama    pgtype.Time `json:"batasJamHariSama"`
+	MaksBookingPerBulan pgtype.Int2 `json:"maksBookingPerBulan"`
+	UpdatedBy           pgtype.UUID `json:"updatedBy"`
+}
+
+func (q *Queries) UpsertAturanBooking(ctx context.Context, arg UpsertAturanBookingParams) (AturanBooking, error) {
+	row := q.db.QueryRow(ctx, upsertAturanBooking,
+		arg.LokasiID,
+		arg.MinJamPemberitahuan,
+		arg.MaksHariKeDepan,
+		arg.BatasJamHariSama,
+		arg.MaksBookingPerBulan,
+		arg.UpdatedBy,
+	)
+	var i AturanBooking
+	err := row.Scan(
+		&i.ID,
+		&i.MinJamPemberitahuan,
+		&i.MaksHariKeDepan,
+		&i.BatasJamHariSama,
+		&i.MaksBookingPerBulan,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+		&i.LokasiID,
+	)
+	return i, err
+}
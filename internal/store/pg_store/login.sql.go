@@ -0,0 +1,392 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: login.sql
+
+package pg_store
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const countGagalLoginByIP = `-- name: CountGagalLoginByIP :one
+SELECT COUNT(*) FROM percobaan_login
+WHERE ip_address = $1
+  AND alasan IN ('PASSWORD_SALAH', 'TIDAK_DITEMUKAN')
+  AND created_at > CURRENT_TIMESTAMP - make_interval(mins => $2::int)
+`
+
+type CountGagalLoginByIPParams struct {
+	IpAddress pgtype.Text `json:"ipAddress"`
+	Minutes   int32       `json:"minutes"`
+}
+
+func (q *Queries) CountGagalLoginByIP(ctx context.Context, arg CountGagalLoginByIPParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countGagalLoginByIP, arg.IpAddress, arg.Minutes)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countGagalResetByIP = `-- name: CountGagalResetByIP :one
+SELECT COUNT(*) FROM percobaan_login
+WHERE ip_address = $1
+  AND alasan = 'KODE_RESET_SALAH'
+  AND created_at > CURRENT_TIMESTAMP - make_interval(mins => $2::int)
+`
+
+type CountGagalResetByIPParams struct {
+	IpAddress pgtype.Text `json:"ipAddress"`
+	Minutes   int32       `json:"minutes"`
+}
+
+func (q *Queries) CountGagalResetByIP(ctx context.Context, arg CountGagalResetByIPParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countGagalResetByIP, arg.IpAddress, arg.Minutes)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countKunciLoginAdmin = `-- name: CountKunciLoginAdmin :one
+WITH akun AS (
+    SELECT
+        k.id,
+        k.user_type,
+        k.identifier,
+        p.nama_lengkap AS nama,
+        kel.nama_kelurahan,
+        NULL::text AS role,
+        k.gagal_beruntun,
+        k.gagal_terakhir_at,
+        k.ip_terakhir,
+        k.terkunci_sampai
+    FROM kunci_login k
+    JOIN penduduk p ON p.nik = k.identifier
+    LEFT JOIN ref_kelurahan kel ON p.kelurahan_id = kel.id
+    WHERE k.user_type = 'warga'
+      AND k.terkunci_sampai > CURRENT_TIMESTAMP
+      AND ($1::smallint IS NULL OR kel.kecamatan_id = $1)
+      AND ($2::smallint IS NULL OR p.kelurahan_id = $2)
+    UNION ALL
+    SELECT
+        k.id,
+        k.user_type,
+        COALESCE(NULLIF(pt.nip, ''), pt.username),
+        pt.nama_petugas,
+        NULL::text,
+        pt.role,
+        k.gagal_beruntun,
+        k.gagal_terakhir_at,
+        k.ip_terakhir,
+        k.terkunci_sampai
+    FROM kunci_login k
+    JOIN petugas pt ON pt.id::text = k.identifier
+    WHERE k.user_type = 'petugas'
+      AND $3::bool
+      AND k.terkunci_sampai > CURRENT_TIMESTAMP
+      AND ($1::smallint IS NULL OR pt.kecamatan_id = $1)
+      AND ($2::smallint IS NULL OR pt.kelurahan_id = $2)
+)
+SELECT COUNT(*) FROM akun
+`
+
+type CountKunciLoginAdminParams struct {
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+	WithPetugas bool        `json:"withPetugas"`
+}
+
+func (q *Queries) CountKunciLoginAdmin(ctx context.Context, arg CountKunciLoginAdminParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countKunciLoginAdmin, arg.KecamatanID, arg.KelurahanID, arg.WithPetugas)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getKunciLogin = `-- name: GetKunciLogin :one
+SELECT id, user_type, identifier, gagal_beruntun, gagal_terakhir_at, ip_terakhir, terkunci_sampai, dibuka_oleh, dibuka_at FROM kunci_login
+WHERE user_type = $1 AND identifier = $2
+`
+
+type GetKunciLoginParams struct {
+	UserType   string `json:"userType"`
+	Identifier string `json:"identifier"`
+}
+
+func (q *Queries) GetKunciLogin(ctx context.Context, arg GetKunciLoginParams) (KunciLogin, error) {
+	row := q.db.QueryRow(ctx, getKunciLogin, arg.UserType, arg.Identifier)
+	var i KunciLogin
+	err := row.Scan(
+		&i.ID,
+		&i.UserType,
+		&i.Identifier,
+		&i.GagalBeruntun,
+		&i.GagalTerakhirAt,
+		&i.IpTerakhir,
+		&i.TerkunciSampai,
+		&i.DibukaOleh,
+		&i.DibukaAt,
+	)
+	return i, err
+}
+
+const getKunciLoginById = `-- name: GetKunciLoginById :one
+SELECT id, user_type, identifier, gagal_beruntun, gagal_terakhir_at, ip_terakhir, terkunci_sampai, dibuka_oleh, dibuka_at FROM kunci_login WHERE id = $1
+`
+
+func (q *Queries) GetKunciLoginById(ctx context.Context, id uuid.UUID) (KunciLogin, error) {
+	row := q.db.QueryRow(ctx, getKunciLoginById, id)
+	var i KunciLogin
+	err := row.Scan(
+		&i.ID,
+		&i.UserType,
+		&i.Identifier,
+		&i.GagalBeruntun,
+		&i.GagalTerakhirAt,
+		&i.IpTerakhir,
+		&i.TerkunciSampai,
+		&i.DibukaOleh,
+		&i.DibukaAt,
+	)
+	return i, err
+}
+
+const insertPercobaanLogin = `-- name: InsertPercobaanLogin :exec
+INSERT INTO percobaan_login (user_type, identifier, ip_address, user_agent, berhasil, alasan)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type InsertPercobaanLoginParams struct {
+	UserType   string      `json:"userType"`
+	Identifier string      `json:"identifier"`
+	IpAddress  pgtype.Text `json:"ipAddress"`
+	UserAgent  pgtype.Text `json:"userAgent"`
+	Berhasil   bool        `json:"berhasil"`
+	Alasan     string      `json:"alasan"`
+}
+
+func (q *Queries) InsertPercobaanLogin(ctx context.Context, arg InsertPercobaanLoginParams) error {
+	_, err := q.db.Exec(ctx, insertPercobaanLogin,
+		arg.UserType,
+		arg.Identifier,
+		arg.IpAddress,
+		arg.UserAgent,
+		arg.Berhasil,
+		arg.Alasan,
+	)
+	return err
+}
+
+const listKunciLoginAdmin = `-- name: ListKunciLoginAdmin :many
+WITH akun AS (
+    SELECT
+        k.id,
+        k.user_type,
+        k.identifier,
+        p.nama_lengkap AS nama,
+        kel.nama_kelurahan,
+        NULL::text AS role,
+        k.gagal_beruntun,
+        k.gagal_terakhir_at,
+        k.ip_terakhir,
+        k.terkunci_sampai
+    FROM kunci_login k
+    JOIN penduduk p ON p.nik = k.identifier
+    LEFT JOIN ref_kelurahan kel ON p.kelurahan_id = kel.id
+    WHERE k.user_type = 'warga'
+      AND k.terkunci_sampai > CURRENT_TIMESTAMP
+      AND ($5::smallint IS NULL OR kel.kecamatan_id = $5)
+      AND ($6::smallint IS NULL OR p.kelurahan_id = $6)
+    UNION ALL
+    SELECT
+        k.id,
+        k.user_type,
+        COALESCE(NULLIF(pt.nip, ''), pt.username),
+        pt.nama_petugas,
+        NULL::text,
+        pt.role,
+        k.gagal_beruntun,
+        k.gagal_terakhir_at,
+        k.ip_terakhir,
+        k.terkunci_sampai
+    FROM kunci_login k
+    JOIN petugas pt ON pt.id::text = k.identifier
+    WHERE k.user_type = 'petugas'
+      AND $7::bool
+      AND k.terkunci_sampai > CURRENT_TIMESTAMP
+      AND ($5::smallint IS NULL OR pt.kecamatan_id = $5)
+      AND ($6::smallint IS NULL OR pt.kelurahan_id = $6)
+)
+SELECT
+    id,
+    user_type,
+    identifier,
+    nama,
+    nama_kelurahan,
+    role,
+    gagal_beruntun,
+    gagal_terakhir_at,
+    ip_terakhir,
+    terkunci_sampai
+FROM akun
+ORDER BY
+    CASE WHEN $3::text = 'nama' AND NOT $4::bool THEN nama END ASC,
+    CASE WHEN $3::text = 'nama' AND $4::bool THEN nama END DESC,
+    CASE WHEN $3::text = 'gagal' AND NOT $4::bool THEN gagal_beruntun END ASC,
+    CASE WHEN $3::text = 'gagal' AND $4::bool THEN gagal_beruntun END DESC,
+    CASE WHEN $3::text = 'sampai' AND NOT $4::bool THEN terkunci_sampai END ASC,
+    terkunci_sampai DESC,
+    id
+LIMIT $1 OFFSET $2
+`
+
+type ListKunciLoginAdminParams struct {
+	Limit       int32       `json:"limit"`
+	Offset      int32       `json:"offset"`
+	SortBy      string      `json:"sortBy"`
+	SortDesc    bool        `json:"sortDesc"`
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+	WithPetugas bool        `json:"withPetugas"`
+}
+
+type ListKunciLoginAdminRow struct {
+	ID              uuid.UUID          `json:"id"`
+	UserType        string             `json:"userType"`
+	Identifier      string             `json:"identifier"`
+	Nama            string             `json:"nama"`
+	NamaKelurahan   pgtype.Text        `json:"namaKelurahan"`
+	Role            pgtype.Text        `json:"role"`
+	GagalBeruntun   int32              `json:"gagalBeruntun"`
+	GagalTerakhirAt time.Time          `json:"gagalTerakhirAt"`
+	IpTerakhir      pgtype.Text        `json:"ipTerakhir"`
+	TerkunciSampai  pgtype.Timestamptz `json:"terkunciSampai"`
+}
+
+// One page of the warga, and with with_petugas the petugas, locked out in the
+// wilayah. The identifier is the NIK of a warga and the NIP, or else the
+// username, of a petugas. Petugas lockouts are keyed by the petugas ID;
+// guesses at unknown NIPs have no account and are not listed. sort_by is
+// sampai, nama or gagal.
+func (q *Queries) ListKunciLoginAdmin(ctx context.Context, arg ListKunciLoginAdminParams) ([]ListKunciLoginAdminRow, error) {
+	rows, err := q.db.Query(ctx, listKunciLoginAdmin,
+		arg.Limit,
+		arg.Offset,
+		arg.SortBy,
+		arg.SortDesc,
+		arg.KecamatanID,
+		arg.KelurahanID,
+		arg.WithPetugas,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListKunciLoginAdminRow
+	for rows.Next() {
+		var i ListKunciLoginAdminRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserType,
+			&i.Identifier,
+			&i.Nama,
+			&i.NamaKelurahan,
+			&i.Role,
+			&i.GagalBeruntun,
+			&i.GagalTerakhirAt,
+			&i.IpTerakhir,
+			&i.TerkunciSampai,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockKunciLogin = `-- name: LockKunciLogin :one
+UPDATE kunci_login
+SET terkunci_sampai = CURRENT_TIMESTAMP + make_interval(secs => $3::int)
+WHERE user_type = $1 AND identifier = $2
+RETURNING terkunci_sampai
+`
+
+type LockKunciLoginParams struct {
+	UserType   string `json:"userType"`
+	Identifier string `json:"identifier"`
+	Seconds    int32  `json:"seconds"`
+}
+
+func (q *Queries) LockKunciLogin(ctx context.Context, arg LockKunciLoginParams) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, lockKunciLogin, arg.UserType, arg.Identifier, arg.Seconds)
+	var terkunci_sampai pgtype.Timestamptz
+	err := row.Scan(&terkunci_sampai)
+	return terkunci_sampai, err
+}
+
+const recordGagalLogin = `-- name: RecordGagalLogin :one
+INSERT INTO kunci_login (user_type, identifier, gagal_beruntun, ip_terakhir)
+VALUES ($1, $2, 1, $3)
+ON CONFLICT (user_type, identifier) DO UPDATE
+SET gagal_beruntun = CASE
+        WHEN kunci_login.gagal_terakhir_at < CURRENT_TIMESTAMP - INTERVAL '1 day' THEN 1
+        ELSE kunci_login.gagal_beruntun + 1
+    END,
+    gagal_terakhir_at = CURRENT_TIMESTAMP,
+    ip_terakhir = EXCLUDED.ip_terakhir
+RETURNING gagal_beruntun
+`
+
+type RecordGagalLoginParams struct {
+	UserType   string      `json:"userType"`
+	Identifier string      `json:"identifier"`
+	IpTerakhir pgtype.Text `json:"ipTerakhir"`
+}
+
+// A failure more than a day after the previous one starts a new streak
+func (q *Queries) RecordGagalLogin(ctx context.Context, arg RecordGagalLoginParams) (int32, error) {
+	row := q.db.QueryRow(ctx, recordGagalLogin, arg.UserType, arg.Identifier, arg.IpTerakhir)
+	var gagal_beruntun int32
+	err := row.Scan(&gagal_beruntun)
+	return gagal_beruntun, err
+}
+
+const resetKunciLogin = `-- name: ResetKunciLogin :exec
+DELETE FROM kunci_login WHERE user_type = $1 AND identifier = $2
+`
+
+type ResetKunciLoginParams struct {
+	UserType   string `json:"userType"`
+	Identifier string `json:"identifier"`
+}
+
+func (q *Queries) ResetKunciLogin(ctx context.Context, arg ResetKunciLoginParams) error {
+	_, err := q.db.Exec(ctx, resetKunciLogin, arg.UserType, arg.Identifier)
+	return err
+}
+
+const unlockKunciLogin = `-- name: UnlockKunciLogin :exec
+UPDATE kunci_login
+SET gagal_beruntun = 0,
+    terkunci_sampai = NULL,
+    dibuka_oleh = $2,
+    dibuka_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UnlockKunciLoginParams struct {
+	ID         uuid.UUID   `json:"id"`
+	DibukaOleh pgtype.UUID `json:"dibukaOleh"`
+}
+
+func (q *Queries) UnlockKunciLogin(ctx context.Context, arg UnlockKunciLoginParams) error {
+	_, err := q.db.Exec(ctx, unlockKunciLogin, arg.ID, arg.DibukaOleh)
+	return err
+}
@@ -5,77 +5,333 @@
 package pg_store
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AturanBooking struct {
+	ID                  int16              `json:"id"`
+	MinJamPemberitahuan int16              `json:"minJamPemberitahuan"`
+	MaksHariKeDepan     int16              `json:"maksHariKeDepan"`
+	BatasJamHariSama    pgtype.Time        `json:"batasJamHariSama"`
+	MaksBookingPerBulan pgtype.Int2        `json:"maksBookingPerBulan"`
+	UpdatedBy           pgtype.UUID        `json:"updatedBy"`
+	UpdatedAt           pgtype.Timestamptz `json:"updatedAt"`
+	LokasiID            int16              `json:"lokasiId"`
+}
+
+type DigestSla struct {
+	Tanggal     pgtype.Date        `json:"tanggal"`
+	DiklaimPada time.Time          `json:"diklaimPada"`
+	SelesaiPada pgtype.Timestamptz `json:"selesaiPada"`
+}
+
+type DigestSlaPenerima struct {
+	Tanggal     pgtype.Date `json:"tanggal"`
+	PetugasID   uuid.UUID   `json:"petugasId"`
+	DikirimPada time.Time   `json:"dikirimPada"`
+}
+
 type DokumenSyarat struct {
-	ID           uuid.UUID        `json:"id"`
-	PermohonanID pgtype.UUID      `json:"permohonanId"`
-	FilePath     string           `json:"filePath"`
-	UploadedAt   pgtype.Timestamp `json:"uploadedAt"`
-	JenisDokumen string           `json:"jenisDokumen"`
+	ID           uuid.UUID          `json:"id"`
+	PermohonanID pgtype.UUID        `json:"permohonanId"`
+	FilePath     string             `json:"filePath"`
+	UploadedAt   pgtype.Timestamptz `json:"uploadedAt"`
+	JenisDokumen string             `json:"jenisDokumen"`
 }
 
 type JadwalSesi struct {
-	ID                uuid.UUID   `json:"id"`
-	LokasiKelurahanID pgtype.Int2 `json:"lokasiKelurahanId"`
-	Tanggal           pgtype.Date `json:"tanggal"`
-	JamMulai          pgtype.Time `json:"jamMulai"`
-	JamSelesai        pgtype.Time `json:"jamSelesai"`
-	KuotaMaksimal     int16       `json:"kuotaMaksimal"`
-	KuotaTerisi       int16       `json:"kuotaTerisi"`
-	StatusSesi        pgtype.Text `json:"statusSesi"`
+	ID            uuid.UUID   `json:"id"`
+	Tanggal       pgtype.Date `json:"tanggal"`
+	JamMulai      pgtype.Time `json:"jamMulai"`
+	JamSelesai    pgtype.Time `json:"jamSelesai"`
+	KuotaMaksimal int16       `json:"kuotaMaksimal"`
+	KuotaTerisi   int16       `json:"kuotaTerisi"`
+	StatusSesi    pgtype.Text `json:"statusSesi"`
+	LokasiID      int16       `json:"lokasiId"`
+}
+
+type KartuKeluarga struct {
+	NoKk        string      `json:"noKk"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+	Alamat      pgtype.Text `json:"alamat"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
+
+type Kebijakan2fa struct {
+	Role  string `json:"role"`
+	Wajib bool   `json:"wajib"`
+}
+
+type KodePemulihan struct {
+	ID          uuid.UUID          `json:"id"`
+	PetugasID   uuid.UUID          `json:"petugasId"`
+	KodeHash    string             `json:"kodeHash"`
+	DigunakanAt pgtype.Timestamptz `json:"digunakanAt"`
+	CreatedAt   time.Time          `json:"createdAt"`
+}
+
+type KodeResetPassword struct {
+	ID          uuid.UUID          `json:"id"`
+	Nik         string             `json:"nik"`
+	KodeHash    string             `json:"kodeHash"`
+	Kanal       string             `json:"kanal"`
+	Percobaan   int16              `json:"percobaan"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	ExpiresAt   time.Time          `json:"expiresAt"`
+	DigunakanAt pgtype.Timestamptz `json:"digunakanAt"`
+}
+
+type KunciLogin struct {
+	ID              uuid.UUID          `json:"id"`
+	UserType        string             `json:"userType"`
+	Identifier      string             `json:"identifier"`
+	GagalBeruntun   int32              `json:"gagalBeruntun"`
+	GagalTerakhirAt time.Time          `json:"gagalTerakhirAt"`
+	IpTerakhir      pgtype.Text        `json:"ipTerakhir"`
+	TerkunciSampai  pgtype.Timestamptz `json:"terkunciSampai"`
+	DibukaOleh      pgtype.UUID        `json:"dibukaOleh"`
+	DibukaAt        pgtype.Timestamptz `json:"dibukaAt"`
+}
+
+type LogEkspor struct {
+	ID          int64       `json:"id"`
+	PetugasID   pgtype.UUID `json:"petugasId"`
+	NamaPetugas string      `json:"namaPetugas"`
+	Jenis       string      `json:"jenis"`
+	Format      string      `json:"format"`
+	Filter      string      `json:"filter"`
+	JumlahBaris int32       `json:"jumlahBaris"`
+	DataLengkap bool        `json:"dataLengkap"`
+	IpAddress   pgtype.Text `json:"ipAddress"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
+
+type LokasiLayanan struct {
+	ID          int16              `json:"id"`
+	KecamatanID int16              `json:"kecamatanId"`
+	KelurahanID pgtype.Int2        `json:"kelurahanId"`
+	Kode        string             `json:"kode"`
+	NamaLokasi  string             `json:"namaLokasi"`
+	Alamat      pgtype.Text        `json:"alamat"`
+	JamBuka     pgtype.Time        `json:"jamBuka"`
+	JamTutup    pgtype.Time        `json:"jamTutup"`
+	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
+}
+
+type MvLaporanDurasi struct {
+	PermohonanID    uuid.UUID   `json:"permohonanId"`
+	Minggu          pgtype.Date `json:"minggu"`
+	JenisPermohonan string      `json:"jenisPermohonan"`
+	KecamatanID     int16       `json:"kecamatanId"`
+	KelurahanID     int16       `json:"kelurahanId"`
+	DurasiJam       float64     `json:"durasiJam"`
+}
+
+type MvLaporanPenolakan struct {
+	Minggu      pgtype.Date `json:"minggu"`
+	KecamatanID int16       `json:"kecamatanId"`
+	KelurahanID int16       `json:"kelurahanId"`
+	Alasan      string      `json:"alasan"`
+	Jumlah      int32       `json:"jumlah"`
+}
+
+type MvLaporanPermohonan struct {
+	Minggu             pgtype.Date `json:"minggu"`
+	JenisPermohonan    string      `json:"jenisPermohonan"`
+	KecamatanID        int16       `json:"kecamatanId"`
+	KelurahanID        int16       `json:"kelurahanId"`
+	KelurahanPemohonID int16       `json:"kelurahanPemohonId"`
+	Jumlah             int32       `json:"jumlah"`
+	Ditolak            int32       `json:"ditolak"`
+}
+
+type MvLaporanSesi struct {
+	JadwalSesiID    uuid.UUID   `json:"jadwalSesiId"`
+	Tanggal         pgtype.Date `json:"tanggal"`
+	Minggu          pgtype.Date `json:"minggu"`
+	KecamatanID     int16       `json:"kecamatanId"`
+	KelurahanID     int16       `json:"kelurahanId"`
+	KuotaMaksimal   int32       `json:"kuotaMaksimal"`
+	KuotaTerisi     int32       `json:"kuotaTerisi"`
+	Terdaftar       int32       `json:"terdaftar"`
+	Hadir           int32       `json:"hadir"`
+	PencatatanHadir bool        `json:"pencatatanHadir"`
 }
 
 type Penduduk struct {
-	Nik          string           `json:"nik"`
-	KelurahanID  pgtype.Int2      `json:"kelurahanId"`
-	Email        pgtype.Text      `json:"email"`
-	PasswordHash pgtype.Text      `json:"passwordHash"`
-	NamaLengkap  string           `json:"namaLengkap"`
-	Alamat       pgtype.Text      `json:"alamat"`
-	NoHp         pgtype.Text      `json:"noHp"`
-	CreatedAt    pgtype.Timestamp `json:"createdAt"`
-	JenisKelamin string           `json:"jenisKelamin"`
+	Nik               string             `json:"nik"`
+	KelurahanID       pgtype.Int2        `json:"kelurahanId"`
+	Email             pgtype.Text        `json:"email"`
+	PasswordHash      pgtype.Text        `json:"passwordHash"`
+	NamaLengkap       string             `json:"namaLengkap"`
+	Alamat            pgtype.Text        `json:"alamat"`
+	NoHp              pgtype.Text        `json:"noHp"`
+	CreatedAt         pgtype.Timestamptz `json:"createdAt"`
+	JenisKelamin      string             `json:"jenisKelamin"`
+	TanggalLahir      pgtype.Date        `json:"tanggalLahir"`
+	StatusPerkawinan  string             `json:"statusPerkawinan"`
+	NoKk              pgtype.Text        `json:"noKk"`
+	HubunganKeluarga  pgtype.Text        `json:"hubunganKeluarga"`
+	WaliDisetujuiOleh pgtype.UUID        `json:"waliDisetujuiOleh"`
+	WaliDisetujuiAt   pgtype.Timestamptz `json:"waliDisetujuiAt"`
+}
+
+type PercobaanLacak struct {
+	ID          int64       `json:"id"`
+	KodeBooking string      `json:"kodeBooking"`
+	IpAddress   pgtype.Text `json:"ipAddress"`
+	Berhasil    bool        `json:"berhasil"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
+
+type PercobaanLogin struct {
+	ID         int64       `json:"id"`
+	UserType   string      `json:"userType"`
+	Identifier string      `json:"identifier"`
+	IpAddress  pgtype.Text `json:"ipAddress"`
+	UserAgent  pgtype.Text `json:"userAgent"`
+	Berhasil   bool        `json:"berhasil"`
+	Alasan     string      `json:"alasan"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+type PermintaanAnggotaKeluarga struct {
+	Nik              string    `json:"nik"`
+	NoKk             string    `json:"noKk"`
+	HubunganKeluarga string    `json:"hubunganKeluarga"`
+	DiajukanOleh     string    `json:"diajukanOleh"`
+	CreatedAt        time.Time `json:"createdAt"`
 }
 
 type Permohonan struct {
-	ID               uuid.UUID        `json:"id"`
-	Nik              pgtype.Text      `json:"nik"`
-	JadwalSesiID     pgtype.UUID      `json:"jadwalSesiId"`
-	KodeBooking      pgtype.Text      `json:"kodeBooking"`
-	NomorAntrianSesi pgtype.Int2      `json:"nomorAntrianSesi"`
-	CreatedAt        pgtype.Timestamp `json:"createdAt"`
-	JenisPermohonan  string           `json:"jenisPermohonan"`
-	StatusTerkini    pgtype.Text      `json:"statusTerkini"`
+	ID               uuid.UUID          `json:"id"`
+	Nik              pgtype.Text        `json:"nik"`
+	JadwalSesiID     pgtype.UUID        `json:"jadwalSesiId"`
+	KodeBooking      pgtype.Text        `json:"kodeBooking"`
+	NomorAntrianSesi pgtype.Int2        `json:"nomorAntrianSesi"`
+	CreatedAt        pgtype.Timestamptz `json:"createdAt"`
+	JenisPermohonan  string             `json:"jenisPermohonan"`
+	StatusTerkini    pgtype.Text        `json:"statusTerkini"`
+	PemohonNik       pgtype.Text        `json:"pemohonNik"`
+	WaktuHadir       pgtype.Timestamptz `json:"waktuHadir"`
+	HadirDicatatOleh pgtype.UUID        `json:"hadirDicatatOleh"`
+	DitanganiOleh    pgtype.UUID        `json:"ditanganiOleh"`
+	DitugaskanPada   pgtype.Timestamptz `json:"ditugaskanPada"`
+	Versi            int32              `json:"versi"`
 }
 
 type Petugas struct {
-	ID           uuid.UUID        `json:"id"`
-	KelurahanID  pgtype.Int2      `json:"kelurahanId"`
-	Nip          pgtype.Text      `json:"nip"`
-	NamaPetugas  string           `json:"namaPetugas"`
-	CreatedBy    pgtype.UUID      `json:"createdBy"`
-	Username     string           `json:"username"`
-	PasswordHash string           `json:"passwordHash"`
-	IsActive     pgtype.Bool      `json:"isActive"`
-	CreatedAt    pgtype.Timestamp `json:"createdAt"`
-	Role         string           `json:"role"`
+	ID           uuid.UUID          `json:"id"`
+	KelurahanID  pgtype.Int2        `json:"kelurahanId"`
+	Nip          pgtype.Text        `json:"nip"`
+	NamaPetugas  string             `json:"namaPetugas"`
+	CreatedBy    pgtype.UUID        `json:"createdBy"`
+	Username     string             `json:"username"`
+	PasswordHash string             `json:"passwordHash"`
+	IsActive     bool               `json:"isActive"`
+	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
+	Role         string             `json:"role"`
+	KecamatanID  pgtype.Int2        `json:"kecamatanId"`
+	UpdatedBy    pgtype.UUID        `json:"updatedBy"`
+	UpdatedAt    pgtype.Timestamptz `json:"updatedAt"`
+	Email        pgtype.Text        `json:"email"`
+}
+
+type PetugasTotp struct {
+	PetugasID    uuid.UUID          `json:"petugasId"`
+	Secret       string             `json:"secret"`
+	Aktif        bool               `json:"aktif"`
+	TerakhirStep int64              `json:"terakhirStep"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	AktifAt      pgtype.Timestamptz `json:"aktifAt"`
+}
+
+type RefKecamatan struct {
+	ID            int16              `json:"id"`
+	NamaKecamatan string             `json:"namaKecamatan"`
+	NamaKota      string             `json:"namaKota"`
+	KodeWilayah   string             `json:"kodeWilayah"`
+	CreatedAt     pgtype.Timestamptz `json:"createdAt"`
 }
 
 type RefKelurahan struct {
-	ID            int16            `json:"id"`
-	NamaKelurahan string           `json:"namaKelurahan"`
-	KodeArea      string           `json:"kodeArea"`
-	CreatedAt     pgtype.Timestamp `json:"createdAt"`
+	ID            int16              `json:"id"`
+	NamaKelurahan string             `json:"namaKelurahan"`
+	KodeArea      string             `json:"kodeArea"`
+	CreatedAt     pgtype.Timestamptz `json:"createdAt"`
+	KecamatanID   int16              `json:"kecamatanId"`
+}
+
+type RefPermission struct {
+	Kode      string `json:"kode"`
+	Deskripsi string `json:"deskripsi"`
+}
+
+type RefSyaratDokumen struct {
+	JenisPermohonan string `json:"jenisPermohonan"`
+	JenisDokumen    string `json:"jenisDokumen"`
+}
+
+type RiwayatPetugas struct {
+	ID            uuid.UUID   `json:"id"`
+	PetugasID     uuid.UUID   `json:"petugasId"`
+	Aksi          string      `json:"aksi"`
+	DilakukanOleh pgtype.UUID `json:"dilakukanOleh"`
+	Keterangan    pgtype.Text `json:"keterangan"`
+	Waktu         time.Time   `json:"waktu"`
 }
 
 type RiwayatStatus struct {
-	ID            uuid.UUID        `json:"id"`
-	PermohonanID  pgtype.UUID      `json:"permohonanId"`
-	PetugasID     pgtype.UUID      `json:"petugasId"`
-	StatusBaru    string           `json:"statusBaru"`
-	CatatanProses pgtype.Text      `json:"catatanProses"`
-	WaktuProses   pgtype.Timestamp `json:"waktuProses"`
+	ID              uuid.UUID          `json:"id"`
+	PermohonanID    pgtype.UUID        `json:"permohonanId"`
+	PetugasID       pgtype.UUID        `json:"petugasId"`
+	StatusBaru      string             `json:"statusBaru"`
+	CatatanProses   pgtype.Text        `json:"catatanProses"`
+	WaktuProses     pgtype.Timestamptz `json:"waktuProses"`
+	AlasanPenolakan pgtype.Text        `json:"alasanPenolakan"`
+}
+
+type RolePermission struct {
+	Role       string `json:"role"`
+	Permission string `json:"permission"`
+}
+
+type SesiLogin struct {
+	ID          uuid.UUID   `json:"id"`
+	TokenHash   string      `json:"tokenHash"`
+	UserType    string      `json:"userType"`
+	UserID      string      `json:"userId"`
+	UserName    string      `json:"userName"`
+	UserRole    string      `json:"userRole"`
+	KecamatanID pgtype.Int2 `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+	Permissions []string    `json:"permissions"`
+	UserAgent   pgtype.Text `json:"userAgent"`
+	IpAddress   pgtype.Text `json:"ipAddress"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	LastSeenAt  time.Time   `json:"lastSeenAt"`
+	ExpiresAt   time.Time   `json:"expiresAt"`
+	Tahap2fa    string      `json:"tahap2fa"`
+	IngatSaya   bool        `json:"ingatSaya"`
+	CsrfToken   string      `json:"csrfToken"`
+}
+
+type TampilanTersimpan struct {
+	ID        uuid.UUID `json:"id"`
+	PetugasID uuid.UUID `json:"petugasId"`
+	Halaman   string    `json:"halaman"`
+	Nama      string    `json:"nama"`
+	Query     string    `json:"query"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type TargetSla struct {
+	Status          string      `json:"status"`
+	JenisPermohonan string      `json:"jenisPermohonan"`
+	TargetHari      int16       `json:"targetHari"`
+	UpdatedBy       pgtype.UUID `json:"updatedBy"`
+	UpdatedAt       time.Time   `json:"updatedAt"`
 }
@@ -12,6 +12,27 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countPermohonanByNIKInPeriod = `-- name: CountPermohonanByNIKInPeriod :one
+SELECT COUNT(*) as count
+FROM permohonan
+WHERE nik = $1
+  AND created_at >= $2
+  AND created_at < $3
+`
+
+type CountPermohonanByNIKInPeriodParams struct {
+	Nik         pgtype.Text        `json:"nik"`
+	PeriodStart pgtype.Timestamptz `json:"periodStart"`
+	PeriodEnd   pgtype.Timestamptz `json:"periodEnd"`
+}
+
+func (q *Queries) CountPermohonanByNIKInPeriod(ctx context.Context, arg CountPermohonanByNIKInPeriodParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countPermohonanByNIKInPeriod, arg.Nik, arg.PeriodStart, arg.PeriodEnd)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countPermohonanByStatus = `-- name: CountPermohonanByStatus :one
 SELECT 
     COUNT(*) FILTER (WHERE status_terkini = 'VERIFIKASI') as verifikasi,
@@ -69,8 +90,9 @@ const createPermohonan = `-- name: CreatePermohonan :one
 INSERT INTO permohonan (
     nik,
     jadwal_sesi_id,
-    jenis_permohonan
-) VALUES ($1, $2, $3)
+    jenis_permohonan,
+    pemohon_nik
+) VALUES ($1, $2, $3, $4)
 RETURNING id
 `
 
@@ -78,10 +100,16 @@ type CreatePermohonanParams struct {
 	Nik             pgtype.Text `json:"nik"`
 	JadwalSesiID    pgtype.UUID `json:"jadwalSesiId"`
 	JenisPermohonan string      `json:"jenisPermohonan"`
+	PemohonNik      pgtype.Text `json:"pemohonNik"`
 }
 
 func (q *Queries) CreatePermohonan(ctx context.Context, arg CreatePermohonanParams) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, createPermohonan, arg.Nik, arg.JadwalSesiID, arg.JenisPermohonan)
+	row := q.db.QueryRow(ctx, createPermohonan,
+		arg.Nik,
+		arg.JadwalSesiID,
+		arg.JenisPermohonan,
+		arg.PemohonNik,
+	)
 	var id uuid.UUID
 	err := row.Scan(&id)
 	return id, err
@@ -100,33 +128,35 @@ SELECT
     pd.jenis_kelamin,
     pd.alamat,
     pd.no_hp,
-    COALESCE(k.nama_kelurahan, 'Kecamatan Pademangan')::text as nama_kelurahan,
+    l.nama_lokasi,
     js.tanggal as jadwal_tanggal,
     js.jam_mulai as jadwal_jam_mulai,
-    js.jam_selesai as jadwal_jam_selesai
+    js.jam_selesai as jadwal_jam_selesai,
+    p.pemohon_nik
 FROM permohonan p
 JOIN penduduk pd ON p.nik = pd.nik
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
 WHERE p.id = $1
 `
 
 type GetPermohonanDetailRow struct {
-	ID               uuid.UUID        `json:"id"`
-	KodeBooking      pgtype.Text      `json:"kodeBooking"`
-	JenisPermohonan  string           `json:"jenisPermohonan"`
-	StatusTerkini    pgtype.Text      `json:"statusTerkini"`
-	NomorAntrian     pgtype.Int2      `json:"nomorAntrian"`
-	CreatedAt        pgtype.Timestamp `json:"createdAt"`
-	Nik              string           `json:"nik"`
-	NamaLengkap      string           `json:"namaLengkap"`
-	JenisKelamin     string           `json:"jenisKelamin"`
-	Alamat           pgtype.Text      `json:"alamat"`
-	NoHp             pgtype.Text      `json:"noHp"`
-	NamaKelurahan    string           `json:"namaKelurahan"`
-	JadwalTanggal    pgtype.Date      `json:"jadwalTanggal"`
-	JadwalJamMulai   pgtype.Time      `json:"jadwalJamMulai"`
-	JadwalJamSelesai pgtype.Time      `json:"jadwalJamSelesai"`
+	ID               uuid.UUID          `json:"id"`
+	KodeBooking      pgtype.Text        `json:"kodeBooking"`
+	JenisPermohonan  string             `json:"jenisPermohonan"`
+	StatusTerkini    pgtype.Text        `json:"statusTerkini"`
+	NomorAntrian     pgtype.Int2        `json:"nomorAntrian"`
+	CreatedAt        pgtype.Timestamptz `json:"createdAt"`
+	Nik              string             `json:"nik"`
+	NamaLengkap      string             `json:"namaLengkap"`
+	JenisKelamin     string             `json:"jenisKelamin"`
+	Alamat           pgtype.Text        `json:"alamat"`
+	NoHp             pgtype.Text        `json:"noHp"`
+	NamaLokasi       pgtype.Text        `json:"namaLokasi"`
+	JadwalTanggal    pgtype.Date        `json:"jadwalTanggal"`
+	JadwalJamMulai   pgtype.Time        `json:"jadwalJamMulai"`
+	JadwalJamSelesai pgtype.Time        `json:"jadwalJamSelesai"`
+	PemohonNik       pgtype.Text        `json:"pemohonNik"`
 }
 
 func (q *Queries) GetPermohonanDetail(ctx context.Context, id uuid.UUID) (GetPermohonanDetailRow, error) {
@@ -144,10 +174,11 @@ func (q *Queries) GetPermohonanDetail(ctx context.Context, id uuid.UUID) (GetPer
 		&i.JenisKelamin,
 		&i.Alamat,
 		&i.NoHp,
-		&i.NamaKelurahan,
+		&i.NamaLokasi,
 		&i.JadwalTanggal,
 		&i.JadwalJamMulai,
 		&i.JadwalJamSelesai,
+		&i.PemohonNik,
 	)
 	return i, err
 }
@@ -185,10 +216,10 @@ ORDER BY rs.waktu_proses DESC
 `
 
 type GetRiwayatStatusByPermohonanRow struct {
-	StatusBaru    string           `json:"statusBaru"`
-	CatatanProses pgtype.Text      `json:"catatanProses"`
-	WaktuProses   pgtype.Timestamp `json:"waktuProses"`
-	NamaPetugas   pgtype.Text      `json:"namaPetugas"`
+	StatusBaru    string             `json:"statusBaru"`
+	CatatanProses pgtype.Text        `json:"catatanProses"`
+	WaktuProses   pgtype.Timestamptz `json:"waktuProses"`
+	NamaPetugas   pgtype.Text        `json:"namaPetugas"`
 }
 
 func (q *Queries) GetRiwayatStatusByPermohonan(ctx context.Context, permohonanID pgtype.UUID) ([]GetRiwayatStatusByPermohonanRow, error) {
@@ -216,21 +247,43 @@ func (q *Queries) GetRiwayatStatusByPermohonan(ctx context.Context, permohonanID
 	return items, nil
 }
 
+const hasPermohonanSelesai = `-- name: HasPermohonanSelesai :one
+SELECT EXISTS(
+    SELECT 1 FROM permohonan
+    WHERE nik = $1 AND jenis_permohonan = $2 AND status_terkini = 'SELESAI'
+) AS exists
+`
+
+type HasPermohonanSelesaiParams struct {
+	Nik             pgtype.Text `json:"nik"`
+	JenisPermohonan string      `json:"jenisPermohonan"`
+}
+
+// Whether the citizen has a completed application of the given type
+func (q *Queries) HasPermohonanSelesai(ctx context.Context, arg HasPermohonanSelesaiParams) (bool, error) {
+	row := q.db.QueryRow(ctx, hasPermohonanSelesai, arg.Nik, arg.JenisPermohonan)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const insertRiwayatStatus = `-- name: InsertRiwayatStatus :exec
 INSERT INTO riwayat_status (
     permohonan_id,
     petugas_id,
     status_baru,
     catatan_proses,
+    alasan_penolakan,
     waktu_proses
-) VALUES ($1, $2, $3, $4, NOW())
+) VALUES ($1, $2, $3, $4, $5, NOW())
 `
 
 type InsertRiwayatStatusParams struct {
-	PermohonanID  pgtype.UUID `json:"permohonanId"`
-	PetugasID     pgtype.UUID `json:"petugasId"`
-	StatusBaru    string      `json:"statusBaru"`
-	CatatanProses pgtype.Text `json:"catatanProses"`
+	PermohonanID    pgtype.UUID `json:"permohonanId"`
+	PetugasID       pgtype.UUID `json:"petugasId"`
+	StatusBaru      string      `json:"statusBaru"`
+	CatatanProses   pgtype.Text `json:"catatanProses"`
+	AlasanPenolakan pgtype.Text `json:"alasanPenolakan"`
 }
 
 func (q *Queries) InsertRiwayatStatus(ctx context.Context, arg InsertRiwayatStatusParams) error {
@@ -239,6 +292,7 @@ func (q *Queries) InsertRiwayatStatus(ctx context.Context, arg InsertRiwayatStat
 		arg.PetugasID,
 		arg.StatusBaru,
 		arg.CatatanProses,
+		arg.AlasanPenolakan,
 	)
 	return err
 }
@@ -270,16 +324,16 @@ type ListPermohonanByStatusParams struct {
 }
 
 type ListPermohonanByStatusRow struct {
-	ID              uuid.UUID        `json:"id"`
-	KodeBooking     pgtype.Text      `json:"kodeBooking"`
-	Nik             string           `json:"nik"`
-	NamaLengkap     string           `json:"namaLengkap"`
-	JenisPermohonan string           `json:"jenisPermohonan"`
-	StatusTerkini   pgtype.Text      `json:"statusTerkini"`
-	TanggalDaftar   pgtype.Timestamp `json:"tanggalDaftar"`
-	JadwalTanggal   pgtype.Date      `json:"jadwalTanggal"`
-	JadwalJam       pgtype.Time      `json:"jadwalJam"`
-	NomorAntrian    pgtype.Int2      `json:"nomorAntrian"`
+	ID              uuid.UUID          `json:"id"`
+	KodeBooking     pgtype.Text        `json:"kodeBooking"`
+	Nik             string             `json:"nik"`
+	NamaLengkap     string             `json:"namaLengkap"`
+	JenisPermohonan string             `json:"jenisPermohonan"`
+	StatusTerkini   pgtype.Text        `json:"statusTerkini"`
+	TanggalDaftar   pgtype.Timestamptz `json:"tanggalDaftar"`
+	JadwalTanggal   pgtype.Date        `json:"jadwalTanggal"`
+	JadwalJam       pgtype.Time        `json:"jadwalJam"`
+	NomorAntrian    pgtype.Int2        `json:"nomorAntrian"`
 }
 
 func (q *Queries) ListPermohonanByStatus(ctx context.Context, arg ListPermohonanByStatusParams) ([]ListPermohonanByStatusRow, error) {
@@ -313,6 +367,45 @@ func (q *Queries) ListPermohonanByStatus(ctx context.Context, arg ListPermohonan
 	return items, nil
 }
 
+const listSyaratDokumen = `-- name: ListSyaratDokumen :many
+SELECT jenis_dokumen FROM ref_syarat_dokumen
+WHERE jenis_permohonan = $1
+ORDER BY jenis_dokumen
+`
+
+// Documents an application of the given type needs, whose originals the
+// citizen brings to the session
+func (q *Queries) ListSyaratDokumen(ctx context.Context, jenisPermohonan string) ([]string, error) {
+	rows, err := q.db.Query(ctx, listSyaratDokumen, jenisPermohonan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var jenis_dokumen string
+		if err := rows.Scan(&jenis_dokumen); err != nil {
+			return nil, err
+		}
+		items = append(items, jenis_dokumen)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockPermohonanNIK = `-- name: LockPermohonanNIK :exec
+SELECT pg_advisory_xact_lock(hashtextextended('permohonan:' || $1::text, 0))
+`
+
+// Serialises new applications for one NIK until the transaction ends, so
+// the active and monthly counts cannot be raced by parallel submits
+func (q *Queries) LockPermohonanNIK(ctx context.Context, nik string) error {
+	_, err := q.db.Exec(ctx, lockPermohonanNIK, nik)
+	return err
+}
+
 const updatePermohonanStatus = `-- name: UpdatePermohonanStatus :exec
 UPDATE permohonan
 SET status_terkini = $2, updated_at = NOW()
@@ -12,52 +12,252 @@ import (
 )
 
 type Querier interface {
+	ActivatePetugasTotp(ctx context.Context, arg ActivatePetugasTotpParams) error
+	// Links the citizen to the requested KK, provided they have not joined a KK
+	// meanwhile. The request is removed by the caller in the same transaction.
+	ApprovePermintaanAnggota(ctx context.Context, nik string) (int64, error)
+	ApproveWaliPenduduk(ctx context.Context, arg ApproveWaliPendudukParams) (int64, error)
+	// Assigns the application to petugas_id, or releases it when NULL, provided
+	// it is still at versi. The versi trigger then moves it on, so of two
+	// petugas claiming the same application only the first succeeds.
+	AssignPermohonan(ctx context.Context, arg AssignPermohonanParams) (int64, error)
 	CheckEmailExists(ctx context.Context, email pgtype.Text) (bool, error)
 	CheckHealth(ctx context.Context) (int32, error)
 	CheckPendudukExists(ctx context.Context, nik string) (bool, error)
+	// Claims the digest of a day for ten minutes; no rows means it is done or
+	// another run holds it
+	ClaimDigestSLA(ctx context.Context, tanggal pgtype.Date) (int64, error)
+	CompleteDigestSLA(ctx context.Context, tanggal pgtype.Date) error
+	// Rotates the token so that the pre-2FA cookie cannot be reused
+	CompleteSesi2FA(ctx context.Context, arg CompleteSesi2FAParams) error
+	CountGagalLacakByIP(ctx context.Context, arg CountGagalLacakByIPParams) (int64, error)
+	CountGagalLoginByIP(ctx context.Context, arg CountGagalLoginByIPParams) (int64, error)
+	CountGagalResetByIP(ctx context.Context, arg CountGagalResetByIPParams) (int64, error)
+	CountKehadiranAdmin(ctx context.Context, arg CountKehadiranAdminParams) (int64, error)
+	CountKodePemulihanTersisa(ctx context.Context, petugasID uuid.UUID) (int64, error)
+	CountKodeResetPasswordSince(ctx context.Context, arg CountKodeResetPasswordSinceParams) (int64, error)
+	CountKunciLoginAdmin(ctx context.Context, arg CountKunciLoginAdminParams) (int64, error)
+	CountPendudukAdmin(ctx context.Context, arg CountPendudukAdminParams) (int64, error)
+	// Takes the same filters as ListPermohonanAdmin
 	CountPermohonanAdmin(ctx context.Context, arg CountPermohonanAdminParams) (int64, error)
 	CountPermohonanByJadwal(ctx context.Context, jadwalSesiID pgtype.UUID) (int64, error)
 	CountPermohonanByNIK(ctx context.Context, nik pgtype.Text) (CountPermohonanByNIKRow, error)
+	CountPermohonanByNIKInPeriod(ctx context.Context, arg CountPermohonanByNIKInPeriodParams) (int64, error)
+	// Counts applications for the citizen together with those they submitted
+	// for household members
+	CountPermohonanByPemohon(ctx context.Context, nik pgtype.Text) (CountPermohonanByPemohonRow, error)
 	CountPermohonanByStatus(ctx context.Context) (CountPermohonanByStatusRow, error)
+	CountPetugasAdmin(ctx context.Context, arg CountPetugasAdminParams) (int64, error)
+	// Members added by a guardian have no password until they claim the account
+	CreateAnggotaKeluarga(ctx context.Context, arg CreateAnggotaKeluargaParams) error
 	CreateDokumenSyarat(ctx context.Context, arg CreateDokumenSyaratParams) error
 	CreateJadwalSesi(ctx context.Context, arg CreateJadwalSesiParams) (uuid.UUID, error)
+	// A KK already registered by another household member is kept as-is
+	CreateKartuKeluarga(ctx context.Context, arg CreateKartuKeluargaParams) error
+	CreateKecamatan(ctx context.Context, arg CreateKecamatanParams) (RefKecamatan, error)
 	CreateKelurahan(ctx context.Context, arg CreateKelurahanParams) (RefKelurahan, error)
+	// Issuing a new code spends any earlier one
+	CreateKodeResetPassword(ctx context.Context, arg CreateKodeResetPasswordParams) error
+	CreateLokasiLayanan(ctx context.Context, arg CreateLokasiLayananParams) (LokasiLayanan, error)
 	CreatePenduduk(ctx context.Context, arg CreatePendudukParams) (Penduduk, error)
+	CreatePenerimaDigestSLA(ctx context.Context, arg CreatePenerimaDigestSLAParams) error
+	// A citizen has at most one link request open; a second guardian asking for
+	// the same citizen is refused
+	CreatePermintaanAnggota(ctx context.Context, arg CreatePermintaanAnggotaParams) (int64, error)
 	CreatePermohonan(ctx context.Context, arg CreatePermohonanParams) (uuid.UUID, error)
 	CreatePetugas(ctx context.Context, arg CreatePetugasParams) (Petugas, error)
+	CreateSesiLogin(ctx context.Context, arg CreateSesiLoginParams) error
+	DeleteExpiredSesiLogin(ctx context.Context) error
 	DeleteJadwalSesi(ctx context.Context, arg DeleteJadwalSesiParams) error
-	GetAdminDashboardStats(ctx context.Context, kelurahanID pgtype.Int2) (GetAdminDashboardStatsRow, error)
+	DeleteKodePemulihan(ctx context.Context, petugasID uuid.UUID) error
+	DeletePermintaanAnggota(ctx context.Context, nik string) (int64, error)
+	DeletePetugasTotp(ctx context.Context, petugasID uuid.UUID) error
+	DeleteSesiLogin(ctx context.Context, tokenHash string) error
+	DeleteSesiLoginByUser(ctx context.Context, arg DeleteSesiLoginByUserParams) error
+	DeleteTampilanTersimpan(ctx context.Context, arg DeleteTampilanTersimpanParams) error
+	DeleteTargetSLA(ctx context.Context, arg DeleteTargetSLAParams) error
+	// Admin Kota: no filter, Admin Kecamatan: their kecamatan, Admin Kelurahan: their kelurahan
+	GetAdminDashboardStats(ctx context.Context, arg GetAdminDashboardStatsParams) (GetAdminDashboardStatsRow, error)
+	GetAturanBooking(ctx context.Context, lokasiID int16) (AturanBooking, error)
 	GetDokumenByPermohonan(ctx context.Context, permohonanID pgtype.UUID) ([]GetDokumenByPermohonanRow, error)
+	// Failed lookups of one booking code from one address, for the backoff
+	// between guesses. Without failures terakhir is the zero time.Time.
+	GetGagalLacakByIPKode(ctx context.Context, arg GetGagalLacakByIPKodeParams) (GetGagalLacakByIPKodeRow, error)
 	GetJadwalSesiById(ctx context.Context, id uuid.UUID) (GetJadwalSesiByIdRow, error)
+	GetKartuKeluarga(ctx context.Context, noKk string) (KartuKeluarga, error)
+	GetKecamatanById(ctx context.Context, id int16) (GetKecamatanByIdRow, error)
+	GetKecamatanByKodeWilayah(ctx context.Context, kodeWilayah string) (RefKecamatan, error)
 	GetKelurahanById(ctx context.Context, id int16) (GetKelurahanByIdRow, error)
 	GetKelurahanByKodeArea(ctx context.Context, kodeArea string) (RefKelurahan, error)
+	GetKodeResetPasswordAktif(ctx context.Context, nik string) (KodeResetPassword, error)
+	GetKunciLogin(ctx context.Context, arg GetKunciLoginParams) (KunciLogin, error)
+	GetKunciLoginById(ctx context.Context, id uuid.UUID) (KunciLogin, error)
+	// The same figures over the whole period
+	GetLaporanDurasi(ctx context.Context, arg GetLaporanDurasiParams) (GetLaporanDurasiRow, error)
+	GetLokasiLayananById(ctx context.Context, id int16) (LokasiLayanan, error)
+	// NULL kelurahan_id returns the kantor kecamatan
+	GetLokasiLayananByWilayah(ctx context.Context, arg GetLokasiLayananByWilayahParams) (LokasiLayanan, error)
 	GetPendudukByNIK(ctx context.Context, nik string) (Penduduk, error)
 	GetPendudukProfile(ctx context.Context, nik string) (GetPendudukProfileRow, error)
-	GetPendudukStatsAdmin(ctx context.Context, kelurahanID pgtype.Int2) (GetPendudukStatsAdminRow, error)
+	GetPendudukStatsAdmin(ctx context.Context, arg GetPendudukStatsAdminParams) (GetPendudukStatsAdminRow, error)
+	GetPenugasanPermohonan(ctx context.Context, id uuid.UUID) (GetPenugasanPermohonanRow, error)
 	GetPermohonanByKodeBooking(ctx context.Context, kodeBooking pgtype.Text) (GetPermohonanByKodeBookingRow, error)
+	// Applications for the citizen and those they submitted for household members
 	GetPermohonanByNIK(ctx context.Context, arg GetPermohonanByNIKParams) ([]GetPermohonanByNIKRow, error)
+	// Looks a booking up for the check-in counter
+	GetPermohonanCheckIn(ctx context.Context, kodeBooking pgtype.Text) (GetPermohonanCheckInRow, error)
 	GetPermohonanDetail(ctx context.Context, id uuid.UUID) (GetPermohonanDetailRow, error)
 	GetPermohonanDetailAdmin(ctx context.Context, arg GetPermohonanDetailAdminParams) (GetPermohonanDetailAdminRow, error)
 	GetPermohonanStatusById(ctx context.Context, id uuid.UUID) (GetPermohonanStatusByIdRow, error)
+	GetPermohonanWilayah(ctx context.Context, id uuid.UUID) (GetPermohonanWilayahRow, error)
+	GetPetugasById(ctx context.Context, id uuid.UUID) (Petugas, error)
 	GetPetugasByNIP(ctx context.Context, nip pgtype.Text) (Petugas, error)
 	GetPetugasByUsername(ctx context.Context, username string) (Petugas, error)
-	GetPetugasStatsAdmin(ctx context.Context) (GetPetugasStatsAdminRow, error)
+	GetPetugasStatsAdmin(ctx context.Context, arg GetPetugasStatsAdminParams) (GetPetugasStatsAdminRow, error)
+	GetPetugasTotp(ctx context.Context, petugasID uuid.UUID) (PetugasTotp, error)
 	GetRiwayatStatusByPermohonan(ctx context.Context, permohonanID pgtype.UUID) ([]GetRiwayatStatusByPermohonanRow, error)
+	GetSesiLogin(ctx context.Context, tokenHash string) (SesiLogin, error)
+	GetWajib2FA(ctx context.Context, role string) (bool, error)
+	// Whether the citizen has a completed application of the given type
+	HasPermohonanSelesai(ctx context.Context, arg HasPermohonanSelesaiParams) (bool, error)
 	IncrementKuotaTerisi(ctx context.Context, id uuid.UUID) error
+	// Counts a try before the code is compared; no row means the code is spent
+	// or out of tries, so parallel guesses cannot exceed max_tries
+	IncrementPercobaanKodeReset(ctx context.Context, arg IncrementPercobaanKodeResetParams) (int16, error)
+	InsertKodePemulihan(ctx context.Context, arg InsertKodePemulihanParams) error
+	InsertLogEkspor(ctx context.Context, arg InsertLogEksporParams) error
+	InsertPercobaanLacak(ctx context.Context, arg InsertPercobaanLacakParams) error
+	InsertPercobaanLogin(ctx context.Context, arg InsertPercobaanLoginParams) error
+	InsertRiwayatPetugas(ctx context.Context, arg InsertRiwayatPetugasParams) error
 	InsertRiwayatStatus(ctx context.Context, arg InsertRiwayatStatusParams) error
+	// Recipients of the SLA digest; admins without an email are reported and
+	// skipped
+	ListAdminKecamatanAktif(ctx context.Context) ([]ListAdminKecamatanAktifRow, error)
 	ListAllKelurahan(ctx context.Context) ([]ListAllKelurahanRow, error)
+	ListAnggotaKeluarga(ctx context.Context, noKk pgtype.Text) ([]ListAnggotaKeluargaRow, error)
+	// One page of a session's queue. sort_by is antrian, nama or status.
+	ListAntrianJadwal(ctx context.Context, arg ListAntrianJadwalParams) ([]ListAntrianJadwalRow, error)
 	ListJadwalSesi(ctx context.Context, arg ListJadwalSesiParams) ([]ListJadwalSesiRow, error)
+	ListKecamatan(ctx context.Context) ([]RefKecamatan, error)
+	// Arrivals recorded for the sessions of one day in the wilayah. sort_by is
+	// hadir, nama or antrian.
+	ListKehadiranAdmin(ctx context.Context, arg ListKehadiranAdminParams) ([]ListKehadiranAdminRow, error)
 	ListKelurahan(ctx context.Context) ([]RefKelurahan, error)
+	// One page of the warga, and with with_petugas the petugas, locked out in the
+	// wilayah. The identifier is the NIK of a warga and the NIP, or else the
+	// username, of a petugas. Petugas lockouts are keyed by the petugas ID;
+	// guesses at unknown NIPs have no account and are not listed. sort_by is
+	// sampai, nama or gagal.
+	ListKunciLoginAdmin(ctx context.Context, arg ListKunciLoginAdminParams) ([]ListKunciLoginAdminRow, error)
+	// Average and 90th percentile hours from VERIFIKASI to SELESAI per week of
+	// completion
+	ListLaporanDurasiMingguan(ctx context.Context, arg ListLaporanDurasiMingguanParams) ([]ListLaporanDurasiMingguanRow, error)
+	// Rejections per reason from dari onwards, most common first
+	ListLaporanPenolakan(ctx context.Context, arg ListLaporanPenolakanParams) ([]ListLaporanPenolakanRow, error)
+	// Applications per kelurahan of the applicant from dari onwards, busiest first
+	ListLaporanPermohonanKelurahan(ctx context.Context, arg ListLaporanPermohonanKelurahanParams) ([]ListLaporanPermohonanKelurahanRow, error)
+	// Applications per week and jenis from dari onwards
+	ListLaporanPermohonanMingguan(ctx context.Context, arg ListLaporanPermohonanMingguanParams) ([]ListLaporanPermohonanMingguanRow, error)
+	// Quota use of sessions up to hari_ini per week. Attendance counts only past
+	// sessions where check-in was used.
+	ListLaporanSesiMingguan(ctx context.Context, arg ListLaporanSesiMingguanParams) ([]ListLaporanSesiMingguanRow, error)
+	ListLokasiLayanan(ctx context.Context, arg ListLokasiLayananParams) ([]LokasiLayanan, error)
+	// sort_by is tanggal, nama, kelurahan or relevansi, which ranks the closest
+	// matches to search first
 	ListPendudukAdmin(ctx context.Context, arg ListPendudukAdminParams) ([]ListPendudukAdminRow, error)
+	ListPenerimaDigestSLA(ctx context.Context, tanggal pgtype.Date) ([]uuid.UUID, error)
+	ListPermintaanAnggotaByKK(ctx context.Context, noKk string) ([]ListPermintaanAnggotaByKKRow, error)
+	// Link requests waiting for a petugas, in the wilayah of the citizen to be
+	// linked
+	ListPermintaanAnggotaMenunggu(ctx context.Context, arg ListPermintaanAnggotaMenungguParams) ([]ListPermintaanAnggotaMenungguRow, error)
+	ListPermissionByRole(ctx context.Context, role string) ([]string, error)
+	// sort_by is tanggal, nama, status, sesi or relevansi; status sorts in
+	// workflow order and relevansi ranks the closest matches to search first.
+	// Names also match search when they are similar rather than equal, so
+	// "Muhamad" finds "Muhammad".
+	// daftar_sampai is exclusive; tertahan_hari keeps applications whose status
+	// has not changed for that many days, and lewat_sla those past the SLA
+	// target of their status. ditangani_oleh is the queue of one petugas and
+	// belum_ditangani the unassigned applications; both keep open ones only.
 	ListPermohonanAdmin(ctx context.Context, arg ListPermohonanAdminParams) ([]ListPermohonanAdminRow, error)
+	// Open applications within the scope that nobody holds, oldest first
+	ListPermohonanBelumDitangani(ctx context.Context, arg ListPermohonanBelumDitanganiParams) ([]ListPermohonanBelumDitanganiRow, error)
 	ListPermohonanByJadwal(ctx context.Context, jadwalSesiID pgtype.UUID) ([]ListPermohonanByJadwalRow, error)
 	ListPermohonanByStatus(ctx context.Context, arg ListPermohonanByStatusParams) ([]ListPermohonanByStatusRow, error)
-	ListPetugasAdmin(ctx context.Context) ([]ListPetugasAdminRow, error)
-	ListTodayJadwal(ctx context.Context, kelurahanID pgtype.Int2) ([]ListTodayJadwalRow, error)
+	// Applications past the SLA target of their status, by kecamatan and
+	// kelurahan of the lokasi, longest waiting first
+	ListPermohonanLewatSLA(ctx context.Context) ([]ListPermohonanLewatSLARow, error)
+	// sort_by is tanggal, nama, role or status
+	ListPetugasAdmin(ctx context.Context, arg ListPetugasAdminParams) ([]ListPetugasAdminRow, error)
+	// Active petugas who may verify the application and whose wilayah covers
+	// its lokasi, kelurahan staff first. beban counts the open applications
+	// each one holds.
+	ListPetugasPenangan(ctx context.Context, id uuid.UUID) ([]ListPetugasPenanganRow, error)
+	ListRiwayatPetugas(ctx context.Context, petugasID uuid.UUID) ([]ListRiwayatPetugasRow, error)
+	ListSesiLoginByUser(ctx context.Context, arg ListSesiLoginByUserParams) ([]ListSesiLoginByUserRow, error)
+	// Documents an application of the given type needs, whose originals the
+	// citizen brings to the session
+	ListSyaratDokumen(ctx context.Context, jenisPermohonan string) ([]string, error)
+	ListTampilanTersimpan(ctx context.Context, arg ListTampilanTersimpanParams) ([]TampilanTersimpan, error)
+	// Household members the given citizen may apply for. The head of household
+	// and their spouse are the household's guardians once a petugas has verified
+	// their place in the KK.
+	ListTanggunganKeluarga(ctx context.Context, nik string) ([]ListTanggunganKeluargaRow, error)
+	ListTargetSLA(ctx context.Context) ([]TargetSla, error)
+	ListTodayJadwal(ctx context.Context, arg ListTodayJadwalParams) ([]ListTodayJadwalRow, error)
+	// Guardian claims waiting for a petugas to check them against the KK
+	ListWaliMenunggu(ctx context.Context, arg ListWaliMenungguParams) ([]ListWaliMenungguRow, error)
+	LockKunciLogin(ctx context.Context, arg LockKunciLoginParams) (pgtype.Timestamptz, error)
+	// Serialises new applications for one NIK until the transaction ends, so
+	// the active and monthly counts cannot be raced by parallel submits
+	LockPermohonanNIK(ctx context.Context, nik string) error
+	// Picks the next petugas for round-robin distribution: among the staff of
+	// the lokasi's kelurahan, or its admin kecamatan when the kelurahan has
+	// none, the one holding the fewest open applications, then the one who was
+	// assigned one longest ago. Admin kota never receive work this way.
+	PickPetugasBergilir(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+	// A failure more than a day after the previous one starts a new streak
+	RecordGagalLogin(ctx context.Context, arg RecordGagalLoginParams) (int32, error)
+	// Records arrival once; returns no rows when it was already recorded
+	RecordKehadiran(ctx context.Context, arg RecordKehadiranParams) (pgtype.Timestamptz, error)
+	RefreshLaporanDurasi(ctx context.Context) error
+	RefreshLaporanPenolakan(ctx context.Context) error
+	RefreshLaporanPermohonan(ctx context.Context) error
+	RefreshLaporanSesi(ctx context.Context) error
+	// The citizen is unlinked from the KK and may link it again with the
+	// relationship printed on it
+	RejectWaliPenduduk(ctx context.Context, nik string) (int64, error)
+	ResetKunciLogin(ctx context.Context, arg ResetKunciLoginParams) error
+	// Saving under an existing name replaces that view
+	SaveTampilanTersimpan(ctx context.Context, arg SaveTampilanTersimpanParams) error
+	// A new place in a KK has to be verified again before it grants guardianship
+	SetKeluargaPenduduk(ctx context.Context, arg SetKeluargaPendudukParams) error
+	SetPendudukPassword(ctx context.Context, arg SetPendudukPasswordParams) error
+	SetPetugasActive(ctx context.Context, arg SetPetugasActiveParams) error
+	// Throttled so that busy pages do not write on every request
+	TouchSesiLogin(ctx context.Context, id uuid.UUID) error
 	TruncateSeedTables(ctx context.Context) error
+	UnlockKunciLogin(ctx context.Context, arg UnlockKunciLoginParams) error
 	UpdateJadwalSesi(ctx context.Context, arg UpdateJadwalSesiParams) error
+	UpdateLokasiLayanan(ctx context.Context, arg UpdateLokasiLayananParams) error
 	UpdatePermohonanStatus(ctx context.Context, arg UpdatePermohonanStatusParams) error
-	UpdatePermohonanStatusAdmin(ctx context.Context, arg UpdatePermohonanStatusAdminParams) error
+	// Changes the status only for the petugas holding the application and only
+	// at the versi their form was loaded with; no rows means someone else holds
+	// it or it changed in the meantime.
+	UpdatePermohonanStatusAdmin(ctx context.Context, arg UpdatePermohonanStatusAdminParams) (int64, error)
+	UpdatePetugasPassword(ctx context.Context, arg UpdatePetugasPasswordParams) error
+	UpdatePetugasProfil(ctx context.Context, arg UpdatePetugasProfilParams) error
+	// Affects no row when the step was already used, which rejects replays
+	UpdatePetugasTotpStep(ctx context.Context, arg UpdatePetugasTotpStepParams) (int64, error)
+	// The role trigger derives the new role from the wilayah columns
+	UpdatePetugasWilayah(ctx context.Context, arg UpdatePetugasWilayahParams) error
+	UpsertAturanBooking(ctx context.Context, arg UpsertAturanBookingParams) (AturanBooking, error)
+	// Starts or restarts an enrolment; an active secret is never replaced here
+	UpsertPetugasTotpPending(ctx context.Context, arg UpsertPetugasTotpPendingParams) error
+	UpsertTargetSLA(ctx context.Context, arg UpsertTargetSLAParams) error
+	UseKodePemulihan(ctx context.Context, arg UseKodePemulihanParams) (int64, error)
+	// No row means another request used the code first
+	UseKodeResetPassword(ctx context.Context, id uuid.UUID) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
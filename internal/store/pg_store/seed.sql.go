@@ -11,52 +11,124 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const createKecamatan = `-- name: CreateKecamatan :one
+INSERT INTO ref_kecamatan (nama_kecamatan, nama_kota, kode_wilayah)
+VALUES ($1, $2, $3)
+RETURNING id, nama_kecamatan, nama_kota, kode_wilayah, created_at
+`
+
+type CreateKecamatanParams struct {
+	NamaKecamatan string `json:"namaKecamatan"`
+	NamaKota      string `json:"namaKota"`
+	KodeWilayah   string `json:"kodeWilayah"`
+}
+
+func (q *Queries) CreateKecamatan(ctx context.Context, arg CreateKecamatanParams) (RefKecamatan, error) {
+	row := q.db.QueryRow(ctx, createKecamatan, arg.NamaKecamatan, arg.NamaKota, arg.KodeWilayah)
+	var i RefKecamatan
+	err := row.Scan(
+		&i.ID,
+		&i.NamaKecamatan,
+		&i.NamaKota,
+		&i.KodeWilayah,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createKelurahan = `-- name: CreateKelurahan :one
-INSERT INTO ref_kelurahan (nama_kelurahan, kode_area)
-VALUES ($1, $2)
-RETURNING id, nama_kelurahan, kode_area, created_at
+INSERT INTO ref_kelurahan (kecamatan_id, nama_kelurahan, kode_area)
+VALUES ($1, $2, $3)
+RETURNING id, nama_kelurahan, kode_area, created_at, kecamatan_id
 `
 
 type CreateKelurahanParams struct {
+	KecamatanID   int16  `json:"kecamatanId"`
 	NamaKelurahan string `json:"namaKelurahan"`
 	KodeArea      string `json:"kodeArea"`
 }
 
 func (q *Queries) CreateKelurahan(ctx context.Context, arg CreateKelurahanParams) (RefKelurahan, error) {
-	row := q.db.QueryRow(ctx, createKelurahan, arg.NamaKelurahan, arg.KodeArea)
+	row := q.db.QueryRow(ctx, createKelurahan, arg.KecamatanID, arg.NamaKelurahan, arg.KodeArea)
 	var i RefKelurahan
 	err := row.Scan(
 		&i.ID,
 		&i.NamaKelurahan,
 		&i.KodeArea,
 		&i.CreatedAt,
+		&i.KecamatanID,
+	)
+	return i, err
+}
+
+const createLokasiLayanan = `-- name: CreateLokasiLayanan :one
+INSERT INTO lokasi_layanan (kecamatan_id, kelurahan_id, kode, nama_lokasi, alamat, jam_buka, jam_tutup)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, kecamatan_id, kelurahan_id, kode, nama_lokasi, alamat, jam_buka, jam_tutup, created_at
+`
+
+type CreateLokasiLayananParams struct {
+	KecamatanID int16       `json:"kecamatanId"`
+	KelurahanID pgtype.Int2 `json:"kelurahanId"`
+	Kode        string      `json:"kode"`
+	NamaLokasi  string      `json:"namaLokasi"`
+	Alamat      pgtype.Text `json:"alamat"`
+	JamBuka     pgtype.Time `json:"jamBuka"`
+	JamTutup    pgtype.Time `json:"jamTutup"`
+}
+
+func (q *Queries) CreateLokasiLayanan(ctx context.Context, arg CreateLokasiLayananParams) (LokasiLayanan, error) {
+	row := q.db.QueryRow(ctx, createLokasiLayanan,
+		arg.KecamatanID,
+		arg.KelurahanID,
+		arg.Kode,
+		arg.NamaLokasi,
+		arg.Alamat,
+		arg.JamBuka,
+		arg.JamTutup,
+	)
+	var i LokasiLayanan
+	err := row.Scan(
+		&i.ID,
+		&i.KecamatanID,
+		&i.KelurahanID,
+		&i.Kode,
+		&i.NamaLokasi,
+		&i.Alamat,
+		&i.JamBuka,
+		&i.JamTutup,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const createPetugas = `-- name: CreatePetugas :one
-INSERT INTO petugas (kelurahan_id, nip, nama_petugas, created_by, username, password_hash)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, kelurahan_id, nip, nama_petugas, created_by, username, password_hash, is_active, created_at, role
+INSERT INTO petugas (kecamatan_id, kelurahan_id, nip, nama_petugas, created_by, username, password_hash, email)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, kelurahan_id, nip, nama_petugas, created_by, username, password_hash, is_active, created_at, role, kecamatan_id, updated_by, updated_at, email
 `
 
 type CreatePetugasParams struct {
+	KecamatanID  pgtype.Int2 `json:"kecamatanId"`
 	KelurahanID  pgtype.Int2 `json:"kelurahanId"`
 	Nip          pgtype.Text `json:"nip"`
 	NamaPetugas  string      `json:"namaPetugas"`
 	CreatedBy    pgtype.UUID `json:"createdBy"`
 	Username     string      `json:"username"`
 	PasswordHash string      `json:"passwordHash"`
+	Email        pgtype.Text `json:"email"`
 }
 
 func (q *Queries) CreatePetugas(ctx context.Context, arg CreatePetugasParams) (Petugas, error) {
 	row := q.db.QueryRow(ctx, createPetugas,
+		arg.KecamatanID,
 		arg.KelurahanID,
 		arg.Nip,
 		arg.NamaPetugas,
 		arg.CreatedBy,
 		arg.Username,
 		arg.PasswordHash,
+		arg.Email,
 	)
 	var i Petugas
 	err := row.Scan(
@@ -70,12 +142,33 @@ func (q *Queries) CreatePetugas(ctx context.Context, arg CreatePetugasParams) (P
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.Role,
+		&i.KecamatanID,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+		&i.Email,
+	)
+	return i, err
+}
+
+const getKecamatanByKodeWilayah = `-- name: GetKecamatanByKodeWilayah :one
+SELECT id, nama_kecamatan, nama_kota, kode_wilayah, created_at FROM ref_kecamatan WHERE kode_wilayah = $1
+`
+
+func (q *Queries) GetKecamatanByKodeWilayah(ctx context.Context, kodeWilayah string) (RefKecamatan, error) {
+	row := q.db.QueryRow(ctx, getKecamatanByKodeWilayah, kodeWilayah)
+	var i RefKecamatan
+	err := row.Scan(
+		&i.ID,
+		&i.NamaKecamatan,
+		&i.NamaKota,
+		&i.KodeWilayah,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getKelurahanByKodeArea = `-- name: GetKelurahanByKodeArea :one
-SELECT id, nama_kelurahan, kode_area, created_at FROM ref_kelurahan WHERE kode_area = $1
+SELECT id, nama_kelurahan, kode_area, created_at, kecamatan_id FROM ref_kelurahan WHERE kode_area = $1
 `
 
 func (q *Queries) GetKelurahanByKodeArea(ctx context.Context, kodeArea string) (RefKelurahan, error) {
@@ -86,12 +179,13 @@ func (q *Queries) GetKelurahanByKodeArea(ctx context.Context, kodeArea string) (
 		&i.NamaKelurahan,
 		&i.KodeArea,
 		&i.CreatedAt,
+		&i.KecamatanID,
 	)
 	return i, err
 }
 
 const getPetugasByUsername = `-- name: GetPetugasByUsername :one
-SELECT id, kelurahan_id, nip, nama_petugas, created_by, username, password_hash, is_active, created_at, role FROM petugas WHERE username = $1
+SELECT id, kelurahan_id, nip, nama_petugas, created_by, username, password_hash, is_active, created_at, role, kecamatan_id, updated_by, updated_at, email FROM petugas WHERE username = $1
 `
 
 func (q *Queries) GetPetugasByUsername(ctx context.Context, username string) (Petugas, error) {
@@ -108,12 +202,46 @@ func (q *Queries) GetPetugasByUsername(ctx context.Context, username string) (Pe
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.Role,
+		&i.KecamatanID,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+		&i.Email,
 	)
 	return i, err
 }
 
+const listKecamatan = `-- name: ListKecamatan :many
+SELECT id, nama_kecamatan, nama_kota, kode_wilayah, created_at FROM ref_kecamatan ORDER BY nama_kota, nama_kecamatan
+`
+
+func (q *Queries) ListKecamatan(ctx context.Context) ([]RefKecamatan, error) {
+	rows, err := q.db.Query(ctx, listKecamatan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefKecamatan
+	for rows.Next() {
+		var i RefKecamatan
+		if err := rows.Scan(
+			&i.ID,
+			&i.NamaKecamatan,
+			&i.NamaKota,
+			&i.KodeWilayah,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listKelurahan = `-- name: ListKelurahan :many
-SELECT id, nama_kelurahan, kode_area, created_at FROM ref_kelurahan ORDER BY id
+SELECT id, nama_kelurahan, kode_area, created_at, kecamatan_id FROM ref_kelurahan ORDER BY id
 `
 
 func (q *Queries) ListKelurahan(ctx context.Context) ([]RefKelurahan, error) {
@@ -130,6 +258,7 @@ func (q *Queries) ListKelurahan(ctx context.Context) ([]RefKelurahan, error) {
 			&i.NamaKelurahan,
 			&i.KodeArea,
 			&i.CreatedAt,
+			&i.KecamatanID,
 		); err != nil {
 			return nil, err
 		}
@@ -142,7 +271,7 @@ func (q *Queries) ListKelurahan(ctx context.Context) ([]RefKelurahan, error) {
 }
 
 const truncateSeedTables = `-- name: TruncateSeedTables :exec
-TRUNCATE riwayat_status, dokumen_syarat, permohonan, jadwal_sesi, petugas, penduduk, ref_kelurahan RESTART IDENTITY CASCADE
+TRUNCATE riwayat_status, dokumen_syarat, permohonan, jadwal_sesi, aturan_booking, lokasi_layanan, petugas, penduduk, ref_kelurahan, ref_kecamatan RESTART IDENTITY CASCADE
 `
 
 func (q *Queries) TruncateSeedTables(ctx context.Context) error {
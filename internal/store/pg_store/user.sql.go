@@ -47,6 +47,43 @@ func (q *Queries) CountPermohonanByNIK(ctx context.Context, nik pgtype.Text) (Co
 	return i, err
 }
 
+const countPermohonanByPemohon = `-- name: CountPermohonanByPemohon :one
+SELECT 
+    COUNT(*) as total,
+    COUNT(*) FILTER (WHERE status_terkini = 'VERIFIKASI') as verifikasi,
+    COUNT(*) FILTER (WHERE status_terkini = 'PROSES') as proses,
+    COUNT(*) FILTER (WHERE status_terkini = 'SIAP_AMBIL') as siap_ambil,
+    COUNT(*) FILTER (WHERE status_terkini = 'SELESAI') as selesai,
+    COUNT(*) FILTER (WHERE status_terkini = 'DITOLAK') as ditolak
+FROM permohonan 
+WHERE nik = $1 OR pemohon_nik = $1
+`
+
+type CountPermohonanByPemohonRow struct {
+	Total      int64 `json:"total"`
+	Verifikasi int64 `json:"verifikasi"`
+	Proses     int64 `json:"proses"`
+	SiapAmbil  int64 `json:"siapAmbil"`
+	Selesai    int64 `json:"selesai"`
+	Ditolak    int64 `json:"ditolak"`
+}
+
+// Counts applications for the citizen together with those they submitted
+// for household members
+func (q *Queries) CountPermohonanByPemohon(ctx context.Context, nik pgtype.Text) (CountPermohonanByPemohonRow, error) {
+	row := q.db.QueryRow(ctx, countPermohonanByPemohon, nik)
+	var i CountPermohonanByPemohonRow
+	err := row.Scan(
+		&i.Total,
+		&i.Verifikasi,
+		&i.Proses,
+		&i.SiapAmbil,
+		&i.Selesai,
+		&i.Ditolak,
+	)
+	return i, err
+}
+
 const getPendudukProfile = `-- name: GetPendudukProfile :one
 SELECT 
     p.nik,
@@ -56,21 +93,25 @@ SELECT
     p.no_hp,
     p.jenis_kelamin,
     p.created_at,
-    k.nama_kelurahan
+    k.nama_kelurahan,
+    p.tanggal_lahir,
+    p.status_perkawinan
 FROM penduduk p
 LEFT JOIN ref_kelurahan k ON p.kelurahan_id = k.id
 WHERE p.nik = $1
 `
 
 type GetPendudukProfileRow struct {
-	Nik           string           `json:"nik"`
-	NamaLengkap   string           `json:"namaLengkap"`
-	Email         pgtype.Text      `json:"email"`
-	Alamat        pgtype.Text      `json:"alamat"`
-	NoHp          pgtype.Text      `json:"noHp"`
-	JenisKelamin  string           `json:"jenisKelamin"`
-	CreatedAt     pgtype.Timestamp `json:"createdAt"`
-	NamaKelurahan pgtype.Text      `json:"namaKelurahan"`
+	Nik              string             `json:"nik"`
+	NamaLengkap      string             `json:"namaLengkap"`
+	Email            pgtype.Text        `json:"email"`
+	Alamat           pgtype.Text        `json:"alamat"`
+	NoHp             pgtype.Text        `json:"noHp"`
+	JenisKelamin     string             `json:"jenisKelamin"`
+	CreatedAt        pgtype.Timestamptz `json:"createdAt"`
+	NamaKelurahan    pgtype.Text        `json:"namaKelurahan"`
+	TanggalLahir     pgtype.Date        `json:"tanggalLahir"`
+	StatusPerkawinan string             `json:"statusPerkawinan"`
 }
 
 func (q *Queries) GetPendudukProfile(ctx context.Context, nik string) (GetPendudukProfileRow, error) {
@@ -85,6 +126,8 @@ func (q *Queries) GetPendudukProfile(ctx context.Context, nik string) (GetPendud
 		&i.JenisKelamin,
 		&i.CreatedAt,
 		&i.NamaKelurahan,
+		&i.TanggalLahir,
+		&i.StatusPerkawinan,
 	)
 	return i, err
 }
@@ -100,27 +143,33 @@ SELECT
     js.tanggal as jadwal_tanggal,
     js.jam_mulai as jadwal_jam_mulai,
     js.jam_selesai as jadwal_jam_selesai,
-    k.nama_kelurahan as lokasi_kelurahan,
-    pd.nama_lengkap
+    l.nama_lokasi,
+    pd.nama_lengkap,
+    p.nik,
+    p.pemohon_nik,
+    pd.tanggal_lahir
 FROM permohonan p
 LEFT JOIN penduduk pd ON p.nik = pd.nik
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
 WHERE p.kode_booking = $1
 `
 
 type GetPermohonanByKodeBookingRow struct {
-	ID               uuid.UUID        `json:"id"`
-	KodeBooking      pgtype.Text      `json:"kodeBooking"`
-	JenisPermohonan  string           `json:"jenisPermohonan"`
-	StatusTerkini    pgtype.Text      `json:"statusTerkini"`
-	TanggalDaftar    pgtype.Timestamp `json:"tanggalDaftar"`
-	NomorAntrian     pgtype.Int2      `json:"nomorAntrian"`
-	JadwalTanggal    pgtype.Date      `json:"jadwalTanggal"`
-	JadwalJamMulai   pgtype.Time      `json:"jadwalJamMulai"`
-	JadwalJamSelesai pgtype.Time      `json:"jadwalJamSelesai"`
-	LokasiKelurahan  pgtype.Text      `json:"lokasiKelurahan"`
-	NamaLengkap      pgtype.Text      `json:"namaLengkap"`
+	ID               uuid.UUID          `json:"id"`
+	KodeBooking      pgtype.Text        `json:"kodeBooking"`
+	JenisPermohonan  string             `json:"jenisPermohonan"`
+	StatusTerkini    pgtype.Text        `json:"statusTerkini"`
+	TanggalDaftar    pgtype.Timestamptz `json:"tanggalDaftar"`
+	NomorAntrian     pgtype.Int2        `json:"nomorAntrian"`
+	JadwalTanggal    pgtype.Date        `json:"jadwalTanggal"`
+	JadwalJamMulai   pgtype.Time        `json:"jadwalJamMulai"`
+	JadwalJamSelesai pgtype.Time        `json:"jadwalJamSelesai"`
+	NamaLokasi       pgtype.Text        `json:"namaLokasi"`
+	NamaLengkap      pgtype.Text        `json:"namaLengkap"`
+	Nik              pgtype.Text        `json:"nik"`
+	PemohonNik       pgtype.Text        `json:"pemohonNik"`
+	TanggalLahir     pgtype.Date        `json:"tanggalLahir"`
 }
 
 func (q *Queries) GetPermohonanByKodeBooking(ctx context.Context, kodeBooking pgtype.Text) (GetPermohonanByKodeBookingRow, error) {
@@ -136,8 +185,11 @@ func (q *Queries) GetPermohonanByKodeBooking(ctx context.Context, kodeBooking pg
 		&i.JadwalTanggal,
 		&i.JadwalJamMulai,
 		&i.JadwalJamSelesai,
-		&i.LokasiKelurahan,
+		&i.NamaLokasi,
 		&i.NamaLengkap,
+		&i.Nik,
+		&i.PemohonNik,
+		&i.TanggalLahir,
 	)
 	return i, err
 }
@@ -153,11 +205,15 @@ SELECT
     js.tanggal as jadwal_tanggal,
     js.jam_mulai as jadwal_jam_mulai,
     js.jam_selesai as jadwal_jam_selesai,
-    k.nama_kelurahan as lokasi_kelurahan
+    l.nama_lokasi,
+    p.nik,
+    pd.nama_lengkap,
+    p.waktu_hadir
 FROM permohonan p
+LEFT JOIN penduduk pd ON p.nik = pd.nik
 LEFT JOIN jadwal_sesi js ON p.jadwal_sesi_id = js.id
-LEFT JOIN ref_kelurahan k ON js.lokasi_kelurahan_id = k.id
-WHERE p.nik = $1
+LEFT JOIN lokasi_layanan l ON js.lokasi_id = l.id
+WHERE p.nik = $1 OR p.pemohon_nik = $1
 ORDER BY p.created_at DESC
 LIMIT $2 OFFSET $3
 `
@@ -169,18 +225,22 @@ type GetPermohonanByNIKParams struct {
 }
 
 type GetPermohonanByNIKRow struct {
-	ID               uuid.UUID        `json:"id"`
-	KodeBooking      pgtype.Text      `json:"kodeBooking"`
-	JenisPermohonan  string           `json:"jenisPermohonan"`
-	StatusTerkini    pgtype.Text      `json:"statusTerkini"`
-	NomorAntrian     pgtype.Int2      `json:"nomorAntrian"`
-	CreatedAt        pgtype.Timestamp `json:"createdAt"`
-	JadwalTanggal    pgtype.Date      `json:"jadwalTanggal"`
-	JadwalJamMulai   pgtype.Time      `json:"jadwalJamMulai"`
-	JadwalJamSelesai pgtype.Time      `json:"jadwalJamSelesai"`
-	LokasiKelurahan  pgtype.Text      `json:"lokasiKelurahan"`
+	ID               uuid.UUID          `json:"id"`
+	KodeBooking      pgtype.Text        `json:"kodeBooking"`
+	JenisPermohonan  string             `json:"jenisPermohonan"`
+	StatusTerkini    pgtype.Text        `json:"statusTerkini"`
+	NomorAntrian     pgtype.Int2        `json:"nomorAntrian"`
+	CreatedAt        pgtype.Timestamptz `json:"createdAt"`
+	JadwalTanggal    pgtype.Date        `json:"jadwalTanggal"`
+	JadwalJamMulai   pgtype.Time        `json:"jadwalJamMulai"`
+	JadwalJamSelesai pgtype.Time        `json:"jadwalJamSelesai"`
+	NamaLokasi       pgtype.Text        `json:"namaLokasi"`
+	Nik              pgtype.Text        `json:"nik"`
+	NamaLengkap      pgtype.Text        `json:"namaLengkap"`
+	WaktuHadir       pgtype.Timestamptz `json:"waktuHadir"`
 }
 
+// Applications for the citizen and those they submitted for household members
 func (q *Queries) GetPermohonanByNIK(ctx context.Context, arg GetPermohonanByNIKParams) ([]GetPermohonanByNIKRow, error) {
 	rows, err := q.db.Query(ctx, getPermohonanByNIK, arg.Nik, arg.Limit, arg.Offset)
 	if err != nil {
@@ -200,7 +260,10 @@ func (q *Queries) GetPermohonanByNIK(ctx context.Context, arg GetPermohonanByNIK
 			&i.JadwalTanggal,
 			&i.JadwalJamMulai,
 			&i.JadwalJamSelesai,
-			&i.LokasiKelurahan,
+			&i.NamaLokasi,
+			&i.Nik,
+			&i.NamaLengkap,
+			&i.WaktuHadir,
 		); err != nil {
 			return nil, err
 		}
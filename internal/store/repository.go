@@ -1,9 +1,12 @@
 package store
 
 import (
+	"context"
+
 	"github.com/nobuww/simpel-ktp/internal/store/pg_store"
 )
 
 type Repository interface {
 	pg_store.Querier
+	ExecTx(ctx context.Context, fn func(*pg_store.Queries) error) error
 }
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TestWordSimilarityMatchesMisspelledName runs against a migrated database
+// named by TEST_DATABASE_URL and is skipped without one.
+func TestWordSimilarityMatchesMisspelledName(t *testing.T) {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	var threshold string
+	if err := db.QueryRow(ctx, "SELECT current_setting('pg_trgm.word_similarity_threshold')").Scan(&threshold); err != nil {
+		t.Fatalf("read threshold: %v", err)
+	}
+	if threshold != "0.6" {
+		t.Errorf("pg_trgm.word_similarity_threshold = %s, want 0.6", threshold)
+	}
+
+	tests := []struct {
+		nama   string
+		search string
+		want   bool
+	}{
+		{"Muhammad Rizki", "Muhamad", true},
+		{"Siti Muhammad", "Muhamad", true},
+		{"Budi Santoso", "Muhamad", false},
+	}
+	for _, tt := range tests {
+		var got bool
+		// The same operator ListPendudukAdmin and ListPermohonanAdmin filter with
+		if err := db.QueryRow(ctx, "SELECT $1::text %> $2::text", tt.nama, tt.search).Scan(&got); err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("%q %%> %q = %v, want %v", tt.nama, tt.search, got, tt.want)
+		}
+	}
+}
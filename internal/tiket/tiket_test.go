@@ -0,0 +1,126 @@
+package tiket
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+var testSigner = NewSigner([]byte("test-key"))
+
+func TestPayloadRoundTrip(t *testing.T) {
+	for _, kode := range []string{"KEC-20261019-001", "A", " KEC-001 "} {
+		payload := testSigner.Payload(kode)
+		if !strings.HasPrefix(payload, prefix+".") {
+			t.Errorf("Payload(%q) = %q, want the %s prefix", kode, payload, prefix)
+		}
+		got, err := testSigner.Verify(payload)
+		if err != nil {
+			t.Fatalf("Verify(Payload(%q)) = %v", kode, err)
+		}
+		if want := strings.TrimSpace(kode); got != want {
+			t.Errorf("Verify(Payload(%q)) = %q, want %q", kode, got, want)
+		}
+		// Scanners may add a newline
+		if got, err := testSigner.Verify(payload + "\n"); err != nil || got != strings.TrimSpace(kode) {
+			t.Errorf("Verify with a trailing newline = %q, %v", got, err)
+		}
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	payload := testSigner.Payload("KEC-20261019-001")
+	i := strings.LastIndexByte(payload, '.')
+	msg, sig := payload[:i], payload[i+1:]
+
+	flipped := []byte(sig)
+	flipped[0] = alphabet[(strings.IndexByte(alphabet, flipped[0])+1)%len(alphabet)]
+
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{"empty", ""},
+		{"no separator", "SKTP1KEC001"},
+		{"tampered kode", strings.Replace(msg, "001", "002", 1) + "." + sig},
+		{"tampered signature", msg + "." + string(flipped)},
+		{"truncated signature", msg + "." + sig[:len(sig)-1]},
+		{"empty signature", msg + "."},
+		{"signed with another key", NewSigner([]byte("other-key")).Payload("KEC-20261019-001")},
+		{"missing prefix", strings.TrimPrefix(payload, prefix+".")},
+		{"other prefix", "SKTP2" + strings.TrimPrefix(payload, prefix)},
+		{"empty kode", prefix + ".." + base64.RawURLEncoding.EncodeToString(testSigner.mac(prefix+"."))},
+		{"padded signature", payload + "=="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kode, err := testSigner.Verify(tt.payload); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Verify(%q) = %q, %v; want %v", tt.payload, kode, err, ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsAlternativeSpelling(t *testing.T) {
+	payload := testSigner.Payload("KEC-20261019-001")
+	i := strings.LastIndexByte(payload, '.')
+	msg, sig := payload[:i], payload[i+1:]
+
+	// 16 bytes take 22 characters, the last of which carries 2 bits of data
+	// and 4 unused bits. Setting an unused bit decodes to the same MAC.
+	last := strings.IndexByte(alphabet, sig[len(sig)-1])
+	alt := sig[:len(sig)-1] + string(alphabet[last^1])
+
+	want, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(alt)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("alternative spelling decodes to %x, %v; the test expects %x", got, err, want)
+	}
+	if _, err := testSigner.Verify(msg + "." + alt); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify accepted the alternative spelling %q of %q", alt, sig)
+	}
+
+	// The standard alphabet spells 62 and 63 as + and /
+	for n := 0; ; n++ {
+		payload := testSigner.Payload("KEC-" + strings.Repeat("0", n))
+		i := strings.LastIndexByte(payload, '.')
+		msg, sig := payload[:i], payload[i+1:]
+		if !strings.ContainsAny(sig, "-_") {
+			continue
+		}
+		std := base64.RawStdEncoding.EncodeToString(testSigner.mac(msg))
+		if _, err := testSigner.Verify(msg + "." + std); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Verify accepted the standard base64 spelling %q of %q", std, sig)
+		}
+		break
+	}
+}
+
+func TestRefIsNotAPayloadSignature(t *testing.T) {
+	for _, nik := range []string{"3172050101800001", "KEC-20261019-001", ""} {
+		ref := testSigner.Ref(nik)
+		if ref != testSigner.Ref(nik) {
+			t.Errorf("Ref(%q) is not stable", nik)
+		}
+		payload := testSigner.Payload(nik)
+		if sig := payload[strings.LastIndexByte(payload, '.')+1:]; ref == sig {
+			t.Errorf("Ref(%q) equals the payload signature", nik)
+		}
+		if _, err := testSigner.Verify(prefix + "." + nik + "." + ref); !errors.Is(err, ErrInvalid) {
+			t.Errorf("a ref verified as the signature of %q", nik)
+		}
+	}
+	if testSigner.Ref("3172050101800001") == testSigner.Ref("3172050101800002") {
+		t.Error("two NIKs share a ref")
+	}
+	if testSigner.Ref("3172050101800001") == NewSigner([]byte("other-key")).Ref("3172050101800001") {
+		t.Error("refs do not depend on the key")
+	}
+}